@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github-gold-miner/internal/service"
+)
+
+const chatHelp = `可用命令:
+  /search <问题>     按新的问题检索候选项目并加入当前对话
+  /open <序号|ID>    在浏览器中打开项目
+  /save [序号|ID...] 收藏项目 (默认收藏上一次推荐的全部项目)，写入收藏文件
+  /shortlist         查看收藏
+  /export <文件>     导出对话，.json 结尾导出 JSON，否则导出 Markdown
+  /reset             开始新的对话 (收藏保留)
+  /help              显示帮助
+  /quit              退出
+直接输入问题即可对话，例如 "有没有命令行编程 Agent"、"只要 Go 的"、"对比前两个"`
+
+// runChat 交互式多轮检索
+func runChat(chat *service.ChatService, in io.Reader, out io.Writer, shortlistFile string) {
+	fmt.Fprintln(out, "💬 进入对话模式，输入 /help 查看命令，/quit 退出")
+
+	ctx := context.Background()
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "\n> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "/") {
+			reply, err := chat.Ask(ctx, line)
+			if err != nil {
+				fmt.Fprintf(out, "❌ %v\n", err)
+				continue
+			}
+			fmt.Fprintln(out, reply.Message)
+			if len(reply.Results) > 0 {
+				fmt.Fprintln(out)
+				fmt.Fprint(out, formatSearchResults(reply.Results))
+			}
+			continue
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "/quit", "/exit":
+			return
+		case "/help":
+			fmt.Fprintln(out, chatHelp)
+		case "/search":
+			if arg == "" {
+				fmt.Fprintln(out, "⚠️ 用法: /search <问题>")
+				continue
+			}
+			added, err := chat.Retrieve(ctx, arg)
+			if err != nil {
+				fmt.Fprintf(out, "❌ %v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "📚 新增 %d 个候选项目\n", added)
+		case "/open":
+			repo, err := chat.Resolve(arg)
+			if err != nil {
+				fmt.Fprintf(out, "❌ %v\n", err)
+				continue
+			}
+			if err := openBrowser(repo.URL); err != nil {
+				fmt.Fprintf(out, "⚠️ 无法打开浏览器 (%v)，请手动访问 %s\n", err, repo.URL)
+				continue
+			}
+			fmt.Fprintf(out, "🌐 已打开 %s\n", repo.URL)
+		case "/save":
+			added, err := chat.Save(strings.Fields(arg)...)
+			if err != nil {
+				fmt.Fprintf(out, "❌ %v\n", err)
+				continue
+			}
+			if err := writeFile(shortlistFile, chat.WriteShortlist); err != nil {
+				fmt.Fprintf(out, "❌ 保存收藏失败: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "⭐ 新收藏 %d 个项目，共 %d 个，已写入 %s\n", len(added), len(chat.Shortlist()), shortlistFile)
+		case "/shortlist":
+			if len(chat.Shortlist()) == 0 {
+				fmt.Fprintln(out, "还没有收藏项目")
+				continue
+			}
+			chat.WriteShortlist(out)
+		case "/export":
+			if arg == "" {
+				fmt.Fprintln(out, "⚠️ 用法: /export <文件>")
+				continue
+			}
+			format := "markdown"
+			if strings.EqualFold(filepath.Ext(arg), ".json") {
+				format = "json"
+			}
+			err := writeFile(arg, func(w io.Writer) error { return chat.Export(w, format) })
+			if err != nil {
+				fmt.Fprintf(out, "❌ 导出失败: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "📝 已导出到 %s\n", arg)
+		case "/reset":
+			chat.Reset()
+			fmt.Fprintln(out, "🧹 已开始新的对话")
+		default:
+			fmt.Fprintf(out, "⚠️ 未知命令 %s，输入 /help 查看命令\n", cmd)
+		}
+	}
+}
+
+// writeFile 先写临时文件再重命名，写入失败时不会留下半个文件
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// openBrowser 用系统默认浏览器打开链接
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
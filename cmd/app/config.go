@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github-gold-miner/internal/adapter/render"
+	"github-gold-miner/internal/adapter/router"
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/config"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+)
+
+// runConfig 处理 config 子命令:
+//
+//	github-gold-miner config validate [-config file]    校验配置和引用的路由、流水线、模板文件
+//	github-gold-miner config print [-config file]       以 YAML 输出生效的配置 (含环境变量覆盖，密钥已隐藏)
+func runConfig(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	configFile := fs.String("config", "", "配置文件 (YAML)，未指定时读取当前目录下的 "+config.DefaultPath+" (如果存在)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: github-gold-miner config <validate|print> [-config file]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("缺少配置命令")
+	}
+	command := args[0]
+	fs.Parse(args[1:])
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "validate":
+		if err := validateConfig(cfg); err != nil {
+			return err
+		}
+		fmt.Println("✅ 配置有效")
+	case "print":
+		return cfg.Redacted().WriteYAML(os.Stdout)
+	default:
+		fs.Usage()
+		return fmt.Errorf("未知配置命令: %s", command)
+	}
+	return nil
+}
+
+// loadConfig 读取配置文件并应用环境变量；path 为空时使用当前目录下的默认配置文件 (如果存在)
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		if _, err := os.Stat(config.DefaultPath); err == nil {
+			path = config.DefaultPath
+		}
+	}
+	return config.Load(path)
+}
+
+// validateConfig 校验配置本身，以及各挖矿方向引用的流水线、通知路由和模板文件
+func validateConfig(cfg *config.Config) error {
+	errs := []error{cfg.Validate()}
+
+	// 多个方向引用同一个文件时只校验一次
+	checked := make(map[string]bool)
+	check := func(key, path string, validate func(string) error) {
+		if path == "" || checked[key+path] {
+			return
+		}
+		checked[key+path] = true
+		errs = append(errs, wrapFile(key, path, validate(path)))
+	}
+
+	for _, p := range cfg.ResolveProfiles() {
+		check("mining.pipeline", p.Mining.Pipeline, func(path string) error {
+			pipeline, err := service.LoadPipelineConfig(path)
+			if err != nil {
+				return err
+			}
+			return service.ValidatePipeline(pipeline)
+		})
+		check("notify.routes", p.Notify.Routes, func(path string) error {
+			routes, err := router.LoadConfig(path)
+			if err != nil {
+				return err
+			}
+			available := map[string]bool{router.DefaultChannel: true}
+			for name := range routes.Channels {
+				available[name] = true
+			}
+			return routes.Validate(available)
+		})
+		check("notify.templates", p.Notify.Templates, func(path string) error {
+			_, err := render.New(path)
+			return err
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// wrapFile 在错误前标注出错的配置项和文件
+func wrapFile(key, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s (%s): %w", key, path, err)
+}
+
+// databaseDSN 返回配置的数据库地址，使用默认的本地 SQLite 时给出提示
+func databaseDSN(cfg *config.Config) string {
+	if cfg.Database.URL == config.DefaultDSN {
+		fmt.Printf("💡 DATABASE_URL 未设置，使用本地 SQLite 数据库: %s\n", cfg.Database.URL)
+	}
+	return cfg.Database.URL
+}
+
+// geminiLimiter 所有挖矿方向、搜索和对话共用的 Gemini 限流器，未配置并发上限时使用 mining.concurrency
+func geminiLimiter(cfg *config.Config) *common.LLMLimiter {
+	limits := common.LLMLimiterConfig{RPM: cfg.Gemini.RPM, TPM: cfg.Gemini.TPM, MaxConcurrency: cfg.Gemini.MaxConcurrency}
+	if limits.MaxConcurrency == 0 {
+		limits.MaxConcurrency = cfg.Mining.Concurrency
+	}
+	return common.NewLLMLimiter("Gemini", limits, nil)
+}
+
+// budgetConfig 把配置文件中的预算和模型单价转换为 service.BudgetConfig
+func budgetConfig(b config.LLMBudgetConfig) service.BudgetConfig {
+	prices := make(map[string]domain.ModelPrice, len(b.Prices))
+	for model, price := range b.Prices {
+		prices[model] = domain.ModelPrice{Prompt: price.Prompt, Completion: price.Completion}
+	}
+	return service.BudgetConfig{DailyUSD: b.DailyUSD, MonthlyUSD: b.MonthlyUSD, Prices: prices}
+}
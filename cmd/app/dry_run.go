@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+)
+
+// dryRunResult -dry-run -json 的输出
+type dryRunResult struct {
+	*domain.MiningReport
+	Saved    []string `json:"saved"`    // 本应入库 (新增或更新) 的项目
+	Notified []string `json:"notified"` // 本应推送的项目
+	Error    string   `json:"error,omitempty"`
+}
+
+// runDryRun 试运行挖矿方向的一次挖矿：抓取、过滤和 LLM 评估照常执行，入库和推送只记录，
+// 最后输出各阶段漏斗和每个项目的去向。试运行不保存执行记录
+func runDryRun(m *profileMiner, jsonOutput bool) error {
+	miningService, err := m.newService()
+	if err != nil {
+		return err
+	}
+	recorder := miningService.EnableDryRun()
+
+	// JSON 输出时把挖矿过程的日志转到标准错误，标准输出只有报告
+	stdout := os.Stdout
+	if jsonOutput {
+		os.Stdout = os.Stderr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.profile.Mining.CycleTimeout)
+	defer cancel()
+	report, cycleErr := miningService.ExecuteMiningCycle(ctx, m.profile.Mining.Concurrency)
+	os.Stdout = stdout
+
+	if jsonOutput {
+		result := dryRunResult{MiningReport: report, Saved: recorder.Saved(), Notified: recorder.Notified()}
+		if cycleErr != nil {
+			result.Error = cycleErr.Error()
+		}
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		return cycleErr
+	}
+
+	printDryRunReport(os.Stdout, report, recorder)
+	return cycleErr
+}
+
+// printDryRunReport 打印阶段漏斗、每个项目的去向，以及本应入库和推送的项目
+func printDryRunReport(w io.Writer, report *domain.MiningReport, recorder *service.DryRunRecorder) {
+	fmt.Fprintln(w, "\n🧪 试运行完成，没有写入数据库，也没有推送消息")
+	fmt.Fprintf(w, "  GitHub API 请求: %d 次，LLM Token: %d\n", report.APIRequests, report.LLMTokens)
+
+	printStages(w, report.Stages)
+	printErrorCodes(w, "错误统计", report.ErrorCodes())
+	printErrorCodes(w, "重试统计", report.Retries())
+
+	if len(report.Fates) > 0 {
+		nameWidth := displayWidth("项目")
+		for _, fate := range report.Fates {
+			nameWidth = max(nameWidth, displayWidth(fate.Name))
+		}
+		fmt.Fprintf(w, "\n项目去向 (%d):\n", len(report.Fates))
+		fmt.Fprintf(w, "  %s%s  %s  %s\n", "项目", strings.Repeat(" ", nameWidth-displayWidth("项目")), "评分", "结果")
+		for _, fate := range report.Fates {
+			score := "-"
+			if fate.Score > 0 {
+				score = fmt.Sprint(fate.Score)
+			}
+			fmt.Fprintf(w, "  %s%s  %4s  %s\n", fate.Name, strings.Repeat(" ", nameWidth-displayWidth(fate.Name)), score, fateResult(fate, report))
+		}
+	}
+
+	if saved := recorder.Saved(); len(saved) > 0 {
+		fmt.Fprintf(w, "\n本应入库 (%d):\n", len(saved))
+		fmt.Fprintf(w, "  %s\n", strings.Join(saved, "\n  "))
+	}
+	if notified := recorder.Notified(); len(notified) > 0 {
+		fmt.Fprintf(w, "\n本应推送 (%d):\n", len(notified))
+		fmt.Fprintf(w, "  %s\n", strings.Join(notified, "\n  "))
+	}
+}
+
+// fateResult 项目的结果：在哪个阶段因为什么被丢弃，或者通过了全部阶段
+func fateResult(fate *domain.RepoFate, report *domain.MiningReport) string {
+	switch {
+	case fate.Stage != "":
+		return fmt.Sprintf("❌ %s: %s", stageLabel(fate.Stage), fate.Reason)
+	case slices.Contains(report.Pushed, fate.Name):
+		return "✅ 推送"
+	default:
+		return "✅ 通过全部阶段"
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/adapter/analyzer"
+	"github-gold-miner/internal/adapter/gemini"
+	"github-gold-miner/internal/config"
+	"github-gold-miner/internal/service"
+)
+
+// evalResult eval -json 的输出
+type evalResult struct {
+	Single     *service.EvalResult     `json:"single"`
+	Batch      *service.EvalResult     `json:"batch"`
+	Comparison *service.EvalComparison `json:"comparison"`
+}
+
+// runEval 处理 eval 子命令:
+//
+//	github-gold-miner eval [-dataset eval/appraisal.json] [-batch-size 8] [-json]
+//
+// 用真实的 LLM 分别逐个评估和批量评估标注好的数据集，对比两种方式的准确率、一致性和 Token 用量。
+// 评估不经过缓存，也不计入用量账本
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	configFile := fs.String("config", "", "配置文件 (YAML)")
+	dataset := fs.String("dataset", "eval/appraisal.json", "标注好的评估数据集 (JSON)")
+	batchSize := fs.Int("batch-size", 8, "批量评估时每次 LLM 调用评估的项目数")
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: github-gold-miner eval [-config file] [-dataset file] [-batch-size 8] [-json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *batchSize < 2 || *batchSize > config.MaxBatchSize {
+		return fmt.Errorf("-batch-size 必须在 2-%d 之间", config.MaxBatchSize)
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	cases, err := service.LoadEvalCases(*dataset)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	appraiser, err := gemini.NewGeminiAppraiser(ctx, cfg.Gemini.APIKey)
+	if err != nil {
+		return fmt.Errorf("AI 初始化失败: %w", err)
+	}
+	defer appraiser.Close()
+	appraiser.SetLimiter(geminiLimiter(cfg))
+
+	evaluate := func(size int) (*service.EvalResult, error) {
+		repoAnalyzer := analyzer.NewRepoAnalyzer(appraiser)
+		repoAnalyzer.SetMaxGoroutines(cfg.Mining.Concurrency)
+		repoAnalyzer.SetTimeout(cfg.Mining.AppraiseTimeout)
+		repoAnalyzer.SetBatchSize(size)
+		result, err := service.EvaluateAppraisals(ctx, repoAnalyzer, cases, cfg.Mining.MinScore)
+		if result != nil {
+			result.Mode, result.BatchSize = "single", size
+			if size > 1 {
+				result.Mode = "batch"
+			}
+		}
+		return result, err
+	}
+
+	// 评估过程的日志转到标准错误，标准输出只有报告
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	single, err := evaluate(1)
+	var batch *service.EvalResult
+	if err == nil {
+		batch, err = evaluate(*batchSize)
+	}
+	os.Stdout = stdout
+	if err != nil {
+		return err
+	}
+
+	result := evalResult{Single: single, Batch: batch, Comparison: service.CompareEvals(cases, single, batch)}
+	if *jsonOutput {
+		return printJSON(result)
+	}
+	printEval(os.Stdout, len(cases), result)
+	return nil
+}
+
+// printEval 并列打印两种评估方式的指标，以及批量评估相对逐个评估的一致性
+func printEval(w io.Writer, cases int, r evalResult) {
+	fmt.Fprintf(w, "\n📊 评估数据集: %d 个项目，推送阈值: %d 分\n\n", cases, r.Single.MinScore)
+	// 中文字符占两列，按显示宽度对齐：标签左对齐，数值右对齐
+	line := func(label, single, batch string) {
+		fmt.Fprintf(w, "  %s%s", label, strings.Repeat(" ", max(12-displayWidth(label), 0)))
+		for _, v := range []string{single, batch} {
+			fmt.Fprintf(w, "  %s%s", strings.Repeat(" ", max(12-displayWidth(v), 0)), v)
+		}
+		fmt.Fprintln(w)
+	}
+	line("", "逐个评估", fmt.Sprintf("每批 %d 个", r.Batch.BatchSize))
+	row := func(label string, format func(*service.EvalResult) string) {
+		line(label, format(r.Single), format(r.Batch))
+	}
+	percent := func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) }
+	row("评估失败", func(e *service.EvalResult) string { return fmt.Sprint(e.Failed) })
+	row("准确率", func(e *service.EvalResult) string { return percent(e.Accuracy()) })
+	row("精确率", func(e *service.EvalResult) string { return percent(e.Precision()) })
+	row("召回率", func(e *service.EvalResult) string { return percent(e.Recall()) })
+	row("输入 Token", func(e *service.EvalResult) string { return fmt.Sprint(e.Usage.PromptTokens) })
+	row("输出 Token", func(e *service.EvalResult) string { return fmt.Sprint(e.Usage.CompletionTokens) })
+	row("费用", func(e *service.EvalResult) string { return fmt.Sprintf("$%.4f", e.Usage.CostUSD) })
+	row("耗时", func(e *service.EvalResult) string { return e.Duration.Round(100 * time.Millisecond).String() })
+
+	cmp := r.Comparison
+	fmt.Fprintf(w, "\n🔍 批量评估与逐个评估对比 (%d 个项目两种方式都评估成功):\n", cmp.Compared)
+	fmt.Fprintf(w, "  是否推送的判断一致: %s\n", percent(cmp.Agreement))
+	fmt.Fprintf(w, "  评分差: 平均 %.1f 分，最大 %d 分\n", cmp.MeanScoreDiff, cmp.MaxScoreDiff)
+	for _, name := range cmp.Disagreements {
+		fmt.Fprintf(w, "  ⚠️ 判断不一致: %s\n", name)
+	}
+}
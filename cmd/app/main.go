@@ -2,25 +2,31 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
+	"strconv"
+	"strings"
 	"time"
 
-	"github-gold-miner/internal/adapter/analyzer"
 	"github-gold-miner/internal/adapter/feishu"
-	"github-gold-miner/internal/adapter/filter"
 	"github-gold-miner/internal/adapter/gemini"
+	"github-gold-miner/internal/adapter/llmcache"
+	"github-gold-miner/internal/adapter/render"
 	"github-gold-miner/internal/adapter/github"
+	"github-gold-miner/internal/adapter/ollama"
+	"github-gold-miner/internal/adapter/openai"
 	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/adapter/router"
+	"github-gold-miner/internal/config"
+	"github-gold-miner/internal/domain"
 	"github-gold-miner/internal/port"
 	"github-gold-miner/internal/service"
 
 	"github.com/joho/godotenv"
-	"github.com/robfig/cron/v3"
 )
 
 func main() {
@@ -29,185 +35,454 @@ func main() {
 		log.Println("💡 未找到 .env 文件，将使用系统环境变量")
 	}
 
-	// 1. 定义命令行参数
-	mode := flag.String("mode", "mine", "运行模式: mine (挖矿) 或 search (搜索)")
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("❌ 数据库迁移失败: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "runs" {
+		if err := runRuns(os.Args[2:]); err != nil {
+			log.Fatalf("❌ 查询挖矿执行记录失败: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "usage" {
+		if err := runUsage(os.Args[2:]); err != nil {
+			log.Fatalf("❌ 查询 LLM 用量失败: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := runEval(os.Args[2:]); err != nil {
+			log.Fatalf("❌ 评估失败: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(os.Args[2:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	// 1. 定义命令行参数，显式指定的参数覆盖配置文件和环境变量
+	configFile := flag.String("config", "", "配置文件 (YAML)，未指定时读取当前目录下的 "+config.DefaultPath+" (如果存在)")
+	mode := flag.String("mode", "mine", "运行模式: mine (挖矿)、search (搜索)、chat (对话式搜索)、serve (REST API 服务) 或 track (复查已推送项目)")
 	query := flag.String("q", "", "搜索关键词 (仅在 search 模式下有效)")
 	interval := flag.Int("interval", 0, "定时执行间隔（分钟），0表示只执行一次")
 	schedule := flag.String("schedule", "", "定时执行 cron 表达式，如 '30 9 * * *' 表示每天9:30执行")
 	concurrency := flag.Int("concurrency", 3, "LLM分析并发数")
+	batchSize := flag.Int("batch-size", 1, "每次 LLM 调用评估的项目数，1 表示逐个评估")
+	routesFile := flag.String("routes", "", "通知路由规则文件 (YAML)，按分类/语言/评分/增速把项目分发到不同渠道")
+	templateDir := flag.String("templates", "", "自定义通知模板目录，按 <渠道>/<类型>.tmpl 组织，如 feishu/single.tmpl")
+	trackMilestones := flag.String("track-milestones", "1000,5000,10000,50000", "推送后追踪的 Star 里程碑，逗号分隔")
+	trackWindow := flag.Duration("track-window", 30*24*time.Hour, "只追踪这段时间内推送过的项目")
+	trackEvery := flag.Duration("track-every", 6*time.Hour, "同一项目两次追踪检查的最小间隔")
+	embedderName := flag.String("embedder", "auto", "语义搜索的向量模型: gemini、openai、ollama、none，auto 表示有 GEMINI_API_KEY 时使用 gemini")
+	embedModel := flag.String("embed-model", "", "Embedding 模型名称，为空时使用各服务商的默认模型")
+	topK := flag.Int("top-k", 30, "语义搜索时向量检索召回的项目数")
+	jsonOutput := flag.Bool("json", false, "以 JSON 格式输出搜索结果或试运行报告 (仅在 search 模式和 -dry-run 下有效)")
+	shortlistFile := flag.String("shortlist", "shortlist.md", "对话模式下 /save 收藏项目写入的文件")
+	listen := flag.String("listen", ":8080", "serve 模式下 API 服务的监听地址")
+	pipelineFile := flag.String("pipeline", "", "挖矿流水线配置文件 (YAML)，调整阶段顺序、超时和错误策略")
+	dryRun := flag.Bool("dry-run", false, "试运行挖矿: 完整执行流水线，但不写数据库、不推送，最后输出每个项目的去向")
+	profileName := flag.String("profile", "", "只运行指定的挖矿方向 (配置文件 profiles 中的名称)，默认运行全部方向")
 	flag.Parse()
 
-	// 2. 初始化公共依赖 (数据库)
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		log.Println("⚠️  DATABASE_URL 未设置，使用本地默认配置（仅限开发环境）")
-		dsn = "host=localhost user=postgres password=postgres dbname=gold_miner port=5432 sslmode=disable TimeZone=Asia/Shanghai"
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	var flagErr error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "interval":
+			cfg.Mining.Interval = time.Duration(*interval) * time.Minute
+		case "schedule":
+			cfg.Mining.Schedule = *schedule
+		case "concurrency":
+			cfg.Mining.Concurrency = *concurrency
+		case "batch-size":
+			cfg.Mining.BatchSize = *batchSize
+		case "pipeline":
+			cfg.Mining.Pipeline = *pipelineFile
+		case "routes":
+			cfg.Notify.Routes = *routesFile
+		case "templates":
+			cfg.Notify.Templates = *templateDir
+		case "track-milestones":
+			cfg.Tracking.Milestones, flagErr = parseMilestones(*trackMilestones)
+		case "track-window":
+			cfg.Tracking.Window = *trackWindow
+		case "track-every":
+			cfg.Tracking.CheckEvery = *trackEvery
+		case "embedder":
+			cfg.Search.Embedder = *embedderName
+		case "embed-model":
+			cfg.Search.EmbedModel = *embedModel
+		case "top-k":
+			cfg.Search.TopK = *topK
+		case "listen":
+			cfg.Serve.Listen = *listen
+		}
+	})
+	if flagErr != nil {
+		log.Fatalf("❌ 里程碑配置无效: %v", flagErr)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	profiles, err := selectProfiles(cfg, *profileName)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *dryRun {
+		if *mode != "mine" {
+			log.Fatal("❌ -dry-run 只能用于单次执行的 -mode=mine")
+		}
+		if len(profiles) > 1 {
+			log.Fatal("❌ 配置了多个挖矿方向，-dry-run 需要用 -profile 指定其中一个")
+		}
+		if profiles[0].Mining.Schedule != "" || profiles[0].Mining.Interval > 0 {
+			log.Fatal("❌ -dry-run 只能用于单次执行的 -mode=mine")
+		}
+	}
+
+	trackingCfg := trackingConfig(cfg)
+
+	// 2. 初始化公共依赖 (数据库)，试运行不执行迁移
+	openDB := repository.Open
+	if *dryRun {
+		openDB = repository.OpenReadOnly
 	}
-	repoStore, err := repository.NewPostgresRepo(dsn)
+	repoStore, err := openDB(databaseDSN(cfg))
 	if err != nil {
 		log.Fatalf("❌ DB 初始化失败: %v", err)
 	}
+	// 每次挖矿的执行记录和阶段报告，通过 runs 子命令查看
+	runs := service.NewRunHistory(repoStore)
 
 	// 3. 初始化 AI 依赖
 	ctx := context.Background()
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	appraiser, err := gemini.NewGeminiAppraiser(ctx, geminiKey)
+	appraiser, err := gemini.NewGeminiAppraiser(ctx, cfg.Gemini.APIKey)
 	if err != nil {
 		log.Fatalf("❌ AI 初始化失败: %v", err)
 	}
 	defer appraiser.Close() // 程序退出时关闭 Gemini 客户端
+	// 按 RPM / TPM 限流，并按是否被限流自动调整并发
+	appraiser.SetLimiter(geminiLimiter(cfg))
+
+	// 初始化通知器，推送后追踪使用全局的通知配置
+	notifier, err := buildNotifierFromConfig(cfg.Notify)
+	if err != nil {
+		log.Fatalf("❌ 通知初始化失败: %v", err)
+	}
+	persistDeferred(notifier, repoStore, "")
+
+	// 挖矿时按内容缓存评估结果，README 和描述没变的项目不重复调用 LLM；试运行只读缓存
+	var miningAppraiser port.Appraiser = appraiser
+	if cfg.LLMCache.TTL > 0 {
+		cached := llmcache.New(appraiser, repoStore, cfg.LLMCache.TTL, cfg.LLMCache.MaxEntries)
+		cached.SetReadOnly(*dryRun)
+		cached.SetReadmeInspector(github.NewFetcher(cfg.GitHub.Token))
+		miningAppraiser = cached
+	}
+
+	// LLM 用量按天、挖矿方向和模型记入数据库，超出费用预算后停止评估并告警；试运行只按已有用量执行预算，不记账
+	var usageStore port.UsageStore = repoStore
+	if *dryRun {
+		usageStore = service.NewDryRunUsageStore(repoStore)
+	}
+	budget := service.NewBudget(usageStore, budgetConfig(cfg.LLMBudget))
+
+	// 向量索引在挖矿入库后补齐，搜索和对话只读取已有的向量；只有搜索、对话和 API 服务要求向量模型可用
+	searchMode := *mode == "search" || *mode == "chat" || *mode == "serve"
+	embedder, err := buildEmbedder(ctx, cfg)
+	if err != nil {
+		if searchMode {
+			log.Fatalf("❌ Embedding 初始化失败: %v", err)
+		}
+		log.Printf("⚠️ Embedding 初始化失败，入库后不生成向量: %v", err)
+	}
+	if closer, ok := embedder.(io.Closer); ok {
+		defer closer.Close()
+	}
+	searchCfg := service.DefaultSearchConfig()
+	searchCfg.TopK = cfg.Search.TopK
+	searcher := service.NewSearchService(repoStore, appraiser, embedder, searchCfg)
+	searcher.SetBudget(budget)
 
-	// 初始化通知器
-	feishuWebhook := os.Getenv("FEISHU_WEBHOOK")
-	notifier := feishu.NewNotifier(feishuWebhook)
+	// 各挖矿方向的挖矿任务，启动前先校验流水线配置
+	miners, err := buildMiners(cfg, profiles, repoStore, miningAppraiser, notifier, budget, searcher)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	track := func() {
+		executeTrackingCycle(cfg.GitHub.Token, repoStore, notifier, trackingCfg)
+	}
 
 	// 4. 根据模式分流
-	if *schedule != "" {
-		// cron 定时执行模式
-		runCronScheduledMining(repoStore, appraiser, notifier, *schedule, *concurrency)
-	} else if *interval > 0 {
-		// 间隔执行模式
-		runScheduledMining(repoStore, appraiser, notifier, *interval, *concurrency)
+	if scheduled(miners) {
+		// 定时执行模式：各挖矿方向按自己的 cron 表达式或间隔执行
+		runScheduledMining(runs, miners, track)
 	} else {
 		// 单次执行模式
 		switch *mode {
-		case "search":
-			runSearch(repoStore, appraiser, *query)
+		case "search", "chat", "serve":
+			// 启动时补齐还没有向量的项目 (如升级前入库的项目)，API 服务在后台补齐
+			switch *mode {
+			case "chat":
+				indexRepos(ctx, searcher)
+				runChat(service.NewChatService(searcher, appraiser), os.Stdin, os.Stdout, *shortlistFile)
+			case "serve":
+				go indexRepos(ctx, searcher)
+				if err := runServe(cfg.Serve.Listen, cfg.Serve.APIToken, repoStore, searcher, appraiser, budget, runs, miners); err != nil {
+					log.Fatalf("❌ API 服务异常退出: %v", err)
+				}
+			default:
+				indexRepos(ctx, searcher)
+				runSearch(searcher, *query, *jsonOutput)
+			}
 		case "mine":
-			runMining(repoStore, appraiser, notifier, *concurrency)
+			if *dryRun {
+				if err := runDryRun(miners[0], *jsonOutput); err != nil {
+					log.Fatalf("❌ 试运行失败: %v", err)
+				}
+			} else {
+				runMining(runs, miners)
+			}
+		case "track":
+			track()
 		default:
-			fmt.Println("❌ 未知模式，请使用 -mode=mine 或 -mode=search")
+			fmt.Println("❌ 未知模式，请使用 -mode=mine、-mode=search、-mode=chat、-mode=serve 或 -mode=track")
 		}
 	}
 }
 
-// runCronScheduledMining 使用 cron 表达式定时执行挖矿任务
-func runCronScheduledMining(repoStore port.Repository, appraiser port.Appraiser, notifier port.Notifier, schedule string, concurrency int) {
-	// 创建 cron 调度器（使用标准 cron 格式：分 时 日 月 周）
-	c := cron.New()
+// buildNotifier 创建通知器：未配置路由文件时直接使用飞书默认 Webhook，
+// 否则按路由规则把项目分发到多个飞书群
+func buildNotifier(defaultWebhook, routesFile string, renderer *render.Renderer) (port.Notifier, error) {
+	newFeishu := func(webhook string) *feishu.Notifier {
+		n := feishu.NewNotifier(webhook)
+		n.SetRenderer(renderer)
+		return n
+	}
 
-	// 添加定时任务
-	_, err := c.AddFunc(schedule, func() {
-		fmt.Printf("\n⏰ [%s] 定时任务触发，开始执行挖矿...\n", time.Now().Format("2006-01-02 15:04:05"))
-		executeMiningCycle(repoStore, appraiser, notifier, concurrency)
-	})
-	if err != nil {
-		log.Fatalf("❌ 无效的 cron 表达式 '%s': %v", schedule, err)
-	}
-
-	// 设置信号处理，优雅关闭
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// 启动 cron 调度器
-	c.Start()
-	fmt.Printf("⏰ Cron 定时执行模式已启动\n")
-	fmt.Printf("📅 调度规则: %s\n", schedule)
-	fmt.Println("💡 常用表达式:")
-	fmt.Println("   '30 9 * * *'  = 每天 9:30")
-	fmt.Println("   '0 */2 * * *' = 每2小时整点")
-	fmt.Println("   '0 9,18 * * *' = 每天 9:00 和 18:00")
-	fmt.Println("按下 Ctrl+C 可以优雅停止程序")
-
-	// 等待停止信号
-	<-sigChan
-	fmt.Println("\n👋 收到停止信号，正在退出...")
-	c.Stop()
-}
+	if routesFile == "" {
+		return newFeishu(defaultWebhook), nil
+	}
 
-// runScheduledMining 运行定时挖矿任务（按间隔）
-func runScheduledMining(repoStore port.Repository, appraiser port.Appraiser, notifier port.Notifier, interval int, concurrency int) {
-	// 创建带取消功能的context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	cfg, err := router.LoadConfig(routesFile)
+	if err != nil {
+		return nil, err
+	}
+	channels := map[string]port.Notifier{
+		router.DefaultChannel: newFeishu(defaultWebhook),
+	}
+	for name, webhook := range cfg.Channels {
+		channels[name] = newFeishu(webhook)
+	}
 
-	// 设置信号处理，优雅关闭
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	
-	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
-	defer ticker.Stop()
-	
-	fmt.Printf("⏰ 定时执行模式已启动，每 %d 分钟执行一次\n", interval)
-	fmt.Println("按下 Ctrl+C 可以优雅停止程序")
-	
-	// 立即执行一次
-	executeMiningCycle(repoStore, appraiser, notifier, concurrency)
-	
-	// 定时执行
-	for {
-		select {
-		case <-ticker.C:
-			executeMiningCycle(repoStore, appraiser, notifier, concurrency)
-		case <-sigChan:
-			fmt.Println("\n👋 收到停止信号，正在退出...")
-			return
-		case <-ctx.Done():
-			fmt.Println("👋 定时任务已停止")
-			return
-		}
+	r, err := router.New(cfg, channels)
+	if err != nil {
+		return nil, err
 	}
+	fmt.Printf("🧭 已加载 %d 个通知渠道的路由规则: %s\n", len(channels), routesFile)
+	return r, nil
 }
 
-// executeMiningCycle 执行一次挖矿周期
-func executeMiningCycle(repoStore port.Repository, appraiser port.Appraiser, notifier port.Notifier, concurrency int) {
-	// 获取环境变量
-	githubToken := os.Getenv("GITHUB_TOKEN")
+// persistDeferred 路由器免打扰期间暂存的消息保存到数据库，进程重启或单次运行退出后不会丢失
+// owner 为挖矿方向名称，共用数据库的多个路由器各自只补发自己的消息
+func persistDeferred(notifier port.Notifier, repoStore port.Repository, owner string) {
+	r, ok := notifier.(*router.Router)
+	if !ok {
+		return
+	}
+	if store, ok := repoStore.(port.DeferredStore); ok {
+		r.SetStore(store, owner)
+	}
+}
 
-	// 为整个挖矿周期设置超时时间(5分钟)
+// executeTrackingCycle 复查已推送的项目，发送爆发/撤回通知
+// 每个项目按 CheckInterval 限流，因此可以跟随每轮挖矿一起执行
+func executeTrackingCycle(githubToken string, repoStore port.Repository, notifier port.Notifier, cfg service.TrackingConfig) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	// 初始化组件
 	fetcher := github.NewFetcher(githubToken)
-	repoFilter := filter.NewRepoFilter(githubToken)
-	repoAnalyzer := analyzer.NewRepoAnalyzer(appraiser)
-	repoAnalyzer.SetMaxGoroutines(concurrency) // 设置并发数
+	trackingService := service.NewTrackingService(fetcher, repoStore, notifier, cfg)
+	if _, err := trackingService.Run(ctx); err != nil {
+		log.Printf("❌ 追踪已推送项目失败: %v", err)
+	}
+}
+
+// trackingConfig 把配置文件中的追踪配置转换为追踪服务的配置，单独设置了爆发阈值的挖矿方向按方向覆盖
+func trackingConfig(cfg *config.Config) service.TrackingConfig {
+	t := service.DefaultTrackingConfig()
+	t.Window = cfg.Tracking.Window
+	t.CheckInterval = cfg.Tracking.CheckEvery
+	t.Milestones = cfg.Tracking.Milestones
+	t.MinGrowth = cfg.Tracking.MinGrowth
+	t.Retract = nil
+	for _, event := range cfg.Tracking.Retract {
+		t.Retract = append(t.Retract, domain.TrackingEventType(event))
+	}
+	t.Profiles = make(map[string]service.BreakoutRule)
+	for i, p := range cfg.ResolveProfiles() {
+		if p.Name == "" {
+			continue
+		}
+		if override := cfg.Profiles[i].Tracking; len(override.Milestones) == 0 && override.MinGrowth == 0 {
+			continue
+		}
+		t.Profiles[p.Name] = service.BreakoutRule{Milestones: p.Tracking.Milestones, MinGrowth: p.Tracking.MinGrowth}
+	}
+	return t
+}
 
-	// 创建挖矿服务
-	miningService := service.NewMiningService(fetcher, repoFilter, repoAnalyzer, repoStore, appraiser, notifier)
+// parseMilestones 解析逗号分隔的里程碑列表
+func parseMilestones(s string) ([]int, error) {
+	var milestones []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		m, err := strconv.Atoi(part)
+		if err != nil || m <= 0 {
+			return nil, fmt.Errorf("里程碑必须是正整数: %q", part)
+		}
+		milestones = append(milestones, m)
+	}
+	return milestones, nil
+}
+
+// buildEmbedder 根据 search.embedder 创建向量模型，none 或 auto 且没有 Gemini Key 时返回 nil (不使用向量检索)
+func buildEmbedder(ctx context.Context, cfg *config.Config) (port.Embedder, error) {
+	name, model := cfg.Search.Embedder, cfg.Search.EmbedModel
+	if name == "auto" {
+		name = "none"
+		if cfg.Gemini.APIKey != "" {
+			name = "gemini"
+		}
+	}
 
-	// 执行挖矿周期
-	miningService.ExecuteMiningCycle(ctx, concurrency)
+	switch name {
+	case "none", "":
+		return nil, nil
+	case "gemini":
+		embedder, err := gemini.NewEmbedder(ctx, cfg.Gemini.APIKey, model)
+		if err != nil {
+			return nil, err
+		}
+		return embedder, nil
+	case "openai":
+		if cfg.OpenAI.APIKey == "" {
+			return nil, fmt.Errorf("使用 openai 需要设置 OPENAI_API_KEY")
+		}
+		return openai.NewEmbedder(cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL, model), nil
+	case "ollama":
+		return ollama.NewEmbedder(cfg.Ollama.Host, model), nil
+	default:
+		return nil, fmt.Errorf("未知的向量模型: %s", name)
+	}
+}
+
+// indexRepos 为还没有向量或内容变化后向量已过期的项目补齐向量索引，失败只记录日志
+func indexRepos(ctx context.Context, searcher *service.SearchService) {
+	if !searcher.VectorEnabled() {
+		return
+	}
+	indexed, err := searcher.Index(ctx)
+	if err != nil {
+		log.Printf("⚠️ 补齐向量索引失败: %v", err)
+	}
+	if indexed > 0 {
+		log.Printf("🧮 已为 %d 个项目生成向量", indexed)
+	}
 }
 
 // --- 搜索模式逻辑 ---
-func runSearch(repoStore port.Repository, appraiser port.Appraiser, query string) {
+func runSearch(searcher *service.SearchService, query string, jsonOutput bool) {
 	if query == "" {
 		fmt.Println("⚠️ 请输入你的需求，用大白话就行。")
 		fmt.Println("例如: -q '我想找一个Python的机器学习库' 或 -q '有没有好用的代码生成工具'")
 		return
 	}
 
-	fmt.Println("🤖 正在读取数据库，并进行 AI 语义分析...")
+	// JSON 输出只打印结果，方便被其他程序解析
+	logf := func(format string, args ...interface{}) {
+		if !jsonOutput {
+			fmt.Printf(format, args...)
+		}
+	}
+
+	if searcher.VectorEnabled() {
+		logf("🤖 正在进行向量检索，并进行 AI 语义分析...\n")
+	} else {
+		logf("🤖 正在读取数据库，并进行 AI 语义分析...\n")
+	}
 
-	// 1. 取出候选项目 (比如最近入库的 50 个)
-	candidates, err := repoStore.GetAllCandidates(context.Background())
+	// 1. 召回候选项目 (向量检索全库；未配置向量模型时取最近入库的项目)
+	// 2. 这里的 query 不再是 SQL 关键词，而是你的自然语言问题
+	results, candidates, err := searcher.Search(context.Background(), query)
 	if err != nil {
-		log.Fatalf("读取数据库失败: %v", err)
+		log.Fatalf("❌ 搜索失败: %v", err)
 	}
 
-	if len(candidates) == 0 {
-		fmt.Println("📭 数据库是空的。请先运行 -mode=mine 抓取一些项目！")
+	if jsonOutput {
+		if results == nil {
+			results = []*domain.SearchResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			log.Fatalf("❌ 输出结果失败: %v", err)
+		}
 		return
 	}
 
-	fmt.Printf("📚 已加载 %d 个项目作为上下文，AI 正在匹配你的需求: [%s] ...\n", len(candidates), query)
-
-	// 2. 这里的 query 不再是 SQL 关键词，而是你的自然语言问题
-	answer, err := appraiser.SemanticSearch(context.Background(), candidates, query)
-	if err != nil {
-		log.Printf("❌ AI 分析失败: %v", err)
+	if len(candidates) == 0 {
+		fmt.Println("📭 数据库是空的。请先运行 -mode=mine 抓取一些项目！")
 		return
 	}
 
+	fmt.Printf("📚 AI 已从 %d 个候选项目中匹配你的需求: [%s]\n", len(candidates), query)
+
 	// 3. 打印结果
 	fmt.Println("\n================ [ 智能搜索结果 ] ================")
-	fmt.Println(answer)
+	fmt.Print(formatSearchResults(results))
 	fmt.Println("==================================================")
 }
 
+// formatSearchResults 把结构化的检索结果格式化为终端文本
+func formatSearchResults(results []*domain.SearchResult) string {
+	if len(results) == 0 {
+		return "没有找到合适的项目\n"
+	}
+
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "### 🎯 %d. %s  (相关度 %d)\n", i+1, r.Repo.Name, r.Relevance)
+		fmt.Fprintf(&b, "- 🔗 %s  ⭐ %d  🤖 评分 %d\n", r.Repo.URL, r.Repo.Stars, r.Repo.LLMScore)
+		fmt.Fprintf(&b, "- **匹配理由**：%s\n", r.Reason)
+		if r.Action != "" {
+			fmt.Fprintf(&b, "- **行动建议**：%s\n", r.Action)
+		}
+	}
+	return b.String()
+}
+
 // --- 挖矿模式逻辑 ---
-func runMining(repoStore port.Repository, appraiser port.Appraiser, notifier port.Notifier, concurrency int) {
-	executeMiningCycle(repoStore, appraiser, notifier, concurrency)
+// runMining 依次执行每个挖矿方向的一次挖矿
+func runMining(runs *service.RunHistory, miners []*profileMiner) {
+	for _, m := range miners {
+		executeMiningCycle(runs, domain.TriggerManual, m)
+	}
 }
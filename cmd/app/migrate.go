@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github-gold-miner/internal/adapter/repository"
+)
+
+// runMigrate 处理 migrate 子命令:
+//
+//	github-gold-miner migrate up          执行所有未执行的迁移
+//	github-gold-miner migrate down [n]    回滚最近 n 个迁移 (默认 1)
+//	github-gold-miner migrate status      查看迁移状态
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configFile := fs.String("config", "", "配置文件 (YAML)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: github-gold-miner migrate [-config file] <up|down [n]|status>")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("缺少迁移命令")
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	// 迁移命令只连接数据库，不在启动时自动迁移
+	repo, err := repository.Connect(databaseDSN(cfg))
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	migrator, err := repo.Migrator()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("⬆️  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("✅ 数据库已是最新版本")
+		}
+
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("回滚数量必须是正整数: %s", fs.Arg(1))
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("⬇️  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("💡 没有可回滚的迁移")
+		}
+
+	case "status":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("当前版本: %d，最新版本: %d\n", version, migrator.Latest())
+		for _, s := range status {
+			state := "未执行"
+			if s.Applied {
+				state = "已执行 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %04d_%-24s %s\n", s.Version, s.Name, state)
+		}
+		if version > migrator.Latest() {
+			return fmt.Errorf("%w: 数据库版本 %d 高于程序已知的最新版本", repository.ErrUnknownSchemaVersion, version)
+		}
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("未知迁移命令: %s", fs.Arg(0))
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github-gold-miner/internal/adapter/analyzer"
+	"github-gold-miner/internal/adapter/filter"
+	"github-gold-miner/internal/adapter/github"
+	"github-gold-miner/internal/adapter/render"
+	"github-gold-miner/internal/adapter/router"
+	"github-gold-miner/internal/config"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github-gold-miner/internal/service"
+
+	"github.com/robfig/cron/v3"
+)
+
+// profileMiner 一个挖矿方向的挖矿任务：自己的评估标准、通知渠道和流水线，共用同一个数据库
+type profileMiner struct {
+	profile   config.Profile
+	cfg       *config.Config
+	repoStore port.Repository
+	appraiser port.Appraiser
+	notifier  port.Notifier
+	pipeline  service.PipelineConfig
+	budget    *service.Budget        // 所有方向共用的 LLM 费用预算
+	indexer   *service.SearchService // 入库后补齐向量索引
+}
+
+// selectProfiles 返回要运行的挖矿方向，name 为空时返回全部
+func selectProfiles(cfg *config.Config, name string) ([]config.Profile, error) {
+	profiles := cfg.ResolveProfiles()
+	if name == "" {
+		return profiles, nil
+	}
+	var names []string
+	for _, p := range profiles {
+		if p.Name == name {
+			return []config.Profile{p}, nil
+		}
+		names = append(names, p.Name)
+	}
+	if len(cfg.Profiles) == 0 {
+		return nil, fmt.Errorf("配置文件中没有配置挖矿方向 (profiles)，不能使用 -profile")
+	}
+	return nil, fmt.Errorf("未知的挖矿方向 %q (可选 %s)", name, strings.Join(names, "、"))
+}
+
+// buildMiners 为每个挖矿方向准备评估标准、通知器和流水线，启动前校验流水线配置
+// 通知配置与全局相同的方向共用 notifier，所有方向共用 budget 和 indexer
+func buildMiners(cfg *config.Config, profiles []config.Profile, repoStore port.Repository, appraiser port.Appraiser, notifier port.Notifier, budget *service.Budget, indexer *service.SearchService) ([]*profileMiner, error) {
+	var miners []*profileMiner
+	for _, p := range profiles {
+		m := &profileMiner{profile: p, cfg: cfg, repoStore: repoStore, appraiser: appraiser, notifier: notifier, budget: budget, indexer: indexer}
+
+		if p.Mining.Criteria != "" {
+			criteria, ok := appraiser.(port.CriteriaAppraiser)
+			if !ok {
+				return nil, fmt.Errorf("挖矿方向 %s: 当前的 LLM 不支持自定义评估标准", m.label())
+			}
+			m.appraiser = criteria.WithCriteria(p.Mining.Criteria)
+		}
+
+		if p.Notify != cfg.Notify {
+			n, err := buildNotifierFromConfig(p.Notify)
+			if err != nil {
+				return nil, fmt.Errorf("挖矿方向 %s 的通知配置无效: %w", m.label(), err)
+			}
+			persistDeferred(n, repoStore, p.Name)
+			m.notifier = n
+		}
+		// 路由的评分条件只能在挖矿最低评分之上再收紧
+		if r, ok := m.notifier.(*router.Router); ok {
+			if err := r.CheckMinScore(p.Mining.MinScore); err != nil {
+				return nil, fmt.Errorf("挖矿方向 %s: %w", m.label(), err)
+			}
+		}
+
+		m.pipeline = service.DefaultPipelineConfig()
+		if p.Mining.Pipeline != "" {
+			pipeline, err := service.LoadPipelineConfig(p.Mining.Pipeline)
+			if err != nil {
+				return nil, fmt.Errorf("挖矿方向 %s 的流水线配置无效: %w", m.label(), err)
+			}
+			m.pipeline = pipeline
+		}
+		if _, err := m.newService(); err != nil {
+			return nil, fmt.Errorf("挖矿方向 %s 的流水线配置无效: %w", m.label(), err)
+		}
+
+		miners = append(miners, m)
+	}
+	return miners, nil
+}
+
+// buildNotifierFromConfig 按通知配置加载模板并创建通知器
+func buildNotifierFromConfig(n config.NotifyConfig) (port.Notifier, error) {
+	var renderer *render.Renderer
+	if n.Templates != "" {
+		var err error
+		if renderer, err = render.New(n.Templates); err != nil {
+			return nil, fmt.Errorf("加载通知模板失败: %w", err)
+		}
+	}
+	return buildNotifier(n.FeishuWebhook, n.Routes, renderer)
+}
+
+// label 日志中的方向名称
+func (m *profileMiner) label() string {
+	if m.profile.Name == "" {
+		return "默认"
+	}
+	return m.profile.Name
+}
+
+// newService 按挖矿方向的配置初始化挖矿组件和流水线
+func (m *profileMiner) newService() (*service.MiningService, error) {
+	mining := m.profile.Mining
+	fetcher := github.NewFetcher(m.cfg.GitHub.Token)
+	repoFilter := filter.NewRepoFilter(m.cfg.GitHub.Token)
+	repoAnalyzer := analyzer.NewRepoAnalyzer(m.appraiser)
+	repoAnalyzer.SetMaxGoroutines(mining.Concurrency) // 设置并发数
+	repoAnalyzer.SetTimeout(mining.AppraiseTimeout)
+	repoAnalyzer.SetBatchSize(mining.BatchSize)
+
+	miningService := service.NewMiningService(fetcher, repoFilter, repoAnalyzer, m.repoStore, m.appraiser, m.notifier)
+	miningService.SetConfig(service.MiningConfig{
+		Profile:    m.profile.Name,
+		Topics:     mining.Topics,
+		Languages:  mining.Languages,
+		MaxAgeDays: mining.MaxAgeDays,
+		MinScore:   mining.MinScore,
+
+		DailyBudgetUSD: mining.DailyBudgetUSD,
+	})
+	miningService.SetBudget(m.budget)
+	miningService.SetIndexer(m.indexer)
+	if err := miningService.SetPipeline(m.pipeline); err != nil {
+		return nil, err
+	}
+	return miningService, nil
+}
+
+// mine 在 ctx 下执行一次挖矿周期，整个周期最长 mining.cycle_timeout (默认 5 分钟)
+// 数据库支持时每个阶段的输出保存为 run 的检查点，超时或中断后由下一次执行续跑
+func (m *profileMiner) mine(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.profile.Mining.CycleTimeout)
+	defer cancel()
+
+	miningService, err := m.newService()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoints, ok := m.repoStore.(port.CheckpointStore); ok {
+		miningService.EnableCheckpoints(checkpoints, run)
+	}
+	if m.profile.Name != "" {
+		fmt.Printf("🧭 挖矿方向: %s\n", m.profile.Name)
+	}
+	return miningService.ExecuteMiningCycle(ctx, m.profile.Mining.Concurrency)
+}
+
+// scheduled 是否有挖矿方向配置了 cron 表达式或执行间隔
+func scheduled(miners []*profileMiner) bool {
+	for _, m := range miners {
+		if m.profile.Mining.Schedule != "" || m.profile.Mining.Interval > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runScheduledMining 按各挖矿方向的 cron 表达式或执行间隔定时挖矿，收到 SIGINT/SIGTERM 后停止
+// 按间隔执行的方向和没有执行计划的方向在启动时先执行一次；不同方向可以同时执行，同一方向不会重叠
+func runScheduledMining(runs *service.RunHistory, miners []*profileMiner, track func()) {
+	// 使用标准 cron 格式：分 时 日 月 周
+	c := cron.New()
+	var startup []*profileMiner
+	usesCron := false
+	for _, m := range miners {
+		mining := m.profile.Mining
+		switch {
+		case mining.Schedule != "":
+			_, err := c.AddFunc(mining.Schedule, func() {
+				fmt.Printf("\n⏰ [%s] 定时任务触发，开始执行挖矿 (%s)...\n", time.Now().Format("2006-01-02 15:04:05"), m.label())
+				executeMiningCycle(runs, domain.TriggerCron, m)
+				track()
+			})
+			if err != nil {
+				log.Fatalf("❌ 挖矿方向 %s 的 cron 表达式 '%s' 无效: %v", m.label(), mining.Schedule, err)
+			}
+			usesCron = true
+			fmt.Printf("📅 [%s] 调度规则: %s\n", m.label(), mining.Schedule)
+		case mining.Interval > 0:
+			c.Schedule(cron.Every(mining.Interval), cron.FuncJob(func() {
+				executeMiningCycle(runs, domain.TriggerInterval, m)
+				track()
+			}))
+			startup = append(startup, m)
+			fmt.Printf("⏰ [%s] 每 %s 执行一次\n", m.label(), mining.Interval)
+		default:
+			startup = append(startup, m)
+			fmt.Printf("💡 [%s] 没有配置执行计划，只在启动时执行一次\n", m.label())
+		}
+	}
+	if usesCron {
+		fmt.Println("💡 常用表达式:")
+		fmt.Println("   '30 9 * * *'  = 每天 9:30")
+		fmt.Println("   '0 */2 * * *' = 每2小时整点")
+		fmt.Println("   '0 9,18 * * *' = 每天 9:00 和 18:00")
+	}
+
+	// 设置信号处理，优雅关闭
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	c.Start()
+	fmt.Println("⏰ 定时执行模式已启动，按下 Ctrl+C 可以优雅停止程序")
+	go func() {
+		for _, m := range startup {
+			trigger := domain.TriggerInterval
+			if m.profile.Mining.Interval == 0 {
+				trigger = domain.TriggerManual
+			}
+			executeMiningCycle(runs, trigger, m)
+			track()
+		}
+	}()
+
+	// 等待停止信号
+	<-sigChan
+	fmt.Println("\n👋 收到停止信号，正在退出...")
+	c.Stop()
+}
+
+// executeMiningCycle 执行挖矿方向的一次挖矿周期并保存执行记录
+func executeMiningCycle(runs *service.RunHistory, trigger domain.RunTrigger, m *profileMiner) {
+	run, err := runs.Run(context.Background(), trigger, m.profile.Name, m.mine)
+	if err != nil {
+		log.Printf("❌ 挖矿周期失败 (%s): %v", m.label(), err)
+	}
+	if run != nil {
+		fmt.Printf("📝 执行记录 #%d，查看报告: github-gold-miner runs %d\n", run.ID, run.ID)
+	}
+}
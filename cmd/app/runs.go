@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+)
+
+// stageLabels 报告中各阶段的中文名称
+var stageLabels = map[string]string{
+	domain.StageFetch:          "抓取",
+	domain.StageDedup:          "去重",
+	domain.StageFilterLanguage: "语言过滤",
+	domain.StageFilterAge:      "时效性过滤",
+	domain.StageFilterActivity: "活跃度过滤",
+	domain.StageVelocity:       "增长率计算",
+	domain.StageAppraise:       "LLM 评估",
+	domain.StageSelect:         "评分筛选",
+	domain.StageStore:          "入库",
+	domain.StageNotify:         "推送",
+}
+
+// runRuns 处理 runs 子命令:
+//
+//	github-gold-miner runs [-n 20] [-json]    列出最近的挖矿执行记录
+//	github-gold-miner runs [-json] <id>       查看一次执行的阶段报告
+func runRuns(args []string) error {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	limit := fs.Int("n", 20, "列出的记录数")
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	configFile := fs.String("config", "", "配置文件 (YAML)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: github-gold-miner runs [-config file] [-n 20] [-json] [id]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	repo, err := repository.Open(databaseDSN(cfg))
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if fs.NArg() == 0 {
+		runs, err := repo.ListRuns(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printJSON(runs)
+		}
+		printRunList(os.Stdout, runs)
+		return nil
+	}
+
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的执行记录 ID: %s", fs.Arg(0))
+	}
+	run, err := repo.GetRun(ctx, id)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return printJSON(run)
+	}
+	printRunReport(os.Stdout, run)
+	return nil
+}
+
+// printRunList 每次执行一行: 编号、开始时间、触发方式、状态、耗时、推送数、用量、费用和挖矿方向
+func printRunList(w io.Writer, runs []*domain.MiningRun) {
+	if len(runs) == 0 {
+		fmt.Fprintln(w, "💡 还没有挖矿执行记录")
+		return
+	}
+	// 中文表头按显示宽度 (每个汉字占两列) 对齐
+	fmt.Fprintf(w, "%-6s %-15s  %-6s  %-7s  %6s  %2s  %6s  %8s  %5s  %s\n", "ID", "开始时间", "触发", "状态", "耗时", "推送", "API", "Token", "费用", "方向")
+	for _, run := range runs {
+		fmt.Fprintf(w, "#%-5d %-19s  %-8s  %-9s  %8s  %4d  %6d  %8d  %7s  %s\n",
+			run.ID, run.StartedAt.Local().Format(time.DateTime), run.Trigger, run.Status,
+			runDuration(run), len(run.Pushed), run.APIRequests, run.LLMTokens, fmt.Sprintf("$%.2f", run.Usage.CostUSD), profileLabel(run.Profile))
+	}
+}
+
+// printRunReport 打印一次执行的阶段漏斗、错误和推送的项目
+func printRunReport(w io.Writer, run *domain.MiningRun) {
+	fmt.Fprintf(w, "挖矿执行 #%d (%s)\n", run.ID, run.Trigger)
+	if run.Profile != "" {
+		fmt.Fprintf(w, "  挖矿方向: %s\n", run.Profile)
+	}
+	fmt.Fprintf(w, "  状态: %s，开始于 %s，耗时 %s\n", run.Status, run.StartedAt.Local().Format(time.DateTime), runDuration(run))
+	if run.Error != "" {
+		fmt.Fprintf(w, "  错误: %s\n", run.Error)
+	}
+	if run.ResumedFrom != 0 {
+		fmt.Fprintf(w, "  续跑自: #%d\n", run.ResumedFrom)
+	}
+	fmt.Fprintf(w, "  GitHub API 请求: %d 次，LLM Token: %d\n", run.APIRequests, run.LLMTokens)
+	if run.Usage.Total() > 0 {
+		fmt.Fprintf(w, "  LLM 用量: 输入 %d / 输出 %d Token，费用 $%.4f\n", run.Usage.PromptTokens, run.Usage.CompletionTokens, run.Usage.CostUSD)
+	}
+	for _, budget := range run.Budgets {
+		mark := ""
+		if budget.Exceeded() {
+			mark = "  ⚠️ 已用完"
+		}
+		fmt.Fprintf(w, "  预算: %s%s\n", service.DescribeBudget(budget), mark)
+	}
+	if run.BudgetBlocked > 0 {
+		fmt.Fprintf(w, "  超出预算未评估: %d 个项目\n", run.BudgetBlocked)
+	}
+	if lookups := run.CacheHits + run.CacheMisses; lookups > 0 {
+		fmt.Fprintf(w, "  LLM 缓存: 命中 %d / %d 次 (%.0f%%)\n", run.CacheHits, lookups, float64(run.CacheHits)*100/float64(lookups))
+	}
+	if run.Reused > 0 {
+		fmt.Fprintf(w, "  复用已有评估: %d 个项目\n", run.Reused)
+	}
+
+	printStages(w, run.Stages)
+	printErrorCodes(w, "错误统计", run.ErrorCodes())
+	printErrorCodes(w, "重试统计", run.Retries())
+
+	if len(run.Pushed) > 0 {
+		fmt.Fprintf(w, "\n已推送 (%d):\n", len(run.Pushed))
+		fmt.Fprintf(w, "  %s\n", strings.Join(run.Pushed, "\n  "))
+	}
+}
+
+// printStages 打印各阶段的输入输出数量、耗时和错误
+func printStages(w io.Writer, stages []*domain.StageReport) {
+	if len(stages) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n阶段:")
+	for _, stage := range stages {
+		label := stageLabel(stage.Name)
+		fmt.Fprintf(w, "  %s%s %5d → %-5d %8s", label, strings.Repeat(" ", max(12-displayWidth(label), 0)),
+			stage.In, stage.Out, (time.Duration(stage.DurationMS) * time.Millisecond).String())
+		if stage.Skipped {
+			fmt.Fprint(w, "  (已跳过)")
+		}
+		if stage.Resumed {
+			fmt.Fprint(w, "  (来自检查点)")
+		}
+		if len(stage.Errors) > 0 {
+			fmt.Fprintf(w, "  ⚠️ %d 个错误", len(stage.Errors))
+		}
+		fmt.Fprintln(w)
+		for _, e := range stage.Errors {
+			fmt.Fprintf(w, "      - %s\n", e)
+		}
+	}
+}
+
+// printErrorCodes 以 title 为标题，按次数从多到少打印各错误码的次数
+func printErrorCodes(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if counts[codes[i]] != counts[codes[j]] {
+			return counts[codes[i]] > counts[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %-22s %d\n", code, counts[code])
+	}
+}
+
+// stageLabel 阶段的中文名称，自定义阶段使用原名
+func stageLabel(name string) string {
+	if label := stageLabels[name]; label != "" {
+		return label
+	}
+	return name
+}
+
+// profileLabel 未配置挖矿方向时显示 "-"
+func profileLabel(profile string) string {
+	if profile == "" {
+		return "-"
+	}
+	return profile
+}
+
+// runDuration 已结束的执行返回耗时，执行中返回 "-"
+func runDuration(run *domain.MiningRun) string {
+	if run.FinishedAt == nil {
+		return "-"
+	}
+	return run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+}
+
+// displayWidth 终端显示宽度，中文字符占两列
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x2E80 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// printJSON 以缩进的 JSON 输出到标准输出
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github-gold-miner/internal/adapter/httpapi"
+	"github-gold-miner/internal/adapter/web"
+	"github-gold-miner/internal/port"
+	"github-gold-miner/internal/service"
+)
+
+// runServe 启动 REST API 服务和网页看板，收到 SIGINT/SIGTERM 后优雅关闭
+// 配置了挖矿方向时，通过 POST /api/v1/runs?profile=<名称> 触发指定方向的挖矿
+func runServe(addr, token string, repoStore port.Repository, searcher *service.SearchService, appraiser port.Appraiser, budget *service.Budget, runs *service.RunHistory, miners []*profileMiner) error {
+	var mine service.MineFunc
+	var profiles map[string]service.MineFunc
+	if len(miners) == 1 && miners[0].profile.Name == "" {
+		mine = miners[0].mine
+	} else {
+		profiles = make(map[string]service.MineFunc, len(miners))
+		for _, m := range miners {
+			profiles[m.profile.Name] = m.mine
+		}
+	}
+
+	dashboard, err := web.New(web.Config{Token: token, Repos: repoStore, Searcher: searcher})
+	if err != nil {
+		return err
+	}
+	srv, err := httpapi.New(httpapi.Config{
+		Token:     token,
+		Repos:     repoStore,
+		Searcher:  searcher,
+		Appraiser: appraiser,
+		Budget:    budget,
+		Mine:      mine,
+		Profiles:  profiles,
+		Runs:      runs,
+		Dashboard: dashboard,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return srv.ListenAndServe(ctx, addr)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+)
+
+// runUsage 处理 usage 子命令:
+//
+//	github-gold-miner usage [-days 7] [-json]    按天、挖矿方向和模型列出 LLM 用量和费用
+func runUsage(args []string) error {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	days := fs.Int("days", 7, "统计最近几天 (含今天)")
+	jsonOutput := fs.Bool("json", false, "以 JSON 格式输出")
+	configFile := fs.String("config", "", "配置文件 (YAML)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: github-gold-miner usage [-config file] [-days 7] [-json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *days <= 0 {
+		return fmt.Errorf("-days 必须大于 0")
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	repo, err := repository.Open(databaseDSN(cfg))
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	since := time.Now().AddDate(0, 0, 1-*days).Format(time.DateOnly)
+	records, err := repo.ListUsage(context.Background(), since)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		return printJSON(records)
+	}
+	printUsage(os.Stdout, records)
+	return nil
+}
+
+// printUsage 每天、每个方向、每个模型一行，最后按挖矿方向汇总
+func printUsage(w io.Writer, records []*domain.UsageRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "💡 这段时间没有 LLM 用量记录")
+		return
+	}
+	fmt.Fprintf(w, "%-10s  %-12s  %-22s  %10s  %10s  %6s\n", "日期", "方向", "模型", "输入", "输出", "费用")
+	byProfile := make(map[string]*domain.TokenUsage)
+	var total domain.TokenUsage
+	for _, r := range records {
+		fmt.Fprintf(w, "%-12s  %-14s  %-24s  %12d  %12d  %8s\n",
+			r.Day, profileLabel(r.Profile), r.Model, r.PromptTokens, r.CompletionTokens, fmt.Sprintf("$%.4f", r.CostUSD))
+		if byProfile[r.Profile] == nil {
+			byProfile[r.Profile] = &domain.TokenUsage{}
+		}
+		byProfile[r.Profile].Add(r.TokenUsage)
+		total.Add(r.TokenUsage)
+	}
+
+	profiles := make([]string, 0, len(byProfile))
+	for profile := range byProfile {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	fmt.Fprintln(w, "\n按挖矿方向:")
+	for _, profile := range profiles {
+		u := byProfile[profile]
+		fmt.Fprintf(w, "  %-12s  %12d Token  $%.4f\n", profileLabel(profile), u.Total(), u.CostUSD)
+	}
+	fmt.Fprintf(w, "  %-10s  %12d Token  $%.4f\n", "合计", total.Total(), total.CostUSD)
+}
@@ -6,14 +6,20 @@ import (
 	"sync"
 	"time"
 
+	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
 	"github-gold-miner/internal/port"
 )
 
+// maxBatchAttempts 批量评估中被 LLM 漏掉的项目最多重新排进批次的轮数 (含第一次)，之后逐个评估
+const maxBatchAttempts = 2
+
 // RepoAnalyzer 实现了 port.Analyzer 接口
 type RepoAnalyzer struct {
 	appraiser     port.Appraiser
-	maxGoroutines int // 最大并发数
+	maxGoroutines int           // 最大并发数
+	timeout       time.Duration // 单个项目的评估超时时间
+	batchSize     int           // 每次 LLM 调用评估的项目数，1 表示逐个评估
 	nowFunc       func() time.Time
 }
 
@@ -21,7 +27,9 @@ type RepoAnalyzer struct {
 func NewRepoAnalyzer(appraiser port.Appraiser) *RepoAnalyzer {
 	return &RepoAnalyzer{
 		appraiser:     appraiser,
-		maxGoroutines: 3,        // 默认并发数为3
+		maxGoroutines: 3, // 默认并发数为3
+		timeout:       30 * time.Second,
+		batchSize:     1,
 		nowFunc:       time.Now, // 便于测试注入当前时间
 	}
 }
@@ -33,6 +41,20 @@ func (a *RepoAnalyzer) SetMaxGoroutines(max int) {
 	}
 }
 
+// SetTimeout 设置单个项目的评估超时时间
+func (a *RepoAnalyzer) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		a.timeout = timeout
+	}
+}
+
+// SetBatchSize 设置每次 LLM 调用评估的项目数，大于 1 且鉴定师支持批量评估 (port.BatchAppraiser) 时生效
+func (a *RepoAnalyzer) SetBatchSize(size int) {
+	if size > 0 {
+		a.batchSize = size
+	}
+}
+
 // CalculateStarGrowthRate 计算Star增长率
 func (a *RepoAnalyzer) CalculateStarGrowthRate(repos []*domain.Repo) []*domain.Repo {
 	current := time.Now()
@@ -66,61 +88,138 @@ func (a *RepoAnalyzer) analyzeRepoWorker(
 
 	for repo := range jobs {
 		fmt.Printf("   [Worker-%d] 正在分析 %s...\n", workerID, repo.Name)
+		a.appraiseOne(ctx, repo, results, errors, workerID)
+	}
+}
 
-		// 为每个项目设置超时时间(30秒)
-		projectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+// appraiseOne 评估单个项目，无论成败都把项目发送到 results
+func (a *RepoAnalyzer) appraiseOne(ctx context.Context, repo *domain.Repo, results chan<- *domain.Repo, errors chan<- error, workerID int) {
+	// 为每个项目设置超时时间 (默认 30 秒)
+	projectCtx, cancel := context.WithTimeout(ctx, a.timeout)
 
-		// 使用现有的Appraiser进行分析
-		analyzedRepo, err := a.appraiser.Appraise(projectCtx, repo)
-		cancel() // 立即释放资源
+	// 使用现有的Appraiser进行分析
+	analyzedRepo, err := a.appraiser.Appraise(projectCtx, repo)
+	cancel() // 立即释放资源
 
-		if err != nil {
-			// 如果分析失败，记录错误
-			fmt.Printf("   [Worker-%d] ❌ %s 分析失败: %v\n", workerID, repo.Name, err)
-			errors <- fmt.Errorf("分析 %s 失败: %w", repo.Name, err)
-			// 即使失败也返回原始repo，这样不会阻塞主流程
-			results <- repo
-			continue
-		}
+	if err != nil {
+		// 如果分析失败，记录错误
+		fmt.Printf("   [Worker-%d] ❌ %s 分析失败: %v\n", workerID, repo.Name, err)
+		errors <- fmt.Errorf("分析 %s 失败: %w", repo.Name, err)
+		// 即使失败也返回原始repo，这样不会阻塞主流程
+		results <- repo
+		return
+	}
 
-		// 防止空指针
-		if analyzedRepo == nil {
-			fmt.Printf("   [Worker-%d] ⚠️ %s 返回空结果\n", workerID, repo.Name)
-			results <- repo
-			continue
-		}
+	// 防止空指针
+	if analyzedRepo == nil {
+		fmt.Printf("   [Worker-%d] ⚠️ %s 返回空结果\n", workerID, repo.Name)
+		results <- repo
+		return
+	}
 
-		// 更新repo信息
-		repo.IsAIProgrammingTool = analyzedRepo.IsAIProgrammingTool
-		repo.LLMScore = analyzedRepo.LLMScore
-		repo.LLMReview = analyzedRepo.LLMReview
+	applyAppraisal(repo, analyzedRepo)
+	fmt.Printf("   [Worker-%d] ✅ %s 分析完成 (评分: %d)\n", workerID, repo.Name, repo.LLMScore)
+	results <- repo
+}
 
-		fmt.Printf("   [Worker-%d] ✅ %s 分析完成 (评分: %d)\n", workerID, repo.Name, repo.LLMScore)
-		results <- repo
+// analyzeBatchWorker 工作协程，一次 LLM 调用评估一批项目
+// LLM 漏掉的项目重新组成一批评估，超过 maxBatchAttempts 轮后逐个评估；整批调用失败时这批项目都记为失败
+func (a *RepoAnalyzer) analyzeBatchWorker(
+	ctx context.Context,
+	batcher port.BatchAppraiser,
+	jobs <-chan []*domain.Repo,
+	results chan<- *domain.Repo,
+	errors chan<- error,
+	wg *sync.WaitGroup,
+	workerID int,
+) {
+	defer wg.Done()
+
+	for pending := range jobs {
+		for attempt := 1; attempt <= maxBatchAttempts && len(pending) > 0; attempt++ {
+			fmt.Printf("   [Worker-%d] 正在批量分析 %d 个项目 (第 %d 轮)...\n", workerID, len(pending), attempt)
+
+			// 一批项目的超时时间按项目数累加
+			batchCtx, cancel := context.WithTimeout(ctx, a.timeout*time.Duration(len(pending)))
+			appraised, err := batcher.AppraiseBatch(batchCtx, pending)
+			cancel()
+
+			if err != nil {
+				fmt.Printf("   [Worker-%d] ❌ 批量分析失败: %v\n", workerID, err)
+				for _, repo := range pending {
+					errors <- fmt.Errorf("分析 %s 失败: %w", repo.Name, err)
+					results <- repo
+				}
+				pending = nil
+				break
+			}
+
+			var missing []*domain.Repo
+			for _, repo := range pending {
+				analyzedRepo := appraised[repo.ID]
+				if analyzedRepo == nil {
+					missing = append(missing, repo)
+					continue
+				}
+				applyAppraisal(repo, analyzedRepo)
+				fmt.Printf("   [Worker-%d] ✅ %s 分析完成 (评分: %d)\n", workerID, repo.Name, repo.LLMScore)
+				results <- repo
+			}
+			if len(missing) > 0 {
+				fmt.Printf("   [Worker-%d] ⚠️ %d 个项目没有返回有效结果，重新评估\n", workerID, len(missing))
+			}
+			pending = missing
+		}
+
+		for _, repo := range pending {
+			fmt.Printf("   [Worker-%d] 正在逐个分析 %s...\n", workerID, repo.Name)
+			a.appraiseOne(ctx, repo, results, errors, workerID)
+		}
 	}
 }
 
+// applyAppraisal 把评估结果回填到项目
+func applyAppraisal(repo, analyzedRepo *domain.Repo) {
+	repo.IsAIProgrammingTool = analyzedRepo.IsAIProgrammingTool
+	repo.LLMScore = analyzedRepo.LLMScore
+	repo.LLMReview = analyzedRepo.LLMReview
+	repo.Categories = analyzedRepo.Categories
+	repo.AppraisalModel = analyzedRepo.AppraisalModel
+	repo.PromptVersion = analyzedRepo.PromptVersion
+	repo.AppraisedAt = analyzedRepo.AppraisedAt
+}
+
 // AnalyzeWithLLM 使用LLM并发分析项目是否为AI编程工具及其评分
 func (a *RepoAnalyzer) AnalyzeWithLLM(ctx context.Context, repos []*domain.Repo) ([]*domain.Repo, error) {
-	fmt.Printf("🤖 开始LLM分析，共 %d 个项目，最大并发数: %d\n", len(repos), a.maxGoroutines)
-
-	// 创建channel用于传递jobs和results
-	jobs := make(chan *domain.Repo, len(repos))
+	// 创建channel用于传递results
 	results := make(chan *domain.Repo, len(repos))
 	errors := make(chan error, len(repos))
 
-	// 启动workers
+	// 启动workers并发送jobs
 	var wg sync.WaitGroup
-	for i := 0; i < a.maxGoroutines; i++ {
-		wg.Add(1)
-		go a.analyzeRepoWorker(ctx, jobs, results, errors, &wg, i+1)
-	}
-
-	// 发送jobs
-	for _, repo := range repos {
-		jobs <- repo
+	if batcher, ok := a.appraiser.(port.BatchAppraiser); ok && a.batchSize > 1 {
+		fmt.Printf("🤖 开始LLM分析，共 %d 个项目，每批 %d 个，最大并发数: %d\n", len(repos), a.batchSize, a.maxGoroutines)
+		jobs := make(chan []*domain.Repo, len(repos)/a.batchSize+1)
+		for i := 0; i < a.maxGoroutines; i++ {
+			wg.Add(1)
+			go a.analyzeBatchWorker(ctx, batcher, jobs, results, errors, &wg, i+1)
+		}
+		for start := 0; start < len(repos); start += a.batchSize {
+			jobs <- repos[start:min(start+a.batchSize, len(repos))]
+		}
+		close(jobs)
+	} else {
+		fmt.Printf("🤖 开始LLM分析，共 %d 个项目，最大并发数: %d\n", len(repos), a.maxGoroutines)
+		jobs := make(chan *domain.Repo, len(repos))
+		for i := 0; i < a.maxGoroutines; i++ {
+			wg.Add(1)
+			go a.analyzeRepoWorker(ctx, jobs, results, errors, &wg, i+1)
+		}
+		for _, repo := range repos {
+			jobs <- repo
+		}
+		close(jobs)
 	}
-	close(jobs)
 
 	// 等待所有workers完成
 	done := make(chan struct{})
@@ -152,10 +251,11 @@ func (a *RepoAnalyzer) AnalyzeWithLLM(ctx context.Context, repos []*domain.Repo)
 		analyzedRepos = append(analyzedRepos, result)
 	}
 
-	// 收集并打印错误信息
+	// 收集并打印错误信息，同时上报给调用方按错误码统计
 	var collectedErrors []error
 	for err := range errors {
 		collectedErrors = append(collectedErrors, err)
+		common.RecordError(ctx, err)
 	}
 	if len(collectedErrors) > 0 {
 		fmt.Printf("⚠️  共有 %d 个分析错误:\n", len(collectedErrors))
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,9 +22,10 @@ func (m *MockAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domai
 	return args.Get(0).(*domain.Repo), args.Error(1)
 }
 
-func (m *MockAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) (string, error) {
+func (m *MockAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error) {
 	args := m.Called(ctx, repos, userQuery)
-	return args.String(0), args.Error(1)
+	results, _ := args.Get(0).([]*domain.SearchResult)
+	return results, args.Error(1)
 }
 
 func TestRepoAnalyzer_CalculateStarGrowthRate(t *testing.T) {
@@ -200,3 +202,89 @@ func TestRepoAnalyzer_SetMaxGoroutines(t *testing.T) {
 		})
 	}
 }
+
+// fakeBatchAppraiser 批量评估时按 missing 漏掉项目 (值为漏掉的轮数)，记录每批的项目 ID
+type fakeBatchAppraiser struct {
+	mu      sync.Mutex
+	missing map[string]int
+	err     error
+	batches [][]string
+	singles []string
+}
+
+func (f *fakeBatchAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.singles = append(f.singles, repo.ID)
+	return &domain.Repo{ID: repo.ID, IsAIProgrammingTool: true, LLMScore: 60, PromptVersion: "v2"}, nil
+}
+
+func (f *fakeBatchAppraiser) AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	results := make(map[string]*domain.Repo)
+	for _, repo := range repos {
+		ids = append(ids, repo.ID)
+		if f.missing[repo.ID] > 0 {
+			f.missing[repo.ID]--
+			continue
+		}
+		results[repo.ID] = &domain.Repo{ID: repo.ID, IsAIProgrammingTool: true, LLMScore: 80, PromptVersion: "v2"}
+	}
+	f.batches = append(f.batches, ids)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return results, nil
+}
+
+func (f *fakeBatchAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error) {
+	return nil, nil
+}
+
+func TestRepoAnalyzer_AnalyzeWithLLM_Batch(t *testing.T) {
+	var repos []*domain.Repo
+	for _, id := range []string{"r1", "r2", "r3", "r4", "r5"} {
+		repos = append(repos, &domain.Repo{ID: id, Name: "acme/" + id})
+	}
+	// r2 第一轮被漏掉，r5 两轮都被漏掉
+	appraiser := &fakeBatchAppraiser{missing: map[string]int{"r2": 1, "r5": 2}}
+	analyzer := NewRepoAnalyzer(appraiser)
+	analyzer.SetMaxGoroutines(1)
+	analyzer.SetBatchSize(3)
+
+	result, err := analyzer.AnalyzeWithLLM(context.Background(), repos)
+	assert.NoError(t, err)
+	assert.Len(t, result, 5)
+	// 只重新评估漏掉的项目，两轮后仍漏掉的项目逐个评估
+	assert.Equal(t, [][]string{{"r1", "r2", "r3"}, {"r2"}, {"r4", "r5"}, {"r5"}}, appraiser.batches)
+	assert.Equal(t, []string{"r5"}, appraiser.singles)
+	for _, repo := range repos {
+		assert.True(t, repo.IsAIProgrammingTool, repo.ID)
+		assert.Equal(t, "v2", repo.PromptVersion)
+	}
+	assert.Equal(t, 60, repos[4].LLMScore)
+}
+
+func TestRepoAnalyzer_AnalyzeWithLLM_BatchError(t *testing.T) {
+	repos := []*domain.Repo{{ID: "r1", Name: "acme/r1"}, {ID: "r2", Name: "acme/r2"}}
+	appraiser := &fakeBatchAppraiser{err: errors.New("超出预算")}
+	analyzer := NewRepoAnalyzer(appraiser)
+	analyzer.SetBatchSize(5)
+
+	// 整批失败时不再逐个重试
+	result, err := analyzer.AnalyzeWithLLM(context.Background(), repos)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Len(t, appraiser.batches, 1)
+	assert.Empty(t, appraiser.singles)
+	assert.Zero(t, repos[0].LLMScore)
+
+	// 批大小为 1 时逐个评估
+	analyzer.SetBatchSize(1)
+	_, err = analyzer.AnalyzeWithLLM(context.Background(), repos)
+	assert.NoError(t, err)
+	assert.Len(t, appraiser.batches, 1)
+	assert.ElementsMatch(t, []string{"r1", "r2"}, appraiser.singles)
+}
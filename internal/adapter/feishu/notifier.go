@@ -3,109 +3,70 @@ package feishu
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github-gold-miner/internal/adapter/render"
 	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
 )
 
 type Notifier struct {
 	webhookURL string
+	renderer   *render.Renderer
 }
 
 func NewNotifier(webhook string) *Notifier {
 	if webhook == "" {
 		log.Println("⚠️ 警告: 飞书 Webhook 为空，推送功能将无法工作！")
 	}
-	return &Notifier{webhookURL: webhook}
+	return &Notifier{webhookURL: webhook, renderer: render.Default()}
 }
 
-// Notify 发送飞书卡片消息 (Schema 2.0)
-func (n *Notifier) Notify(ctx context.Context, repo *domain.Repo) error {
-	if n.webhookURL == "" {
-		return fmt.Errorf("Webhook URL 为空")
+// SetRenderer 替换消息模板渲染器 (例如加载了用户自定义模板)
+func (n *Notifier) SetRenderer(r *render.Renderer) {
+	if r != nil {
+		n.renderer = r
 	}
+}
 
-	// 1. 准备标题
-	title := fmt.Sprintf("🚨 发现AI编程工具: %s", repo.Name)
+// Notify 发送飞书卡片消息 (Schema 2.0)
+func (n *Notifier) Notify(ctx context.Context, repo *domain.Repo) error {
+	return n.send(ctx, render.KindSingle, render.Data{Repo: repo})
+}
 
-	// 2. 构造 Markdown 内容
-	mdContent := fmt.Sprintf(`**⭐ Stars:** %d  |  **语言:** %s  |  **创建日期:** %s
-**🏆 LLM评分:** %d/100
+// NotifyDigest 把多个项目汇总成一张卡片发送
+func (n *Notifier) NotifyDigest(ctx context.Context, title string, repos []*domain.Repo) error {
+	return n.send(ctx, render.KindDigest, render.Data{Title: title, Repos: repos})
+}
 
-**📝 项目描述:**
-%s
+// NotifyAlert 发送告警卡片 (例如预算超限、Webhook 失效)
+func (n *Notifier) NotifyAlert(ctx context.Context, level, title, message string) error {
+	return n.send(ctx, render.KindAlert, render.Data{Level: level, Title: title, Message: message})
+}
 
-**🤖 AI评价:**
-%s
+// NotifyUpdate 发送推送后的追踪更新 (爆发/撤回)
+// Webhook 机器人无法修改已发送的卡片，因此以新卡片的形式发送，沿用相同的 update_multi 卡片配置
+func (n *Notifier) NotifyUpdate(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error {
+	return n.send(ctx, render.KindUpdate, render.Data{Repo: repo, Event: event})
+}
 
-**📈 Star增长速率:** %.2f stars/天
-`,
-		repo.Stars, repo.Language, repo.CreatedAt.Format("2006-01-02"),
-		repo.LLMScore,
-		repo.Description,
-		repo.LLMReview,
-		repo.StarGrowthRate)
+// send 渲染指定类型的模板并发送
+func (n *Notifier) send(ctx context.Context, kind render.Kind, data render.Data) error {
+	if n.webhookURL == "" {
+		return fmt.Errorf("Webhook URL 为空")
+	}
 
-	// 3. 构造 Schema 2.0 JSON 结构 (飞书卡片格式)
-	payload := map[string]interface{}{
-		"msg_type": "interactive",
-		"card": map[string]interface{}{
-			"schema": "2.0",
-			"config": map[string]interface{}{
-				"update_multi": true,
-			},
-			"header": map[string]interface{}{
-				"title": map[string]interface{}{
-					"tag":     "plain_text",
-					"content": title,
-				},
-				"template": "blue",
-				"padding":  "12px 12px 12px 12px",
-			},
-			"body": map[string]interface{}{
-				"direction": "vertical",
-				"padding":   "12px 12px 12px 12px",
-				"elements": []map[string]interface{}{
-					{
-						"tag":        "markdown",
-						"content":    mdContent,
-						"text_align": "left",
-						"text_size":  "normal_v2",
-						"margin":     "0px 0px 0px 0px",
-					},
-					{
-						"tag": "button",
-						"text": map[string]interface{}{
-							"tag":     "plain_text",
-							"content": "🔗 查看源码",
-						},
-						"type":   "default",
-						"width":  "default",
-						"size":   "medium",
-						"margin": "0px 0px 0px 0px",
-						"behaviors": []map[string]interface{}{
-							{
-								"type":        "open_url",
-								"default_url": repo.URL,
-								"pc_url":      "",
-								"ios_url":     "",
-								"android_url": "",
-							},
-						},
-					},
-				},
-			},
-		},
+	// 1. 渲染卡片 JSON (飞书卡片 Schema 2.0，模板可由用户覆盖)
+	body, err := n.renderer.RenderJSON(render.ChannelFeishu, kind, data)
+	if err != nil {
+		return fmt.Errorf("渲染消息失败: %w", err)
 	}
 
-	// 4. 发送请求 (带重试机制)
-	body, _ := json.Marshal(payload)
-	err := common.Do(ctx, func() error {
+	// 2. 发送请求 (带重试机制)
+	err = common.Do(ctx, func() error {
 		resp, postErr := http.Post(n.webhookURL, "application/json", bytes.NewBuffer(body))
 		if postErr != nil {
 			return postErr
@@ -113,16 +74,17 @@ func (n *Notifier) Notify(ctx context.Context, repo *domain.Repo) error {
 		defer resp.Body.Close()
 
 		if resp.StatusCode != 200 {
-			return fmt.Errorf("飞书 API 报错: 状态码 %d", resp.StatusCode)
+			return common.NewHTTPError("飞书 API", resp)
 		}
 		return nil
 	},
 		common.WithMaxRetries(3),
 		common.WithInitialDelay(500*time.Millisecond),
+		common.LogRetry("飞书通知", common.NotifyError),
 	)
 	if err != nil {
-		return fmt.Errorf("发送请求失败: %w", err)
+		return common.NotifyError("发送请求失败", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
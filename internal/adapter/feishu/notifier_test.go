@@ -6,9 +6,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github-gold-miner/internal/adapter/render"
 	"github-gold-miner/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
@@ -519,3 +522,99 @@ func TestNotifier_Notify_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifier_NotifyDigestAndAlert(t *testing.T) {
+	now := time.Now()
+	repos := []*domain.Repo{
+		{ID: "github-1", Name: "test/digest-one", URL: "https://github.com/test/digest-one", Stars: 1500, LLMScore: 88, CreatedAt: now.AddDate(0, 0, -2)},
+		{ID: "github-2", Name: "test/digest-two", URL: "https://github.com/test/digest-two", Stars: 30, LLMScore: 61, CreatedAt: now.AddDate(0, 0, -1)},
+	}
+
+	t.Run("汇总卡片", func(t *testing.T) {
+		server := mockFeishuServer(t, http.StatusOK, func(t *testing.T, payload map[string]interface{}) {
+			card := payload["card"].(map[string]interface{})
+			title := card["header"].(map[string]interface{})["title"].(map[string]interface{})
+			assert.Contains(t, title["content"], "本周精选 (2)")
+
+			elements := card["body"].(map[string]interface{})["elements"].([]interface{})
+			content := elements[0].(map[string]interface{})["content"].(string)
+			assert.Contains(t, content, "test/digest-one")
+			assert.Contains(t, content, "1.5k")
+			assert.Contains(t, content, "test/digest-two")
+		})
+		defer server.Close()
+
+		err := NewNotifier(server.URL).NotifyDigest(context.Background(), "本周精选", repos)
+		assert.NoError(t, err)
+	})
+
+	t.Run("告警卡片", func(t *testing.T) {
+		server := mockFeishuServer(t, http.StatusOK, func(t *testing.T, payload map[string]interface{}) {
+			card := payload["card"].(map[string]interface{})
+			header := card["header"].(map[string]interface{})
+			assert.Equal(t, "orange", header["template"])
+			assert.Contains(t, header["title"].(map[string]interface{})["content"], "预算告警")
+		})
+		defer server.Close()
+
+		err := NewNotifier(server.URL).NotifyAlert(context.Background(), "warning", "预算告警", "今日花费超过 90%")
+		assert.NoError(t, err)
+	})
+}
+
+func TestNotifier_NotifyUpdate(t *testing.T) {
+	notifiedAt := time.Now().AddDate(0, 0, -3)
+	repo := &domain.Repo{ID: "github-1", Name: "test/tracked", URL: "https://github.com/test/tracked", Stars: 400, LLMScore: 80, NotifiedAt: &notifiedAt}
+
+	tests := []struct {
+		name     string
+		event    *domain.TrackingEvent
+		template string
+		title    string
+	}{
+		{name: "爆发", event: &domain.TrackingEvent{Type: domain.EventBreakout, PreviousStars: 400, CurrentStars: 1200, Milestone: 1000}, template: "orange", title: "突破 1k Stars"},
+		{name: "归档撤回", event: &domain.TrackingEvent{Type: domain.EventArchived, PreviousStars: 400, CurrentStars: 410}, template: "grey", title: "已归档"},
+		{name: "删除撤回", event: &domain.TrackingEvent{Type: domain.EventDeleted, PreviousStars: 400, CurrentStars: 400}, template: "grey", title: "已删除"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mockFeishuServer(t, http.StatusOK, func(t *testing.T, payload map[string]interface{}) {
+				card := payload["card"].(map[string]interface{})
+				// 与首次推送使用相同的卡片配置
+				assert.Equal(t, true, card["config"].(map[string]interface{})["update_multi"])
+				header := card["header"].(map[string]interface{})
+				assert.Equal(t, tt.template, header["template"])
+				assert.Contains(t, header["title"].(map[string]interface{})["content"], tt.title)
+			})
+			defer server.Close()
+
+			err := NewNotifier(server.URL).NotifyUpdate(context.Background(), repo, tt.event)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNotifier_CustomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, render.ChannelFeishu), 0o755))
+	assert.NoError(t, os.WriteFile(
+		filepath.Join(dir, render.ChannelFeishu, "single.tmpl"),
+		[]byte(`{"msg_type": "text", "content": {"text": {{json (printf "新项目 %s ⭐%s" .Repo.Name (humanize .Repo.Stars))}}}}`),
+		0o644,
+	))
+	renderer, err := render.New(dir)
+	assert.NoError(t, err)
+
+	server := mockFeishuServer(t, http.StatusOK, func(t *testing.T, payload map[string]interface{}) {
+		assert.Equal(t, "text", payload["msg_type"])
+		content := payload["content"].(map[string]interface{})
+		assert.Equal(t, "新项目 test/custom ⭐2.5k", content["text"])
+	})
+	defer server.Close()
+
+	notifier := NewNotifier(server.URL)
+	notifier.SetRenderer(renderer)
+	err = notifier.Notify(context.Background(), &domain.Repo{Name: "test/custom", Stars: 2500})
+	assert.NoError(t, err)
+}
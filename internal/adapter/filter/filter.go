@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -17,29 +18,39 @@ import (
 
 // RepoFilter 实现了 port.Filter 接口
 type RepoFilter struct {
-	client  *github.Client
-	nowFunc func() time.Time
+	client   *github.Client
+	requests *common.RequestCounter
+	nowFunc  func() time.Time
 }
 
 // NewRepoFilter 创建新的过滤器实例
 func NewRepoFilter(token string) *RepoFilter {
-	var client *github.Client
-
-	if token == "" {
-		client = github.NewClient(nil)
-	} else {
+	// 所有请求经过计数器，用于统计每轮挖矿消耗的 API 配额
+	requests := &common.RequestCounter{}
+	httpClient := &http.Client{}
+	if token != "" {
 		ctx := context.Background()
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
-		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
+		httpClient = oauth2.NewClient(ctx, ts)
 	}
+	httpClient.Transport = requests.Wrap(httpClient.Transport)
+	client := github.NewClient(httpClient)
 
 	return &RepoFilter{
-		client:  client,
-		nowFunc: time.Now,
+		client:   client,
+		requests: requests,
+		nowFunc:  time.Now,
+	}
+}
+
+// Requests 返回累计的 GitHub API 请求次数，实现 port.RequestCounter
+func (f *RepoFilter) Requests() int64 {
+	if f.requests == nil {
+		return 0
 	}
+	return f.requests.Count()
 }
 
 // FilterByCreatedAt 过滤掉创建时间超过指定天数的项目
@@ -125,7 +136,7 @@ func (f *RepoFilter) hasNonReadmeCommit(ctx context.Context, owner, repoName str
 	})
 
 	if err != nil {
-		return false, fmt.Errorf("获取提交列表失败: %w", err)
+		return false, common.GitHubError("获取提交列表失败", err)
 	}
 
 	if len(commits) == 0 {
@@ -179,10 +190,11 @@ func (f *RepoFilter) commitHasNonReadmeChanges(ctx context.Context, owner, repoN
 			return err
 		}
 		return nil
-	}, common.WithMaxRetries(2), common.WithInitialDelay(500*time.Millisecond))
+	}, common.WithMaxRetries(2), common.WithInitialDelay(500*time.Millisecond),
+		common.WithJitter(common.FullJitter), common.LogRetry("GitHub API", common.GitHubError))
 
 	if retryErr != nil {
-		return false, fmt.Errorf("获取提交详情失败 (SHA: %s): %w", sha, retryErr)
+		return false, common.GitHubError(fmt.Sprintf("获取提交详情失败 (SHA: %s)", sha), retryErr)
 	}
 
 	if commit == nil || len(commit.Files) == 0 {
@@ -2,21 +2,41 @@ package gemini
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
 )
 
+const (
+	// defaultModel 默认使用的 Gemini 模型
+	defaultModel = "gemini-2.5-pro"
+
+	// promptVersion 评估 Prompt 的版本，修改 Prompt 时递增，便于在评估历史中对比
+	promptVersion = "v2"
+
+	// expectedCompletionTokens 限流时预估的每次调用输出 Token 数
+	expectedCompletionTokens = 1024
+)
+
 type GeminiAppraiser struct {
-	client *genai.Client
-	model  ContentGenerator // 👈 修改点：这里使用接口类型，而不是具体的结构体指针
+	client    *genai.Client
+	model     ContentGenerator // 👈 修改点：这里使用接口类型，而不是具体的结构体指针
+	modelName string
+	tokens    atomic.Int64       // 累计消耗的 Token 数
+	limiter   *common.LLMLimiter // 所有调用共用的限流器，为 nil 时不限流
 }
 
 func NewGeminiAppraiser(ctx context.Context, apiKey string) (*GeminiAppraiser, error) {
@@ -25,13 +45,14 @@ func NewGeminiAppraiser(ctx context.Context, apiKey string) (*GeminiAppraiser, e
 		return nil, err
 	}
 
-	model := client.GenerativeModel("gemini-2.5-pro")
+	model := client.GenerativeModel(defaultModel)
 	// 强制要求返回 JSON，降低解析错误的概率
 	model.ResponseMIMEType = "application/json"
 
 	return &GeminiAppraiser{
-		client: client,
-		model:  model,
+		client:    client,
+		model:     chatModel{model},
+		modelName: defaultModel,
 	}, nil
 }
 
@@ -43,24 +64,158 @@ func (g *GeminiAppraiser) Close() error {
 	return nil
 }
 
+// SetLimiter 设置限流器，评估、检索和对话的所有调用都按它排队
+// 派生出的自定义标准鉴定师共用同一个限流器，应在开始调用前设置
+func (g *GeminiAppraiser) SetLimiter(limiter *common.LLMLimiter) {
+	g.limiter = limiter
+}
+
+// TokensUsed 返回累计消耗的 Token 数，实现 port.TokenCounter
+func (g *GeminiAppraiser) TokensUsed() int64 {
+	return g.tokens.Load()
+}
+
+// recordUsage 累加一次调用的 Token 用量 (重试的调用同样计费)，并记录到 ctx 中的 common.UsageMeter
+// 输出 Token 按总数减去输入计算，包括思考过程的 Token
+func (g *GeminiAppraiser) recordUsage(ctx context.Context, resp *genai.GenerateContentResponse) {
+	if resp != nil && resp.UsageMetadata != nil {
+		usage := resp.UsageMetadata
+		g.tokens.Add(int64(usage.TotalTokenCount))
+		prompt := int64(usage.PromptTokenCount)
+		common.RecordUsage(ctx, g.modelName, prompt, max(int64(usage.TotalTokenCount)-prompt, 0))
+	}
+}
+
+// generate 经过限流器调用一次模型 (不重试)，并记录用量
+func (g *GeminiAppraiser) generate(ctx context.Context, prompt string) (*genai.GenerateContentResponse, error) {
+	return g.call(ctx, estimateTokens(prompt), func() (*genai.GenerateContentResponse, error) {
+		return g.model.GenerateContent(ctx, genai.Text(prompt))
+	})
+}
+
+// call 经过限流器执行一次模型调用 (不重试)，estimate 为预估的 Token 用量
+func (g *GeminiAppraiser) call(ctx context.Context, estimate int64, fn func() (*genai.GenerateContentResponse, error)) (*genai.GenerateContentResponse, error) {
+	var resp *genai.GenerateContentResponse
+	err := g.limiter.Do(ctx, estimate, func() (int64, error) {
+		var err error
+		resp, err = fn()
+		if err != nil {
+			// 被安全策略拦截时重试也会被拦截
+			var blocked *genai.BlockedError
+			if errors.As(err, &blocked) {
+				err = common.Permanent(common.WrapError(common.ErrCodeLLMSafetyBlocked, "AI 拒绝回答", err))
+			}
+			return 0, err
+		}
+		g.recordUsage(ctx, resp)
+		if resp.UsageMetadata == nil {
+			return 0, nil
+		}
+		return int64(resp.UsageMetadata.TotalTokenCount), nil
+	})
+	return resp, err
+}
+
+// estimateTokens 调用前预估的 Token 用量，用于 TPM 限流：输入按每两个字符一个 Token 粗略估计，
+// 输出 (含思考过程) 按 expectedCompletionTokens 估计，调用后按实际用量修正
+func estimateTokens(prompt string) int64 {
+	return int64(utf8.RuneCountInString(prompt)/2) + expectedCompletionTokens
+}
+
 // ContentGenerator 定义了我们需要用到的 AI 能力
 // 这样我们在测试时就可以用假的实现来替换真的 SDK
 type ContentGenerator interface {
 	GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
 }
 
+// HistoryGenerator 能在已有对话历史上继续生成的模型，多轮对话时历史按轮次发送，
+// 不需要每一轮都把候选项目和说明重新拼进一个 Prompt
+type HistoryGenerator interface {
+	GenerateWithHistory(ctx context.Context, history []*genai.Content, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+}
+
+// chatModel 用 genai.ChatSession 在对话历史上继续生成
+type chatModel struct {
+	*genai.GenerativeModel
+}
+
+func (m chatModel) GenerateWithHistory(ctx context.Context, history []*genai.Content, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	cs := m.StartChat()
+	// SendMessage 会追加到 History，使用副本，失败重试时不会污染调用方的历史
+	cs.History = append([]*genai.Content(nil), history...)
+	return cs.SendMessage(ctx, parts...)
+}
+
 // 1. 修改接收 AI 结果的结构体 (在 Appraiser 结构体下方)
 type aiResponse struct {
-	IsAIProgrammingTool bool   `json:"is_ai_programming_tool"`
-	LLMScore            int    `json:"llm_score"`
-	LLMReview           string `json:"llm_review"`
+	IsAIProgrammingTool bool     `json:"is_ai_programming_tool"`
+	LLMScore            int      `json:"llm_score"`
+	LLMReview           string   `json:"llm_review"`
+	Categories          []string `json:"categories"`
 }
 
 // Appraise 评估项目是否为AI编程工具
 func (g *GeminiAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	return g.appraise(ctx, repo, "")
+}
+
+// Model 返回评估使用的模型名称，实现 port.ModelNamer
+func (g *GeminiAppraiser) Model() string {
+	return g.modelName
+}
+
+// PromptVersion 返回评估 Prompt 的版本，实现 port.PromptVersioner
+func (g *GeminiAppraiser) PromptVersion() string {
+	return promptVersion
+}
+
+// WithCriteria 返回按自定义标准评估的鉴定师，实现 port.CriteriaAppraiser
+// 返回的鉴定师与原鉴定师共用客户端和 Token 统计；criteria 为空时使用默认标准
+func (g *GeminiAppraiser) WithCriteria(criteria string) port.Appraiser {
+	criteria = strings.TrimSpace(criteria)
+	if criteria == "" {
+		return g
+	}
+	return &criteriaAppraiser{GeminiAppraiser: g, criteria: criteria}
+}
+
+// criteriaAppraiser 按挖矿方向自定义的标准评估项目
+type criteriaAppraiser struct {
+	*GeminiAppraiser
+	criteria string
+}
+
+func (c *criteriaAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	return c.appraise(ctx, repo, c.criteria)
+}
+
+func (c *criteriaAppraiser) PromptVersion() string {
+	return criteriaPromptVersion(c.criteria)
+}
+
+// criteriaPromptVersion 自定义标准的 Prompt 版本，带上标准的摘要，标准变化后评估历史可以区分
+func criteriaPromptVersion(criteria string) string {
+	if criteria == "" {
+		return promptVersion
+	}
+	sum := sha256.Sum256([]byte(criteria))
+	return promptVersion + "+" + hex.EncodeToString(sum[:4])
+}
+
+// appraisePrompt 评估 Prompt；criteria 为空时判断是否为 AI 编程工具，否则判断是否符合 criteria
+func appraisePrompt(repo *domain.Repo, criteria string) string {
+	question := "判断它是否为AI编程工具（如AI代码助手、机器学习库、自然语言处理工具等）"
+	matchHint := "如果是AI编程工具则分数较高，否则较低"
+	reviewHint := "说明为什么认为它是或不是AI编程工具"
+	if criteria != "" {
+		question = "判断它是否符合以下要求：" + criteria
+		matchHint = "越符合要求分数越高"
+		reviewHint = "说明为什么认为它符合或不符合要求"
+	}
+
 	// 这是一个极具针对性的 Prompt
-	prompt := fmt.Sprintf(`
-请分析以下GitHub项目，判断它是否为AI编程工具（如AI代码助手、机器学习库、自然语言处理工具等）。
+	return fmt.Sprintf(`
+请分析以下GitHub项目，%s。
 
 项目名称：%s
 项目描述：%s
@@ -69,39 +224,50 @@ func (g *GeminiAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*dom
 请严格按照以下JSON格式返回结果（严禁Markdown，必须是纯JSON）：
 {
   "is_ai_programming_tool": true/false,
-  "llm_score": 1-100的整数分数（如果是AI编程工具则分数较高，否则较低）,
-  "llm_review": "简短评价，说明为什么认为它是或不是AI编程工具"
+  "llm_score": 1-100的整数分数（%s）,
+  "llm_review": "简短评价，%s",
+  "categories": ["从以下分类中选择1-3个: %s"]
+}
+`, question, repo.Name, repo.Description, repo.URL, matchHint, reviewHint, strings.Join(domain.Categories(), ", "))
 }
-`, repo.Name, repo.Description, repo.URL)
+
+func (g *GeminiAppraiser) appraise(ctx context.Context, repo *domain.Repo, criteria string) (*domain.Repo, error) {
+	// 超出费用预算时不再调用
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
+	}
+	prompt := appraisePrompt(repo, criteria)
 
 	// 2. 调用 AI (带重试机制)
 	var resp *genai.GenerateContentResponse
 	err := common.Do(ctx, func() error {
 		var apiErr error
-		resp, apiErr = g.model.GenerateContent(ctx, genai.Text(prompt))
+		resp, apiErr = g.generate(ctx, prompt)
 		if apiErr != nil {
 			return apiErr
 		}
 		// 空响应也视为需要重试的错误
-		if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-			return fmt.Errorf("AI 返回内容为空")
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+			return common.NewError(common.ErrCodeLLMEmptyResponse, "AI 返回内容为空")
 		}
 		return nil
 	},
 		common.WithMaxRetries(5),
 		common.WithInitialDelay(2*time.Second),
 		common.WithMaxDelay(30*time.Second),
+		common.WithJitter(common.DecorrelatedJitter),
+		common.LogRetry("Gemini", common.LLMError),
 	)
 	if err != nil {
 		// 即使 AI 挂了，也要返回 repo，防止 main.go 崩溃
-		return repo, fmt.Errorf("AI 调用失败: %w", err)
+		return repo, common.LLMError("AI 调用失败", err)
 	}
 
 	// 3. 解析结果 (智能清洗逻辑)
 	part := resp.Candidates[0].Content.Parts[0]
 	jsonStr, ok := part.(genai.Text)
 	if !ok {
-		return repo, fmt.Errorf("AI 返回格式错误")
+		return repo, common.NewError(common.ErrCodeLLMInvalidJSON, "AI 返回格式错误")
 	}
 
 	// ... 获取到 rawContent 字符串后 ...
@@ -110,13 +276,19 @@ func (g *GeminiAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*dom
 	// 👇 修改点：直接调用提取出来的函数
 	res, err := parseAIResponse(rawContent)
 	if err != nil {
-		return repo, fmt.Errorf("解析响应失败: %w | 原文: %s", err, rawContent)
+		return repo, common.WrapError(common.ErrCodeLLMInvalidJSON, "解析响应失败", fmt.Errorf("%w | 原文: %s", err, rawContent))
 	}
 
 	// 回填数据
 	repo.IsAIProgrammingTool = res.IsAIProgrammingTool
 	repo.LLMScore = res.LLMScore
 	repo.LLMReview = res.LLMReview
+	repo.Categories = normalizeCategories(res.Categories)
+
+	appraisedAt := time.Now()
+	repo.AppraisalModel = g.modelName
+	repo.PromptVersion = criteriaPromptVersion(criteria)
+	repo.AppraisedAt = &appraisedAt
 
 	return repo, nil
 }
@@ -143,18 +315,39 @@ func parseAIResponse(rawContent string) (*aiResponse, error) {
 	return &res, nil
 }
 
-// SemanticSearch 让 AI 根据用户意图，从数据库中筛选项目
-func (g *GeminiAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) (string, error) {
-	// 1. 数据精简：为了节省 Token，我们只把关键字段喂给 AI
-	// 我们创建一个临时的精简结构体，或者直接拼接字符串
-	var promptData strings.Builder
-	for i, r := range repos {
-		promptData.WriteString(fmt.Sprintf("%d. ID: %s | 名称: %s\n", i+1, r.ID, r.Name))
-		promptData.WriteString(fmt.Sprintf("   [描述]: %s\n", r.Description))
-		promptData.WriteString(fmt.Sprintf("   [LLM评分]: %d\n", r.LLMScore))
-		promptData.WriteString(fmt.Sprintf("   [LLM评价]: %s\n", r.LLMReview))
-		promptData.WriteString("---\n")
+// normalizeCategories 丢弃不在分类表中的值，避免 LLM 自造分类影响路由
+func normalizeCategories(raw []string) []string {
+	known := make(map[string]bool)
+	for _, c := range domain.Categories() {
+		known[c] = true
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, c := range raw {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if known[c] && !seen[c] {
+			seen[c] = true
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// searchResponse SemanticSearch 要求 AI 返回的 JSON 结构
+type searchResponse struct {
+	Results []*domain.SearchResult `json:"results"`
+}
+
+// SemanticSearch 让 AI 根据用户意图，从候选项目中挑选最匹配的项目
+// 返回的 RepoID 由调用方对照候选集合校验，这里只负责解析
+func (g *GeminiAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error) {
+	// 超出费用预算时不再调用
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
 	}
+	// 1. 数据精简：为了节省 Token，我们只把关键字段喂给 AI
+	candidates := formatCandidates(repos)
 
 	// 2. 构造"AI 选品"提示词
 	prompt := fmt.Sprintf(`
@@ -163,44 +356,107 @@ func (g *GeminiAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Re
 
 用户的搜索请求是："%s"
 
-请根据用户的真实意图，从上述列表中**挑选出最匹配的 1-3 个项目**。
-
-请按以下格式输出分析结果（直接输出文本，不要 JSON）：
+请根据用户的真实意图，从上述列表中**挑选出最匹配的 1-3 个项目**，按相关度从高到低排列。
 
-### 🎯 最佳匹配：[项目名称]
-- **匹配理由**：为什么这个项目符合用户的请求？
-- **功能简介**：它是什么，解决了什么问题。
-- **行动建议**：建议用户如何使用这个项目。
+请严格按照以下JSON格式返回结果（严禁Markdown，必须是纯JSON）：
+{
+  "results": [
+    {
+      "repo_id": "列表中的 ID 原样返回，例如 github-123",
+      "reason": "匹配理由：为什么这个项目符合用户的请求，它解决了什么问题",
+      "relevance": 1-100的整数，表示与用户请求的相关度,
+      "action": "行动建议：建议用户如何使用这个项目"
+    }
+  ]
+}
 
-（如果没有匹配的项目，请直接回答"没有找到合适的项目"）
-`, promptData.String(), userQuery)
+只能返回上述列表中存在的项目。如果没有匹配的项目，返回 {"results": []}
+`, candidates, userQuery)
 
 	// 3. 调用 AI (带重试机制)
+	text, err := g.generateText(ctx, prompt)
+	if err != nil {
+		return nil, common.LLMError("AI 检索失败", err)
+	}
+
+	results, err := parseSearchResponse(string(text))
+	if err != nil {
+		return nil, common.WrapError(common.ErrCodeLLMInvalidJSON, "解析检索结果失败", fmt.Errorf("%w | 原文: %s", err, text))
+	}
+	return results, nil
+}
+
+// parseSearchResponse 从 AI 回复中提取检索结果
+func parseSearchResponse(rawContent string) ([]*domain.SearchResult, error) {
+	start := strings.Index(rawContent, "{")
+	end := strings.LastIndex(rawContent, "}")
+	if start == -1 || end == -1 || end <= start {
+		return nil, fmt.Errorf("无法提取 JSON")
+	}
+
+	var res searchResponse
+	if err := json.Unmarshal([]byte(rawContent[start:end+1]), &res); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+
+	results := make([]*domain.SearchResult, 0, len(res.Results))
+	for _, r := range res.Results {
+		if r != nil {
+			r.RepoID = strings.TrimSpace(r.RepoID)
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// formatCandidates 把候选项目整理成 Prompt 中的列表，只保留检索需要的字段
+func formatCandidates(repos []*domain.Repo) string {
+	var b strings.Builder
+	for i, r := range repos {
+		b.WriteString(fmt.Sprintf("%d. ID: %s | 名称: %s\n", i+1, r.ID, r.Name))
+		b.WriteString(fmt.Sprintf("   [描述]: %s\n", r.Description))
+		b.WriteString(fmt.Sprintf("   [语言]: %s | [Star]: %d | [分类]: %s\n", r.Language, r.Stars, strings.Join(r.Categories, ", ")))
+		b.WriteString(fmt.Sprintf("   [LLM评分]: %d\n", r.LLMScore))
+		b.WriteString(fmt.Sprintf("   [LLM评价]: %s\n", r.LLMReview))
+		b.WriteString("---\n")
+	}
+	return b.String()
+}
+
+// generateText 调用模型 (带重试机制) 并返回第一段文本
+func (g *GeminiAppraiser) generateText(ctx context.Context, prompt string) (string, error) {
+	return g.retryText(ctx, func() (*genai.GenerateContentResponse, error) {
+		return g.generate(ctx, prompt)
+	})
+}
+
+// retryText 重试 fn 直到返回非空内容，并返回第一段文本
+func (g *GeminiAppraiser) retryText(ctx context.Context, fn func() (*genai.GenerateContentResponse, error)) (string, error) {
 	var resp *genai.GenerateContentResponse
 	err := common.Do(ctx, func() error {
 		var apiErr error
-		resp, apiErr = g.model.GenerateContent(ctx, genai.Text(prompt))
+		resp, apiErr = fn()
 		if apiErr != nil {
 			return apiErr
 		}
-		if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-			return fmt.Errorf("AI 返回内容为空")
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+			return common.NewError(common.ErrCodeLLMEmptyResponse, "AI 返回内容为空")
 		}
 		return nil
 	},
 		common.WithMaxRetries(5),
 		common.WithInitialDelay(2*time.Second),
 		common.WithMaxDelay(30*time.Second),
+		common.WithJitter(common.DecorrelatedJitter),
+		common.LogRetry("Gemini", common.LLMError),
 	)
 	if err != nil {
-		return "", fmt.Errorf("AI 检索失败: %w", err)
+		return "", err
 	}
 
-	part := resp.Candidates[0].Content.Parts[0]
-	result, ok := part.(genai.Text)
+	text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
 	if !ok {
-		return "", fmt.Errorf("AI 返回格式错误")
+		return "", common.NewError(common.ErrCodeLLMInvalidJSON, "AI 返回格式错误")
 	}
-
-	return string(result), nil
+	return string(text), nil
 }
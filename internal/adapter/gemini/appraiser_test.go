@@ -1,9 +1,19 @@
 package gemini
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"github.com/google/generative-ai-go/genai"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseAIResponse(t *testing.T) {
@@ -56,4 +66,198 @@ func TestParseAIResponse(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestNormalizeCategories(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{name: "合法分类", input: []string{"ide-extension", "cli-agent"}, expected: []string{"ide-extension", "cli-agent"}},
+		{name: "大小写与空格", input: []string{" IDE-Extension "}, expected: []string{"ide-extension"}},
+		{name: "未知分类被丢弃", input: []string{"blockchain", "testing"}, expected: []string{"testing"}},
+		{name: "去重", input: []string{"testing", "testing"}, expected: []string{"testing"}},
+		{name: "空输入", input: nil, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizeCategories(tt.input))
+		})
+	}
+}
+
+func TestParseSearchResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    []*domain.SearchResult
+	}{
+		{
+			name:  "Valid results",
+			input: `{"results": [{"repo_id": " github-1 ", "reason": "命令行 Agent", "relevance": 90, "action": "先试用"}]}`,
+			expected: []*domain.SearchResult{
+				{RepoID: "github-1", Reason: "命令行 Agent", Relevance: 90, Action: "先试用"},
+			},
+		},
+		{
+			name:     "No match",
+			input:    "```json\n{\"results\": []}\n```",
+			expected: []*domain.SearchResult{},
+		},
+		{
+			name:        "Markdown instead of JSON",
+			input:       `### 🎯 最佳匹配：ai/coder`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := parseSearchResponse(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, results)
+		})
+	}
+}
+
+func TestTokensUsed(t *testing.T) {
+	gen := &fakeGenerator{tokens: 120, replies: []string{
+		`{"is_ai_programming_tool": true, "llm_score": 80, "llm_review": "ok"}`,
+		`{"is_ai_programming_tool": false, "llm_score": 10, "llm_review": "no"}`,
+	}}
+	g := &GeminiAppraiser{model: gen}
+
+	for _, name := range []string{"a/one", "a/two"} {
+		_, err := g.Appraise(context.Background(), &domain.Repo{ID: name, Name: name})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(240), g.TokensUsed())
+}
+
+// recordingMeter 记录每次调用的用量，blocked 时拒绝调用
+type recordingMeter struct {
+	blocked bool
+	calls   []string
+}
+
+func (m *recordingMeter) Allow(ctx context.Context, items int) error {
+	if m.blocked {
+		return errors.New("超出预算")
+	}
+	return nil
+}
+
+func (m *recordingMeter) Record(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	m.calls = append(m.calls, fmt.Sprintf("%s %d/%d", model, promptTokens, completionTokens))
+}
+
+func TestUsageMeter(t *testing.T) {
+	gen := &fakeGenerator{tokens: 120, input: 90, replies: []string{
+		`{"is_ai_programming_tool": true, "llm_score": 80, "llm_review": "ok"}`,
+	}}
+	g := &GeminiAppraiser{model: gen, modelName: defaultModel}
+	meter := &recordingMeter{}
+	ctx := common.WithUsageMeter(context.Background(), meter)
+
+	_, err := g.Appraise(ctx, &domain.Repo{ID: "a/one", Name: "a/one"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gemini-2.5-pro 90/30"}, meter.calls)
+
+	// 超出预算后不再调用模型
+	meter.blocked = true
+	_, err = g.Appraise(ctx, &domain.Repo{ID: "a/two", Name: "a/two"})
+	assert.ErrorContains(t, err, "超出预算")
+	assert.Len(t, gen.prompts, 1)
+}
+
+func TestWithCriteria(t *testing.T) {
+	gen := &fakeGenerator{tokens: 50, replies: []string{
+		`{"is_ai_programming_tool": true, "llm_score": 80, "llm_review": "ok"}`,
+		`{"is_ai_programming_tool": true, "llm_score": 70, "llm_review": "matches"}`,
+	}}
+	g := &GeminiAppraiser{model: gen}
+	assert.Same(t, g, g.WithCriteria("  "))
+
+	repo, err := g.Appraise(context.Background(), &domain.Repo{ID: "a/one", Name: "a/one"})
+	require.NoError(t, err)
+	assert.Equal(t, promptVersion, repo.PromptVersion)
+	assert.Contains(t, gen.prompts[0], "AI编程工具")
+
+	custom := g.WithCriteria("面向 Kubernetes 运维的开源工具")
+	repo, err = custom.Appraise(context.Background(), &domain.Repo{ID: "a/two", Name: "a/two"})
+	require.NoError(t, err)
+	assert.Contains(t, gen.prompts[1], "判断它是否符合以下要求：面向 Kubernetes 运维的开源工具")
+	assert.NotContains(t, gen.prompts[1], "AI编程工具")
+	assert.True(t, repo.IsAIProgrammingTool)
+	// 不同标准的评估在历史中可以区分
+	assert.NotEqual(t, promptVersion, repo.PromptVersion)
+	assert.Equal(t, repo.PromptVersion, criteriaPromptVersion("面向 Kubernetes 运维的开源工具"))
+	assert.NotEqual(t, repo.PromptVersion, criteriaPromptVersion("其他标准"))
+	assert.Equal(t, repo.PromptVersion, custom.(port.PromptVersioner).PromptVersion())
+	assert.Equal(t, promptVersion, g.PromptVersion())
+	// Token 统计共用
+	assert.Equal(t, int64(100), g.TokensUsed())
+}
+
+// sleepRecorder 假时钟，Sleep 立即返回并把时间拨快
+type sleepRecorder struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *sleepRecorder) Now() time.Time { return c.now }
+
+func (c *sleepRecorder) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	c.slept += d
+	return nil
+}
+
+func TestSetLimiter(t *testing.T) {
+	gen := &fakeGenerator{tokens: 50, replies: []string{
+		`{"is_ai_programming_tool": true, "llm_score": 80, "llm_review": "ok"}`,
+		`{"is_ai_programming_tool": true, "llm_score": 70, "llm_review": "matches"}`,
+	}}
+	g := &GeminiAppraiser{model: gen}
+	clock := &sleepRecorder{now: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}
+	g.SetLimiter(common.NewLLMLimiter("Gemini", common.LLMLimiterConfig{RPM: 1, MaxConcurrency: 2}, clock))
+
+	// 自定义标准的鉴定师与原鉴定师共用限流器，第二次调用等到下一分钟
+	_, err := g.Appraise(context.Background(), &domain.Repo{ID: "a/one", Name: "a/one"})
+	require.NoError(t, err)
+	_, err = g.WithCriteria("CLI 工具").Appraise(context.Background(), &domain.Repo{ID: "a/two", Name: "a/two"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, clock.slept)
+	assert.Equal(t, 2, g.limiter.Concurrency())
+}
+
+// partGenerator 返回预设的内容片段，模拟非文本回复
+type partGenerator struct {
+	parts []genai.Part
+}
+
+func (f *partGenerator) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: f.parts}}}}, nil
+}
+
+func TestAppraise_ErrorCodes(t *testing.T) {
+	repo := &domain.Repo{ID: "a/one", Name: "a/one"}
+
+	// 返回内容不是文本或不是合法 JSON 时都按 LLM_INVALID_JSON 统计
+	g := &GeminiAppraiser{model: &partGenerator{parts: []genai.Part{genai.Blob{MIMEType: "image/png"}}}}
+	_, err := g.Appraise(context.Background(), repo)
+	assert.Equal(t, common.ErrCodeLLMInvalidJSON, common.Code(err))
+	_, err = g.generateText(context.Background(), "hi")
+	assert.Equal(t, common.ErrCodeLLMInvalidJSON, common.Code(err))
+
+	g = &GeminiAppraiser{model: &fakeGenerator{replies: []string{"抱歉，我无法评估"}}}
+	_, err = g.Appraise(context.Background(), repo)
+	assert.Equal(t, common.ErrCodeLLMInvalidJSON, common.Code(err))
+}
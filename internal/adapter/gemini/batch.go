@@ -0,0 +1,128 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+)
+
+// batchItem 批量评估时 AI 返回的数组元素，repo_id 对应请求中的项目 ID
+type batchItem struct {
+	RepoID string `json:"repo_id"`
+	aiResponse
+}
+
+// AppraiseBatch 一次调用评估多个项目，实现 port.BatchAppraiser
+func (g *GeminiAppraiser) AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error) {
+	return g.appraiseBatch(ctx, repos, "")
+}
+
+func (c *criteriaAppraiser) AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error) {
+	return c.appraiseBatch(ctx, repos, c.criteria)
+}
+
+// batchPrompt 批量评估 Prompt：评估说明与单个评估相同，只出现一次，后面列出所有项目
+func batchPrompt(repos []*domain.Repo, criteria string) string {
+	question := "判断它们是否为AI编程工具（如AI代码助手、机器学习库、自然语言处理工具等）"
+	matchHint := "如果是AI编程工具则分数较高，否则较低"
+	reviewHint := "说明为什么认为它是或不是AI编程工具"
+	if criteria != "" {
+		question = "判断它们是否符合以下要求：" + criteria
+		matchHint = "越符合要求分数越高"
+		reviewHint = "说明为什么认为它符合或不符合要求"
+	}
+
+	var list strings.Builder
+	for i, r := range repos {
+		list.WriteString(fmt.Sprintf("%d. ID: %s\n", i+1, r.ID))
+		list.WriteString(fmt.Sprintf("   项目名称：%s\n", r.Name))
+		list.WriteString(fmt.Sprintf("   项目描述：%s\n", r.Description))
+		list.WriteString(fmt.Sprintf("   项目URL：%s\n", r.URL))
+	}
+
+	return fmt.Sprintf(`
+请逐个分析以下 %d 个GitHub项目，%s。每个项目独立评估，不要互相比较。
+
+%s
+请严格按照以下JSON格式返回结果（严禁Markdown，必须是纯JSON数组），每个项目一个元素：
+[
+  {
+    "repo_id": "列表中的 ID 原样返回，例如 github-123",
+    "is_ai_programming_tool": true/false,
+    "llm_score": 1-100的整数分数（%s）,
+    "llm_review": "简短评价，%s",
+    "categories": ["从以下分类中选择1-3个: %s"]
+  }
+]
+`, len(repos), question, list.String(), matchHint, reviewHint, strings.Join(domain.Categories(), ", "))
+}
+
+func (g *GeminiAppraiser) appraiseBatch(ctx context.Context, repos []*domain.Repo, criteria string) (map[string]*domain.Repo, error) {
+	if len(repos) == 0 {
+		return map[string]*domain.Repo{}, nil
+	}
+	// 超出费用预算时不再调用，整批项目计为跳过
+	if err := common.AllowLLMBatch(ctx, len(repos)); err != nil {
+		return nil, err
+	}
+
+	text, err := g.generateText(ctx, batchPrompt(repos, criteria))
+	if err != nil {
+		return nil, common.LLMError("AI 调用失败", err)
+	}
+	items, err := parseBatchResponse(text)
+	if err != nil {
+		return nil, common.WrapError(common.ErrCodeLLMInvalidJSON, "解析响应失败", fmt.Errorf("%w | 原文: %s", err, text))
+	}
+
+	byID := make(map[string]*domain.Repo, len(repos))
+	for _, r := range repos {
+		byID[r.ID] = r
+	}
+	appraisedAt := time.Now()
+	results := make(map[string]*domain.Repo, len(items))
+	for _, item := range items {
+		repo, ok := byID[item.RepoID]
+		// 丢弃列表外的 ID、重复的 ID 和超出范围的分数，这些项目由调用方重新评估
+		if !ok || results[item.RepoID] != nil || item.LLMScore < 0 || item.LLMScore > 100 {
+			continue
+		}
+		repo.IsAIProgrammingTool = item.IsAIProgrammingTool
+		repo.LLMScore = item.LLMScore
+		repo.LLMReview = item.LLMReview
+		repo.Categories = normalizeCategories(item.Categories)
+		repo.AppraisalModel = g.modelName
+		repo.PromptVersion = criteriaPromptVersion(criteria)
+		repo.AppraisedAt = &appraisedAt
+		results[item.RepoID] = repo
+	}
+	return results, nil
+}
+
+// parseBatchResponse 从 AI 回复中提取批量评估结果数组
+func parseBatchResponse(rawContent string) ([]*batchItem, error) {
+	start := strings.Index(rawContent, "[")
+	end := strings.LastIndex(rawContent, "]")
+	if start == -1 || end == -1 || end <= start {
+		return nil, fmt.Errorf("无法提取 JSON 数组")
+	}
+
+	var items []*batchItem
+	if err := json.Unmarshal([]byte(rawContent[start:end+1]), &items); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+
+	valid := items[:0]
+	for _, item := range items {
+		if item != nil {
+			item.RepoID = strings.TrimSpace(item.RepoID)
+			valid = append(valid, item)
+		}
+	}
+	return valid, nil
+}
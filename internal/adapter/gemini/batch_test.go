@@ -0,0 +1,91 @@
+package gemini
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchResponse(t *testing.T) {
+	items, err := parseBatchResponse("```json\n" + `[
+		{"repo_id": " github-1 ", "is_ai_programming_tool": true, "llm_score": 85, "llm_review": "代码助手", "categories": ["ide-extension"]},
+		null,
+		{"repo_id": "github-2", "is_ai_programming_tool": false, "llm_score": 20, "llm_review": "博客主题"}
+	]` + "\n```")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "github-1", items[0].RepoID)
+	assert.Equal(t, 85, items[0].LLMScore)
+	assert.Equal(t, []string{"ide-extension"}, items[0].Categories)
+	assert.False(t, items[1].IsAIProgrammingTool)
+
+	_, err = parseBatchResponse(`{"is_ai_programming_tool": true}`)
+	assert.Error(t, err)
+	_, err = parseBatchResponse(`[{"repo_id": "github-1", "llm_score": }]`)
+	assert.Error(t, err)
+}
+
+func TestAppraiseBatch(t *testing.T) {
+	gen := &fakeGenerator{tokens: 300, input: 200, replies: []string{`[
+		{"repo_id": "github-1", "is_ai_programming_tool": true, "llm_score": 85, "llm_review": "代码助手", "categories": ["IDE-Extension"]},
+		{"repo_id": "github-1", "is_ai_programming_tool": false, "llm_score": 5, "llm_review": "重复"},
+		{"repo_id": "github-3", "is_ai_programming_tool": true, "llm_score": 120, "llm_review": "分数越界"},
+		{"repo_id": "github-404", "is_ai_programming_tool": true, "llm_score": 90, "llm_review": "编造"}
+	]`}}
+	g := &GeminiAppraiser{model: gen, modelName: defaultModel}
+	meter := &recordingMeter{}
+	ctx := common.WithUsageMeter(context.Background(), meter)
+	repos := []*domain.Repo{
+		{ID: "github-1", Name: "acme/coder", Description: "AI pair programmer"},
+		{ID: "github-2", Name: "acme/theme", Description: "Blog theme"},
+		{ID: "github-3", Name: "acme/agent", Description: "CLI agent"},
+	}
+
+	results, err := g.AppraiseBatch(ctx, repos)
+	require.NoError(t, err)
+	// 漏掉的、重复的、越界的和列表外的结果都不返回
+	require.Len(t, results, 1)
+	repo := results["github-1"]
+	assert.Same(t, repos[0], repo)
+	assert.True(t, repo.IsAIProgrammingTool)
+	assert.Equal(t, 85, repo.LLMScore)
+	assert.Equal(t, []string{"ide-extension"}, repo.Categories)
+	assert.Equal(t, defaultModel, repo.AppraisalModel)
+	assert.Equal(t, promptVersion, repo.PromptVersion)
+	assert.NotNil(t, repo.AppraisedAt)
+
+	// 评估说明只出现一次，所有项目都在同一个 Prompt 中
+	require.Len(t, gen.prompts, 1)
+	assert.Equal(t, 1, strings.Count(gen.prompts[0], "AI编程工具（"))
+	for _, r := range repos {
+		assert.Contains(t, gen.prompts[0], "ID: "+r.ID)
+	}
+	assert.Equal(t, []string{"gemini-2.5-pro 200/100"}, meter.calls)
+
+	// 超出预算时整批不调用
+	meter.blocked = true
+	_, err = g.AppraiseBatch(ctx, repos[1:])
+	assert.ErrorContains(t, err, "超出预算")
+	assert.Len(t, gen.prompts, 1)
+}
+
+func TestAppraiseBatch_Criteria(t *testing.T) {
+	gen := &fakeGenerator{replies: []string{
+		`[{"repo_id": "github-1", "is_ai_programming_tool": true, "llm_score": 70, "llm_review": "符合"}]`,
+	}}
+	g := &GeminiAppraiser{model: gen}
+	custom := g.WithCriteria("面向 Kubernetes 运维的开源工具").(port.BatchAppraiser)
+
+	results, err := custom.AppraiseBatch(context.Background(), []*domain.Repo{{ID: "github-1", Name: "acme/kube"}})
+	require.NoError(t, err)
+	assert.Contains(t, gen.prompts[0], "判断它们是否符合以下要求：面向 Kubernetes 运维的开源工具")
+	assert.NotContains(t, gen.prompts[0], "AI编程工具")
+	assert.Equal(t, criteriaPromptVersion("面向 Kubernetes 运维的开源工具"), results["github-1"].PromptVersion)
+}
@@ -0,0 +1,222 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// maxChatTurns 对话历史中保留的轮数 (第一轮和最近的轮次)，避免长对话的 Token 无限增长
+const maxChatTurns = 10
+
+// chatInstructions 第一轮发送的对话说明，之后的轮次不再重复
+const chatInstructions = `
+你是一个智能项目库检索助手，正在和用户进行多轮对话，帮助用户挑选 AI 编程工具。
+你的数据库里有以下候选项目，之后的对话中可能会加入新的候选项目：
+%s
+
+请结合之前的对话理解用户的追问，例如"只要 Go 的"表示在上一轮推荐的基础上按语言筛选，
+"对比前两个"指上一轮推荐列表中的第 1、2 个项目。
+
+每一轮都请严格按照以下JSON格式返回结果（严禁Markdown代码块，必须是纯JSON）：
+{
+  "message": "给用户的回答，可以使用 Markdown，对比时可以使用表格",
+  "results": [
+    {
+      "repo_id": "列表中的 ID 原样返回，例如 github-123",
+      "reason": "匹配理由",
+      "relevance": 1-100的整数,
+      "action": "行动建议"
+    }
+  ]
+}
+
+results 只能包含候选列表中存在的项目，按相关度从高到低排列；本轮不涉及具体项目时返回空数组。
+`
+
+// chatTurn 对话历史中的一轮
+type chatTurn struct {
+	user      string
+	added     []*domain.Repo // 这一轮新加入的候选项目，随用户消息一起发送
+	assistant string
+}
+
+// chatSession 在同一组候选项目上进行多轮对话
+// 对话说明和候选项目只在第一轮发送，之后每一轮只发送新消息和新加入的候选项目，之前的轮次作为对话历史，
+// 不需要重新检索；历史的开头在对话中保持不变，便于命中 Gemini 的上下文缓存
+type chatSession struct {
+	g          *GeminiAppraiser
+	candidates []*domain.Repo
+	known      map[string]bool
+	pending    []*domain.Repo // 还没有发送给模型的候选项目
+	turns      []chatTurn
+}
+
+// chatResponse 对话时要求 AI 返回的 JSON 结构
+type chatResponse struct {
+	Message string                 `json:"message"`
+	Results []*domain.SearchResult `json:"results"`
+}
+
+// StartChat 基于候选项目开启多轮对话
+func (g *GeminiAppraiser) StartChat(candidates []*domain.Repo) port.ChatSession {
+	s := &chatSession{g: g, known: make(map[string]bool)}
+	s.AddCandidates(candidates)
+	return s
+}
+
+// AddCandidates 追加候选项目，已有的项目不会重复加入，下一轮对话时发送给模型
+func (s *chatSession) AddCandidates(repos []*domain.Repo) {
+	for _, r := range repos {
+		if !s.known[r.ID] {
+			s.known[r.ID] = true
+			s.candidates = append(s.candidates, r)
+			s.pending = append(s.pending, r)
+		}
+	}
+}
+
+// Send 发送一条消息，回复中的项目由调用方对照候选集合校验
+func (s *chatSession) Send(ctx context.Context, message string) (*domain.ChatReply, error) {
+	// 超出费用预算时不再调用
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
+	}
+	turn := chatTurn{user: message, added: s.pending}
+	history := s.history()
+	parts := []genai.Part{genai.Text(s.render(len(s.turns), turn))}
+
+	text, err := s.g.retryText(ctx, func() (*genai.GenerateContentResponse, error) {
+		return s.generate(ctx, history, parts)
+	})
+	if err != nil {
+		return nil, common.LLMError("AI 对话失败", err)
+	}
+
+	reply, err := parseChatResponse(text)
+	if err != nil {
+		return nil, common.WrapError(common.ErrCodeLLMInvalidJSON, "解析对话回复失败", fmt.Errorf("%w | 原文: %s", err, text))
+	}
+
+	turn.assistant = s.summarize(reply)
+	s.turns = append(s.turns, turn)
+	s.pending = nil
+	s.trim()
+	return reply, nil
+}
+
+// generate 在对话历史上继续生成；模型不支持多轮对话时把历史拼成一个 Prompt
+func (s *chatSession) generate(ctx context.Context, history []*genai.Content, parts []genai.Part) (*genai.GenerateContentResponse, error) {
+	estimate := int64(expectedCompletionTokens)
+	for _, c := range append(history, genai.NewUserContent(parts...)) {
+		for _, p := range c.Parts {
+			if t, ok := p.(genai.Text); ok {
+				estimate += int64(utf8.RuneCountInString(string(t)) / 2)
+			}
+		}
+	}
+
+	return s.g.call(ctx, estimate, func() (*genai.GenerateContentResponse, error) {
+		if hg, ok := s.g.model.(HistoryGenerator); ok {
+			return hg.GenerateWithHistory(ctx, history, parts...)
+		}
+		var b strings.Builder
+		for _, c := range history {
+			for _, p := range c.Parts {
+				fmt.Fprintf(&b, "%s: %s\n", c.Role, p)
+			}
+		}
+		for _, p := range parts {
+			fmt.Fprintf(&b, "%s", p)
+		}
+		return s.g.model.GenerateContent(ctx, genai.Text(b.String()))
+	})
+}
+
+// history 已完成的轮次，按 Gemini 多轮对话的格式组织
+func (s *chatSession) history() []*genai.Content {
+	history := make([]*genai.Content, 0, 2*len(s.turns))
+	for i, t := range s.turns {
+		history = append(history,
+			genai.NewUserContent(genai.Text(s.render(i, t))),
+			&genai.Content{Role: "model", Parts: []genai.Part{genai.Text(t.assistant)}},
+		)
+	}
+	return history
+}
+
+// render 生成第 i 轮发送给模型的内容：第一轮包括对话说明和候选项目，之后只有新加入的候选项目和用户消息
+func (s *chatSession) render(i int, t chatTurn) string {
+	if i == 0 {
+		return fmt.Sprintf(chatInstructions, formatCandidates(t.added)) + "\n用户: " + t.user
+	}
+	if len(t.added) == 0 {
+		return t.user
+	}
+	return "新加入的候选项目：\n" + formatCandidates(t.added) + "\n用户: " + t.user
+}
+
+// trim 只保留第一轮和最近的轮次，被丢弃的轮次中新加入的候选项目转到下一轮，不会从对话中消失
+func (s *chatSession) trim() {
+	for len(s.turns) > maxChatTurns {
+		dropped := s.turns[1]
+		s.turns = append(s.turns[:1], s.turns[2:]...)
+		next := &s.turns[1]
+		next.added = append(append([]*domain.Repo(nil), dropped.added...), next.added...)
+	}
+}
+
+// summarize 压缩助手回复写入历史，保留推荐项目的顺序，便于用户按序号追问
+// 顺序与用户看到的一致：去掉候选集合以外的项目，按相关度排序
+func (s *chatSession) summarize(reply *domain.ChatReply) string {
+	var listed []*domain.SearchResult
+	for _, r := range reply.Results {
+		if s.known[r.RepoID] {
+			listed = append(listed, r)
+		}
+	}
+	sort.SliceStable(listed, func(i, j int) bool { return listed[i].Relevance > listed[j].Relevance })
+
+	var b strings.Builder
+	b.WriteString(reply.Message)
+	if len(listed) > 0 {
+		b.WriteString(" [推荐列表:")
+		for i, r := range listed {
+			b.WriteString(fmt.Sprintf(" %d=%s", i+1, r.RepoID))
+		}
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+// parseChatResponse 从 AI 回复中提取对话结果
+func parseChatResponse(rawContent string) (*domain.ChatReply, error) {
+	start := strings.Index(rawContent, "{")
+	end := strings.LastIndex(rawContent, "}")
+	if start == -1 || end == -1 || end <= start {
+		return nil, fmt.Errorf("无法提取 JSON")
+	}
+
+	var res chatResponse
+	if err := json.Unmarshal([]byte(rawContent[start:end+1]), &res); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+
+	reply := &domain.ChatReply{Message: strings.TrimSpace(res.Message)}
+	for _, r := range res.Results {
+		if r != nil {
+			r.RepoID = strings.TrimSpace(r.RepoID)
+			reply.Results = append(reply.Results, r)
+		}
+	}
+	return reply, nil
+}
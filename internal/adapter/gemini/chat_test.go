@@ -0,0 +1,119 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"github-gold-miner/internal/domain"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGenerator 按顺序返回预设回复，并记录收到的 Prompt 和对话历史
+type fakeGenerator struct {
+	replies   []string
+	prompts   []string
+	histories [][]*genai.Content
+	tokens    int32 // 每次调用返回的 Token 用量
+	input     int32 // 其中输入的 Token 数
+}
+
+func (f *fakeGenerator) GenerateWithHistory(ctx context.Context, history []*genai.Content, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	f.histories = append(f.histories, history)
+	return f.GenerateContent(ctx, parts...)
+}
+
+func (f *fakeGenerator) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	f.prompts = append(f.prompts, string(parts[0].(genai.Text)))
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return &genai.GenerateContentResponse{
+		Candidates:    []*genai.Candidate{{Content: &genai.Content{Parts: []genai.Part{genai.Text(reply)}}}},
+		UsageMetadata: &genai.UsageMetadata{PromptTokenCount: f.input, TotalTokenCount: f.tokens},
+	}, nil
+}
+
+func TestChatSession_Send(t *testing.T) {
+	gen := &fakeGenerator{replies: []string{
+		`{"message": "推荐以下工具", "results": [
+			{"repo_id": "github-2", "reason": "Python", "relevance": 60},
+			{"repo_id": "github-1", "reason": "Go", "relevance": 90},
+			{"repo_id": "github-404", "reason": "编造", "relevance": 99}
+		]}`,
+		`{"message": "| 项目 | 语言 |\n|---|---|", "results": []}`,
+	}}
+	g := &GeminiAppraiser{model: gen}
+
+	session := g.StartChat([]*domain.Repo{
+		{ID: "github-1", Name: "ai/go-agent", Language: "Go"},
+		{ID: "github-2", Name: "ai/py-agent", Language: "Python"},
+	})
+
+	reply, err := session.Send(context.Background(), "有什么命令行 Agent")
+	require.NoError(t, err)
+	assert.Equal(t, "推荐以下工具", reply.Message)
+	assert.Len(t, reply.Results, 3)
+	assert.Empty(t, gen.histories[0])
+	assert.Contains(t, gen.prompts[0], "ai/go-agent")
+	assert.Contains(t, gen.prompts[0], "[语言]: Go")
+	assert.Contains(t, gen.prompts[0], "用户: 有什么命令行 Agent")
+
+	// 追问时只发送新消息和新加入的候选项目，之前的轮次作为历史原样发送
+	session.AddCandidates([]*domain.Repo{{ID: "github-1", Name: "ai/go-agent"}, {ID: "github-3", Name: "ai/reviewer"}})
+	_, err = session.Send(context.Background(), "对比前两个")
+	require.NoError(t, err)
+	assert.Contains(t, gen.prompts[1], "ai/reviewer")
+	assert.Contains(t, gen.prompts[1], "用户: 对比前两个")
+	assert.NotContains(t, gen.prompts[1], "ai/go-agent")
+	assert.NotContains(t, gen.prompts[1], "JSON")
+
+	history := gen.histories[1]
+	require.Len(t, history, 2)
+	assert.Equal(t, "user", history[0].Role)
+	assert.Equal(t, genai.Text(gen.prompts[0]), history[0].Parts[0])
+	// 推荐列表按用户看到的顺序编号，编造的项目不进入历史
+	assert.Equal(t, "model", history[1].Role)
+	assert.Contains(t, string(history[1].Parts[0].(genai.Text)), "[推荐列表: 1=github-1 2=github-2]")
+
+	// 之后的追问不再重复发送已经发送过的候选项目
+	gen.replies = append(gen.replies, `{"message": "ok", "results": []}`)
+	_, err = session.Send(context.Background(), "只要 Go 的")
+	require.NoError(t, err)
+	assert.Equal(t, "只要 Go 的", gen.prompts[2])
+	require.Len(t, gen.histories[2], 4)
+	assert.Equal(t, genai.Text(gen.prompts[1]), gen.histories[2][2].Parts[0])
+}
+
+func TestChatSession_HistoryIsBounded(t *testing.T) {
+	gen := &fakeGenerator{}
+	for i := 0; i < maxChatTurns+2; i++ {
+		gen.replies = append(gen.replies, `{"message": "ok", "results": []}`)
+	}
+	session := (&GeminiAppraiser{model: gen}).StartChat([]*domain.Repo{{ID: "github-1", Name: "ai/go-agent"}}).(*chatSession)
+
+	for i := 0; i < maxChatTurns+2; i++ {
+		if i == 1 {
+			session.AddCandidates([]*domain.Repo{{ID: "github-2", Name: "ai/reviewer"}})
+		}
+		_, err := session.Send(context.Background(), "hi")
+		require.NoError(t, err)
+	}
+	assert.Len(t, session.turns, maxChatTurns)
+	// 第一轮的说明和候选项目始终保留，被丢弃的轮次中加入的候选项目转到下一轮
+	history := session.history()
+	assert.Contains(t, string(history[0].Parts[0].(genai.Text)), "ai/go-agent")
+	assert.Contains(t, string(history[2].Parts[0].(genai.Text)), "ai/reviewer")
+}
+
+func TestParseChatResponse(t *testing.T) {
+	reply, err := parseChatResponse(`{"message": " 没有找到 ", "results": [null, {"repo_id": " github-1 "}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "没有找到", reply.Message)
+	require.Len(t, reply.Results, 1)
+	assert.Equal(t, "github-1", reply.Results[0].RepoID)
+
+	_, err = parseChatResponse("纯文本回答")
+	assert.Error(t, err)
+}
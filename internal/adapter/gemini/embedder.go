@@ -0,0 +1,119 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github-gold-miner/internal/common"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+const (
+	// defaultEmbeddingModel 默认使用的 Gemini Embedding 模型 (768 维)
+	defaultEmbeddingModel = "text-embedding-004"
+
+	// maxEmbedBatch BatchEmbedContents 单次请求最多 100 条
+	maxEmbedBatch = 100
+)
+
+// batchEmbedFunc 对一批文本调用 BatchEmbedContents，测试时替换为假实现
+type batchEmbedFunc func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error)
+
+// Embedder 使用 Gemini Embedding 模型把项目和查询转换为向量
+type Embedder struct {
+	client    *genai.Client
+	embed     batchEmbedFunc
+	modelName string
+	retryOpts []common.Option
+}
+
+// NewEmbedder 创建 Gemini Embedder，model 为空时使用 text-embedding-004
+func NewEmbedder(ctx context.Context, apiKey, model string) (*Embedder, error) {
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+
+	em := client.EmbeddingModel(model)
+	return &Embedder{
+		client: client,
+		embed: func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error) {
+			batch := em.NewBatch()
+			for _, text := range texts {
+				batch.AddContent(genai.Text(text))
+			}
+			return em.BatchEmbedContents(ctx, batch)
+		},
+		modelName: model,
+		retryOpts: []common.Option{
+			common.WithMaxRetries(3),
+			common.WithInitialDelay(2 * time.Second),
+			common.WithJitter(common.FullJitter),
+			common.LogRetry("Gemini 向量化", common.LLMError),
+		},
+	}, nil
+}
+
+// Close 关闭 Gemini 客户端
+func (e *Embedder) Close() error {
+	if e.client != nil {
+		return e.client.Close()
+	}
+	return nil
+}
+
+// Model 返回 Embedding 模型名称
+func (e *Embedder) Model() string {
+	return e.modelName
+}
+
+// Embed 批量向量化，超过单次请求上限时自动分批
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		end := min(start+maxEmbedBatch, len(texts))
+		chunk := texts[start:end]
+		// 超出费用预算时不再调用
+		if err := common.AllowLLMCall(ctx); err != nil {
+			return nil, err
+		}
+
+		var resp *genai.BatchEmbedContentsResponse
+		err := common.Do(ctx, func() error {
+			var apiErr error
+			resp, apiErr = e.embed(ctx, chunk)
+			return apiErr
+		}, e.retryOpts...)
+		if err != nil {
+			return nil, common.LLMError("Gemini 向量化失败", err)
+		}
+		// BatchEmbedContents 不返回 Token 用量，按文本长度估算后记账
+		common.RecordUsage(ctx, e.modelName, estimateEmbedTokens(chunk), 0)
+		if len(resp.Embeddings) != len(chunk) {
+			return nil, fmt.Errorf("Gemini 返回 %d 个向量，期望 %d 个", len(resp.Embeddings), len(chunk))
+		}
+		for _, emb := range resp.Embeddings {
+			if emb == nil || len(emb.Values) == 0 {
+				return nil, fmt.Errorf("Gemini 返回了空向量")
+			}
+			vectors = append(vectors, emb.Values)
+		}
+	}
+	return vectors, nil
+}
+
+// estimateEmbedTokens 按每两个字符一个 Token 估算向量化的输入 Token 数
+func estimateEmbedTokens(texts []string) int64 {
+	var tokens int64
+	for _, text := range texts {
+		tokens += int64(utf8.RuneCountInString(text)/2) + 1
+	}
+	return tokens
+}
@@ -0,0 +1,76 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github-gold-miner/internal/common"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	var batches []int
+	e := &Embedder{
+		modelName: defaultEmbeddingModel,
+		embed: func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error) {
+			batches = append(batches, len(texts))
+			resp := &genai.BatchEmbedContentsResponse{}
+			for range texts {
+				resp.Embeddings = append(resp.Embeddings, &genai.ContentEmbedding{Values: []float32{float32(len(batches)), 0}})
+			}
+			return resp, nil
+		},
+	}
+
+	texts := make([]string, 150)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("repo %d", i)
+	}
+	vectors, err := e.Embed(context.Background(), texts)
+	require.NoError(t, err)
+	require.Len(t, vectors, 150)
+	// 超过 100 条时分两批请求，结果保持原顺序
+	assert.Equal(t, []int{100, 50}, batches)
+	assert.Equal(t, float32(1), vectors[99][0])
+	assert.Equal(t, float32(2), vectors[100][0])
+	assert.Equal(t, "text-embedding-004", e.Model())
+}
+
+func TestEmbedder_EmbedErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		embed batchEmbedFunc
+	}{
+		{
+			name: "API 错误",
+			embed: func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error) {
+				return nil, errors.New("quota exceeded")
+			},
+		},
+		{
+			name: "返回数量不一致",
+			embed: func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error) {
+				return &genai.BatchEmbedContentsResponse{}, nil
+			},
+		},
+		{
+			name: "空向量",
+			embed: func(ctx context.Context, texts []string) (*genai.BatchEmbedContentsResponse, error) {
+				return &genai.BatchEmbedContentsResponse{Embeddings: []*genai.ContentEmbedding{{}}}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Embedder{embed: tt.embed, retryOpts: []common.Option{common.WithMaxRetries(0)}}
+			_, err := e.Embed(context.Background(), []string{"a"})
+			assert.Error(t, err)
+		})
+	}
+}
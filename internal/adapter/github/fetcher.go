@@ -3,10 +3,13 @@ package github
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
 
 	"github.com/google/go-github/v53/github"
 	"golang.org/x/oauth2"
@@ -14,25 +17,34 @@ import (
 
 // Fetcher 实现了 port.Scouter 接口
 type Fetcher struct {
-	client *github.Client
+	client   *github.Client
+	requests *common.RequestCounter
 }
 
 // NewFetcher 初始化 GitHub 客户端
 func NewFetcher(token string) *Fetcher {
-	var client *github.Client
-
-	if token == "" {
-		client = github.NewClient(nil)
-	} else {
+	// 所有请求经过计数器，用于统计每轮挖矿消耗的 API 配额
+	requests := &common.RequestCounter{}
+	httpClient := &http.Client{}
+	if token != "" {
 		ctx := context.Background()
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: token},
 		)
-		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
+		httpClient = oauth2.NewClient(ctx, ts)
 	}
+	httpClient.Transport = requests.Wrap(httpClient.Transport)
+	client := github.NewClient(httpClient)
 
-	return &Fetcher{client: client}
+	return &Fetcher{client: client, requests: requests}
+}
+
+// Requests 返回累计的 GitHub API 请求次数，实现 port.RequestCounter
+func (f *Fetcher) Requests() int64 {
+	if f.requests == nil {
+		return 0
+	}
+	return f.requests.Count()
 }
 
 // GetTrendingRepos 获取GitHub Trending项目
@@ -68,9 +80,11 @@ func (f *Fetcher) GetTrendingRepos(ctx context.Context, language string, since s
 	},
 		common.WithMaxRetries(3),
 		common.WithInitialDelay(time.Second),
+		common.WithJitter(common.FullJitter),
+		common.LogRetry("GitHub API", common.GitHubError),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("GitHub API 调用失败: %w", err)
+		return nil, common.GitHubError("GitHub API 调用失败", err)
 	}
 
 	var repos []*domain.Repo
@@ -110,9 +124,11 @@ func (f *Fetcher) GetReposByTopic(ctx context.Context, topic string) ([]*domain.
 	},
 		common.WithMaxRetries(3),
 		common.WithInitialDelay(time.Second),
+		common.WithJitter(common.FullJitter),
+		common.LogRetry("GitHub API", common.GitHubError),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("GitHub API 调用失败: %w", err)
+		return nil, common.GitHubError("GitHub API 调用失败", err)
 	}
 
 	var repos []*domain.Repo
@@ -131,4 +147,81 @@ func (f *Fetcher) GetReposByTopic(ctx context.Context, topic string) ([]*domain.
 	}
 
 	return repos, nil
-}
\ No newline at end of file
+}
+
+// GetRepo 获取单个项目的最新状态，实现 port.Inspector 接口
+func (f *Fetcher) GetRepo(ctx context.Context, fullName string) (*domain.Repo, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("无效的仓库名: %s", fullName)
+	}
+
+	var item *github.Repository
+	notFound := false
+	err := common.Do(ctx, func() error {
+		var apiErr error
+		var resp *github.Response
+		item, resp, apiErr = f.client.Repositories.Get(ctx, owner, name)
+		if apiErr != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+			// 仓库已删除或转为私有，重试没有意义
+			notFound = true
+			return nil
+		}
+		return apiErr
+	},
+		common.WithMaxRetries(3),
+		common.WithInitialDelay(time.Second),
+		common.WithJitter(common.FullJitter),
+		common.LogRetry("GitHub API", common.GitHubError),
+	)
+	if notFound {
+		return nil, common.WrapError(common.ErrCodeGitHubNotFound, "仓库 "+fullName, port.ErrNotFound)
+	}
+	if err != nil {
+		return nil, common.GitHubError("GitHub API 调用失败", err)
+	}
+
+	repo := &domain.Repo{
+		ID:          fmt.Sprintf("github-%d", item.GetID()),
+		Name:        item.GetFullName(),
+		URL:         item.GetHTMLURL(),
+		Description: item.GetDescription(),
+		Stars:       item.GetStargazersCount(),
+		Language:    item.GetLanguage(),
+		CreatedAt:   item.GetCreatedAt().Time,
+		UpdatedAt:   item.GetUpdatedAt().Time,
+		Archived:    item.GetArchived(),
+	}
+	return repo, nil
+}
+
+// ReadmeSHA 返回项目 README 的 Git blob SHA，实现 port.ReadmeInspector
+// 项目没有 README 时返回空字符串
+func (f *Fetcher) ReadmeSHA(ctx context.Context, fullName string) (string, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" {
+		return "", fmt.Errorf("无效的仓库名: %s", fullName)
+	}
+
+	var readme *github.RepositoryContent
+	err := common.Do(ctx, func() error {
+		var apiErr error
+		var resp *github.Response
+		readme, resp, apiErr = f.client.Repositories.GetReadme(ctx, owner, name, nil)
+		if apiErr != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+			// 没有 README，重试没有意义
+			readme = nil
+			return nil
+		}
+		return apiErr
+	},
+		common.WithMaxRetries(3),
+		common.WithInitialDelay(time.Second),
+		common.WithJitter(common.FullJitter),
+		common.LogRetry("GitHub API", common.GitHubError),
+	)
+	if err != nil {
+		return "", common.GitHubError("GitHub API 调用失败", err)
+	}
+	return readme.GetSHA(), nil
+}
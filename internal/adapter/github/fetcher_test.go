@@ -9,7 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
 	"github.com/google/go-github/v53/github"
 	"github.com/stretchr/testify/assert"
 )
@@ -173,6 +175,8 @@ func TestFetcher_GetTrendingRepos_APIError(t *testing.T) {
 		responseBody   string
 		expectError    bool
 		errorSubstring string
+		header         map[string]string
+		code           string
 	}{
 		{
 			name:           "GitHub API 返回 403 Forbidden",
@@ -180,6 +184,17 @@ func TestFetcher_GetTrendingRepos_APIError(t *testing.T) {
 			responseBody:   `{"message": "API rate limit exceeded"}`,
 			expectError:    true,
 			errorSubstring: "GitHub API 调用失败",
+			code:           common.ErrCodeGitHubUnauthorized,
+		},
+		{
+			name:         "GitHub API 限流",
+			statusCode:   http.StatusForbidden,
+			responseBody: `{"message": "API rate limit exceeded"}`,
+			// 重置时间已过，重试时不需要等待
+			header:         map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1"},
+			expectError:    true,
+			errorSubstring: "GitHub API 调用失败",
+			code:           common.ErrCodeGitHubRateLimited,
 		},
 		{
 			name:           "GitHub API 返回 500 内部错误",
@@ -187,6 +202,7 @@ func TestFetcher_GetTrendingRepos_APIError(t *testing.T) {
 			responseBody:   `{"message": "Internal server error"}`,
 			expectError:    true,
 			errorSubstring: "GitHub API 调用失败",
+			code:           common.ErrCodeGitHubUnavailable,
 		},
 		{
 			name:           "GitHub API 返回 404 Not Found",
@@ -194,12 +210,16 @@ func TestFetcher_GetTrendingRepos_APIError(t *testing.T) {
 			responseBody:   `{"message": "Not Found"}`,
 			expectError:    true,
 			errorSubstring: "GitHub API 调用失败",
+			code:           common.ErrCodeGitHubNotFound,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			server, fetcher := setupMockGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
 				w.WriteHeader(tt.statusCode)
 				w.Write([]byte(tt.responseBody))
 			})
@@ -211,6 +231,7 @@ func TestFetcher_GetTrendingRepos_APIError(t *testing.T) {
 			if tt.expectError {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.errorSubstring)
+				assert.Equal(t, tt.code, common.Code(err))
 				assert.Nil(t, repos)
 			} else {
 				assert.NoError(t, err)
@@ -419,3 +440,82 @@ func TestFetcher_GetReposByTopic_ContextCancellation(t *testing.T) {
 	assert.Nil(t, repos)
 	assert.Contains(t, err.Error(), "GitHub API 调用失败")
 }
+
+func TestFetcher_GetRepo(t *testing.T) {
+	now := time.Now()
+
+	t.Run("获取仓库最新状态", func(t *testing.T) {
+		server, fetcher := setupMockGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repos/test/tracked", r.URL.Path)
+			repo := createMockRepo(42, "test/tracked", "Tracked repo", "Go", 5200, now.AddDate(0, 0, -8), now)
+			repo.Archived = github.Bool(true)
+			json.NewEncoder(w).Encode(repo)
+		})
+		defer server.Close()
+
+		repo, err := fetcher.GetRepo(context.Background(), "test/tracked")
+		assert.NoError(t, err)
+		assert.Equal(t, "github-42", repo.ID)
+		assert.Equal(t, 5200, repo.Stars)
+		assert.True(t, repo.Archived)
+	})
+
+	t.Run("仓库已删除", func(t *testing.T) {
+		calls := 0
+		server, fetcher := setupMockGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		})
+		defer server.Close()
+
+		_, err := fetcher.GetRepo(context.Background(), "test/deleted")
+		assert.ErrorIs(t, err, port.ErrNotFound)
+		assert.True(t, common.HasCode(err, common.ErrCodeGitHubNotFound))
+		assert.Equal(t, 1, calls, "404 不应该重试")
+	})
+
+	t.Run("无效的仓库名", func(t *testing.T) {
+		fetcher := NewFetcher("")
+		_, err := fetcher.GetRepo(context.Background(), "no-slash")
+		assert.Error(t, err)
+	})
+}
+
+func TestFetcher_ReadmeSHA(t *testing.T) {
+	t.Run("返回 README 的 SHA", func(t *testing.T) {
+		server, fetcher := setupMockGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repos/test/tool/readme", r.URL.Path)
+			json.NewEncoder(w).Encode(&github.RepositoryContent{
+				Name: github.String("README.md"),
+				SHA:  github.String("3d21ec53a331a6f037a91c368710b99387d012c1"),
+			})
+		})
+		defer server.Close()
+
+		sha, err := fetcher.ReadmeSHA(context.Background(), "test/tool")
+		assert.NoError(t, err)
+		assert.Equal(t, "3d21ec53a331a6f037a91c368710b99387d012c1", sha)
+	})
+
+	t.Run("没有 README", func(t *testing.T) {
+		calls := 0
+		server, fetcher := setupMockGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		})
+		defer server.Close()
+
+		sha, err := fetcher.ReadmeSHA(context.Background(), "test/bare")
+		assert.NoError(t, err)
+		assert.Empty(t, sha)
+		assert.Equal(t, 1, calls, "404 不应该重试")
+	})
+
+	t.Run("无效的仓库名", func(t *testing.T) {
+		fetcher := NewFetcher("")
+		_, err := fetcher.ReadmeSHA(context.Background(), "no-slash")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,353 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+)
+
+// appraiseTimeout 单次重新评估的超时时间
+const appraiseTimeout = 2 * time.Minute
+
+// searchTimeout 语义搜索的超时时间 (含补齐向量索引)
+const searchTimeout = 2 * time.Minute
+
+// repoListResponse 项目列表
+type repoListResponse struct {
+	Items  []*domain.Repo `json:"items"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// repoDetailResponse 项目详情，包含评估历史和用户反馈
+type repoDetailResponse struct {
+	Repo       *domain.Repo        `json:"repo"`
+	Appraisals []*domain.Appraisal `json:"appraisals"`
+	Feedback   []*domain.Feedback  `json:"feedback"`
+}
+
+// searchResponse 搜索结果，关键词搜索的结果只有 repo_id 和 repo，没有理由和相关度
+type searchResponse struct {
+	Mode    string                 `json:"mode"`
+	Results []*domain.SearchResult `json:"results"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) handleListRepos(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSearchQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	repos, err := s.cfg.Repos.Search(r.Context(), query)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, repoListResponse{Items: nonNil(repos), Limit: query.Limit, Offset: query.Offset})
+}
+
+func (s *Server) handleGetRepo(w http.ResponseWriter, r *http.Request) {
+	repo, err := s.cfg.Repos.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	detail, err := s.repoDetail(r.Context(), repo)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// repoDetail 读取项目的评估历史和用户反馈
+func (s *Server) repoDetail(ctx context.Context, repo *domain.Repo) (*repoDetailResponse, error) {
+	appraisals, err := s.cfg.Repos.GetAppraisals(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	feedback, err := s.cfg.Repos.GetFeedback(ctx, repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &repoDetailResponse{Repo: repo, Appraisals: nonNil(appraisals), Feedback: nonNil(feedback)}, nil
+}
+
+func (s *Server) handleAppraise(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Appraiser == nil {
+		writeError(w, http.StatusNotImplemented, "未配置 AI 鉴定师")
+		return
+	}
+	repo, err := s.cfg.Repos.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), appraiseTimeout)
+	defer cancel()
+	appraised, err := s.cfg.Appraiser.Appraise(s.cfg.Budget.Meter(ctx, ""), repo)
+	if err != nil {
+		writeServiceError(w, fmt.Errorf("重新评估失败: %w", err))
+		return
+	}
+	if err := s.cfg.Repos.Save(ctx, appraised); err != nil {
+		writeServiceError(w, fmt.Errorf("保存评估结果失败: %w", err))
+		return
+	}
+	// 评价变化后向量已过期，在后台重新生成，搜索时只读取已有的向量
+	if s.cfg.Searcher != nil && s.cfg.Searcher.VectorEnabled() {
+		s.startJob(func(ctx context.Context) {
+			if _, err := s.cfg.Searcher.Index(ctx); err != nil {
+				log.Printf("⚠️ 补齐向量索引失败: %v", err)
+			}
+		})
+	}
+	detail, err := s.repoDetail(ctx, appraised)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSearchQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Text == "" {
+		writeError(w, http.StatusBadRequest, "缺少查询参数 q")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "keyword"
+	}
+	switch mode {
+	case "keyword":
+		repos, err := s.cfg.Repos.Search(r.Context(), query)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		results := make([]*domain.SearchResult, len(repos))
+		for i, repo := range repos {
+			results[i] = &domain.SearchResult{RepoID: repo.ID, Repo: repo}
+		}
+		writeJSON(w, http.StatusOK, searchResponse{Mode: mode, Results: results})
+	case "semantic":
+		if s.cfg.Searcher == nil {
+			writeError(w, http.StatusNotImplemented, "未配置语义搜索")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+		defer cancel()
+		results, _, err := s.cfg.Searcher.Search(ctx, query.Text)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, searchResponse{Mode: mode, Results: nonNil(results)})
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("不支持的搜索模式: %s (可选 keyword、semantic)", mode))
+	}
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r.URL.Query().Get("limit"), "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	runs, err := s.cfg.Runs.List(r.Context(), min(limit, domain.MaxSearchLimit))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": nonNil(runs)})
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "无效的执行记录 ID")
+		return
+	}
+	run, err := s.cfg.Runs.Get(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// handleStartRun 在后台触发一次挖矿，立即返回 202 和执行记录，通过 GET /api/v1/runs/{id} 查询结果
+// 配置了多个挖矿方向时用 profile 参数指定方向
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Mine == nil && len(s.cfg.Profiles) == 0 {
+		writeError(w, http.StatusNotImplemented, "未配置挖矿任务")
+		return
+	}
+	profile, mine, err := s.mineFunc(r.URL.Query().Get("profile"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.isClosed() {
+		writeError(w, http.StatusServiceUnavailable, errShuttingDown.Error())
+		return
+	}
+	run, err := s.cfg.Runs.Start(r.Context(), domain.TriggerAPI, profile)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	// 后台任务会修改执行记录，响应使用开始时的副本
+	started := *run
+
+	ok := s.startJob(func(ctx context.Context) {
+		report, err := mine(ctx, run)
+		if err != nil {
+			log.Printf("❌ 挖矿任务 #%d 失败: %v", run.ID, err)
+		}
+		s.cfg.Runs.Finish(ctx, run, report, err)
+	})
+	if !ok {
+		// 登记执行记录后服务开始关闭，记为失败，避免一直显示为执行中
+		s.cfg.Runs.Finish(r.Context(), run, nil, errShuttingDown)
+		writeError(w, http.StatusServiceUnavailable, errShuttingDown.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/runs/%d", started.ID))
+	writeJSON(w, http.StatusAccepted, &started)
+}
+
+// mineFunc 按 profile 参数选择挖矿任务；只有一个挖矿方向时可以省略
+func (s *Server) mineFunc(profile string) (string, service.MineFunc, error) {
+	if profile == "" {
+		if s.cfg.Mine != nil {
+			return "", s.cfg.Mine, nil
+		}
+		if len(s.cfg.Profiles) == 1 {
+			for name, mine := range s.cfg.Profiles {
+				return name, mine, nil
+			}
+		}
+		return "", nil, fmt.Errorf("配置了多个挖矿方向，需要用 profile 参数指定: %s", strings.Join(s.profileNames(), ", "))
+	}
+	mine, ok := s.cfg.Profiles[profile]
+	if !ok {
+		return "", nil, fmt.Errorf("未知的挖矿方向 %q", profile)
+	}
+	return profile, mine, nil
+}
+
+// profileNames 按名称排序的挖矿方向
+func (s *Server) profileNames() []string {
+	names := make([]string, 0, len(s.cfg.Profiles))
+	for name := range s.cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseSearchQuery 解析列表和搜索共用的查询参数
+func parseSearchQuery(r *http.Request) (domain.SearchQuery, error) {
+	values := r.URL.Query()
+	query := domain.SearchQuery{
+		Text:     strings.TrimSpace(values.Get("q")),
+		Language: values.Get("language"),
+		Profile:  values.Get("profile"),
+	}
+
+	// category 可以重复，也可以用逗号分隔
+	for _, v := range values["category"] {
+		for _, c := range strings.Split(v, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				query.Categories = append(query.Categories, c)
+			}
+		}
+	}
+
+	var err error
+	if query.Sort, err = domain.ParseSortField(values.Get("sort")); err != nil {
+		return query, err
+	}
+	switch order := values.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, fmt.Errorf("参数 order 必须是 asc 或 desc: %q", order)
+	}
+	if query.MinScore, err = intParam(values.Get("min_score"), "min_score"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = intParam(values.Get("limit"), "limit"); err != nil {
+		return query, err
+	}
+	if query.Offset, err = intParam(values.Get("offset"), "offset"); err != nil {
+		return query, err
+	}
+	if query.CreatedAfter, err = timeParam(values.Get("created_after"), "created_after"); err != nil {
+		return query, err
+	}
+	if query.CreatedBefore, err = timeParam(values.Get("created_before"), "created_before"); err != nil {
+		return query, err
+	}
+	return query.Normalize(), nil
+}
+
+func intParam(v, name string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("参数 %s 必须是非负整数: %q", name, v)
+	}
+	return n, nil
+}
+
+// timeParam 支持 RFC 3339 和 YYYY-MM-DD 两种格式
+func timeParam(v, name string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("参数 %s 必须是 RFC 3339 或 YYYY-MM-DD 格式的时间: %q", name, v)
+}
+
+// nonNil 让空列表序列化为 [] 而不是 null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,224 @@
+// Package httpapi 以 REST API 的形式提供项目查询、搜索、挖矿和重新评估能力，
+// 接口定义见 openapi.yaml (运行时可通过 GET /openapi.yaml 获取)
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/port"
+	"github-gold-miner/internal/service"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// shutdownTimeout 优雅关闭时等待进行中请求的最长时间
+const shutdownTimeout = 30 * time.Second
+
+// errShuttingDown 服务关闭后不再接受挖矿任务
+var errShuttingDown = errors.New("服务正在关闭")
+
+// Config API 服务依赖
+type Config struct {
+	Token     string                      // 访问令牌，请求需携带 Authorization: Bearer <Token>
+	Repos     port.Repository             // 项目存储
+	Searcher  *service.SearchService      // 语义搜索，为 nil 时只支持关键词搜索
+	Appraiser port.Appraiser              // 重新评估项目
+	Budget    *service.Budget             // LLM 费用预算，重新评估的用量记入账本，超出预算时拒绝；为 nil 时不限制
+	Mine      service.MineFunc            // 触发挖矿，为 nil 时不支持
+	Profiles  map[string]service.MineFunc // 按名称触发各挖矿方向的挖矿，与 Mine 二选一
+	Runs      *service.RunHistory         // 挖矿执行记录，为 nil 时使用 Repos (需要实现 port.RunStore)
+	Dashboard http.Handler                // 挂载在 / 下的网页看板 (自行鉴权)，为 nil 时不提供
+}
+
+// Server REST API 服务
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+
+	// 后台挖矿任务使用的 context，关闭服务时取消并等待任务结束
+	jobs      sync.WaitGroup
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	jobMu     sync.Mutex // 保护 closed，保证 Close 开始等待后不再有新任务加入 jobs
+	closed    bool
+}
+
+// route 一个 API 路由，public 表示不需要令牌
+type route struct {
+	method  string
+	path    string
+	public  bool
+	handler func(s *Server, w http.ResponseWriter, r *http.Request)
+}
+
+// routes 所有路由，与 openapi.yaml 中的 paths 保持一致
+var routes = []route{
+	{method: http.MethodGet, path: "/healthz", public: true, handler: (*Server).handleHealth},
+	{method: http.MethodGet, path: "/openapi.yaml", public: true, handler: (*Server).handleSpec},
+	{method: http.MethodGet, path: "/api/v1/repos", handler: (*Server).handleListRepos},
+	{method: http.MethodGet, path: "/api/v1/repos/{id}", handler: (*Server).handleGetRepo},
+	{method: http.MethodPost, path: "/api/v1/repos/{id}/appraise", handler: (*Server).handleAppraise},
+	{method: http.MethodGet, path: "/api/v1/search", handler: (*Server).handleSearch},
+	{method: http.MethodGet, path: "/api/v1/runs", handler: (*Server).handleListRuns},
+	{method: http.MethodPost, path: "/api/v1/runs", handler: (*Server).handleStartRun},
+	{method: http.MethodGet, path: "/api/v1/runs/{id}", handler: (*Server).handleGetRun},
+}
+
+// New 创建 API 服务，必须配置访问令牌
+func New(cfg Config) (*Server, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("未配置 API 访问令牌")
+	}
+	if cfg.Repos == nil {
+		return nil, errors.New("未配置项目存储")
+	}
+	if cfg.Runs == nil {
+		store, ok := cfg.Repos.(port.RunStore)
+		if !ok {
+			return nil, errors.New("未配置挖矿执行记录存储")
+		}
+		cfg.Runs = service.NewRunHistory(store)
+	}
+
+	s := &Server{cfg: cfg, mux: http.NewServeMux()}
+	s.jobCtx, s.cancelJob = context.WithCancel(context.Background())
+	for _, rt := range routes {
+		h := rt.handler
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { h(s, w, r) })
+		if !rt.public {
+			handler = s.requireToken(handler)
+		}
+		s.mux.Handle(rt.method+" "+rt.path, handler)
+	}
+	if cfg.Dashboard != nil {
+		s.mux.Handle("/", cfg.Dashboard)
+	}
+	return s, nil
+}
+
+// Handler 返回 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe 监听 addr 直到 ctx 被取消，然后停止接收新请求，
+// 等待进行中的请求完成 (最多 30 秒)，再取消并等待后台挖矿任务
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	fmt.Printf("🌐 API 服务已启动: http://%s (OpenAPI: /openapi.yaml)\n", addr)
+	if s.cfg.Dashboard != nil {
+		fmt.Printf("📊 项目看板: http://%s/\n", addr)
+	}
+
+	select {
+	case err := <-errCh:
+		s.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Println("👋 正在关闭 API 服务...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.Close()
+	return err
+}
+
+// Close 取消并等待后台挖矿任务，之后不再接受新任务
+func (s *Server) Close() {
+	s.jobMu.Lock()
+	s.closed = true
+	s.jobMu.Unlock()
+	s.cancelJob()
+	s.jobs.Wait()
+}
+
+// startJob 在后台执行 job，服务已关闭时不执行并返回 false
+func (s *Server) startJob(job func(ctx context.Context)) bool {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		job(s.jobCtx)
+	}()
+	return true
+}
+
+// isClosed 服务是否已关闭
+func (s *Server) isClosed() bool {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+	return s.closed
+}
+
+// requireToken 校验 Authorization: Bearer <token>
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	expected := []byte(s.cfg.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gold-miner"`)
+			writeError(w, http.StatusUnauthorized, "缺少或无效的访问令牌")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errorResponse 统一的错误响应
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("⚠️ 写入响应失败: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+// writeServiceError 把服务层错误映射为 HTTP 状态码
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, port.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRunInProgress):
+		writeError(w, http.StatusConflict, err.Error())
+	case common.HasCode(err, common.ErrCodeLLMBudgetExceeded):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, err.Error())
+	default:
+		log.Printf("❌ API 请求失败: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,391 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testToken = "secret"
+
+// fakeAppraiser 评估时把评分改为 score，语义搜索返回第一个候选项目；与真实评估器一样按预算检查并记录用量
+type fakeAppraiser struct {
+	score int
+}
+
+func (f *fakeAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
+	}
+	common.RecordUsage(ctx, "fake", 1000, 500)
+	copied := *repo
+	now := time.Now()
+	copied.LLMScore = f.score
+	copied.LLMReview = "重新评估"
+	copied.AppraisalModel = "fake"
+	copied.AppraisedAt = &now
+	return &copied, nil
+}
+
+func (f *fakeAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, query string) ([]*domain.SearchResult, error) {
+	return []*domain.SearchResult{
+		{RepoID: repos[0].ID, Reason: "最相关", Relevance: 90},
+		{RepoID: "github-unknown", Reason: "编造的", Relevance: 80},
+	}, nil
+}
+
+func newTestServer(t *testing.T, mine service.MineFunc) (*Server, *repository.MemoryRepo) {
+	t.Helper()
+	repo := repository.NewMemoryRepo()
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range []*domain.Repo{
+		{ID: "github-1", Name: "acme/agent", Description: "coding agent", Language: "Go", LLMScore: 90, Categories: []string{domain.CategoryAgentFramework}, CreatedAt: created},
+		{ID: "github-2", Name: "acme/review", Description: "code review bot", Language: "Python", LLMScore: 70, CreatedAt: created},
+	} {
+		require.NoError(t, repo.Save(ctx, r))
+	}
+
+	appraiser := &fakeAppraiser{score: 95}
+	srv, err := New(Config{
+		Token:     testToken,
+		Repos:     repo,
+		Searcher:  service.NewSearchService(repo, appraiser, nil, service.SearchConfig{}),
+		Appraiser: appraiser,
+		Mine:      mine,
+	})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv, repo
+}
+
+func do(t *testing.T, srv *Server, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+}
+
+func TestNew_RequiresToken(t *testing.T) {
+	_, err := New(Config{Repos: repository.NewMemoryRepo()})
+	assert.Error(t, err)
+}
+
+func TestAuth(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	for _, header := range []string{"", "Bearer wrong", testToken} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/repos", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "Authorization: %q", header)
+	}
+
+	// 健康检查和接口文档不需要令牌
+	for _, path := range []string{"/healthz", "/openapi.yaml"} {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestListRepos(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/repos?language=go&min_score=80&limit=5")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp repoListResponse
+	decode(t, rec, &resp)
+	assert.Equal(t, 5, resp.Limit)
+	if assert.Len(t, resp.Items, 1) {
+		assert.Equal(t, "github-1", resp.Items[0].ID)
+	}
+
+	rec = do(t, srv, http.MethodGet, "/api/v1/repos?created_after=2025-01-01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[],"limit":10,"offset":0}`, rec.Body.String())
+
+	for _, bad := range []string{"limit=abc", "offset=-1", "created_before=yesterday"} {
+		rec = do(t, srv, http.MethodGet, "/api/v1/repos?"+bad)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, bad)
+	}
+}
+
+func TestGetRepo(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/repos/github-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp repoDetailResponse
+	decode(t, rec, &resp)
+	assert.Equal(t, "acme/agent", resp.Repo.Name)
+	assert.NotNil(t, resp.Appraisals)
+
+	rec = do(t, srv, http.MethodGet, "/api/v1/repos/github-404")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAppraiseRepo(t *testing.T) {
+	srv, repo := newTestServer(t, nil)
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/repos/github-2/appraise")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp repoDetailResponse
+	decode(t, rec, &resp)
+	assert.Equal(t, 95, resp.Repo.LLMScore)
+	assert.Len(t, resp.Appraisals, 1)
+
+	saved, err := repo.Get(context.Background(), "github-2")
+	require.NoError(t, err)
+	assert.Equal(t, 95, saved.LLMScore)
+
+	rec = do(t, srv, http.MethodPost, "/api/v1/repos/github-404/appraise")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAppraiseRepo_BudgetExceeded(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	ctx := context.Background()
+	require.NoError(t, repo.Save(ctx, &domain.Repo{ID: "github-2", Name: "acme/review", LLMScore: 70}))
+
+	// 一次评估花费 $1.5，超出每日预算 $1
+	budget := service.NewBudget(repo, service.BudgetConfig{
+		DailyUSD: 1,
+		Prices:   map[string]domain.ModelPrice{"fake": {Prompt: 1000, Completion: 1000}},
+	})
+	appraiser := &fakeAppraiser{score: 95}
+	srv, err := New(Config{Token: testToken, Repos: repo, Appraiser: appraiser, Budget: budget})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/repos/github-2/appraise")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	records, err := repo.ListUsage(ctx, "")
+	require.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "fake", records[0].Model)
+		assert.InDelta(t, 1.5, records[0].CostUSD, 1e-9)
+	}
+
+	// 预算用完后拒绝重新评估，不改动已有评分
+	require.NoError(t, repo.Save(ctx, &domain.Repo{ID: "github-2", Name: "acme/review", LLMScore: 70}))
+	rec = do(t, srv, http.MethodPost, "/api/v1/repos/github-2/appraise")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "超出预算")
+	saved, err := repo.Get(ctx, "github-2")
+	require.NoError(t, err)
+	assert.Equal(t, 70, saved.LLMScore)
+}
+
+func TestSearch(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	rec := do(t, srv, http.MethodGet, "/api/v1/search?q=review")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp searchResponse
+	decode(t, rec, &resp)
+	assert.Equal(t, "keyword", resp.Mode)
+	if assert.Len(t, resp.Results, 1) {
+		assert.Equal(t, "github-2", resp.Results[0].RepoID)
+	}
+
+	// 语义搜索丢弃 AI 编造的项目
+	rec = do(t, srv, http.MethodGet, "/api/v1/search?q=agent&mode=semantic")
+	require.Equal(t, http.StatusOK, rec.Code)
+	decode(t, rec, &resp)
+	assert.Equal(t, "semantic", resp.Mode)
+	if assert.Len(t, resp.Results, 1) {
+		assert.Equal(t, 90, resp.Results[0].Relevance)
+		assert.NotNil(t, resp.Results[0].Repo)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodGet, "/api/v1/search").Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodGet, "/api/v1/search?q=x&mode=fuzzy").Code)
+}
+
+func TestRuns(t *testing.T) {
+	release := make(chan struct{})
+	mineErr := errors.New("GitHub 限流")
+	srv, _ := newTestServer(t, func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		<-release
+		report := &domain.MiningReport{LLMTokens: 300}
+		report.AddStage(domain.StageFetch, 4).AddError(mineErr)
+		return report, mineErr
+	})
+
+	rec := do(t, srv, http.MethodPost, "/api/v1/runs")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/api/v1/runs/1", rec.Header().Get("Location"))
+	var run domain.MiningRun
+	decode(t, rec, &run)
+	assert.Equal(t, domain.RunRunning, run.Status)
+	assert.Equal(t, domain.TriggerAPI, run.Trigger)
+
+	// 同一时间只允许一个挖矿任务
+	assert.Equal(t, http.StatusConflict, do(t, srv, http.MethodPost, "/api/v1/runs").Code)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		rec := do(t, srv, http.MethodGet, "/api/v1/runs/1")
+		var got domain.MiningRun
+		decode(t, rec, &got)
+		return got.Status == domain.RunFailed && got.Error == mineErr.Error()
+	}, time.Second, 10*time.Millisecond)
+
+	var got domain.MiningRun
+	decode(t, do(t, srv, http.MethodGet, "/api/v1/runs/1"), &got)
+	assert.Equal(t, int64(300), got.LLMTokens)
+	require.Len(t, got.Stages, 1)
+	assert.Equal(t, []string{mineErr.Error()}, got.Stages[0].Errors)
+
+	rec = do(t, srv, http.MethodGet, "/api/v1/runs")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"failed"`)
+
+	assert.Equal(t, http.StatusNotFound, do(t, srv, http.MethodGet, "/api/v1/runs/99").Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodGet, "/api/v1/runs/abc").Code)
+	assert.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodGet, "/api/v1/runs?limit=-1").Code)
+}
+
+func TestRuns_Profiles(t *testing.T) {
+	repo := repository.NewMemoryRepo()
+	mined := make(chan string, 2)
+	mineAs := func(profile string) service.MineFunc {
+		return func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+			mined <- profile
+			return &domain.MiningReport{}, nil
+		}
+	}
+	srv, err := New(Config{
+		Token:    testToken,
+		Repos:    repo,
+		Profiles: map[string]service.MineFunc{"agents": mineAs("agents"), "rust": mineAs("rust")},
+	})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	// 多个挖矿方向时必须指定方向
+	rec := do(t, srv, http.MethodPost, "/api/v1/runs")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "agents, rust")
+	assert.Equal(t, http.StatusBadRequest, do(t, srv, http.MethodPost, "/api/v1/runs?profile=java").Code)
+
+	rec = do(t, srv, http.MethodPost, "/api/v1/runs?profile=rust")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run domain.MiningRun
+	decode(t, rec, &run)
+	assert.Equal(t, "rust", run.Profile)
+	assert.Equal(t, "rust", <-mined)
+
+	// 按挖矿方向过滤项目
+	require.NoError(t, repo.Save(context.Background(), &domain.Repo{ID: "github-1", Name: "acme/rs", Profiles: []string{"rust"}}))
+	require.NoError(t, repo.Save(context.Background(), &domain.Repo{ID: "github-2", Name: "acme/agent", Profiles: []string{"agents"}}))
+	var page repoListResponse
+	decode(t, do(t, srv, http.MethodGet, "/api/v1/repos?profile=agents"), &page)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "acme/agent", page.Items[0].Name)
+}
+
+func TestClose_CancelsRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	srv, _ := newTestServer(t, func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	require.Equal(t, http.StatusAccepted, do(t, srv, http.MethodPost, "/api/v1/runs").Code)
+	<-started
+	srv.Close()
+
+	run, err := srv.cfg.Runs.Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunFailed, run.Status)
+
+	// 关闭后不再接受新的挖矿任务
+	assert.Equal(t, http.StatusServiceUnavailable, do(t, srv, http.MethodPost, "/api/v1/runs").Code)
+}
+
+func TestClose_RaceWithStartRun(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		srv, _ := newTestServer(t, func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		done := make(chan int)
+		go func() { done <- do(t, srv, http.MethodPost, "/api/v1/runs").Code }()
+		srv.Close()
+		code := <-done
+		assert.Contains(t, []int{http.StatusAccepted, http.StatusServiceUnavailable}, code)
+
+		// 无论请求先到还是关闭先到，都不会留下执行中的记录
+		runs, err := srv.cfg.Runs.List(context.Background(), 10)
+		require.NoError(t, err)
+		for _, run := range runs {
+			assert.Equal(t, domain.RunFailed, run.Status)
+		}
+	}
+}
+
+// TestOpenAPISpec_MatchesRoutes 接口文档和实际注册的路由必须一致
+func TestOpenAPISpec_MatchesRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]interface{} `yaml:"paths"`
+	}
+	require.NoError(t, yaml.Unmarshal(openAPISpec, &spec))
+
+	documented := make(map[string]bool)
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	registered := make(map[string]bool)
+	for _, rt := range routes {
+		registered[rt.method+" "+rt.path] = true
+	}
+	assert.Equal(t, documented, registered)
+}
+
+func TestDashboardMount(t *testing.T) {
+	dashboard := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("dashboard " + r.URL.Path))
+	})
+	srv, err := New(Config{Token: testToken, Repos: repository.NewMemoryRepo(), Dashboard: dashboard})
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/repos/github-1", nil))
+	assert.Equal(t, "dashboard /repos/github-1", rec.Body.String())
+
+	// API 路由不受看板影响，仍然需要令牌
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/repos", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
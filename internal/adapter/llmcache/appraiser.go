@@ -0,0 +1,266 @@
+package llmcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// pruneInterval 两次清理过期和超出数量的缓存之间的最小间隔
+const pruneInterval = time.Hour
+
+// Appraiser 给鉴定师加上持久化的评估缓存，实现 port.Appraiser
+// 模型、Prompt 版本、项目名称、描述和 README 版本都相同的项目在有效期内直接返回缓存的结果，不再调用 LLM；
+// 被包装的鉴定师没有报告 Prompt 版本 (port.PromptVersioner) 时无法判断缓存是否过时，不使用缓存；
+// 项目的 README 版本未知且无法查询 (见 SetReadmeInspector) 时同样不使用缓存
+type Appraiser struct {
+	port.Appraiser
+	cache *cache
+}
+
+// cache 缓存存储和统计，由同一个鉴定师派生出的不同评估标准的鉴定师共用
+type cache struct {
+	store      port.AppraisalCache
+	readme     port.ReadmeInspector // 查询 README 版本，为 nil 时只缓存已知 README 版本的项目
+	ttl        time.Duration
+	maxEntries int
+	readOnly   bool
+	nowFunc    func() time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// New 用 store 缓存 inner 的评估结果，缓存 ttl 后过期，最多保留 maxEntries 条
+func New(inner port.Appraiser, store port.AppraisalCache, ttl time.Duration, maxEntries int) *Appraiser {
+	return &Appraiser{
+		Appraiser: inner,
+		cache:     &cache{store: store, ttl: ttl, maxEntries: maxEntries, nowFunc: time.Now},
+	}
+}
+
+// SetReadOnly 只读取缓存，不写入也不清理 (用于试运行)
+func (a *Appraiser) SetReadOnly(readOnly bool) {
+	a.cache.readOnly = readOnly
+}
+
+// SetReadmeInspector 设置查询 README 版本的组件，README 版本未知的项目查询后再读写缓存
+func (a *Appraiser) SetReadmeInspector(inspector port.ReadmeInspector) {
+	a.cache.readme = inspector
+}
+
+// Key 缓存键：模型、Prompt 版本、项目名称、描述和 README 版本的 SHA-256
+func Key(model, promptVersion string, repo *domain.Repo) string {
+	h := sha256.New()
+	for _, part := range []string{model, promptVersion, repo.Name, repo.Description, repo.ReadmeSHA} {
+		h.Write([]byte(part))
+		h.Write([]byte{0}) // 分隔符，避免不同字段拼接后相同
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Appraise 命中缓存时直接返回缓存的评估结果，否则调用被包装的鉴定师并写入缓存
+// 缓存读写失败只记录日志，不影响评估
+func (a *Appraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	versioner, ok := a.Appraiser.(port.PromptVersioner)
+	if !ok {
+		return a.Appraiser.Appraise(ctx, repo)
+	}
+	key, ok := a.key(ctx, versioner, repo)
+	if !ok {
+		return a.Appraiser.Appraise(ctx, repo)
+	}
+
+	entry, err := a.cache.store.GetCachedAppraisal(ctx, key, a.cache.nowFunc().Add(-a.cache.ttl))
+	if err == nil {
+		a.cache.hits.Add(1)
+		return fromCache(repo, entry), nil
+	}
+	if !errors.Is(err, port.ErrNotFound) {
+		log.Printf("⚠️ 读取 %s 的评估缓存失败: %v", repo.Name, err)
+	}
+
+	a.cache.misses.Add(1)
+	appraised, err := a.Appraiser.Appraise(ctx, repo)
+	if err != nil || appraised == nil {
+		return appraised, err
+	}
+	a.cache.put(ctx, key, repo.ID, appraised)
+	return appraised, nil
+}
+
+// AppraiseBatch 命中缓存的项目直接使用缓存，其余项目交给被包装的鉴定师批量评估并写入缓存，实现 port.BatchAppraiser
+// 被包装的鉴定师不支持批量评估时逐个评估，评估失败的项目不在结果中
+func (a *Appraiser) AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error) {
+	versioner, cacheable := a.Appraiser.(port.PromptVersioner)
+	results := make(map[string]*domain.Repo, len(repos))
+	keys := make(map[string]string, len(repos))
+	var misses []*domain.Repo
+	for _, repo := range repos {
+		if !cacheable {
+			misses = append(misses, repo)
+			continue
+		}
+		key, ok := a.key(ctx, versioner, repo)
+		if !ok {
+			misses = append(misses, repo)
+			continue
+		}
+		entry, err := a.cache.store.GetCachedAppraisal(ctx, key, a.cache.nowFunc().Add(-a.cache.ttl))
+		if err == nil {
+			a.cache.hits.Add(1)
+			results[repo.ID] = fromCache(repo, entry)
+			continue
+		}
+		if !errors.Is(err, port.ErrNotFound) {
+			log.Printf("⚠️ 读取 %s 的评估缓存失败: %v", repo.Name, err)
+		}
+		a.cache.misses.Add(1)
+		keys[repo.ID] = key
+		misses = append(misses, repo)
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	appraised := make(map[string]*domain.Repo, len(misses))
+	if batch, ok := a.Appraiser.(port.BatchAppraiser); ok {
+		var err error
+		if appraised, err = batch.AppraiseBatch(ctx, misses); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, repo := range misses {
+			if r, err := a.Appraiser.Appraise(ctx, repo); err == nil && r != nil {
+				appraised[repo.ID] = r
+			}
+		}
+	}
+	for id, r := range appraised {
+		if key, ok := keys[id]; ok {
+			a.cache.put(ctx, key, id, r)
+		}
+		results[id] = r
+	}
+	return results, nil
+}
+
+// key 返回项目的缓存键；README 版本未知时先查询并记录到 repo.ReadmeSHA，无法查询时返回 false，不使用缓存
+// 否则 README 变化后仍会命中按空 README 版本缓存的旧结果
+func (a *Appraiser) key(ctx context.Context, versioner port.PromptVersioner, repo *domain.Repo) (string, bool) {
+	if repo.ReadmeSHA == "" {
+		if a.cache.readme == nil {
+			return "", false
+		}
+		sha, err := a.cache.readme.ReadmeSHA(ctx, repo.Name)
+		if err != nil {
+			log.Printf("⚠️ 获取 %s 的 README 版本失败，本次评估不使用缓存: %v", repo.Name, err)
+			return "", false
+		}
+		repo.ReadmeSHA = sha
+	}
+	return Key(a.Model(), versioner.PromptVersion(), repo), true
+}
+
+// WithCriteria 返回按自定义标准评估、共用同一个缓存的鉴定师，实现 port.CriteriaAppraiser
+// 不同标准的 Prompt 版本不同，缓存互不影响；被包装的鉴定师不支持自定义标准时返回自身
+func (a *Appraiser) WithCriteria(criteria string) port.Appraiser {
+	inner, ok := a.Appraiser.(port.CriteriaAppraiser)
+	if !ok {
+		return a
+	}
+	return &Appraiser{Appraiser: inner.WithCriteria(criteria), cache: a.cache}
+}
+
+// PromptVersion 返回被包装的鉴定师的 Prompt 版本，实现 port.PromptVersioner
+func (a *Appraiser) PromptVersion() string {
+	if versioner, ok := a.Appraiser.(port.PromptVersioner); ok {
+		return versioner.PromptVersion()
+	}
+	return ""
+}
+
+// Model 返回被包装的鉴定师的模型名称，实现 port.ModelNamer
+func (a *Appraiser) Model() string {
+	if namer, ok := a.Appraiser.(port.ModelNamer); ok {
+		return namer.Model()
+	}
+	return ""
+}
+
+// TokensUsed 返回被包装的鉴定师累计消耗的 Token 数，实现 port.TokenCounter
+func (a *Appraiser) TokensUsed() int64 {
+	if counter, ok := a.Appraiser.(port.TokenCounter); ok {
+		return counter.TokensUsed()
+	}
+	return 0
+}
+
+// CacheStats 返回累计的缓存命中和未命中次数，实现 port.CacheCounter
+func (a *Appraiser) CacheStats() (hits, misses int64) {
+	return a.cache.hits.Load(), a.cache.misses.Load()
+}
+
+// put 写入缓存，距上次清理超过 pruneInterval 时顺便清理过期和超出数量的缓存
+func (c *cache) put(ctx context.Context, key, repoID string, appraised *domain.Repo) {
+	if c.readOnly {
+		return
+	}
+	now := c.nowFunc()
+	entry := &domain.CachedAppraisal{
+		CacheKey:            key,
+		RepoID:              repoID,
+		IsAIProgrammingTool: appraised.IsAIProgrammingTool,
+		LLMScore:            appraised.LLMScore,
+		LLMReview:           appraised.LLMReview,
+		Categories:          appraised.Categories,
+		AppraisalModel:      appraised.AppraisalModel,
+		PromptVersion:       appraised.PromptVersion,
+		CreatedAt:           now,
+	}
+	if appraised.AppraisedAt != nil {
+		entry.CreatedAt = *appraised.AppraisedAt
+	}
+	if err := c.store.PutCachedAppraisal(ctx, entry); err != nil {
+		log.Printf("⚠️ 写入评估缓存失败: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	due := now.Sub(c.lastPrune) >= pruneInterval
+	if due {
+		c.lastPrune = now
+	}
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := c.store.PruneAppraisalCache(ctx, now.Add(-c.ttl), c.maxEntries); err != nil {
+		log.Printf("⚠️ 清理评估缓存失败: %v", err)
+	}
+}
+
+// fromCache 用缓存的评估结果填充项目副本，评估时间为缓存写入的时间
+func fromCache(repo *domain.Repo, entry *domain.CachedAppraisal) *domain.Repo {
+	appraised := *repo
+	appraisedAt := entry.CreatedAt
+	appraised.IsAIProgrammingTool = entry.IsAIProgrammingTool
+	appraised.LLMScore = entry.LLMScore
+	appraised.LLMReview = entry.LLMReview
+	appraised.Categories = append([]string(nil), entry.Categories...)
+	appraised.AppraisalModel = entry.AppraisalModel
+	appraised.PromptVersion = entry.PromptVersion
+	appraised.AppraisedAt = &appraisedAt
+	return &appraised
+}
@@ -0,0 +1,311 @@
+package llmcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAppraiser 记录调用次数，评分为已调用的次数
+type fakeAppraiser struct {
+	version string
+	calls   *int
+}
+
+func (f *fakeAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	*f.calls++
+	appraised := *repo
+	appraisedAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	appraised.IsAIProgrammingTool = true
+	appraised.LLMScore = *f.calls
+	appraised.LLMReview = "review"
+	appraised.Categories = []string{domain.CategoryCLIAgent}
+	appraised.AppraisalModel = "fake-model"
+	appraised.PromptVersion = f.version
+	appraised.AppraisedAt = &appraisedAt
+	return &appraised, nil
+}
+
+func (f *fakeAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error) {
+	return nil, nil
+}
+
+func (f *fakeAppraiser) PromptVersion() string { return f.version }
+func (f *fakeAppraiser) Model() string         { return "fake-model" }
+func (f *fakeAppraiser) TokensUsed() int64     { return int64(*f.calls) * 100 }
+
+func (f *fakeAppraiser) WithCriteria(criteria string) port.Appraiser {
+	return &fakeAppraiser{version: f.version + "+" + criteria, calls: f.calls}
+}
+
+// unversionedAppraiser 没有报告 Prompt 版本的鉴定师
+type unversionedAppraiser struct {
+	port.Appraiser
+}
+
+// fakeInspector 按项目名称返回 README 版本，没有记录的项目视为没有 README
+type fakeInspector struct {
+	shas  map[string]string
+	err   error
+	calls int
+}
+
+func (f *fakeInspector) ReadmeSHA(ctx context.Context, fullName string) (string, error) {
+	f.calls++
+	return f.shas[fullName], f.err
+}
+
+func newCached(t *testing.T) (*Appraiser, *repository.MemoryRepo, *int, *time.Time) {
+	t.Helper()
+	store := repository.NewMemoryRepo()
+	calls := 0
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cached := New(&fakeAppraiser{version: "v2", calls: &calls}, store, 24*time.Hour, 100)
+	cached.cache.nowFunc = func() time.Time { return now }
+	cached.SetReadmeInspector(&fakeInspector{})
+	return cached, store, &calls, &now
+}
+
+func TestAppraiser_HitAndMiss(t *testing.T) {
+	ctx := context.Background()
+	cached, _, calls, _ := newCached(t)
+	repo := &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent", ReadmeSHA: "sha-1"}
+
+	first, err := cached.Appraise(ctx, repo)
+	require.NoError(t, err)
+	second, err := cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent", ReadmeSHA: "sha-1", Stars: 500})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, first.LLMScore, second.LLMScore)
+	assert.Equal(t, []string{domain.CategoryCLIAgent}, second.Categories)
+	assert.Equal(t, "fake-model", second.AppraisalModel)
+	assert.Equal(t, "v2", second.PromptVersion)
+	assert.True(t, second.AppraisedAt.Equal(*first.AppraisedAt), "评估时间为首次评估的时间")
+	assert.Equal(t, 500, second.Stars, "GitHub 元数据来自本次的项目")
+
+	// README、描述变化后重新评估
+	_, err = cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent", ReadmeSHA: "sha-2"})
+	require.NoError(t, err)
+	_, err = cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "coding agent", ReadmeSHA: "sha-2"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, *calls)
+
+	hits, misses := cached.CacheStats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(3), misses)
+	assert.Equal(t, int64(300), cached.TokensUsed())
+}
+
+func TestAppraiser_UnknownReadme(t *testing.T) {
+	cached, _, calls, _ := newCached(t)
+	inspector := &fakeInspector{shas: map[string]string{"acme/agent": "sha-1"}}
+	cached.SetReadmeInspector(inspector)
+	ctx := context.Background()
+
+	// 首次出现的项目没有 README 版本，查询后再读写缓存
+	first := &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent"}
+	_, err := cached.Appraise(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, "sha-1", first.ReadmeSHA)
+	_, err = cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	// 只有 README 变化时不能命中旧的缓存
+	inspector.shas["acme/agent"] = "sha-2"
+	appraised, err := cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, 2, appraised.LLMScore)
+	assert.Equal(t, "sha-2", appraised.ReadmeSHA)
+	assert.Equal(t, 3, inspector.calls)
+
+	// 查询失败或无法查询 README 版本时不使用缓存
+	inspector.err = errors.New("rate limited")
+	_, err = cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent", Description: "agent"})
+	require.NoError(t, err)
+	cached.SetReadmeInspector(nil)
+	results, err := cached.AppraiseBatch(ctx, []*domain.Repo{{ID: "github-1", Name: "acme/agent", Description: "agent"}})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, 4, *calls)
+	hits, misses := cached.CacheStats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(2), misses)
+}
+
+func TestAppraiser_Expiry(t *testing.T) {
+	ctx := context.Background()
+	cached, _, calls, now := newCached(t)
+	// 缓存从评估时间开始计算有效期
+	*now = time.Date(2025, 3, 2, 8, 0, 0, 0, time.UTC)
+	repo := &domain.Repo{ID: "github-1", Name: "acme/agent"}
+
+	_, err := cached.Appraise(ctx, repo)
+	require.NoError(t, err)
+	_, err = cached.Appraise(ctx, repo)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	*now = now.Add(2 * time.Hour)
+	_, err = cached.Appraise(ctx, repo)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAppraiser_WithCriteria(t *testing.T) {
+	ctx := context.Background()
+	cached, _, calls, _ := newCached(t)
+	repo := &domain.Repo{ID: "github-1", Name: "acme/agent"}
+
+	rust := cached.WithCriteria("Rust 工具")
+	assert.Equal(t, "v2+Rust 工具", rust.(port.PromptVersioner).PromptVersion())
+	for _, a := range []port.Appraiser{cached, rust, cached, rust} {
+		_, err := a.Appraise(ctx, repo)
+		require.NoError(t, err)
+	}
+	// 不同标准的缓存互不影响，统计共用
+	assert.Equal(t, 2, *calls)
+	hits, misses := cached.CacheStats()
+	assert.Equal(t, int64(2), hits)
+	assert.Equal(t, int64(2), misses)
+}
+
+func TestAppraiser_WithoutPromptVersion(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	calls := 0
+	cached := New(unversionedAppraiser{&fakeAppraiser{calls: &calls}}, store, time.Hour, 100)
+
+	for i := 0; i < 2; i++ {
+		_, err := cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/agent"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+	hits, misses := cached.CacheStats()
+	assert.Zero(t, hits+misses)
+}
+
+func TestAppraiser_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	cached, store, _, _ := newCached(t)
+	cached.SetReadOnly(true)
+	repo := &domain.Repo{ID: "github-1", Name: "acme/agent"}
+
+	_, err := cached.Appraise(ctx, repo)
+	require.NoError(t, err)
+	_, err = store.GetCachedAppraisal(ctx, Key("fake-model", "v2", repo), time.Time{})
+	assert.ErrorIs(t, err, port.ErrNotFound)
+}
+
+func TestAppraiser_Prune(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	calls := 0
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cached := New(&clockAppraiser{fakeAppraiser{version: "v2", calls: &calls}, &now}, store, 24*time.Hour, 1)
+	cached.cache.nowFunc = func() time.Time { return now }
+	cached.SetReadmeInspector(&fakeInspector{})
+
+	one := &domain.Repo{ID: "github-1", Name: "acme/one"}
+	two := &domain.Repo{ID: "github-2", Name: "acme/two"}
+	_, err := cached.Appraise(ctx, one)
+	require.NoError(t, err)
+	// 距上次清理不到 pruneInterval 时不清理
+	now = now.Add(time.Minute)
+	_, err = cached.Appraise(ctx, two)
+	require.NoError(t, err)
+	_, err = store.GetCachedAppraisal(ctx, Key("fake-model", "v2", one), time.Time{})
+	assert.NoError(t, err)
+
+	// 超过最大条数时只保留最新的
+	now = now.Add(pruneInterval)
+	three := &domain.Repo{ID: "github-3", Name: "acme/three"}
+	_, err = cached.Appraise(ctx, three)
+	require.NoError(t, err)
+	for _, repo := range []*domain.Repo{one, two} {
+		_, err = store.GetCachedAppraisal(ctx, Key("fake-model", "v2", repo), time.Time{})
+		assert.ErrorIs(t, err, port.ErrNotFound, repo.Name)
+	}
+	_, err = store.GetCachedAppraisal(ctx, Key("fake-model", "v2", three), time.Time{})
+	assert.NoError(t, err)
+}
+
+// clockAppraiser 评估时间为当前时间的鉴定师
+type clockAppraiser struct {
+	fakeAppraiser
+	now *time.Time
+}
+
+func (u *clockAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	appraised, err := u.fakeAppraiser.Appraise(ctx, repo)
+	appraisedAt := *u.now
+	appraised.AppraisedAt = &appraisedAt
+	return appraised, err
+}
+
+// batchAppraiser 支持批量评估的 fakeAppraiser，记录每批的项目数，drop 中的项目不返回结果
+type batchAppraiser struct {
+	*fakeAppraiser
+	batches []int
+	drop    map[string]bool
+}
+
+func (b *batchAppraiser) AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error) {
+	b.batches = append(b.batches, len(repos))
+	results := make(map[string]*domain.Repo)
+	for _, repo := range repos {
+		if !b.drop[repo.ID] {
+			results[repo.ID], _ = b.Appraise(ctx, repo)
+		}
+	}
+	return results, nil
+}
+
+func TestAppraiser_AppraiseBatch(t *testing.T) {
+	ctx := context.Background()
+	cached, _, calls, _ := newCached(t)
+	inner := &batchAppraiser{fakeAppraiser: cached.Appraiser.(*fakeAppraiser), drop: map[string]bool{"github-3": true}}
+	cached.Appraiser = inner
+
+	_, err := cached.Appraise(ctx, &domain.Repo{ID: "github-1", Name: "acme/one"})
+	require.NoError(t, err)
+
+	results, err := cached.AppraiseBatch(ctx, []*domain.Repo{
+		{ID: "github-1", Name: "acme/one"},
+		{ID: "github-2", Name: "acme/two"},
+		{ID: "github-3", Name: "acme/three"},
+	})
+	require.NoError(t, err)
+	// 只把未命中的项目交给 LLM，漏掉的项目不在结果中
+	assert.Equal(t, []int{2}, inner.batches)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, results["github-1"].LLMScore)
+	assert.NotNil(t, results["github-2"])
+	hits, misses := cached.CacheStats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(3), misses)
+
+	// 批量评估的结果同样写入缓存
+	_, err = cached.Appraise(ctx, &domain.Repo{ID: "github-2", Name: "acme/two"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAppraiser_AppraiseBatchWithoutBatchSupport(t *testing.T) {
+	ctx := context.Background()
+	cached, _, calls, _ := newCached(t)
+
+	results, err := cached.AppraiseBatch(ctx, []*domain.Repo{{ID: "github-1", Name: "acme/one"}, {ID: "github-2", Name: "acme/two"}})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 2, *calls, "被包装的鉴定师不支持批量评估时逐个评估")
+}
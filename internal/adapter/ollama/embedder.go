@@ -0,0 +1,116 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/common"
+)
+
+const (
+	// DefaultHost 本地 Ollama 服务地址
+	DefaultHost = "http://localhost:11434"
+
+	// defaultEmbeddingModel 默认使用的 Embedding 模型，需要先 ollama pull
+	defaultEmbeddingModel = "nomic-embed-text"
+)
+
+// Embedder 调用本地 Ollama 的 /api/embed 接口，不需要 API Key
+type Embedder struct {
+	host      string
+	modelName string
+	client    *http.Client
+	retryOpts []common.Option
+}
+
+// NewEmbedder 创建 Ollama Embedder，host 和 model 为空时使用默认值
+func NewEmbedder(host, model string) *Embedder {
+	if host == "" {
+		host = DefaultHost
+	}
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	return &Embedder{
+		host:      strings.TrimRight(host, "/"),
+		modelName: model,
+		// 本地模型首次加载较慢
+		client: &http.Client{Timeout: 5 * time.Minute},
+		retryOpts: []common.Option{
+			common.WithMaxRetries(2),
+			common.WithInitialDelay(time.Second),
+			common.WithJitter(common.FullJitter),
+			common.LogRetry("Ollama 向量化", common.LLMError),
+		},
+	}
+}
+
+// Model 返回 Embedding 模型名称
+func (e *Embedder) Model() string {
+	return e.modelName
+}
+
+type embedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int64       `json:"prompt_eval_count"` // 输入的 Token 数
+}
+
+// Embed 批量向量化
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	// 超出费用预算时不再调用
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(embedRequest{Model: e.modelName, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	var result embedResponse
+	err = common.Do(ctx, func() error {
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, e.host+"/api/embed", bytes.NewReader(body))
+		if reqErr != nil {
+			return reqErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, postErr := e.client.Do(req)
+		if postErr != nil {
+			return postErr
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return common.NewHTTPError("Ollama", resp)
+		}
+		result = embedResponse{}
+		return json.NewDecoder(resp.Body).Decode(&result)
+	}, e.retryOpts...)
+	if err != nil {
+		return nil, common.LLMError("Ollama 向量化失败", err)
+	}
+	common.RecordUsage(ctx, e.modelName, result.PromptEvalCount, 0)
+
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("Ollama 返回 %d 个向量，期望 %d 个", len(result.Embeddings), len(texts))
+	}
+	for _, v := range result.Embeddings {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("Ollama 返回了空向量")
+		}
+	}
+	return result.Embeddings, nil
+}
@@ -0,0 +1,78 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github-gold-miner/internal/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+
+		var req embedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "nomic-embed-text", req.Model)
+		assert.Equal(t, []string{"first", "second"}, req.Input)
+
+		w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[1,0],[0,1]],"prompt_eval_count":4}`))
+	}))
+	defer server.Close()
+
+	e := NewEmbedder(server.URL, "")
+	meter := &tallyMeter{}
+	vectors, err := e.Embed(common.WithUsageMeter(context.Background(), meter), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	assert.Equal(t, "nomic-embed-text", e.Model())
+	assert.Equal(t, int64(4), meter.prompt[e.Model()], "记录输入的 Token 数")
+}
+
+// tallyMeter 按模型累计输入的 Token 数
+type tallyMeter struct {
+	prompt map[string]int64
+}
+
+func (m *tallyMeter) Allow(ctx context.Context, items int) error { return nil }
+
+func (m *tallyMeter) Record(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	if m.prompt == nil {
+		m.prompt = make(map[string]int64)
+	}
+	m.prompt[model] += promptTokens
+}
+
+func TestEmbedder_EmbedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errStr string
+	}{
+		{name: "模型未下载", status: http.StatusNotFound, body: `{"error":"model not found"}`, errStr: "状态码 404"},
+		{name: "数量不一致", status: http.StatusOK, body: `{"embeddings":[]}`, errStr: "期望 1 个"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			e := NewEmbedder(server.URL, "")
+			e.retryOpts = []common.Option{common.WithMaxRetries(0)}
+			_, err := e.Embed(context.Background(), []string{"a"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errStr)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/common"
+)
+
+const (
+	// DefaultBaseURL OpenAI API 地址，兼容 OpenAI 协议的服务 (如 Azure、vLLM) 可以替换
+	DefaultBaseURL = "https://api.openai.com/v1"
+
+	// defaultEmbeddingModel 默认使用的 Embedding 模型 (1536 维)
+	defaultEmbeddingModel = "text-embedding-3-small"
+
+	// maxEmbedBatch 单次请求的文本数量上限
+	maxEmbedBatch = 512
+)
+
+// Embedder 调用 OpenAI /embeddings 接口
+type Embedder struct {
+	apiKey    string
+	baseURL   string
+	modelName string
+	client    *http.Client
+	retryOpts []common.Option
+}
+
+// NewEmbedder 创建 OpenAI Embedder，baseURL 和 model 为空时使用默认值
+func NewEmbedder(apiKey, baseURL, model string) *Embedder {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	return &Embedder{
+		apiKey:    apiKey,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		modelName: model,
+		client:    &http.Client{Timeout: 60 * time.Second},
+		retryOpts: []common.Option{
+			common.WithMaxRetries(3),
+			common.WithInitialDelay(2 * time.Second),
+			common.WithJitter(common.FullJitter),
+			common.LogRetry("OpenAI 向量化", common.LLMError),
+		},
+	}
+}
+
+// Model 返回 Embedding 模型名称
+func (e *Embedder) Model() string {
+	return e.modelName
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int64 `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+// Embed 批量向量化，超过单次请求上限时自动分批
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		end := min(start+maxEmbedBatch, len(texts))
+		chunk, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, chunk...)
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	// 超出费用预算时不再调用
+	if err := common.AllowLLMCall(ctx); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(embeddingRequest{Model: e.modelName, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	var result embeddingResponse
+	err = common.Do(ctx, func() error {
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+		if reqErr != nil {
+			return reqErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+		resp, postErr := e.client.Do(req)
+		if postErr != nil {
+			return postErr
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return common.NewHTTPError("OpenAI API", resp)
+		}
+		result = embeddingResponse{}
+		return json.NewDecoder(resp.Body).Decode(&result)
+	}, e.retryOpts...)
+	if err != nil {
+		return nil, common.LLMError("OpenAI 向量化失败", err)
+	}
+	common.RecordUsage(ctx, e.modelName, result.Usage.PromptTokens, 0)
+
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("OpenAI 返回 %d 个向量，期望 %d 个", len(result.Data), len(texts))
+	}
+	// 按 index 还原输入顺序
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	vectors := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		if len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("OpenAI 返回了空向量")
+		}
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github-gold-miner/internal/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+
+		var req embeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-small", req.Model)
+		assert.Equal(t, []string{"first", "second"}, req.Input)
+
+		// 故意打乱顺序，客户端按 index 还原
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":4,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	e := NewEmbedder("sk-test", server.URL+"/v1/", "")
+	meter := &tallyMeter{}
+	vectors, err := e.Embed(common.WithUsageMeter(context.Background(), meter), []string{"first", "second"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	assert.Equal(t, "text-embedding-3-small", e.Model())
+	assert.Equal(t, int64(4), meter.prompt[e.Model()], "记录输入的 Token 数")
+}
+
+// tallyMeter 按模型累计输入的 Token 数
+type tallyMeter struct {
+	prompt map[string]int64
+}
+
+func (m *tallyMeter) Allow(ctx context.Context, items int) error { return nil }
+
+func (m *tallyMeter) Record(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	if m.prompt == nil {
+		m.prompt = make(map[string]int64)
+	}
+	m.prompt[model] += promptTokens
+}
+
+func TestEmbedder_EmbedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errStr string
+	}{
+		{name: "认证失败", status: http.StatusUnauthorized, body: `{"error":"invalid key"}`, errStr: "状态码 401"},
+		{name: "数量不一致", status: http.StatusOK, body: `{"data":[]}`, errStr: "期望 1 个"},
+		{name: "空向量", status: http.StatusOK, body: `{"data":[{"index":0,"embedding":[]}]}`, errStr: "空向量"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			e := NewEmbedder("sk-test", server.URL, "")
+			e.retryOpts = []common.Option{common.WithMaxRetries(0)}
+			_, err := e.Embed(context.Background(), []string{"a"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errStr)
+		})
+	}
+}
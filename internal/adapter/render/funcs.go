@@ -0,0 +1,120 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Funcs 返回模板中可用的辅助函数
+// now 是计算相对日期的基准时间，渲染时使用 Data.Now，测试和重放时结果固定
+func Funcs(now time.Time) template.FuncMap {
+	return template.FuncMap{
+		"humanize": humanize,
+		"relTime": func(t time.Time) string {
+			return relativeTime(t, now)
+		},
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02")
+		},
+		"datetime": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04")
+		},
+		"truncate": truncate,
+		"json":     toJSON,
+		"join":     strings.Join,
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"default": func(def, val string) string {
+			if strings.TrimSpace(val) == "" {
+				return def
+			}
+			return val
+		},
+	}
+}
+
+// humanize 把数字转成便于阅读的形式，例如 1234 -> 1.2k, 5300000 -> 5.3M
+func humanize(v interface{}) string {
+	var n float64
+	switch x := v.(type) {
+	case int:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case float64:
+		n = x
+	case float32:
+		n = float64(x)
+	default:
+		return fmt.Sprint(v)
+	}
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= 1e6:
+		return trimZero(fmt.Sprintf("%.1f", n/1e6)) + "M"
+	case abs >= 1e3:
+		return trimZero(fmt.Sprintf("%.1f", n/1e3)) + "k"
+	case n == float64(int64(n)):
+		return fmt.Sprintf("%d", int64(n))
+	default:
+		return fmt.Sprintf("%.2f", n)
+	}
+}
+
+func trimZero(s string) string {
+	return strings.TrimSuffix(s, ".0")
+}
+
+// relativeTime 计算相对日期，例如 "3 天前"、"刚刚"
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "未知"
+	}
+	d := now.Sub(t)
+	if d < 0 {
+		return "刚刚"
+	}
+	switch {
+	case d < time.Minute:
+		return "刚刚"
+	case d < time.Hour:
+		return fmt.Sprintf("%d 分钟前", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d 小时前", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%d 天前", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%d 个月前", int(d/(30*24*time.Hour)))
+	default:
+		return fmt.Sprintf("%d 年前", int(d/(365*24*time.Hour)))
+	}
+}
+
+// truncate 按字符数截断字符串，避免中文被截成乱码
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n]) + "…"
+}
+
+// toJSON 把值编码成 JSON 字面量，用于在 JSON 模板中安全嵌入字符串
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,233 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github-gold-miner/internal/domain"
+)
+
+// Kind 消息类型
+type Kind string
+
+const (
+	KindSingle Kind = "single" // 单个项目推送
+	KindDigest Kind = "digest" // 多个项目汇总
+	KindAlert  Kind = "alert"  // 告警/系统通知
+	KindUpdate Kind = "update" // 推送后追踪更新 (爆发/撤回)
+)
+
+// 内置渠道名称
+const (
+	ChannelFeishu   = "feishu"
+	ChannelMarkdown = "markdown"
+)
+
+// templateExt 模板文件扩展名，文件布局为 <channel>/<kind>.tmpl
+const templateExt = ".tmpl"
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Data 模板渲染时可访问的上下文
+type Data struct {
+	Repo    *domain.Repo          // single 消息的项目
+	Repos   []*domain.Repo        // digest 消息的项目列表
+	Title   string                // digest/alert 标题
+	Message string                // alert 正文
+	Level   string                // alert 级别 (info/warning/critical)
+	Event   *domain.TrackingEvent // update 消息的追踪事件
+	Now     time.Time             // 渲染时间，便于计算相对日期
+}
+
+// Renderer 负责把通知内容渲染成各渠道需要的格式
+// 模板按 (渠道, 消息类型) 索引，用户目录中的同名文件会覆盖内置模板
+type Renderer struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+	nowFunc   func() time.Time
+}
+
+// Default 返回只包含内置模板的新渲染器，每次调用返回独立的实例，
+// 调用方用 SetNowFunc 修改时间函数不会影响其他使用者
+// 内置模板随代码一起编译和测试，解析失败说明是程序缺陷，直接 panic
+func Default() *Renderer {
+	r, err := New("")
+	if err != nil {
+		panic(fmt.Sprintf("内置通知模板解析失败: %v", err))
+	}
+	return r
+}
+
+// New 加载内置模板，并用 dir 下的用户模板覆盖
+// dir 为空时只使用内置模板
+func New(dir string) (*Renderer, error) {
+	r := &Renderer{
+		templates: make(map[string]*template.Template),
+		nowFunc:   time.Now,
+	}
+
+	sub, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadFS(sub); err != nil {
+		return nil, fmt.Errorf("加载内置模板失败: %w", err)
+	}
+
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("模板目录不可用: %w", err)
+		}
+		if err := r.loadFS(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("加载模板目录 %s 失败: %w", dir, err)
+		}
+	}
+
+	return r, nil
+}
+
+// SetNowFunc 设置当前时间函数，便于测试中固定相对日期
+func (r *Renderer) SetNowFunc(now func() time.Time) {
+	if now == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nowFunc = now
+}
+
+// now 返回当前时间，与 SetNowFunc 并发调用是安全的
+func (r *Renderer) now() time.Time {
+	r.mu.RLock()
+	now := r.nowFunc
+	r.mu.RUnlock()
+	return now()
+}
+
+// Has 判断是否存在某个渠道和消息类型的模板
+func (r *Renderer) Has(channel string, kind Kind) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.templates[key(channel, kind)]
+	return ok
+}
+
+// Render 渲染指定渠道和消息类型的模板
+func (r *Renderer) Render(channel string, kind Kind, data Data) ([]byte, error) {
+	r.mu.RLock()
+	tmpl, ok := r.templates[key(channel, kind)]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未找到模板: %s/%s", channel, kind)
+	}
+
+	if data.Now.IsZero() {
+		data.Now = r.now()
+	}
+	exec, err := bind(tmpl, data.Now)
+	if err != nil {
+		return nil, fmt.Errorf("渲染模板 %s/%s 失败: %w", channel, kind, err)
+	}
+
+	var buf bytes.Buffer
+	if err := exec.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s/%s 失败: %w", channel, kind, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderJSON 渲染模板并校验结果是合法 JSON，适用于 Webhook 类渠道
+func (r *Renderer) RenderJSON(channel string, kind Kind, data Data) ([]byte, error) {
+	out, err := r.Render(channel, kind, data)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(out) {
+		return nil, fmt.Errorf("模板 %s/%s 渲染结果不是合法 JSON", channel, kind)
+	}
+	return out, nil
+}
+
+// loadFS 从文件系统中加载 <channel>/<kind>.tmpl 形式的模板
+func (r *Renderer) loadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != templateExt {
+			return nil
+		}
+
+		channel := path.Dir(p)
+		if channel == "." || strings.Contains(channel, "/") {
+			// 只接受一级渠道目录
+			return nil
+		}
+		kind := Kind(strings.TrimSuffix(path.Base(p), templateExt))
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		tmpl, err := r.parse(filepath.ToSlash(p), string(content))
+		if err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		r.templates[key(channel, kind)] = tmpl
+		r.mu.Unlock()
+		return nil
+	})
+}
+
+// parse 解析单个模板文件，每个文件是独立的模板集合，
+// 因此不同文件中的 define 不会互相冲突
+// relTime 和 include 在渲染时由 bind 绑定，解析时只需要函数签名
+func (r *Renderer) parse(name, content string) (*template.Template, error) {
+	tmpl := template.New(name).Option("missingkey=error")
+	funcs := Funcs(time.Time{})
+	funcs["include"] = func(string, interface{}) (string, error) {
+		return "", fmt.Errorf("include 未初始化")
+	}
+	tmpl = tmpl.Funcs(funcs)
+
+	parsed, err := tmpl.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+	}
+	return parsed, nil
+}
+
+// bind 复制解析好的模板集合，绑定本次渲染的时间，相对日期只取决于 Data.Now；
+// include 需要引用复制后的模板集合，被引用的模板使用同一个时间
+func bind(tmpl *template.Template, now time.Time) (*template.Template, error) {
+	exec, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	funcs := Funcs(now)
+	funcs["include"] = func(tplName string, data interface{}) (string, error) {
+		var buf bytes.Buffer
+		if err := exec.ExecuteTemplate(&buf, tplName, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return exec.Funcs(funcs), nil
+}
+
+func key(channel string, kind Kind) string {
+	return channel + "/" + string(kind)
+}
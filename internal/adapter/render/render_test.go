@@ -0,0 +1,223 @@
+package render
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 使用 go test ./internal/adapter/render -update 重新生成 golden 文件
+var update = flag.Bool("update", false, "更新 golden 文件")
+
+var fixedNow = time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+
+func sampleRepos() []*domain.Repo {
+	return []*domain.Repo{
+		{
+			ID:                  "github-123",
+			Name:                "test/awesome-tool",
+			URL:                 "https://github.com/test/awesome-tool",
+			Description:         "An awesome AI coding tool with <special> & \"quoted\" chars",
+			Stars:               1234,
+			Language:            "Python",
+			CreatedAt:           fixedNow.AddDate(0, 0, -5),
+			UpdatedAt:           fixedNow,
+			StarGrowthRate:      246.8,
+			IsAIProgrammingTool: true,
+			LLMScore:            85,
+			LLMReview:           "优秀的AI编程助手，支持多种IDE",
+		},
+		{
+			ID:                  "github-456",
+			Name:                "ai/super-coder",
+			URL:                 "https://github.com/ai/super-coder",
+			Description:         "",
+			Stars:               56000,
+			Language:            "Go",
+			CreatedAt:           fixedNow.Add(-3 * time.Hour),
+			UpdatedAt:           fixedNow,
+			StarGrowthRate:      448000,
+			IsAIProgrammingTool: true,
+			LLMScore:            95,
+			LLMReview:           "Outstanding tool",
+		},
+	}
+}
+
+func TestRenderer_DefaultTemplates_Golden(t *testing.T) {
+	repos := sampleRepos()
+
+	notifiedAt := fixedNow.AddDate(0, 0, -3)
+	notified := repos[0]
+	notified.NotifiedAt = &notifiedAt
+	breakout := &domain.TrackingEvent{Type: domain.EventBreakout, RepoID: notified.ID, PreviousStars: 1234, CurrentStars: 5321, Milestone: 5000, DetectedAt: fixedNow}
+	archived := &domain.TrackingEvent{Type: domain.EventArchived, RepoID: notified.ID, PreviousStars: 1234, CurrentStars: 1300, DetectedAt: fixedNow}
+	deleted := &domain.TrackingEvent{Type: domain.EventDeleted, RepoID: notified.ID, PreviousStars: 1234, CurrentStars: 1234, DetectedAt: fixedNow}
+
+	tests := []struct {
+		name    string
+		channel string
+		kind    Kind
+		data    Data
+	}{
+		{name: "feishu_single", channel: ChannelFeishu, kind: KindSingle, data: Data{Repo: repos[0]}},
+		{name: "feishu_digest", channel: ChannelFeishu, kind: KindDigest, data: Data{Title: "本周精选", Repos: repos}},
+		{name: "feishu_alert", channel: ChannelFeishu, kind: KindAlert, data: Data{Level: "warning", Title: "预算告警", Message: "今日 LLM 花费已达到预算的 90%"}},
+		{name: "markdown_single", channel: ChannelMarkdown, kind: KindSingle, data: Data{Repo: repos[0]}},
+		{name: "markdown_digest", channel: ChannelMarkdown, kind: KindDigest, data: Data{Repos: repos}},
+		{name: "markdown_alert", channel: ChannelMarkdown, kind: KindAlert, data: Data{Title: "Webhook 失效", Message: "飞书返回 403"}},
+		{name: "feishu_update_breakout", channel: ChannelFeishu, kind: KindUpdate, data: Data{Repo: notified, Event: breakout}},
+		{name: "feishu_update_archived", channel: ChannelFeishu, kind: KindUpdate, data: Data{Repo: notified, Event: archived}},
+		{name: "markdown_update_breakout", channel: ChannelMarkdown, kind: KindUpdate, data: Data{Repo: notified, Event: breakout}},
+		{name: "markdown_update_deleted", channel: ChannelMarkdown, kind: KindUpdate, data: Data{Repo: notified, Event: deleted}},
+	}
+
+	r, err := New("")
+	require.NoError(t, err)
+	r.SetNowFunc(func() time.Time { return fixedNow })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := r.Render(tt.channel, tt.kind, tt.data)
+			require.NoError(t, err)
+
+			if tt.channel == ChannelFeishu {
+				assert.True(t, json.Valid(out), "飞书模板必须渲染出合法 JSON")
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, out, 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(out))
+		})
+	}
+}
+
+func TestRenderer_DataNow_Golden(t *testing.T) {
+	// 相对日期以 Data.Now 为准，与渲染器的时间函数无关，重放历史消息时结果不变
+	r := Default()
+	r.SetNowFunc(func() time.Time { return fixedNow.AddDate(1, 0, 0) })
+
+	for _, tt := range []struct {
+		name    string
+		channel string
+		data    Data
+	}{
+		// 飞书 digest 通过 include 引用模板集合中的 markdown，两处使用同一个时间
+		{name: "feishu_digest", channel: ChannelFeishu, data: Data{Title: "本周精选", Repos: sampleRepos(), Now: fixedNow}},
+		{name: "markdown_digest", channel: ChannelMarkdown, data: Data{Repos: sampleRepos(), Now: fixedNow}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := r.Render(tt.channel, KindDigest, tt.data)
+			require.NoError(t, err)
+			expected, err := os.ReadFile(filepath.Join("testdata", tt.name+".golden"))
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(out))
+		})
+	}
+}
+
+func TestRenderer_UserTemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ChannelMarkdown), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, ChannelMarkdown, "single.tmpl"),
+		[]byte(`{{.Repo.Name}} ⭐{{humanize .Repo.Stars}} {{relTime .Repo.CreatedAt}}`),
+		0o644,
+	))
+	// 新渠道也可以只通过用户目录提供
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "slack"), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "slack", "alert.tmpl"),
+		[]byte(`{"text": {{json .Message}}}`),
+		0o644,
+	))
+
+	r, err := New(dir)
+	require.NoError(t, err)
+	r.SetNowFunc(func() time.Time { return fixedNow })
+
+	out, err := r.Render(ChannelMarkdown, KindSingle, Data{Repo: sampleRepos()[0]})
+	require.NoError(t, err)
+	assert.Equal(t, "test/awesome-tool ⭐1.2k 5 天前", string(out))
+
+	out, err = r.RenderJSON("slack", KindAlert, Data{Message: "hello"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": "hello"}`, string(out))
+
+	// 未覆盖的模板仍然使用内置版本
+	assert.True(t, r.Has(ChannelFeishu, KindSingle))
+}
+
+func TestRenderer_Errors(t *testing.T) {
+	t.Run("模板目录不存在", func(t *testing.T) {
+		_, err := New(filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("模板语法错误", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ChannelFeishu), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ChannelFeishu, "single.tmpl"), []byte(`{{.Repo.Name`), 0o644))
+		_, err := New(dir)
+		assert.Error(t, err)
+	})
+
+	t.Run("未知模板", func(t *testing.T) {
+		_, err := Default().Render("dingtalk", KindSingle, Data{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "未找到模板")
+	})
+
+	t.Run("渲染结果不是 JSON", func(t *testing.T) {
+		_, err := Default().RenderJSON(ChannelMarkdown, KindSingle, Data{Repo: sampleRepos()[0]})
+		assert.Error(t, err)
+	})
+}
+
+func TestDefault_Independent(t *testing.T) {
+	// 修改一个实例的时间函数不影响其他实例
+	fixed := Default()
+	fixed.SetNowFunc(func() time.Time { return fixedNow })
+	other := Default()
+	assert.NotSame(t, fixed, other)
+
+	data := Data{Repo: sampleRepos()[0]}
+	a, err := fixed.Render(ChannelMarkdown, KindSingle, data)
+	require.NoError(t, err)
+	assert.Contains(t, string(a), "5 天前")
+	b, err := other.Render(ChannelMarkdown, KindSingle, data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "5 天前")
+}
+
+func TestFuncs(t *testing.T) {
+	funcs := Funcs(fixedNow)
+	humanizeFn := funcs["humanize"].(func(interface{}) string)
+	relTimeFn := funcs["relTime"].(func(time.Time) string)
+
+	assert.Equal(t, "999", humanizeFn(999))
+	assert.Equal(t, "1.2k", humanizeFn(1234))
+	assert.Equal(t, "5k", humanizeFn(5000))
+	assert.Equal(t, "5.3M", humanizeFn(5300000))
+	assert.Equal(t, "12.50", humanizeFn(12.5))
+
+	assert.Equal(t, "刚刚", relTimeFn(fixedNow.Add(-10*time.Second)))
+	assert.Equal(t, "5 分钟前", relTimeFn(fixedNow.Add(-5*time.Minute)))
+	assert.Equal(t, "3 小时前", relTimeFn(fixedNow.Add(-3*time.Hour)))
+	assert.Equal(t, "10 天前", relTimeFn(fixedNow.AddDate(0, 0, -10)))
+	assert.Equal(t, "未知", relTimeFn(time.Time{}))
+
+	assert.Equal(t, "你好…", truncate(2, "你好世界"))
+	assert.Equal(t, "abc", truncate(5, "abc"))
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCachedAppraisal 按缓存键获取 since 之后写入的缓存，不存在或已过期时返回 port.ErrNotFound
+func (r *GormRepo) GetCachedAppraisal(ctx context.Context, key string, since time.Time) (*domain.CachedAppraisal, error) {
+	var entry domain.CachedAppraisal
+	err := r.db.WithContext(ctx).Where("cache_key = ? AND created_at >= ?", key, since).Take(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: 评估缓存 %s", port.ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// PutCachedAppraisal 写入缓存，同一个键覆盖已有的
+func (r *GormRepo) PutCachedAppraisal(ctx context.Context, entry *domain.CachedAppraisal) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"repo_id", "is_a_iprogramming_tool", "llm_score", "llm_review", "categories",
+			"appraisal_model", "prompt_version", "created_at",
+		}),
+	}).Create(entry).Error
+}
+
+// PruneAppraisalCache 删除 before 之前写入的缓存，并且只保留最新的 keep 条
+func (r *GormRepo) PruneAppraisalCache(ctx context.Context, before time.Time, keep int) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("created_at < ?", before).Delete(&domain.CachedAppraisal{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		newest := tx.Model(&domain.CachedAppraisal{}).Select("cache_key").Order("created_at DESC").Limit(keep)
+		result = tx.Where("cache_key NOT IN (?)", newest).Delete(&domain.CachedAppraisal{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
+
+// GetCachedAppraisal 按缓存键获取 since 之后写入的缓存，不存在或已过期时返回 port.ErrNotFound
+func (m *MemoryRepo) GetCachedAppraisal(ctx context.Context, key string, since time.Time) (*domain.CachedAppraisal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.cache[key]
+	if !ok || entry.CreatedAt.Before(since) {
+		return nil, fmt.Errorf("%w: 评估缓存 %s", port.ErrNotFound, key)
+	}
+	return cloneCachedAppraisal(entry), nil
+}
+
+// PutCachedAppraisal 写入缓存，同一个键覆盖已有的
+func (m *MemoryRepo) PutCachedAppraisal(ctx context.Context, entry *domain.CachedAppraisal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = m.nowFunc()
+	}
+	m.cache[entry.CacheKey] = cloneCachedAppraisal(entry)
+	return nil
+}
+
+// PruneAppraisalCache 删除 before 之前写入的缓存，并且只保留最新的 keep 条
+func (m *MemoryRepo) PruneAppraisalCache(ctx context.Context, before time.Time, keep int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	entries := make([]*domain.CachedAppraisal, 0, len(m.cache))
+	for key, entry := range m.cache {
+		if entry.CreatedAt.Before(before) {
+			delete(m.cache, key)
+			deleted++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) > keep {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+		for _, entry := range entries[keep:] {
+			delete(m.cache, entry.CacheKey)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cloneCachedAppraisal(entry *domain.CachedAppraisal) *domain.CachedAppraisal {
+	copied := *entry
+	copied.Categories = append([]string(nil), entry.Categories...)
+	return &copied
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"github-gold-miner/internal/domain"
+)
+
+// SaveDeferred 保存暂存消息并回填 ID
+func (r *GormRepo) SaveDeferred(ctx context.Context, msg *domain.DeferredMessage) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+// ListDeferred 按暂存顺序返回 owner 的暂存消息
+func (r *GormRepo) ListDeferred(ctx context.Context, owner string) ([]*domain.DeferredMessage, error) {
+	var msgs []*domain.DeferredMessage
+	err := r.db.WithContext(ctx).Where("owner = ?", owner).Order("id").Find(&msgs).Error
+	return msgs, err
+}
+
+// DeleteDeferred 删除已经补发的消息，消息不存在时不报错
+func (r *GormRepo) DeleteDeferred(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&domain.DeferredMessage{}, id).Error
+}
+
+// SaveDeferred 保存暂存消息并回填 ID
+func (m *MemoryRepo) SaveDeferred(ctx context.Context, msg *domain.DeferredMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = m.nowFunc()
+	}
+	m.nextDeferredID++
+	msg.ID = m.nextDeferredID
+	m.deferred = append(m.deferred, cloneDeferred(msg))
+	return nil
+}
+
+// ListDeferred 按暂存顺序返回 owner 的暂存消息
+func (m *MemoryRepo) ListDeferred(ctx context.Context, owner string) ([]*domain.DeferredMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var msgs []*domain.DeferredMessage
+	for _, msg := range m.deferred {
+		if msg.Owner == owner {
+			msgs = append(msgs, cloneDeferred(msg))
+		}
+	}
+	return msgs, nil
+}
+
+// DeleteDeferred 删除已经补发的消息，消息不存在时不报错
+func (m *MemoryRepo) DeleteDeferred(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, msg := range m.deferred {
+		if msg.ID == id {
+			m.deferred = append(m.deferred[:i], m.deferred[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func cloneDeferred(msg *domain.DeferredMessage) *domain.DeferredMessage {
+	copied := *msg
+	if msg.Repo != nil {
+		copied.Repo = cloneRepo(msg.Repo)
+	}
+	if msg.Event != nil {
+		event := *msg.Event
+		copied.Event = &event
+	}
+	return &copied
+}
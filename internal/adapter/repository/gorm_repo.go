@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertColumns 项目重复入库时允许覆盖的列：GitHub 元数据和最新一次评估结果
+// 推送状态 (already_notified/notified_*) 和追踪状态 (retracted/tracked_milestone 等) 只能通过专门的方法修改
+var upsertColumns = []string{
+	"name", "url", "description", "stars", "language", "updated_at",
+	"star_growth_rate",
+	"is_a_iprogramming_tool", "llm_score", "llm_review", "categories",
+	"appraisal_model", "prompt_version", "appraised_at", "readme_sha", "embedding_hash",
+	"profiles", // 必须放在最后，见 Save
+}
+
+// GormRepo 基于 GORM 实现了 port.Repository 接口，Postgres 和 SQLite 共用同一套实现
+type GormRepo struct {
+	db *gorm.DB
+
+	// SQLite (以及没有 pgvector 的 Postgres) 的进程内向量索引，首次向量检索时加载
+	vectorsOnce sync.Once
+	vectors     *vectorIndex
+
+	// Postgres 的 embedding 列是否为 pgvector 类型，首次使用向量时检测
+	pgvectorMu sync.Mutex
+	pgvector   *bool
+}
+
+// newGormRepo 执行未完成的迁移并创建仓库
+// 数据库版本高于程序已知的版本时拒绝启动，避免旧程序写坏新结构
+func newGormRepo(db *gorm.DB) (*GormRepo, error) {
+	repo := &GormRepo{db: db}
+	migrator, err := repo.Migrator()
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("数据库迁移失败: %w", err)
+	}
+	for _, m := range applied {
+		fmt.Printf("🗄️ 已执行数据库迁移 %04d_%s\n", m.Version, m.Name)
+	}
+	return repo, nil
+}
+
+// Migrator 返回当前数据库的迁移器
+func (r *GormRepo) Migrator() (*Migrator, error) {
+	return NewMigrator(r.db)
+}
+
+// Close 关闭底层数据库连接
+func (r *GormRepo) Close() error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Save 保存或更新项目 (INSERT ... ON CONFLICT DO UPDATE)，只覆盖 upsertColumns 中的列，
+// 已有的推送和追踪状态不受影响；挖矿方向与已有的合并；项目带有评估结果时同时写入一条评估历史
+func (r *GormRepo) Save(ctx context.Context, repo *domain.Repo) error {
+	repo.EmbeddingHash = repo.EmbeddingTextHash()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 没有挖矿方向时保留已有的方向，否则与已有的方向合并
+		columns := upsertColumns[:len(upsertColumns)-1]
+		if len(repo.Profiles) > 0 {
+			var stored domain.Repo
+			err := tx.Select("profiles").Where("id = ?", repo.ID).Limit(1).Find(&stored).Error
+			if err != nil {
+				return err
+			}
+			merged := *repo
+			merged.Profiles = domain.MergeProfiles(stored.Profiles, repo.Profiles)
+			repo, columns = &merged, upsertColumns
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns(columns),
+		}).Create(repo).Error
+		if err != nil {
+			return err
+		}
+
+		appraisal := repo.Appraisal()
+		if appraisal == nil {
+			return nil
+		}
+		// 同一次评估重复保存时不产生重复记录
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(appraisal).Error
+	})
+	return dbError("保存项目失败", err)
+}
+
+// GetAppraisals 按时间顺序获取项目的评估历史
+func (r *GormRepo) GetAppraisals(ctx context.Context, repoID string) ([]*domain.Appraisal, error) {
+	var appraisals []*domain.Appraisal
+	err := r.db.WithContext(ctx).
+		Where("repo_id = ?", repoID).
+		Order("appraised_at ASC").
+		Find(&appraisals).Error
+	return appraisals, err
+}
+
+// Get 按 ID 获取项目，不存在时返回 port.ErrNotFound
+func (r *GormRepo) Get(ctx context.Context, repoID string) (*domain.Repo, error) {
+	var repo domain.Repo
+	err := r.db.WithContext(ctx).Where("id = ?", repoID).Take(&repo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", port.ErrNotFound, repoID)
+	}
+	if err != nil {
+		return nil, dbError("查询项目失败", err)
+	}
+	return &repo, nil
+}
+
+// Exists 检查项目是否存在
+func (r *GormRepo) Exists(ctx context.Context, repoID string) (bool, error) {
+	var count int64
+	// SELECT count(*) FROM repos WHERE id = ?
+	err := r.db.WithContext(ctx).Model(&domain.Repo{}).Where("id = ?", repoID).Count(&count).Error
+	return count > 0, dbError("查询项目失败", err)
+}
+
+// MarkAsNotified 标记项目为已推送，同时记录推送时间和当时的 Star 数，供后续追踪对比
+func (r *GormRepo) MarkAsNotified(ctx context.Context, repoID string) error {
+	result := r.db.WithContext(ctx).Model(&domain.Repo{}).Where("id = ?", repoID).Updates(map[string]interface{}{
+		"already_notified": true,
+		"notified_at":      time.Now(),
+		"notified_stars":   gorm.Expr("stars"),
+	})
+	return dbError("标记已推送失败", result.Error)
+}
+
+// GetAllCandidates 获取所有（或最近的 N 个）项目，供 AI 筛选
+func (r *GormRepo) GetAllCandidates(ctx context.Context) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	// 我们取出最近更新、或者分数还不错的项目，上限 100 个
+	// Gemini 1.5 Flash 处理 100 个项目的 JSON 数据非常轻松
+	err := r.db.WithContext(ctx).
+		Order("created_at desc"). // 按创建时间排序
+		Limit(100).               // 限制数量，防止 Token 爆炸
+		Find(&repos).Error
+	return repos, err
+}
+
+// GetUnnotifiedRepos 获取未推送的项目
+func (r *GormRepo) GetUnnotifiedRepos(ctx context.Context) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	err := r.db.WithContext(ctx).
+		Where("already_notified = ?", false).
+		Order("llm_score DESC"). // 按LLM评分排序
+		Find(&repos).Error
+	return repos, err
+}
+
+// GetNotifiedRepos 获取 since 之后推送过、且尚未撤回的项目
+func (r *GormRepo) GetNotifiedRepos(ctx context.Context, since time.Time) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	err := r.db.WithContext(ctx).
+		Where("already_notified = ? AND retracted = ? AND notified_at >= ?", true, false, since).
+		Order("notified_at DESC").
+		Find(&repos).Error
+	return repos, err
+}
+
+// UpdateTracking 只更新追踪相关字段，不影响评分和推送状态
+// NotifiedStars 非 0 时一并保存，用于补齐追踪功能上线前推送的项目的基线
+func (r *GormRepo) UpdateTracking(ctx context.Context, repo *domain.Repo) error {
+	fields := map[string]interface{}{
+		"stars":             repo.Stars,
+		"archived":          repo.Archived,
+		"retracted":         repo.Retracted,
+		"tracked_milestone": repo.TrackedMilestone,
+		"last_checked_at":   repo.LastCheckedAt,
+	}
+	if repo.NotifiedStars > 0 {
+		fields["notified_stars"] = repo.NotifiedStars
+	}
+	result := r.db.WithContext(ctx).Model(&domain.Repo{}).Where("id = ?", repo.ID).Updates(fields)
+	return result.Error
+}
+
+// AddSnapshot 记录一次 Star 快照
+func (r *GormRepo) AddSnapshot(ctx context.Context, snapshot *domain.StarSnapshot) error {
+	return r.db.WithContext(ctx).Create(snapshot).Error
+}
+
+// GetSnapshots 按时间顺序获取项目的 Star 快照
+func (r *GormRepo) GetSnapshots(ctx context.Context, repoID string) ([]*domain.StarSnapshot, error) {
+	var snapshots []*domain.StarSnapshot
+	err := r.db.WithContext(ctx).
+		Where("repo_id = ?", repoID).
+		Order("captured_at ASC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// AddFeedback 记录一条用户反馈
+func (r *GormRepo) AddFeedback(ctx context.Context, feedback *domain.Feedback) error {
+	return r.db.WithContext(ctx).Create(feedback).Error
+}
+
+// GetFeedback 按时间顺序获取项目的用户反馈
+func (r *GormRepo) GetFeedback(ctx context.Context, repoID string) ([]*domain.Feedback, error) {
+	var feedback []*domain.Feedback
+	err := r.db.WithContext(ctx).
+		Where("repo_id = ?", repoID).
+		Order("created_at ASC, id ASC").
+		Find(&feedback).Error
+	return feedback, err
+}
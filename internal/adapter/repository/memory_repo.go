@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// MemoryRepo 基于内存的 port.Repository 实现，进程退出后数据丢失
+// 行为与 GormRepo 保持一致 (见 repositorytest)，适合单元测试和试运行
+type MemoryRepo struct {
+	mu             sync.RWMutex
+	repos          map[string]*domain.Repo
+	appraisals     []*domain.Appraisal
+	snapshots      []*domain.StarSnapshot
+	feedback       []*domain.Feedback
+	runs           []*domain.MiningRun
+	checkpoints    []*domain.RunCheckpoint
+	cache          map[string]*domain.CachedAppraisal
+	usage          []*domain.UsageRecord
+	deferred       []*domain.DeferredMessage
+	vectors        *vectorIndex
+	embedded       map[embeddingKey]string // 生成向量时项目的 EmbeddingHash
+	nextID         uint
+	nextRunID      int64
+	nextDeferredID int64
+	nowFunc        func() time.Time
+}
+
+// NewMemoryRepo 创建一个空的内存仓库
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		repos:    make(map[string]*domain.Repo),
+		cache:    make(map[string]*domain.CachedAppraisal),
+		vectors:  newVectorIndex(),
+		embedded: make(map[embeddingKey]string),
+		nowFunc:  time.Now,
+	}
+}
+
+// Save 保存或更新项目，已有项目只覆盖 GitHub 元数据和评估结果
+func (m *MemoryRepo) Save(ctx context.Context, repo *domain.Repo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	repo.EmbeddingHash = repo.EmbeddingTextHash()
+
+	stored, ok := m.repos[repo.ID]
+	if !ok {
+		m.repos[repo.ID] = cloneRepo(repo)
+	} else {
+		// 与 upsertColumns 保持一致
+		stored.Name = repo.Name
+		stored.URL = repo.URL
+		stored.Description = repo.Description
+		stored.Stars = repo.Stars
+		stored.Language = repo.Language
+		stored.UpdatedAt = repo.UpdatedAt
+		stored.StarGrowthRate = repo.StarGrowthRate
+		stored.IsAIProgrammingTool = repo.IsAIProgrammingTool
+		stored.LLMScore = repo.LLMScore
+		stored.LLMReview = repo.LLMReview
+		stored.Categories = append([]string(nil), repo.Categories...)
+		stored.AppraisalModel = repo.AppraisalModel
+		stored.PromptVersion = repo.PromptVersion
+		stored.AppraisedAt = cloneTime(repo.AppraisedAt)
+		stored.ReadmeSHA = repo.ReadmeSHA
+		stored.EmbeddingHash = repo.EmbeddingHash
+		stored.Profiles = domain.MergeProfiles(stored.Profiles, repo.Profiles)
+	}
+
+	appraisal := repo.Appraisal()
+	if appraisal == nil {
+		return nil
+	}
+	for _, a := range m.appraisals {
+		if a.RepoID == appraisal.RepoID && a.AppraisedAt.Equal(appraisal.AppraisedAt) {
+			return nil
+		}
+	}
+	m.nextID++
+	appraisal.ID = m.nextID
+	appraisal.Categories = append([]string(nil), appraisal.Categories...)
+	m.appraisals = append(m.appraisals, appraisal)
+	return nil
+}
+
+// GetAppraisals 按时间顺序获取项目的评估历史
+func (m *MemoryRepo) GetAppraisals(ctx context.Context, repoID string) ([]*domain.Appraisal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*domain.Appraisal
+	for _, a := range m.appraisals {
+		if a.RepoID == repoID {
+			copied := *a
+			result = append(result, &copied)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].AppraisedAt.Before(result[j].AppraisedAt) })
+	return result, nil
+}
+
+// Get 按 ID 获取项目副本，不存在时返回 port.ErrNotFound
+func (m *MemoryRepo) Get(ctx context.Context, repoID string) (*domain.Repo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	repo, ok := m.repos[repoID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", port.ErrNotFound, repoID)
+	}
+	return cloneRepo(repo), nil
+}
+
+// Exists 检查项目是否存在
+func (m *MemoryRepo) Exists(ctx context.Context, repoID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.repos[repoID]
+	return ok, nil
+}
+
+// MarkAsNotified 标记项目为已推送，项目不存在时不报错
+func (m *MemoryRepo) MarkAsNotified(ctx context.Context, repoID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if repo, ok := m.repos[repoID]; ok {
+		now := m.nowFunc()
+		repo.AlreadyNotified = true
+		repo.NotifiedAt = &now
+		repo.NotifiedStars = repo.Stars
+	}
+	return nil
+}
+
+// Search 按关键词和过滤条件分页搜索，每个关键词都必须出现在名称、描述或 LLM 评价中，按评分排序
+func (m *MemoryRepo) Search(ctx context.Context, query domain.SearchQuery) ([]*domain.Repo, error) {
+	q := query.Normalize()
+	terms := strings.Fields(strings.ToLower(q.Text))
+	result := m.filter(func(r *domain.Repo) bool {
+		if !matchesFilters(r, q) {
+			return false
+		}
+		doc := strings.ToLower(r.Name + " " + r.Description + " " + r.LLMReview)
+		for _, term := range terms {
+			if !strings.Contains(doc, term) {
+				return false
+			}
+		}
+		return true
+	})
+	if q.Sort != domain.SortDefault {
+		sortBy(result, q.Sort, q.Ascending)
+	} else {
+		sortByScore(result)
+	}
+	if q.Offset >= len(result) {
+		return nil, nil
+	}
+	return limit(result[q.Offset:], q.Limit), nil
+}
+
+// GetAllCandidates 按创建时间倒序返回最多 100 个项目
+func (m *MemoryRepo) GetAllCandidates(ctx context.Context) ([]*domain.Repo, error) {
+	result := m.filter(func(*domain.Repo) bool { return true })
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return limit(result, 100), nil
+}
+
+// GetUnnotifiedRepos 按评分排序返回未推送的项目
+func (m *MemoryRepo) GetUnnotifiedRepos(ctx context.Context) ([]*domain.Repo, error) {
+	result := m.filter(func(r *domain.Repo) bool { return !r.AlreadyNotified })
+	sortByScore(result)
+	return result, nil
+}
+
+// GetNotifiedRepos 获取 since 之后推送过、且尚未撤回的项目
+func (m *MemoryRepo) GetNotifiedRepos(ctx context.Context, since time.Time) ([]*domain.Repo, error) {
+	result := m.filter(func(r *domain.Repo) bool {
+		return r.AlreadyNotified && !r.Retracted && r.NotifiedAt != nil && !r.NotifiedAt.Before(since)
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].NotifiedAt.After(*result[j].NotifiedAt) })
+	return result, nil
+}
+
+// UpdateTracking 只更新追踪相关字段
+func (m *MemoryRepo) UpdateTracking(ctx context.Context, repo *domain.Repo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.repos[repo.ID]; ok {
+		stored.Stars = repo.Stars
+		stored.Archived = repo.Archived
+		stored.Retracted = repo.Retracted
+		stored.TrackedMilestone = repo.TrackedMilestone
+		stored.LastCheckedAt = cloneTime(repo.LastCheckedAt)
+		if repo.NotifiedStars > 0 {
+			stored.NotifiedStars = repo.NotifiedStars
+		}
+	}
+	return nil
+}
+
+// AddSnapshot 记录一次 Star 快照
+func (m *MemoryRepo) AddSnapshot(ctx context.Context, snapshot *domain.StarSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	snapshot.ID = m.nextID
+	copied := *snapshot
+	m.snapshots = append(m.snapshots, &copied)
+	return nil
+}
+
+// GetSnapshots 按时间顺序获取项目的 Star 快照
+func (m *MemoryRepo) GetSnapshots(ctx context.Context, repoID string) ([]*domain.StarSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*domain.StarSnapshot
+	for _, s := range m.snapshots {
+		if s.RepoID == repoID {
+			copied := *s
+			result = append(result, &copied)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CapturedAt.Before(result[j].CapturedAt) })
+	return result, nil
+}
+
+// AddFeedback 记录一条用户反馈
+func (m *MemoryRepo) AddFeedback(ctx context.Context, feedback *domain.Feedback) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	feedback.ID = m.nextID
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = m.nowFunc()
+	}
+	copied := *feedback
+	m.feedback = append(m.feedback, &copied)
+	return nil
+}
+
+// GetFeedback 按时间顺序获取项目的用户反馈
+func (m *MemoryRepo) GetFeedback(ctx context.Context, repoID string) ([]*domain.Feedback, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*domain.Feedback
+	for _, f := range m.feedback {
+		if f.RepoID == repoID {
+			copied := *f
+			result = append(result, &copied)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+// embeddingKey 一个项目在一个模型下的向量
+type embeddingKey struct {
+	model  string
+	repoID string
+}
+
+// SaveEmbedding 保存项目的向量，项目不存在时不报错
+func (m *MemoryRepo) SaveEmbedding(ctx context.Context, repoID, model, contentHash string, vector []float32) error {
+	if len(vector) == 0 {
+		return fmt.Errorf("项目 %s 的向量为空", repoID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.repos[repoID]; ok {
+		m.vectors.put(model, repoID, vector)
+		m.embedded[embeddingKey{model, repoID}] = contentHash
+	}
+	return nil
+}
+
+// ReposNeedingEmbedding 按创建时间倒序返回还没有该模型向量、或生成向量后内容发生变化的项目
+func (m *MemoryRepo) ReposNeedingEmbedding(ctx context.Context, model string, n int) ([]*domain.Repo, error) {
+	result := m.filter(func(r *domain.Repo) bool {
+		hash, ok := m.embedded[embeddingKey{model, r.ID}]
+		return !ok || hash != r.EmbeddingHash
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return limit(result, n), nil
+}
+
+// NearestRepos 按余弦相似度返回最相近的 k 个项目
+func (m *MemoryRepo) NearestRepos(ctx context.Context, model string, vector []float32, k int) ([]*domain.ScoredRepo, error) {
+	hits := m.vectors.nearest(model, vector, k)
+	ids := make(map[string]bool, len(hits))
+	for _, h := range hits {
+		ids[h.RepoID] = true
+	}
+	return scoreRepos(hits, m.filter(func(r *domain.Repo) bool { return ids[r.ID] })), nil
+}
+
+// filter 返回满足条件的项目副本，调用方修改结果不会影响仓库内容
+func (m *MemoryRepo) filter(keep func(*domain.Repo) bool) []*domain.Repo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*domain.Repo
+	for _, r := range m.repos {
+		if keep(r) {
+			result = append(result, cloneRepo(r))
+		}
+	}
+	// map 遍历顺序随机，先按 ID 排序保证同分项目的顺序稳定
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// matchesFilters 与 applySearchFilters 保持一致
+func matchesFilters(r *domain.Repo, q domain.SearchQuery) bool {
+	if len(q.Categories) > 0 {
+		matched := false
+		for _, want := range q.Categories {
+			for _, c := range r.Categories {
+				if c == want {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if q.Profile != "" && !r.HasProfile(q.Profile) {
+		return false
+	}
+	if q.Language != "" && !strings.EqualFold(r.Language, q.Language) {
+		return false
+	}
+	if r.LLMScore < q.MinScore {
+		return false
+	}
+	if q.CreatedAfter != nil && r.CreatedAt.Before(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && !r.CreatedAt.Before(*q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func sortByScore(repos []*domain.Repo) {
+	sort.SliceStable(repos, func(i, j int) bool { return repos[i].LLMScore > repos[j].LLMScore })
+}
+
+// sortBy 与 sortOrder 保持一致，同值时按评分从高到低
+func sortBy(repos []*domain.Repo, field domain.SortField, ascending bool) {
+	sortByScore(repos)
+	less := func(a, b *domain.Repo) bool { return a.LLMScore < b.LLMScore }
+	switch field {
+	case domain.SortVelocity:
+		less = func(a, b *domain.Repo) bool { return a.StarGrowthRate < b.StarGrowthRate }
+	case domain.SortStars:
+		less = func(a, b *domain.Repo) bool { return a.Stars < b.Stars }
+	case domain.SortCreated:
+		less = func(a, b *domain.Repo) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		if ascending {
+			return less(repos[i], repos[j])
+		}
+		return less(repos[j], repos[i])
+	})
+}
+
+func limit(repos []*domain.Repo, n int) []*domain.Repo {
+	if len(repos) > n {
+		return repos[:n]
+	}
+	return repos
+}
+
+func cloneRepo(r *domain.Repo) *domain.Repo {
+	copied := *r
+	copied.Categories = append([]string(nil), r.Categories...)
+	copied.Profiles = append([]string(nil), r.Profiles...)
+	copied.NotifiedAt = cloneTime(r.NotifiedAt)
+	copied.AppraisedAt = cloneTime(r.AppraisedAt)
+	copied.LastCheckedAt = cloneTime(r.LastCheckedAt)
+	return &copied
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
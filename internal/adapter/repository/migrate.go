@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFS embed.FS
+
+// migrationLockKey Postgres advisory lock 的键，保证多个实例同时启动时只有一个在执行迁移
+const migrationLockKey int64 = 0x676f6c646d696e // "goldmin"
+
+// ErrUnknownSchemaVersion 数据库结构版本高于程序内置的迁移版本 (通常是用旧版本程序连接了新数据库)
+var ErrUnknownSchemaVersion = errors.New("数据库结构版本未知")
+
+// Migration 一个版本化的迁移，文件布局为 migrations/<dialect>/<version>_<name>.{up,down}.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// appliedMigration schema_migrations 表中的一条记录
+type appliedMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string { return "schema_migrations" }
+
+// Migrator 执行内置的 SQL 迁移
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator 根据数据库方言加载内置迁移
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := loadMigrations(migrationFS, path.Join("migrations", dialect))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest 返回内置迁移的最高版本
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version 返回数据库当前的结构版本，未执行过迁移时为 0
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	var version int
+	err := m.db.WithContext(ctx).Model(&appliedMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check 数据库版本高于程序已知的版本时返回 ErrUnknownSchemaVersion
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("%w: 数据库版本 %d，程序最高支持 %d，请升级程序", ErrUnknownSchemaVersion, version, m.Latest())
+	}
+	return nil
+}
+
+// Pending 返回未执行的迁移，只读取迁移记录，不创建 schema_migrations 表
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&appliedMigration{}) {
+		return append([]Migration(nil), m.migrations...), nil
+	}
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if version > m.Latest() {
+			return nil, fmt.Errorf("%w: 数据库版本 %d，程序最高支持 %d，请升级程序", ErrUnknownSchemaVersion, version, m.Latest())
+		}
+	}
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up 按版本顺序执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		if err := m.Check(ctx); err != nil {
+			return err
+		}
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := execStatements(tx, mig.Up); err != nil {
+					return err
+				}
+				return tx.Create(&appliedMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("执行迁移 %04d_%s 失败: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本倒序回滚最近 steps 个已执行的迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		if err := m.Check(ctx); err != nil {
+			return err
+		}
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := execStatements(tx, mig.Down); err != nil {
+					return err
+				}
+				return tx.Delete(&appliedMigration{}, mig.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("回滚迁移 %04d_%s 失败: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 返回所有内置迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Migration: mig}
+		if record, ok := applied[mig.Version]; ok {
+			appliedAt := record.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	return m.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int]appliedMigration, error) {
+	var records []appliedMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+	result := make(map[int]appliedMigration, len(records))
+	for _, r := range records {
+		result[r.Version] = r
+	}
+	return result, nil
+}
+
+// withLock 在同一个数据库连接上持有迁移锁执行 fn
+// Postgres 使用 advisory lock；SQLite 同一时间只允许一个写入者，每个迁移在事务中执行即可
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+	if m.dialect != "postgres" {
+		return fn(m.db.WithContext(ctx))
+	}
+
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+		// 用独立的 context 释放锁，避免 ctx 取消后锁留在连接上
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		return fn(conn)
+	})
+}
+
+// loadMigrations 从 dir 中读取 <version>_<name>.up.sql / .down.sql 并按版本排序
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移目录 %s 失败: %w", dir, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, migName, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件名无效: %s", name)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: migName}
+			byVersion[version] = mig
+		} else if mig.Name != migName {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s 和 %s", version, mig.Name, migName)
+		}
+		if direction == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("迁移 %04d_%s 缺少 up 或 down 文件", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execStatements 逐条执行 SQL 脚本中的语句
+func execStatements(db *gorm.DB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 按分号拆分 SQL 脚本，忽略注释、字符串和 $$ 代码块中的分号
+func splitStatements(script string) []string {
+	var (
+		stmts    []string
+		current  strings.Builder
+		inQuote  bool
+		inDollar bool
+	)
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case !inQuote && !inDollar && c == '-' && i+1 < len(script) && script[i+1] == '-':
+			// 跳过行注释
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+			continue
+		case !inDollar && c == '\'':
+			inQuote = !inQuote
+		case !inQuote && c == '$' && i+1 < len(script) && script[i+1] == '$':
+			inDollar = !inDollar
+			current.WriteString("$$")
+			i++
+			continue
+		case !inQuote && !inDollar && c == ';':
+			flush()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_SQLite(t *testing.T) {
+	ctx := context.Background()
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "gold_miner.db")
+
+	repo, err := Connect(dsn)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	migrator, err := repo.Migrator()
+	require.NoError(t, err)
+
+	version, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrator.migrations))
+
+	version, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+
+	// 重复执行不会再次迁移
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, status)
+	for _, s := range status {
+		assert.True(t, s.Applied, "迁移 %d 应该已执行", s.Version)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// 回滚全部迁移后表被删除
+	rolledBack, err := migrator.Down(ctx, len(status))
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(status))
+	assert.Equal(t, status[len(status)-1].Version, rolledBack[0].Version)
+	_, err = repo.Exists(ctx, "github-1")
+	assert.Error(t, err)
+
+	version, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+}
+
+func TestOpen_RefusesUnknownSchemaVersion(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "gold_miner.db")
+
+	repo, err := Open(dsn)
+	require.NoError(t, err)
+	// 模拟新版本程序执行过的迁移
+	require.NoError(t, repo.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from_the_future', CURRENT_TIMESTAMP)`).Error)
+	require.NoError(t, repo.Close())
+
+	_, err = Open(dsn)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnknownSchemaVersion))
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "gold_miner.db")
+
+	// 未迁移的数据库：不执行迁移，也不创建迁移记录表
+	_, err := OpenReadOnly(dsn)
+	assert.ErrorContains(t, err, "migrate up")
+	repo, err := Connect(dsn)
+	require.NoError(t, err)
+	assert.False(t, repo.db.Migrator().HasTable(&appliedMigration{}))
+	require.NoError(t, repo.Close())
+
+	repo, err = Open(dsn)
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	repo, err = OpenReadOnly(dsn)
+	require.NoError(t, err)
+	defer repo.Close()
+	exists, err := repo.Exists(context.Background(), "github-1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMigrator_PostgresAdvisoryLock(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	migrator := &Migrator{
+		db:      gormDB,
+		dialect: "postgres",
+		migrations: []Migration{
+			{Version: 1, Name: "init", Up: "CREATE TABLE t (id int);", Down: "DROP TABLE t;"},
+		},
+	}
+
+	ensureTable := regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)
+	mock.ExpectExec(ensureTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(ensureTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM "schema_migrations"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "schema_migrations"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE t (id int)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "schema_migrations"`)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("内置迁移", func(t *testing.T) {
+		for _, dialect := range []string{"postgres", "sqlite"} {
+			migrations, err := loadMigrations(migrationFS, "migrations/"+dialect)
+			require.NoError(t, err, dialect)
+			require.NotEmpty(t, migrations, dialect)
+			for i, m := range migrations {
+				assert.Equal(t, i+1, m.Version, "%s 迁移版本必须连续", dialect)
+			}
+		}
+	})
+
+	tests := []struct {
+		name   string
+		files  fstest.MapFS
+		errStr string
+	}{
+		{
+			name:   "缺少 down 文件",
+			files:  fstest.MapFS{"m/0001_init.up.sql": {Data: []byte("SELECT 1;")}},
+			errStr: "缺少 up 或 down",
+		},
+		{
+			name:   "文件名无效",
+			files:  fstest.MapFS{"m/init.up.sql": {Data: []byte("SELECT 1;")}},
+			errStr: "迁移文件名无效",
+		},
+		{
+			name: "版本重复",
+			files: fstest.MapFS{
+				"m/0001_init.up.sql":  {Data: []byte("SELECT 1;")},
+				"m/0001_other.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			errStr: "重复",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, "m")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errStr)
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- 注释里的分号; 不拆分
+CREATE TABLE a (id int);
+INSERT INTO a VALUES (';');
+CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+  NEW.x := 1;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+SELECT 1`
+
+	stmts := splitStatements(script)
+	require.Len(t, stmts, 4)
+	assert.Equal(t, "CREATE TABLE a (id int)", stmts[0])
+	assert.Equal(t, "INSERT INTO a VALUES (';')", stmts[1])
+	assert.Contains(t, stmts[2], "NEW.x := 1;")
+	assert.Equal(t, "SELECT 1", stmts[3])
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appraisals;
+DROP TABLE IF EXISTS star_snapshots;
+DROP TABLE IF EXISTS repos;
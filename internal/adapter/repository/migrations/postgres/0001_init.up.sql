@@ -0,0 +1,56 @@
+-- 初始表结构
+-- 使用 IF NOT EXISTS，以便接管之前由 GORM AutoMigrate 创建的数据库
+
+CREATE TABLE IF NOT EXISTS repos (
+    id                     text PRIMARY KEY,
+    name                   text,
+    url                    text,
+    description            text,
+    stars                  bigint,
+    language               text,
+    created_at             timestamptz,
+    updated_at             timestamptz,
+    star_growth_rate       numeric,
+    is_a_iprogramming_tool boolean,
+    llm_score              bigint,
+    llm_review             text,
+    already_notified       boolean
+);
+
+-- 以下列是在 AutoMigrate 时期陆续加入的，老数据库可能缺少
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS categories text;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS appraisal_model text;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS prompt_version text;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS appraised_at timestamptz;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS notified_at timestamptz;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS notified_stars bigint NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS archived boolean NOT NULL DEFAULT false;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS retracted boolean NOT NULL DEFAULT false;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS tracked_milestone bigint NOT NULL DEFAULT 0;
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS last_checked_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_repos_already_notified ON repos (already_notified);
+
+CREATE TABLE IF NOT EXISTS star_snapshots (
+    id          bigserial PRIMARY KEY,
+    repo_id     text,
+    stars       bigint,
+    captured_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_star_snapshots_repo_id ON star_snapshots (repo_id);
+CREATE INDEX IF NOT EXISTS idx_star_snapshots_captured_at ON star_snapshots (captured_at);
+
+CREATE TABLE IF NOT EXISTS appraisals (
+    id                     bigserial PRIMARY KEY,
+    repo_id                text,
+    model                  text,
+    prompt_version         text,
+    is_a_iprogramming_tool boolean,
+    score                  bigint,
+    review                 text,
+    categories             text,
+    appraised_at           timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appraisals_repo_time ON appraisals (repo_id, appraised_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_repos_search_trgm;
+DROP INDEX IF EXISTS idx_repos_search_vector;
+ALTER TABLE repos DROP COLUMN IF EXISTS search_vector;
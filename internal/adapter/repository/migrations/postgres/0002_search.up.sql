@@ -0,0 +1,16 @@
+-- 全文检索：生成列 + GIN 索引
+-- 权重: 名称 A > 描述 B > LLM 评价 C
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(llm_review, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_repos_search_vector ON repos USING GIN (search_vector);
+
+-- 模糊匹配兜底 (拼写错误、中文子串)，表达式必须与 search.go 中的 searchDocument 一致
+CREATE INDEX IF NOT EXISTS idx_repos_search_trgm ON repos USING GIN (
+    (coalesce(name, '') || ' ' || coalesce(description, '') || ' ' || coalesce(llm_review, '')) gin_trgm_ops
+);
@@ -0,0 +1,33 @@
+-- 语义检索向量，优先使用 pgvector 扩展 (https://github.com/pgvector/pgvector) 在数据库中检索
+-- 数据库没有安装 pgvector (或没有权限创建扩展) 时，embedding 建为 bytea 列，以 float32 小端字节保存，
+-- 与 SQLite 一样在进程内检索；之后安装了 pgvector 也不会自动转换，需要删除该表后重新执行本迁移
+-- 不同 Embedding 模型的维度不同，embedding 列不限定维度，检索时按 model 过滤后精确扫描
+-- 项目数量很大时可以为常用模型单独建 HNSW 索引，例如:
+--   CREATE INDEX ON repo_embeddings USING hnsw ((embedding::vector(768)) vector_cosine_ops) WHERE model = 'text-embedding-004';
+DO $$
+BEGIN
+    BEGIN
+        CREATE EXTENSION IF NOT EXISTS vector;
+    EXCEPTION WHEN OTHERS THEN
+        RAISE NOTICE 'pgvector 不可用，向量检索改为在进程内完成: %', SQLERRM;
+    END;
+
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector') THEN
+        CREATE TABLE IF NOT EXISTS repo_embeddings (
+            repo_id    text NOT NULL REFERENCES repos (id) ON DELETE CASCADE,
+            model      text NOT NULL,
+            embedding  vector NOT NULL,
+            updated_at timestamptz NOT NULL,
+            PRIMARY KEY (repo_id, model)
+        );
+    ELSE
+        CREATE TABLE IF NOT EXISTS repo_embeddings (
+            repo_id    text NOT NULL REFERENCES repos (id) ON DELETE CASCADE,
+            model      text NOT NULL,
+            embedding  bytea NOT NULL,
+            updated_at timestamptz NOT NULL,
+            PRIMARY KEY (repo_id, model)
+        );
+    END IF;
+END
+$$;
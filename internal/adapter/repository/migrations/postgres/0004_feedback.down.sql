@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_repos_created_at;
+DROP INDEX IF EXISTS idx_repos_star_growth_rate;
+DROP TABLE IF EXISTS feedbacks;
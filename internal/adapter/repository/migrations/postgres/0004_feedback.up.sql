@@ -0,0 +1,15 @@
+-- 用户在看板中对项目的反馈
+CREATE TABLE IF NOT EXISTS feedbacks (
+    id         bigserial PRIMARY KEY,
+    repo_id    text NOT NULL REFERENCES repos (id) ON DELETE CASCADE,
+    verdict    text NOT NULL,
+    comment    text NOT NULL DEFAULT '',
+    author     text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedbacks_repo_id ON feedbacks (repo_id);
+
+-- 看板按 Star 增长率、Star 数和创建时间排序
+CREATE INDEX IF NOT EXISTS idx_repos_star_growth_rate ON repos (star_growth_rate);
+CREATE INDEX IF NOT EXISTS idx_repos_created_at ON repos (created_at);
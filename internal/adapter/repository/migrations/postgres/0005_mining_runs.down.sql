@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mining_runs;
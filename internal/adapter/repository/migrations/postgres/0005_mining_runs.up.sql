@@ -0,0 +1,15 @@
+-- 挖矿执行记录，stages 和 pushed 以 JSON 文本保存
+CREATE TABLE IF NOT EXISTS mining_runs (
+    id           bigserial PRIMARY KEY,
+    "trigger"    text NOT NULL,
+    status       text NOT NULL,
+    started_at   timestamptz NOT NULL,
+    finished_at  timestamptz,
+    error        text NOT NULL DEFAULT '',
+    stages       text,
+    api_requests bigint NOT NULL DEFAULT 0,
+    llm_tokens   bigint NOT NULL DEFAULT 0,
+    pushed       text
+);
+
+CREATE INDEX IF NOT EXISTS idx_mining_runs_started_at ON mining_runs (started_at);
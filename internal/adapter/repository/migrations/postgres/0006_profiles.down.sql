@@ -0,0 +1,2 @@
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS profile;
+ALTER TABLE repos DROP COLUMN IF EXISTS profiles;
@@ -0,0 +1,3 @@
+-- 挖矿方向 (profile)：项目记录发现它的方向 (JSON 数组文本)，执行记录记录所属方向
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS profiles text;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS profile text NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE repos DROP COLUMN IF EXISTS readme_sha;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS reused;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS resumed_from;
+DROP TABLE IF EXISTS run_checkpoints;
@@ -0,0 +1,18 @@
+-- 检查点：挖矿执行中每个阶段的输出 (JSON 文本)，中断的执行由下一次执行续跑
+CREATE TABLE IF NOT EXISTS run_checkpoints (
+    id         bigserial PRIMARY KEY,
+    run_id     bigint NOT NULL REFERENCES mining_runs (id) ON DELETE CASCADE,
+    seq        integer NOT NULL,
+    stage      text NOT NULL,
+    complete   boolean NOT NULL DEFAULT false,
+    repos      text,
+    created_at timestamptz NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_run_checkpoints_run_seq ON run_checkpoints (run_id, seq);
+
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS resumed_from bigint NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS reused integer NOT NULL DEFAULT 0;
+
+-- 评估时 README 的版本，README 未变化的项目复用已有评估结果
+ALTER TABLE repos ADD COLUMN IF NOT EXISTS readme_sha text NOT NULL DEFAULT '';
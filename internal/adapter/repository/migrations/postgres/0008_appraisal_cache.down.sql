@@ -0,0 +1,3 @@
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS cache_misses;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS cache_hits;
+DROP TABLE IF EXISTS cached_appraisals;
@@ -0,0 +1,6 @@
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS budgets;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS budget_blocked;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS cost_usd;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS completion_tokens;
+ALTER TABLE mining_runs DROP COLUMN IF EXISTS prompt_tokens;
+DROP TABLE IF EXISTS usage_records;
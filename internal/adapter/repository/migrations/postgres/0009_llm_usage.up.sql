@@ -0,0 +1,17 @@
+-- LLM 用量账本，按日期、挖矿方向和模型累计 Token 数和费用，用于执行预算
+CREATE TABLE IF NOT EXISTS usage_records (
+    day               text NOT NULL,
+    profile           text NOT NULL DEFAULT '',
+    model             text NOT NULL,
+    prompt_tokens     bigint NOT NULL DEFAULT 0,
+    completion_tokens bigint NOT NULL DEFAULT 0,
+    cost_usd          double precision NOT NULL DEFAULT 0,
+    updated_at        timestamptz NOT NULL,
+    PRIMARY KEY (day, profile, model)
+);
+
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS prompt_tokens bigint NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS completion_tokens bigint NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS cost_usd double precision NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS budget_blocked integer NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN IF NOT EXISTS budgets text;
@@ -0,0 +1,13 @@
+-- 免打扰时段暂存的通知，时段结束后补发；保存在数据库中，进程退出后不会丢失
+CREATE TABLE IF NOT EXISTS deferred_messages (
+    id         bigserial PRIMARY KEY,
+    owner      text NOT NULL DEFAULT '',
+    route      text NOT NULL DEFAULT '',
+    channel    text NOT NULL,
+    repo_id    text NOT NULL,
+    repo       text,
+    event      text,
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_deferred_messages_owner ON deferred_messages (owner);
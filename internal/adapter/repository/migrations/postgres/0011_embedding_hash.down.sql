@@ -0,0 +1,2 @@
+ALTER TABLE repo_embeddings DROP COLUMN content_hash;
+ALTER TABLE repos DROP COLUMN embedding_hash;
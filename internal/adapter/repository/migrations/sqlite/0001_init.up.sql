@@ -0,0 +1,54 @@
+-- 初始表结构
+-- 使用 IF NOT EXISTS，以便接管之前由 GORM AutoMigrate 创建的数据库
+
+CREATE TABLE IF NOT EXISTS repos (
+    id                     text PRIMARY KEY,
+    name                   text,
+    url                    text,
+    description            text,
+    stars                  integer,
+    language               text,
+    created_at             datetime,
+    updated_at             datetime,
+    star_growth_rate       real,
+    is_a_iprogramming_tool numeric,
+    llm_score              integer,
+    llm_review             text,
+    categories             text,
+    appraisal_model        text,
+    prompt_version         text,
+    appraised_at           datetime,
+    already_notified       numeric,
+    notified_at            datetime,
+    notified_stars         integer NOT NULL DEFAULT 0,
+    archived               numeric NOT NULL DEFAULT false,
+    retracted              numeric NOT NULL DEFAULT false,
+    tracked_milestone      integer NOT NULL DEFAULT 0,
+    last_checked_at        datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_repos_already_notified ON repos (already_notified);
+
+CREATE TABLE IF NOT EXISTS star_snapshots (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    repo_id     text,
+    stars       integer,
+    captured_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_star_snapshots_repo_id ON star_snapshots (repo_id);
+CREATE INDEX IF NOT EXISTS idx_star_snapshots_captured_at ON star_snapshots (captured_at);
+
+CREATE TABLE IF NOT EXISTS appraisals (
+    id                     integer PRIMARY KEY AUTOINCREMENT,
+    repo_id                text,
+    model                  text,
+    prompt_version         text,
+    is_a_iprogramming_tool numeric,
+    score                  integer,
+    review                 text,
+    categories             text,
+    appraised_at           datetime
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appraisals_repo_time ON appraisals (repo_id, appraised_at);
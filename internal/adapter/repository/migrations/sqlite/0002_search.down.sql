@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_repos_llm_score;
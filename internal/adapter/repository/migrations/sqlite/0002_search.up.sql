@@ -0,0 +1,2 @@
+-- SQLite 没有启用全文检索，搜索使用 LIKE 匹配后按评分排序
+CREATE INDEX IF NOT EXISTS idx_repos_llm_score ON repos (llm_score);
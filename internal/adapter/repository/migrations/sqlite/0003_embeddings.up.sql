@@ -0,0 +1,8 @@
+-- 语义检索向量，以 float32 小端字节保存，检索在进程内完成
+CREATE TABLE IF NOT EXISTS repo_embeddings (
+    repo_id    text NOT NULL REFERENCES repos (id) ON DELETE CASCADE,
+    model      text NOT NULL,
+    embedding  blob NOT NULL,
+    updated_at datetime NOT NULL,
+    PRIMARY KEY (repo_id, model)
+);
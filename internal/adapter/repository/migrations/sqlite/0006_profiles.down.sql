@@ -0,0 +1,2 @@
+ALTER TABLE mining_runs DROP COLUMN profile;
+ALTER TABLE repos DROP COLUMN profiles;
@@ -0,0 +1,4 @@
+ALTER TABLE repos DROP COLUMN readme_sha;
+ALTER TABLE mining_runs DROP COLUMN reused;
+ALTER TABLE mining_runs DROP COLUMN resumed_from;
+DROP TABLE IF EXISTS run_checkpoints;
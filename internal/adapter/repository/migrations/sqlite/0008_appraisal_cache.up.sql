@@ -0,0 +1,17 @@
+-- LLM 评估结果缓存，按模型、Prompt 版本和项目内容的摘要查找
+CREATE TABLE IF NOT EXISTS cached_appraisals (
+    cache_key              text PRIMARY KEY,
+    repo_id                text NOT NULL,
+    is_a_iprogramming_tool boolean NOT NULL DEFAULT 0,
+    llm_score              integer NOT NULL DEFAULT 0,
+    llm_review             text NOT NULL DEFAULT '',
+    categories             text,
+    appraisal_model        text NOT NULL DEFAULT '',
+    prompt_version         text NOT NULL DEFAULT '',
+    created_at             datetime NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cached_appraisals_created_at ON cached_appraisals (created_at);
+
+ALTER TABLE mining_runs ADD COLUMN cache_hits integer NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN cache_misses integer NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE mining_runs DROP COLUMN budgets;
+ALTER TABLE mining_runs DROP COLUMN budget_blocked;
+ALTER TABLE mining_runs DROP COLUMN cost_usd;
+ALTER TABLE mining_runs DROP COLUMN completion_tokens;
+ALTER TABLE mining_runs DROP COLUMN prompt_tokens;
+DROP TABLE IF EXISTS usage_records;
@@ -0,0 +1,17 @@
+-- LLM 用量账本，按日期、挖矿方向和模型累计 Token 数和费用，用于执行预算
+CREATE TABLE IF NOT EXISTS usage_records (
+    day               text NOT NULL,
+    profile           text NOT NULL DEFAULT '',
+    model             text NOT NULL,
+    prompt_tokens     integer NOT NULL DEFAULT 0,
+    completion_tokens integer NOT NULL DEFAULT 0,
+    cost_usd          real NOT NULL DEFAULT 0,
+    updated_at        datetime NOT NULL,
+    PRIMARY KEY (day, profile, model)
+);
+
+ALTER TABLE mining_runs ADD COLUMN prompt_tokens integer NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN completion_tokens integer NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN cost_usd real NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN budget_blocked integer NOT NULL DEFAULT 0;
+ALTER TABLE mining_runs ADD COLUMN budgets text;
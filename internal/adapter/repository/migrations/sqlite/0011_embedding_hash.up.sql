@@ -0,0 +1,4 @@
+-- 向量对应的内容摘要：项目的描述、分类或 LLM 评价变化后，repos.embedding_hash 与向量的 content_hash 不同，重新生成向量
+-- 已有的项目和向量摘要都为空，视为未变化，项目下次入库时计算摘要
+ALTER TABLE repos ADD COLUMN embedding_hash text NOT NULL DEFAULT '';
+ALTER TABLE repo_embeddings ADD COLUMN content_hash text NOT NULL DEFAULT '';
@@ -60,45 +60,58 @@ func TestPostgresRepo_Save(t *testing.T) {
 				CreatedAt:       now,
 				UpdatedAt:       now,
 				StarGrowthRate:  50.0,
-				IsAIProgrammingTool: true,
-				LLMScore:        85,
-				LLMReview:       "Excellent tool",
 				AlreadyNotified: false,
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
-				// GORM Save uses UPDATE with primary key condition
+				// 使用 INSERT ... ON CONFLICT 代替 UPDATE 全部列
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "repos"`)).
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "repos"`) + `.*` + regexp.QuoteMeta(`ON CONFLICT ("id") DO UPDATE SET "name"="excluded"."name"`)).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
 		{
-			name: "更新已存在的项目",
+			name: "保存带评估结果的项目时记录评估历史",
 			repo: &domain.Repo{
-				ID:              "github-456",
-				Name:            "test/existing-tool",
-				URL:             "https://github.com/test/existing-tool",
-				Description:     "Updated description",
-				Stars:           1000,
-				Language:        "Python",
-				CreatedAt:       now,
-				UpdatedAt:       now,
-				StarGrowthRate:  100.0,
+				ID:                  "github-456",
+				Name:                "test/existing-tool",
+				URL:                 "https://github.com/test/existing-tool",
+				Description:         "Updated description",
+				Stars:               1000,
+				Language:            "Python",
+				CreatedAt:           now,
+				UpdatedAt:           now,
+				StarGrowthRate:      100.0,
 				IsAIProgrammingTool: true,
-				LLMScore:        90,
-				LLMReview:       "Outstanding",
-				AlreadyNotified: true,
+				LLMScore:            90,
+				LLMReview:           "Outstanding",
+				AppraisalModel:      "gemini-2.5-pro",
+				PromptVersion:       "v2",
+				AppraisedAt:         &now,
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "repos"`)).
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "repos"`)).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "appraisals"`) + `.*` + regexp.QuoteMeta(`ON CONFLICT DO NOTHING`)).
+					WithArgs("github-456", "gemini-2.5-pro", "v2", true, 90, "Outstanding", sqlmock.AnyArg(), now).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectCommit()
 			},
 			expectError: false,
 		},
+		{
+			name: "写入失败时回滚",
+			repo: &domain.Repo{ID: "github-789", Name: "test/broken", AppraisedAt: &now},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "repos"`)).
+					WillReturnError(assert.AnError)
+				mock.ExpectRollback()
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -110,7 +123,7 @@ func TestPostgresRepo_Save(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			err := repo.Save(ctx, tt.repo)
@@ -126,6 +139,35 @@ func TestPostgresRepo_Save(t *testing.T) {
 	}
 }
 
+// TestUpsertColumns 重复保存时不能覆盖推送和追踪状态
+func TestUpsertColumns(t *testing.T) {
+	for _, col := range []string{"id", "already_notified", "notified_at", "notified_stars", "archived", "retracted", "tracked_milestone", "last_checked_at"} {
+		assert.NotContains(t, upsertColumns, col)
+	}
+}
+
+func TestPostgresRepo_GetAppraisals(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "appraisals" WHERE repo_id = $1 ORDER BY appraised_at ASC`)).
+		WithArgs("github-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "repo_id", "model", "prompt_version", "score", "review", "categories", "appraised_at"}).
+			AddRow(1, "github-1", "gemini-1.5-flash", "v1", 60, "一般", `["other"]`, now.AddDate(0, 0, -7)).
+			AddRow(2, "github-1", "gemini-2.5-pro", "v2", 85, "优秀", `["cli-agent"]`, now))
+
+	repo := &GormRepo{db: gormDB}
+	appraisals, err := repo.GetAppraisals(context.Background(), "github-1")
+
+	assert.NoError(t, err)
+	assert.Len(t, appraisals, 2)
+	assert.Equal(t, "v1", appraisals[0].PromptVersion)
+	assert.Equal(t, 85, appraisals[1].Score)
+	assert.Equal(t, []string{"cli-agent"}, appraisals[1].Categories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPostgresRepo_Exists(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -177,7 +219,7 @@ func TestPostgresRepo_Exists(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			exists, err := repo.Exists(ctx, tt.repoID)
@@ -246,7 +288,7 @@ func TestPostgresRepo_MarkAsNotified(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			err := repo.MarkAsNotified(ctx, tt.repoID)
@@ -267,14 +309,14 @@ func TestPostgresRepo_Search(t *testing.T) {
 
 	tests := []struct {
 		name        string
-		query       string
+		query       domain.SearchQuery
 		setupMock   func(sqlmock.Sqlmock)
 		expectError bool
 		verify      func(*testing.T, []*domain.Repo)
 	}{
 		{
 			name:  "成功搜索项目",
-			query: "AI coding",
+			query: domain.SearchQuery{Text: "AI coding"},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"id", "name", "url", "description", "stars", "language",
@@ -294,7 +336,8 @@ func TestPostgresRepo_Search(t *testing.T) {
 						true, 90, "Excellent", false,
 					)
 
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos"`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE search_vector @@ websearch_to_tsquery('simple', $1) ORDER BY ts_rank(search_vector, websearch_to_tsquery('simple', $2)) DESC, llm_score DESC LIMIT $3`)).
+					WithArgs("AI coding", "AI coding", 10).
 					WillReturnRows(rows)
 			},
 			expectError: false,
@@ -309,7 +352,7 @@ func TestPostgresRepo_Search(t *testing.T) {
 		},
 		{
 			name:  "搜索无结果",
-			query: "non-existent",
+			query: domain.SearchQuery{Text: "non-existent"},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{
 					"id", "name", "url", "description", "stars", "language",
@@ -317,17 +360,55 @@ func TestPostgresRepo_Search(t *testing.T) {
 					"is_ai_programming_tool", "llm_score", "llm_review", "already_notified",
 				})
 
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos"`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`websearch_to_tsquery`)).
 					WillReturnRows(rows)
+				// 全文检索无结果时退化为 trigram 模糊匹配
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE ($1 <% (coalesce(name, '')`)).
+					WithArgs("non-existent", "%non-existent%", "non-existent", 10).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
 			},
 			expectError: false,
 			verify: func(t *testing.T, repos []*domain.Repo) {
 				assert.Equal(t, 0, len(repos))
 			},
 		},
+		{
+			name:  "指定排序字段时不按相关度排序",
+			query: domain.SearchQuery{Text: "agent", Sort: domain.SortVelocity},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`WHERE search_vector @@ websearch_to_tsquery('simple', $1) ORDER BY star_growth_rate DESC, llm_score DESC LIMIT $2`)).
+					WithArgs("agent", 10).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("github-1"))
+			},
+			expectError: false,
+			verify: func(t *testing.T, repos []*domain.Repo) {
+				assert.Equal(t, 1, len(repos))
+			},
+		},
+		{
+			name: "过滤条件和分页",
+			query: domain.SearchQuery{
+				Categories: []string{domain.CategoryCLIAgent, domain.CategoryIDEExtension},
+				Language:   "go",
+				MinScore:   80,
+				Limit:      500,
+				Offset:     20,
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos" WHERE (categories LIKE $1 ESCAPE '\' OR categories LIKE $2 ESCAPE '\') AND LOWER(language) = LOWER($3) AND llm_score >= $4 ORDER BY llm_score DESC LIMIT $5 OFFSET $6`)).
+					WithArgs(`%"cli-agent"%`, `%"ide-extension"%`, "go", 80, domain.MaxSearchLimit, 20).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("github-1"))
+			},
+			expectError: false,
+			verify: func(t *testing.T, repos []*domain.Repo) {
+				if assert.Len(t, repos, 1) {
+					assert.Equal(t, "github-1", repos[0].ID)
+				}
+			},
+		},
 		{
 			name:  "数据库错误",
-			query: "error",
+			query: domain.SearchQuery{Text: "error"},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos"`)).
 					WillReturnError(gorm.ErrInvalidDB)
@@ -348,7 +429,7 @@ func TestPostgresRepo_Search(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			repos, err := repo.Search(ctx, tt.query)
@@ -446,7 +527,7 @@ func TestPostgresRepo_GetAllCandidates(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			repos, err := repo.GetAllCandidates(ctx)
@@ -547,7 +628,7 @@ func TestPostgresRepo_GetUnnotifiedRepos(t *testing.T) {
 				tt.setupMock(mock)
 			}
 
-			repo := &PostgresRepo{db: gormDB}
+			repo := &GormRepo{db: gormDB}
 			ctx := context.Background()
 
 			repos, err := repo.GetUnnotifiedRepos(ctx)
@@ -579,7 +660,7 @@ func TestPostgresRepo_ContextCancellation(t *testing.T) {
 	gormDB, mock, cleanup := setupMockDB(t)
 	defer cleanup()
 
-	repo := &PostgresRepo{db: gormDB}
+	repo := &GormRepo{db: gormDB}
 
 	// 使用正常的上下文，但让数据库返回上下文取消错误
 	ctx := context.Background()
@@ -594,3 +675,65 @@ func TestPostgresRepo_ContextCancellation(t *testing.T) {
 	assert.False(t, exists)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestPostgresRepo_Tracking(t *testing.T) {
+	now := time.Now()
+
+	t.Run("获取已推送项目", func(t *testing.T) {
+		gormDB, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		rows := sqlmock.NewRows([]string{"id", "name", "stars", "already_notified", "notified_stars", "notified_at"}).
+			AddRow("github-1", "test/tracked", 800, true, 200, now)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos" WHERE already_notified = $1 AND retracted = $2 AND notified_at >= $3`)).
+			WillReturnRows(rows)
+
+		repo := &GormRepo{db: gormDB}
+		repos, err := repo.GetNotifiedRepos(context.Background(), now.AddDate(0, 0, -30))
+
+		assert.NoError(t, err)
+		assert.Len(t, repos, 1)
+		assert.Equal(t, 200, repos[0].NotifiedStars)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("更新追踪字段", func(t *testing.T) {
+		gormDB, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "repos"`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := &GormRepo{db: gormDB}
+		err := repo.UpdateTracking(context.Background(), &domain.Repo{ID: "github-1", Stars: 5200, TrackedMilestone: 5000, LastCheckedAt: &now})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("记录和读取快照", func(t *testing.T) {
+		gormDB, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "star_snapshots"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "star_snapshots" WHERE repo_id = $1 ORDER BY captured_at ASC`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "repo_id", "stars", "captured_at"}).
+				AddRow(1, "github-1", 200, now.AddDate(0, 0, -1)).
+				AddRow(2, "github-1", 5200, now))
+
+		repo := &GormRepo{db: gormDB}
+		err := repo.AddSnapshot(context.Background(), &domain.StarSnapshot{RepoID: "github-1", Stars: 5200, CapturedAt: now})
+		assert.NoError(t, err)
+
+		snapshots, err := repo.GetSnapshots(context.Background(), "github-1")
+		assert.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+		assert.Equal(t, 5200, snapshots[1].Stars)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
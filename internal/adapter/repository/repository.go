@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github-gold-miner/internal/common"
+
+	"gorm.io/gorm"
+)
+
+// Open 根据 DSN 的 scheme 选择存储后端，并执行未完成的迁移：
+//   - sqlite://path/to/file.db、sqlite://:memory: 使用 SQLite
+//   - postgres://...、postgresql://... 以及 key=value 形式的连接串使用 Postgres
+func Open(dsn string) (*GormRepo, error) {
+	db, err := connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return newGormRepo(db)
+}
+
+// Connect 只连接数据库，不执行迁移，供 migrate 子命令使用
+func Connect(dsn string) (*GormRepo, error) {
+	db, err := connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &GormRepo{db: db}, nil
+}
+
+// OpenReadOnly 只连接数据库，不执行迁移，供试运行使用；还有未执行的迁移时返回错误
+func OpenReadOnly(dsn string) (*GormRepo, error) {
+	repo, err := Connect(dsn)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := repo.Migrator()
+	if err == nil {
+		var pending []Migration
+		pending, err = migrator.Pending(context.Background())
+		if err == nil && len(pending) > 0 {
+			err = fmt.Errorf("还有 %d 个未执行的数据库迁移，请先执行 migrate up", len(pending))
+		}
+	}
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+func connect(dsn string) (*gorm.DB, error) {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		// 兼容原来的 "host=... user=..." 形式
+		return openPostgres(dsn)
+	}
+
+	switch strings.ToLower(scheme) {
+	case "sqlite", "sqlite3":
+		return openSQLite(rest)
+	case "postgres", "postgresql":
+		return openPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", scheme)
+	}
+}
+
+// dbError 按数据库错误的类型加上错误码：连不上数据库或连接已断开为 DB_UNAVAILABLE，其他为 DATABASE_ERROR
+func dbError(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := common.ErrCodeDatabase
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		code = common.ErrCodeDBUnavailable
+	}
+	return common.WrapError(code, message, err)
+}
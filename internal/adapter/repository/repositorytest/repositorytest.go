@@ -0,0 +1,772 @@
+// Package repositorytest 提供 port.Repository 的行为一致性测试，
+// 任何存储实现 (Postgres、SQLite、内存) 都应该通过同一套用例:
+//
+//	func TestConformance(t *testing.T) {
+//		repositorytest.Run(t, func(t *testing.T) port.Repository {
+//			return repository.NewMemoryRepo()
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory 为每个子测试创建一个空的仓库实例，需要清理的资源通过 t.Cleanup 注册
+type Factory func(t *testing.T) port.Repository
+
+// Run 对 newRepo 创建的仓库执行完整的 port.Repository 行为测试
+func Run(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	base := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+
+	sample := func(id string, score int, createdAt time.Time) *domain.Repo {
+		return &domain.Repo{
+			ID:                  id,
+			Name:                "test/" + id,
+			URL:                 "https://github.com/test/" + id,
+			Description:         "An AI coding tool",
+			Stars:               100,
+			Language:            "Go",
+			CreatedAt:           createdAt,
+			IsAIProgrammingTool: true,
+			LLMScore:            score,
+			LLMReview:           "review of " + id,
+			Categories:          []string{domain.CategoryCLIAgent},
+		}
+	}
+
+	t.Run("保存和查重", func(t *testing.T) {
+		repo := newRepo(t)
+
+		exists, err := repo.Exists(ctx, "github-1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		require.NoError(t, repo.Save(ctx, sample("github-1", 80, base)))
+		exists, err = repo.Exists(ctx, "github-1")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		got, err := repo.Get(ctx, "github-1")
+		require.NoError(t, err)
+		assert.Equal(t, "test/github-1", got.Name)
+		assert.Equal(t, []string{domain.CategoryCLIAgent}, got.Categories)
+
+		_, err = repo.Get(ctx, "github-404")
+		assert.ErrorIs(t, err, port.ErrNotFound)
+
+		all, err := repo.GetAllCandidates(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, "test/github-1", all[0].Name)
+		assert.Equal(t, []string{domain.CategoryCLIAgent}, all[0].Categories)
+	})
+
+	t.Run("同一项目只保存一份", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first := sample("github-1", 80, base)
+		first.ReadmeSHA = "readme-v1"
+		require.NoError(t, repo.Save(ctx, first))
+		second := sample("github-1", 85, base)
+		second.ReadmeSHA = "readme-v2"
+		require.NoError(t, repo.Save(ctx, second))
+
+		all, err := repo.GetAllCandidates(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, 85, all[0].LLMScore)
+		assert.Equal(t, "readme-v2", all[0].ReadmeSHA)
+	})
+
+	t.Run("标记已推送", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Save(ctx, sample("github-1", 80, base)))
+		before := time.Now().Add(-time.Second)
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-1"))
+		// 标记不存在的项目不报错
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-404"))
+
+		notified, err := repo.GetNotifiedRepos(ctx, before)
+		require.NoError(t, err)
+		require.Len(t, notified, 1)
+		assert.True(t, notified[0].AlreadyNotified)
+		require.NotNil(t, notified[0].NotifiedAt)
+		assert.False(t, notified[0].NotifiedAt.Before(before))
+		// 记录推送时的 Star 数
+		assert.Equal(t, 100, notified[0].NotifiedStars)
+	})
+
+	t.Run("重复保存不覆盖推送状态", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Save(ctx, sample("github-1", 80, base)))
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-1"))
+
+		updated := sample("github-1", 90, base)
+		updated.Stars = 300
+		updated.LLMReview = "new review"
+		require.NoError(t, repo.Save(ctx, updated))
+
+		unnotified, err := repo.GetUnnotifiedRepos(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, unnotified)
+
+		notified, err := repo.GetNotifiedRepos(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, notified, 1)
+		assert.Equal(t, 300, notified[0].Stars)
+		assert.Equal(t, 90, notified[0].LLMScore)
+		assert.Equal(t, "new review", notified[0].LLMReview)
+		assert.Equal(t, 100, notified[0].NotifiedStars)
+		assert.NotNil(t, notified[0].NotifiedAt)
+	})
+
+	t.Run("挖矿方向合并", func(t *testing.T) {
+		repo := newRepo(t)
+
+		agents := sample("github-1", 80, base)
+		agents.Profiles = []string{"agents"}
+		require.NoError(t, repo.Save(ctx, agents))
+		ide := sample("github-1", 85, base)
+		ide.Profiles = []string{"ide", "agents"}
+		require.NoError(t, repo.Save(ctx, ide))
+		// 不带方向的保存不会清掉已有的方向
+		require.NoError(t, repo.Save(ctx, sample("github-1", 90, base)))
+
+		got, err := repo.Get(ctx, "github-1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"agents", "ide"}, got.Profiles)
+		assert.Equal(t, 90, got.LLMScore)
+
+		other := sample("github-2", 70, base)
+		other.Profiles = []string{"ide_tools"}
+		require.NoError(t, repo.Save(ctx, other))
+		results, err := repo.Search(ctx, domain.SearchQuery{Profile: "ide"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "github-1", results[0].ID)
+	})
+
+	t.Run("评估历史", func(t *testing.T) {
+		repo := newRepo(t)
+
+		first := sample("github-1", 60, base)
+		firstAt := base
+		first.AppraisalModel, first.PromptVersion, first.AppraisedAt = "gemini-1.5-flash", "v1", &firstAt
+		require.NoError(t, repo.Save(ctx, first))
+		// 同一次评估重复保存不产生重复记录
+		require.NoError(t, repo.Save(ctx, first))
+
+		second := sample("github-1", 85, base)
+		secondAt := base.Add(24 * time.Hour)
+		second.AppraisalModel, second.PromptVersion, second.AppraisedAt = "gemini-2.5-pro", "v2", &secondAt
+		require.NoError(t, repo.Save(ctx, second))
+
+		// 未经评估的保存不产生记录
+		require.NoError(t, repo.Save(ctx, sample("github-2", 0, base)))
+
+		appraisals, err := repo.GetAppraisals(ctx, "github-1")
+		require.NoError(t, err)
+		require.Len(t, appraisals, 2)
+		assert.Equal(t, "v1", appraisals[0].PromptVersion)
+		assert.Equal(t, 60, appraisals[0].Score)
+		assert.Equal(t, "gemini-2.5-pro", appraisals[1].Model)
+		assert.Equal(t, 85, appraisals[1].Score)
+		assert.Equal(t, []string{domain.CategoryCLIAgent}, appraisals[1].Categories)
+
+		appraisals, err = repo.GetAppraisals(ctx, "github-2")
+		require.NoError(t, err)
+		assert.Empty(t, appraisals)
+	})
+
+	t.Run("关键词搜索", func(t *testing.T) {
+		repo := newRepo(t)
+
+		low := sample("github-1", 60, base)
+		high := sample("github-2", 90, base)
+		other := sample("github-3", 70, base)
+		other.Description, other.LLMReview = "A web framework", "not related"
+		for _, r := range []*domain.Repo{low, high, other} {
+			require.NoError(t, repo.Save(ctx, r))
+		}
+
+		results, err := repo.Search(ctx, domain.SearchQuery{Text: "coding"})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		// 按评分从高到低
+		assert.Equal(t, "github-2", results[0].ID)
+		assert.Equal(t, "github-1", results[1].ID)
+
+		results, err = repo.Search(ctx, domain.SearchQuery{Text: "nothing-matches"})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("搜索结果最多 10 条", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 12; i++ {
+			require.NoError(t, repo.Save(ctx, sample(fmt.Sprintf("github-%02d", i), 50+i, base)))
+		}
+
+		results, err := repo.Search(ctx, domain.SearchQuery{Text: "coding"})
+		require.NoError(t, err)
+		require.Len(t, results, 10)
+		assert.Equal(t, 61, results[0].LLMScore)
+		assert.Equal(t, 52, results[9].LLMScore)
+	})
+
+	t.Run("多个关键词同时命中", func(t *testing.T) {
+		repo := newRepo(t)
+
+		both := sample("github-1", 60, base)
+		both.Description = "Terminal coding agent"
+		one := sample("github-2", 90, base)
+		for _, r := range []*domain.Repo{both, one} {
+			require.NoError(t, repo.Save(ctx, r))
+		}
+
+		results, err := repo.Search(ctx, domain.SearchQuery{Text: "coding terminal"})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "github-1", results[0].ID)
+	})
+
+	t.Run("搜索过滤条件", func(t *testing.T) {
+		repo := newRepo(t)
+
+		goAgent := sample("github-1", 90, base)
+		pyAgent := sample("github-2", 80, base.AddDate(0, 0, 10))
+		pyAgent.Language = "Python"
+		ide := sample("github-3", 50, base.AddDate(0, 0, 20))
+		ide.Categories = []string{domain.CategoryIDEExtension}
+		for _, r := range []*domain.Repo{goAgent, pyAgent, ide} {
+			require.NoError(t, repo.Save(ctx, r))
+		}
+
+		ids := func(q domain.SearchQuery) []string {
+			results, err := repo.Search(ctx, q)
+			require.NoError(t, err)
+			var got []string
+			for _, r := range results {
+				got = append(got, r.ID)
+			}
+			return got
+		}
+		after, before := base.AddDate(0, 0, 5), base.AddDate(0, 0, 15)
+
+		// 不带关键词时只按条件过滤
+		assert.Equal(t, []string{"github-1", "github-2", "github-3"}, ids(domain.SearchQuery{}))
+		assert.Equal(t, []string{"github-3"}, ids(domain.SearchQuery{Categories: []string{domain.CategoryIDEExtension}}))
+		assert.Equal(t, []string{"github-1", "github-3"}, ids(domain.SearchQuery{Categories: []string{domain.CategoryIDEExtension, domain.CategoryCLIAgent}, Language: "go"}))
+		assert.Equal(t, []string{"github-2"}, ids(domain.SearchQuery{Text: "coding", Language: "PYTHON"}))
+		assert.Equal(t, []string{"github-1", "github-2"}, ids(domain.SearchQuery{MinScore: 80}))
+		assert.Equal(t, []string{"github-2"}, ids(domain.SearchQuery{CreatedAfter: &after, CreatedBefore: &before}))
+		assert.Equal(t, []string{"github-2", "github-3"}, ids(domain.SearchQuery{CreatedAfter: &after}))
+	})
+
+	t.Run("搜索分页", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, repo.Save(ctx, sample(fmt.Sprintf("github-%d", i), 50+i, base)))
+		}
+
+		first, err := repo.Search(ctx, domain.SearchQuery{Text: "coding", Limit: 2})
+		require.NoError(t, err)
+		second, err := repo.Search(ctx, domain.SearchQuery{Text: "coding", Limit: 2, Offset: 2})
+		require.NoError(t, err)
+		last, err := repo.Search(ctx, domain.SearchQuery{Text: "coding", Limit: 2, Offset: 4})
+		require.NoError(t, err)
+		beyond, err := repo.Search(ctx, domain.SearchQuery{Text: "coding", Limit: 2, Offset: 10})
+		require.NoError(t, err)
+
+		require.Len(t, first, 2)
+		require.Len(t, second, 2)
+		require.Len(t, last, 1)
+		assert.Empty(t, beyond)
+		assert.Equal(t, []int{54, 53}, []int{first[0].LLMScore, first[1].LLMScore})
+		assert.Equal(t, []int{52, 51}, []int{second[0].LLMScore, second[1].LLMScore})
+		assert.Equal(t, 50, last[0].LLMScore)
+	})
+
+	t.Run("搜索排序", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i, r := range []struct {
+			score, stars int
+			velocity     float64
+		}{{90, 100, 5}, {70, 300, 50}, {80, 200, 20}} {
+			item := sample(fmt.Sprintf("github-%d", i+1), r.score, base.AddDate(0, 0, i))
+			item.Stars = r.stars
+			item.StarGrowthRate = r.velocity
+			require.NoError(t, repo.Save(ctx, item))
+		}
+
+		ids := func(q domain.SearchQuery) []string {
+			results, err := repo.Search(ctx, q)
+			require.NoError(t, err)
+			var got []string
+			for _, r := range results {
+				got = append(got, r.ID)
+			}
+			return got
+		}
+		assert.Equal(t, []string{"github-1", "github-3", "github-2"}, ids(domain.SearchQuery{}))
+		assert.Equal(t, []string{"github-2", "github-3", "github-1"}, ids(domain.SearchQuery{Sort: domain.SortScore, Ascending: true}))
+		assert.Equal(t, []string{"github-2", "github-3", "github-1"}, ids(domain.SearchQuery{Sort: domain.SortVelocity}))
+		assert.Equal(t, []string{"github-2", "github-3", "github-1"}, ids(domain.SearchQuery{Sort: domain.SortStars}))
+		assert.Equal(t, []string{"github-3", "github-2", "github-1"}, ids(domain.SearchQuery{Sort: domain.SortCreated}))
+		assert.Equal(t, []string{"github-1", "github-2"}, ids(domain.SearchQuery{Sort: domain.SortCreated, Ascending: true, Limit: 2}))
+		assert.Equal(t, []string{"github-2", "github-3", "github-1"}, ids(domain.SearchQuery{Text: "coding", Sort: domain.SortStars}))
+	})
+
+	t.Run("候选项目最多 100 个", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i := 0; i < 105; i++ {
+			require.NoError(t, repo.Save(ctx, sample(fmt.Sprintf("github-%03d", i), 70, base.Add(time.Duration(i)*time.Minute))))
+		}
+
+		all, err := repo.GetAllCandidates(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 100)
+		// 保留最新创建的 100 个
+		assert.Equal(t, "github-104", all[0].ID)
+		assert.Equal(t, "github-005", all[99].ID)
+	})
+
+	t.Run("候选项目按创建时间倒序", func(t *testing.T) {
+		repo := newRepo(t)
+
+		for i, id := range []string{"github-1", "github-2", "github-3"} {
+			require.NoError(t, repo.Save(ctx, sample(id, 70, base.AddDate(0, 0, i))))
+		}
+
+		all, err := repo.GetAllCandidates(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.Equal(t, []string{"github-3", "github-2", "github-1"}, []string{all[0].ID, all[1].ID, all[2].ID})
+	})
+
+	t.Run("未推送项目按评分排序", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Save(ctx, sample("github-1", 60, base)))
+		require.NoError(t, repo.Save(ctx, sample("github-2", 90, base)))
+		require.NoError(t, repo.Save(ctx, sample("github-3", 75, base)))
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-3"))
+
+		unnotified, err := repo.GetUnnotifiedRepos(ctx)
+		require.NoError(t, err)
+		require.Len(t, unnotified, 2)
+		assert.Equal(t, "github-2", unnotified[0].ID)
+		assert.Equal(t, "github-1", unnotified[1].ID)
+	})
+
+	t.Run("推送后追踪", func(t *testing.T) {
+		repo := newRepo(t)
+
+		require.NoError(t, repo.Save(ctx, sample("github-1", 80, base)))
+		require.NoError(t, repo.Save(ctx, sample("github-2", 80, base)))
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-1"))
+		require.NoError(t, repo.MarkAsNotified(ctx, "github-2"))
+
+		checkedAt := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, repo.UpdateTracking(ctx, &domain.Repo{ID: "github-1", Stars: 5200, TrackedMilestone: 5000, LastCheckedAt: &checkedAt}))
+		require.NoError(t, repo.UpdateTracking(ctx, &domain.Repo{ID: "github-2", Stars: 100, Archived: true, Retracted: true, LastCheckedAt: &checkedAt}))
+
+		notified, err := repo.GetNotifiedRepos(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		// 已撤回的项目不再追踪
+		require.Len(t, notified, 1)
+		tracked := notified[0]
+		assert.Equal(t, "github-1", tracked.ID)
+		assert.Equal(t, 5200, tracked.Stars)
+		assert.Equal(t, 5000, tracked.TrackedMilestone)
+		// 基线为 0 时不覆盖推送时的 Star 数
+		assert.Equal(t, 100, tracked.NotifiedStars)
+		require.NotNil(t, tracked.LastCheckedAt)
+		assert.True(t, checkedAt.Equal(*tracked.LastCheckedAt))
+		// 追踪更新不影响评估结果
+		assert.Equal(t, 80, tracked.LLMScore)
+
+		notified, err = repo.GetNotifiedRepos(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, notified)
+
+		require.NoError(t, repo.AddSnapshot(ctx, &domain.StarSnapshot{RepoID: "github-1", Stars: 5200, CapturedAt: base.Add(time.Hour)}))
+		require.NoError(t, repo.AddSnapshot(ctx, &domain.StarSnapshot{RepoID: "github-1", Stars: 200, CapturedAt: base}))
+		require.NoError(t, repo.AddSnapshot(ctx, &domain.StarSnapshot{RepoID: "github-2", Stars: 100, CapturedAt: base}))
+
+		snapshots, err := repo.GetSnapshots(ctx, "github-1")
+		require.NoError(t, err)
+		require.Len(t, snapshots, 2)
+		assert.Equal(t, 200, snapshots[0].Stars)
+		assert.Equal(t, 5200, snapshots[1].Stars)
+	})
+
+	t.Run("用户反馈", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.Save(ctx, sample("github-1", 80, base)))
+
+		empty, err := repo.GetFeedback(ctx, "github-1")
+		require.NoError(t, err)
+		assert.Empty(t, empty)
+
+		require.NoError(t, repo.AddFeedback(ctx, &domain.Feedback{RepoID: "github-1", Verdict: domain.FeedbackUseful, Author: "alice", CreatedAt: base}))
+		require.NoError(t, repo.AddFeedback(ctx, &domain.Feedback{RepoID: "github-1", Verdict: domain.FeedbackNotUseful, Comment: "不是编程工具", CreatedAt: base.Add(time.Hour)}))
+
+		feedback, err := repo.GetFeedback(ctx, "github-1")
+		require.NoError(t, err)
+		require.Len(t, feedback, 2)
+		assert.Equal(t, domain.FeedbackUseful, feedback[0].Verdict)
+		assert.Equal(t, "alice", feedback[0].Author)
+		assert.NotZero(t, feedback[0].ID)
+		assert.Equal(t, "不是编程工具", feedback[1].Comment)
+		assert.True(t, feedback[1].CreatedAt.Equal(base.Add(time.Hour)))
+	})
+
+	t.Run("挖矿执行记录", func(t *testing.T) {
+		runs, ok := newRepo(t).(port.RunStore)
+		if !ok {
+			t.Skip("未实现 port.RunStore")
+		}
+
+		first := &domain.MiningRun{Trigger: domain.TriggerCron, Status: domain.RunRunning, StartedAt: base}
+		require.NoError(t, runs.CreateRun(ctx, first))
+		second := &domain.MiningRun{Trigger: domain.TriggerAPI, Profile: "agents", Status: domain.RunRunning, StartedAt: base.Add(time.Hour)}
+		require.NoError(t, runs.CreateRun(ctx, second))
+		assert.NotZero(t, first.ID)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		finished := base.Add(5 * time.Minute)
+		first.Status = domain.RunFailed
+		first.FinishedAt = &finished
+		first.Error = "GitHub 限流"
+		fetch := first.AddStage(domain.StageFetch, 4)
+		fetch.Out = 30
+		fetch.AddError(fmt.Errorf("topic dev-tools: timeout"))
+		first.APIRequests = 42
+		first.LLMTokens = 1800
+		first.Reused = 3
+		first.ResumedFrom = second.ID
+		first.CacheHits = 7
+		first.CacheMisses = 2
+		first.Usage = domain.TokenUsage{PromptTokens: 1500, CompletionTokens: 300, CostUSD: 0.0049}
+		first.BudgetBlocked = 4
+		first.Budgets = []*domain.BudgetStatus{{Scope: domain.BudgetDaily, Period: "2025-03-01", LimitUSD: 5, SpentUSD: 5.2}}
+		first.Pushed = []string{"acme/agent"}
+		require.NoError(t, runs.UpdateRun(ctx, first))
+
+		got, err := runs.GetRun(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.TriggerCron, got.Trigger)
+		assert.Equal(t, domain.RunFailed, got.Status)
+		require.NotNil(t, got.FinishedAt)
+		assert.True(t, got.FinishedAt.Equal(finished))
+		assert.Equal(t, "GitHub 限流", got.Error)
+		require.Len(t, got.Stages, 1)
+		assert.Equal(t, &domain.StageReport{
+			Name: domain.StageFetch, In: 4, Out: 30,
+			Errors:     []string{"topic dev-tools: timeout"},
+			ErrorCodes: map[string]int{domain.ErrorCodeUnknown: 1},
+		}, got.Stages[0])
+		assert.Equal(t, int64(42), got.APIRequests)
+		assert.Equal(t, int64(1800), got.LLMTokens)
+		assert.Equal(t, 3, got.Reused)
+		assert.Equal(t, second.ID, got.ResumedFrom)
+		assert.Equal(t, int64(7), got.CacheHits)
+		assert.Equal(t, int64(2), got.CacheMisses)
+		assert.Equal(t, int64(1500), got.Usage.PromptTokens)
+		assert.Equal(t, int64(300), got.Usage.CompletionTokens)
+		assert.InDelta(t, 0.0049, got.Usage.CostUSD, 1e-9)
+		assert.Equal(t, 4, got.BudgetBlocked)
+		assert.Equal(t, []*domain.BudgetStatus{{Scope: domain.BudgetDaily, Period: "2025-03-01", LimitUSD: 5, SpentUSD: 5.2}}, got.Budgets)
+		assert.Equal(t, []string{"acme/agent"}, got.Pushed)
+
+		_, err = runs.GetRun(ctx, 404)
+		assert.ErrorIs(t, err, port.ErrNotFound)
+		assert.ErrorIs(t, runs.UpdateRun(ctx, &domain.MiningRun{ID: 404, StartedAt: base}), port.ErrNotFound)
+
+		// 按开始时间倒序
+		list, err := runs.ListRuns(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, second.ID, list[0].ID)
+		assert.Equal(t, "agents", list[0].Profile)
+		assert.Equal(t, first.ID, list[1].ID)
+		assert.Empty(t, list[1].Profile)
+
+		list, err = runs.ListRuns(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, second.ID, list[0].ID)
+	})
+
+	t.Run("挖矿检查点", func(t *testing.T) {
+		repo := newRepo(t)
+		runs, ok := repo.(port.RunStore)
+		if !ok {
+			t.Skip("未实现 port.RunStore")
+		}
+		checkpoints, ok := repo.(port.CheckpointStore)
+		if !ok {
+			t.Skip("未实现 port.CheckpointStore")
+		}
+
+		run := &domain.MiningRun{Trigger: domain.TriggerCron, Status: domain.RunRunning, StartedAt: base}
+		require.NoError(t, runs.CreateRun(ctx, run))
+		other := &domain.MiningRun{Trigger: domain.TriggerCron, Status: domain.RunRunning, StartedAt: base}
+		require.NoError(t, runs.CreateRun(ctx, other))
+
+		appraised := base.Add(time.Minute)
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, &domain.RunCheckpoint{
+			RunID: run.ID, Seq: 1, Stage: domain.StageAppraise, CreatedAt: base,
+			Repos: []*domain.Repo{{ID: "a/one", Name: "a/one", LLMScore: 80, AppraisedAt: &appraised}},
+		}))
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, &domain.RunCheckpoint{
+			RunID: run.ID, Seq: 0, Stage: domain.StageFetch, Complete: true, CreatedAt: base,
+			Repos: []*domain.Repo{{ID: "a/one", Name: "a/one"}, {ID: "a/two", Name: "a/two"}},
+		}))
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, &domain.RunCheckpoint{
+			RunID: other.ID, Seq: 0, Stage: domain.StageFetch, Complete: true, CreatedAt: base,
+		}))
+		// 同一位置的检查点覆盖已有的
+		require.NoError(t, checkpoints.SaveCheckpoint(ctx, &domain.RunCheckpoint{
+			RunID: run.ID, Seq: 1, Stage: domain.StageAppraise, Complete: true, CreatedAt: base.Add(time.Hour),
+			Repos: []*domain.Repo{
+				{ID: "a/one", Name: "a/one", LLMScore: 80, AppraisedAt: &appraised},
+				{ID: "a/two", Name: "a/two", LLMScore: 30, AppraisedAt: &appraised},
+			},
+		}))
+
+		got, err := checkpoints.GetCheckpoints(ctx, run.ID)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		assert.Equal(t, domain.StageFetch, got[0].Stage)
+		assert.True(t, got[0].Complete)
+		assert.Len(t, got[0].Repos, 2)
+		assert.Equal(t, domain.StageAppraise, got[1].Stage)
+		assert.True(t, got[1].Complete)
+		assert.True(t, got[1].CreatedAt.Equal(base.Add(time.Hour)))
+		require.Len(t, got[1].Repos, 2)
+		assert.Equal(t, 80, got[1].Repos[0].LLMScore)
+		require.NotNil(t, got[1].Repos[0].AppraisedAt)
+		assert.True(t, got[1].Repos[0].AppraisedAt.Equal(appraised))
+
+		require.NoError(t, checkpoints.DeleteCheckpoints(ctx, run.ID))
+		got, err = checkpoints.GetCheckpoints(ctx, run.ID)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		got, err = checkpoints.GetCheckpoints(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Len(t, got, 1)
+	})
+
+	t.Run("评估缓存", func(t *testing.T) {
+		cache, ok := newRepo(t).(port.AppraisalCache)
+		if !ok {
+			t.Skip("未实现 port.AppraisalCache")
+		}
+
+		entry := func(key string, score int, at time.Time) *domain.CachedAppraisal {
+			return &domain.CachedAppraisal{
+				CacheKey: key, RepoID: "github-" + key, IsAIProgrammingTool: true, LLMScore: score,
+				LLMReview: "review", Categories: []string{domain.CategoryCLIAgent},
+				AppraisalModel: "gemini-2.5-pro", PromptVersion: "v2", CreatedAt: at,
+			}
+		}
+		require.NoError(t, cache.PutCachedAppraisal(ctx, entry("a", 60, base)))
+		require.NoError(t, cache.PutCachedAppraisal(ctx, entry("b", 70, base.Add(time.Hour))))
+		require.NoError(t, cache.PutCachedAppraisal(ctx, entry("c", 80, base.Add(2*time.Hour))))
+		// 同一个键覆盖已有的
+		require.NoError(t, cache.PutCachedAppraisal(ctx, entry("a", 65, base.Add(3*time.Hour))))
+
+		got, err := cache.GetCachedAppraisal(ctx, "a", base)
+		require.NoError(t, err)
+		assert.Equal(t, 65, got.LLMScore)
+		assert.Equal(t, "github-a", got.RepoID)
+		assert.Equal(t, []string{domain.CategoryCLIAgent}, got.Categories)
+		assert.Equal(t, "v2", got.PromptVersion)
+		assert.True(t, got.CreatedAt.Equal(base.Add(3*time.Hour)))
+
+		// 过期和不存在的缓存
+		_, err = cache.GetCachedAppraisal(ctx, "b", base.Add(90*time.Minute))
+		assert.ErrorIs(t, err, port.ErrNotFound)
+		_, err = cache.GetCachedAppraisal(ctx, "missing", base)
+		assert.ErrorIs(t, err, port.ErrNotFound)
+
+		// 先删除过期的，再按写入时间只保留最新的
+		deleted, err := cache.PruneAppraisalCache(ctx, base.Add(90*time.Minute), 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+		_, err = cache.GetCachedAppraisal(ctx, "a", base)
+		assert.NoError(t, err)
+		_, err = cache.GetCachedAppraisal(ctx, "c", base)
+		assert.ErrorIs(t, err, port.ErrNotFound)
+	})
+
+	t.Run("LLM 用量账本", func(t *testing.T) {
+		usage, ok := newRepo(t).(port.UsageStore)
+		if !ok {
+			t.Skip("未实现 port.UsageStore")
+		}
+
+		add := func(day, profile, model string, prompt, completion int64, cost float64) {
+			require.NoError(t, usage.AddUsage(ctx, &domain.UsageRecord{
+				Day: day, Profile: profile, Model: model, UpdatedAt: base,
+				TokenUsage: domain.TokenUsage{PromptTokens: prompt, CompletionTokens: completion, CostUSD: cost},
+			}))
+		}
+		add("2025-02-28", "", "gemini-2.5-pro", 1000, 100, 0.5)
+		add("2025-03-01", "rust", "gemini-2.5-pro", 200, 20, 0.25)
+		add("2025-03-01", "", "gemini-2.5-pro", 300, 30, 0.5)
+		// 同一日期、方向和模型的用量累加
+		add("2025-03-01", "", "gemini-2.5-pro", 100, 10, 0.25)
+		add("2025-03-01", "", "gemini-2.5-flash", 50, 5, 0.01)
+
+		records, err := usage.ListUsage(ctx, "2025-03-01")
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, "gemini-2.5-flash", records[0].Model)
+		assert.Equal(t, "", records[1].Profile)
+		assert.Equal(t, "gemini-2.5-pro", records[1].Model)
+		assert.Equal(t, int64(400), records[1].PromptTokens)
+		assert.Equal(t, int64(40), records[1].CompletionTokens)
+		assert.InDelta(t, 0.75, records[1].CostUSD, 1e-9)
+		assert.Equal(t, "rust", records[2].Profile)
+
+		records, err = usage.ListUsage(ctx, "2025-01-01")
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, "2025-02-28", records[0].Day)
+	})
+
+	t.Run("免打扰暂存消息", func(t *testing.T) {
+		deferred, ok := newRepo(t).(port.DeferredStore)
+		if !ok {
+			t.Skip("未实现 port.DeferredStore")
+		}
+
+		save := func(owner, channel, repoID string, event *domain.TrackingEvent) *domain.DeferredMessage {
+			msg := &domain.DeferredMessage{
+				Owner: owner, Route: "night", Channel: channel, RepoID: repoID,
+				Repo: sample(repoID, 80, base), Event: event, CreatedAt: base,
+			}
+			require.NoError(t, deferred.SaveDeferred(ctx, msg))
+			assert.NotZero(t, msg.ID)
+			return msg
+		}
+		first := save("", "feishu", "github-1", nil)
+		save("rust", "feishu", "github-2", nil)
+		save("", "slack", "github-3", &domain.TrackingEvent{Type: domain.EventBreakout, Milestone: 1000})
+
+		// 只返回自己的消息，按暂存顺序
+		msgs, err := deferred.ListDeferred(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, first.ID, msgs[0].ID)
+		assert.Equal(t, "night", msgs[0].Route)
+		assert.Equal(t, "feishu", msgs[0].Channel)
+		require.NotNil(t, msgs[0].Repo)
+		assert.Equal(t, "github-1", msgs[0].Repo.ID)
+		assert.Nil(t, msgs[0].Event)
+		assert.Equal(t, "github-3", msgs[1].RepoID)
+		require.NotNil(t, msgs[1].Event)
+		assert.Equal(t, 1000, msgs[1].Event.Milestone)
+
+		// 补发后删除，重复删除不报错
+		require.NoError(t, deferred.DeleteDeferred(ctx, first.ID))
+		require.NoError(t, deferred.DeleteDeferred(ctx, first.ID))
+		msgs, err = deferred.ListDeferred(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "github-3", msgs[0].RepoID)
+
+		msgs, err = deferred.ListDeferred(ctx, "rust")
+		require.NoError(t, err)
+		assert.Len(t, msgs, 1)
+	})
+
+	t.Run("向量检索", func(t *testing.T) {
+		repo := newRepo(t)
+		vectors, ok := repo.(port.VectorStore)
+		if !ok {
+			t.Skip("未实现 port.VectorStore")
+		}
+
+		saved := make(map[string]*domain.Repo)
+		for i, id := range []string{"github-1", "github-2", "github-3"} {
+			saved[id] = sample(id, 70, base.AddDate(0, 0, i))
+			require.NoError(t, repo.Save(ctx, saved[id]))
+		}
+		hash := func(id string) string {
+			got, err := repo.Get(ctx, id)
+			require.NoError(t, err)
+			return got.EmbeddingHash
+		}
+		assert.Equal(t, saved["github-1"].EmbeddingTextHash(), hash("github-1"))
+
+		missing, err := vectors.ReposNeedingEmbedding(ctx, "model-a", 10)
+		require.NoError(t, err)
+		require.Len(t, missing, 3)
+		// 按创建时间倒序补齐
+		assert.Equal(t, "github-3", missing[0].ID)
+
+		require.NoError(t, vectors.SaveEmbedding(ctx, "github-1", "model-a", hash("github-1"), []float32{1, 0, 0}))
+		require.NoError(t, vectors.SaveEmbedding(ctx, "github-2", "model-a", hash("github-2"), []float32{0.6, 0.8, 0}))
+		require.NoError(t, vectors.SaveEmbedding(ctx, "github-3", "model-b", hash("github-3"), []float32{1, 0, 0}))
+
+		missing, err = vectors.ReposNeedingEmbedding(ctx, "model-a", 10)
+		require.NoError(t, err)
+		require.Len(t, missing, 1)
+		assert.Equal(t, "github-3", missing[0].ID)
+
+		// Star 数变化不影响向量，评价变化后需要重新生成
+		saved["github-1"].Stars += 100
+		require.NoError(t, repo.Save(ctx, saved["github-1"]))
+		saved["github-2"].LLMReview = "rewritten review"
+		require.NoError(t, repo.Save(ctx, saved["github-2"]))
+		missing, err = vectors.ReposNeedingEmbedding(ctx, "model-a", 10)
+		require.NoError(t, err)
+		require.Len(t, missing, 2)
+		assert.Equal(t, "github-3", missing[0].ID)
+		assert.Equal(t, "github-2", missing[1].ID)
+		assert.Equal(t, "rewritten review", missing[1].LLMReview)
+
+		// 按新内容重新生成后不再返回
+		require.NoError(t, vectors.SaveEmbedding(ctx, "github-2", "model-a", missing[1].EmbeddingHash, []float32{0.6, 0.8, 0}))
+		missing, err = vectors.ReposNeedingEmbedding(ctx, "model-a", 10)
+		require.NoError(t, err)
+		require.Len(t, missing, 1)
+
+		// 只比较同一模型的向量，长度不影响余弦相似度
+		nearest, err := vectors.NearestRepos(ctx, "model-a", []float32{2, 0, 0}, 5)
+		require.NoError(t, err)
+		require.Len(t, nearest, 2)
+		assert.Equal(t, "github-1", nearest[0].Repo.ID)
+		assert.InDelta(t, 1.0, nearest[0].Similarity, 1e-4)
+		assert.Equal(t, "github-2", nearest[1].Repo.ID)
+		assert.InDelta(t, 0.6, nearest[1].Similarity, 1e-4)
+		assert.Equal(t, "review of github-1", nearest[0].Repo.LLMReview)
+
+		// 覆盖已有向量
+		require.NoError(t, vectors.SaveEmbedding(ctx, "github-2", "model-a", hash("github-2"), []float32{1, 0, 0.01}))
+		nearest, err = vectors.NearestRepos(ctx, "model-a", []float32{0, 0, 1}, 1)
+		require.NoError(t, err)
+		require.Len(t, nearest, 1)
+		assert.Equal(t, "github-2", nearest[0].Repo.ID)
+	})
+}
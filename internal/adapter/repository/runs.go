@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultRunLimit ListRuns 未指定数量时返回的记录数
+const defaultRunLimit = 20
+
+// CreateRun 新建执行记录并回填 ID
+func (r *GormRepo) CreateRun(ctx context.Context, run *domain.MiningRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// UpdateRun 覆盖执行记录的所有字段
+func (r *GormRepo) UpdateRun(ctx context.Context, run *domain.MiningRun) error {
+	result := r.db.WithContext(ctx).Model(run).Select("*").Updates(run)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: 执行记录 #%d", port.ErrNotFound, run.ID)
+	}
+	return nil
+}
+
+// GetRun 按 ID 获取执行记录，不存在时返回 port.ErrNotFound
+func (r *GormRepo) GetRun(ctx context.Context, id int64) (*domain.MiningRun, error) {
+	var run domain.MiningRun
+	err := r.db.WithContext(ctx).Where("id = ?", id).Take(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: 执行记录 #%d", port.ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns 按开始时间倒序返回最近 limit 条执行记录
+func (r *GormRepo) ListRuns(ctx context.Context, limit int) ([]*domain.MiningRun, error) {
+	if limit <= 0 {
+		limit = defaultRunLimit
+	}
+	var runs []*domain.MiningRun
+	err := r.db.WithContext(ctx).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// SaveCheckpoint 保存检查点，同一次执行同一位置的检查点覆盖已有的
+func (r *GormRepo) SaveCheckpoint(ctx context.Context, checkpoint *domain.RunCheckpoint) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "run_id"}, {Name: "seq"}},
+		DoUpdates: clause.AssignmentColumns([]string{"stage", "complete", "repos", "created_at"}),
+	}).Create(checkpoint).Error
+}
+
+// GetCheckpoints 按阶段位置顺序返回一次执行的所有检查点
+func (r *GormRepo) GetCheckpoints(ctx context.Context, runID int64) ([]*domain.RunCheckpoint, error) {
+	var checkpoints []*domain.RunCheckpoint
+	err := r.db.WithContext(ctx).Where("run_id = ?", runID).Order("seq ASC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// DeleteCheckpoints 删除一次执行的所有检查点
+func (r *GormRepo) DeleteCheckpoints(ctx context.Context, runID int64) error {
+	return r.db.WithContext(ctx).Where("run_id = ?", runID).Delete(&domain.RunCheckpoint{}).Error
+}
+
+// CreateRun 新建执行记录并回填 ID
+func (m *MemoryRepo) CreateRun(ctx context.Context, run *domain.MiningRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextRunID++
+	run.ID = m.nextRunID
+	m.runs = append(m.runs, cloneRun(run))
+	return nil
+}
+
+// UpdateRun 覆盖执行记录的所有字段
+func (m *MemoryRepo) UpdateRun(ctx context.Context, run *domain.MiningRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, existing := range m.runs {
+		if existing.ID == run.ID {
+			m.runs[i] = cloneRun(run)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: 执行记录 #%d", port.ErrNotFound, run.ID)
+}
+
+// GetRun 按 ID 获取执行记录，不存在时返回 port.ErrNotFound
+func (m *MemoryRepo) GetRun(ctx context.Context, id int64) (*domain.MiningRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, run := range m.runs {
+		if run.ID == id {
+			return cloneRun(run), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: 执行记录 #%d", port.ErrNotFound, id)
+}
+
+// ListRuns 按开始时间倒序返回最近 limit 条执行记录
+func (m *MemoryRepo) ListRuns(ctx context.Context, limit int) ([]*domain.MiningRun, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = defaultRunLimit
+	}
+	result := make([]*domain.MiningRun, 0, len(m.runs))
+	for _, run := range m.runs {
+		result = append(result, cloneRun(run))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].StartedAt.Equal(result[j].StartedAt) {
+			return result[i].StartedAt.After(result[j].StartedAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// SaveCheckpoint 保存检查点，同一次执行同一位置的检查点覆盖已有的
+func (m *MemoryRepo) SaveCheckpoint(ctx context.Context, checkpoint *domain.RunCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if checkpoint.CreatedAt.IsZero() {
+		checkpoint.CreatedAt = m.nowFunc()
+	}
+	for i, existing := range m.checkpoints {
+		if existing.RunID == checkpoint.RunID && existing.Seq == checkpoint.Seq {
+			checkpoint.ID = existing.ID
+			m.checkpoints[i] = cloneCheckpoint(checkpoint)
+			return nil
+		}
+	}
+	m.nextID++
+	checkpoint.ID = int64(m.nextID)
+	m.checkpoints = append(m.checkpoints, cloneCheckpoint(checkpoint))
+	return nil
+}
+
+// GetCheckpoints 按阶段位置顺序返回一次执行的所有检查点
+func (m *MemoryRepo) GetCheckpoints(ctx context.Context, runID int64) ([]*domain.RunCheckpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*domain.RunCheckpoint
+	for _, checkpoint := range m.checkpoints {
+		if checkpoint.RunID == runID {
+			result = append(result, cloneCheckpoint(checkpoint))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Seq < result[j].Seq })
+	return result, nil
+}
+
+// DeleteCheckpoints 删除一次执行的所有检查点
+func (m *MemoryRepo) DeleteCheckpoints(ctx context.Context, runID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpoints = slices.DeleteFunc(m.checkpoints, func(c *domain.RunCheckpoint) bool { return c.RunID == runID })
+	return nil
+}
+
+// cloneRun 深拷贝执行记录，避免调用方修改仓库内的数据
+func cloneRun(run *domain.MiningRun) *domain.MiningRun {
+	copied := *run
+	copied.FinishedAt = cloneTime(run.FinishedAt)
+	copied.Stages = make([]*domain.StageReport, len(run.Stages))
+	for i, s := range run.Stages {
+		stage := *s
+		stage.Errors = append([]string(nil), s.Errors...)
+		copied.Stages[i] = &stage
+	}
+	copied.Budgets = make([]*domain.BudgetStatus, len(run.Budgets))
+	for i, b := range run.Budgets {
+		status := *b
+		copied.Budgets[i] = &status
+	}
+	copied.Pushed = append([]string(nil), run.Pushed...)
+	return &copied
+}
+
+// cloneCheckpoint 深拷贝检查点
+func cloneCheckpoint(checkpoint *domain.RunCheckpoint) *domain.RunCheckpoint {
+	copied := *checkpoint
+	copied.Repos = make([]*domain.Repo, len(checkpoint.Repos))
+	for i, repo := range checkpoint.Repos {
+		copied.Repos[i] = cloneRepo(repo)
+	}
+	return &copied
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github-gold-miner/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// searchConfig 全文检索使用的分词配置
+	// simple 不做词干提取，对中英文混排的项目名和描述最稳妥；中文整句不分词，由 trigram 兜底
+	searchConfig = "simple"
+
+	// searchDocument 参与模糊匹配的文本，与 0002_search 迁移中的 trigram 索引表达式保持一致
+	searchDocument = `(coalesce(name, '') || ' ' || coalesce(description, '') || ' ' || coalesce(llm_review, ''))`
+)
+
+// Search 按关键词和过滤条件分页搜索项目
+// Postgres: 全文检索 (websearch_to_tsquery + ts_rank)，没有结果时退化为 pg_trgm 模糊匹配
+// 其他数据库: 每个关键词都必须出现在名称、描述或 LLM 评价中 (LIKE)，按评分排序
+// 指定了 q.Sort 时按该字段排序，不再按相关度排序
+func (r *GormRepo) Search(ctx context.Context, query domain.SearchQuery) ([]*domain.Repo, error) {
+	q := query.Normalize()
+	text := strings.TrimSpace(q.Text)
+	filtered := func() *gorm.DB {
+		return applySearchFilters(r.db.WithContext(ctx).Model(&domain.Repo{}), q)
+	}
+
+	if text == "" {
+		return findPage(filtered().Order(sortOrder(q, "llm_score DESC")), q)
+	}
+	if !r.isPostgres() {
+		return r.searchLike(filtered(), text, q)
+	}
+
+	tsQuery := "websearch_to_tsquery('" + searchConfig + "', ?)"
+	repos, err := findPage(filtered().
+		Where("search_vector @@ "+tsQuery, text).
+		Order(rankOrder(q, "ts_rank(search_vector, "+tsQuery+") DESC, llm_score DESC", text)), q)
+	if err != nil || len(repos) > 0 {
+		return repos, err
+	}
+
+	if q.Offset > 0 {
+		// 翻页到末尾时不应切换到模糊匹配，只有全文检索完全没有结果才退化
+		var matched int64
+		if err := filtered().Where("search_vector @@ "+tsQuery, text).Limit(1).Count(&matched).Error; err != nil {
+			return nil, err
+		}
+		if matched > 0 {
+			return repos, nil
+		}
+	}
+
+	// 模糊兜底：拼写错误 (word_similarity) 和未分词的中文 (ILIKE 子串，同样走 trigram 索引)
+	return findPage(filtered().
+		Where("(? <% "+searchDocument+" OR "+searchDocument+" ILIKE ? ESCAPE '\\')", text, "%"+escapeLike(text)+"%").
+		Order(rankOrder(q, "word_similarity(?, "+searchDocument+") DESC, llm_score DESC", text)), q)
+}
+
+// rankOrder 没有指定排序字段时按相关度表达式排序
+// 注意 GORM 会忽略 Order(clause.Expr)，带参数的表达式必须包装成 clause.OrderBy
+func rankOrder(q domain.SearchQuery, rank string, vars ...interface{}) interface{} {
+	if order := sortOrder(q, ""); order != "" {
+		return order
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: rank, Vars: vars}}
+}
+
+// searchLike 不支持全文检索的数据库使用 LIKE 匹配
+func (r *GormRepo) searchLike(db *gorm.DB, text string, q domain.SearchQuery) ([]*domain.Repo, error) {
+	for _, term := range strings.Fields(text) {
+		pattern := "%" + escapeLike(term) + "%"
+		db = db.Where(`(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR llm_review LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+	return findPage(db.Order(sortOrder(q, "llm_score DESC")), q)
+}
+
+// sortColumns 排序字段对应的列
+var sortColumns = map[domain.SortField]string{
+	domain.SortScore:    "llm_score",
+	domain.SortVelocity: "star_growth_rate",
+	domain.SortStars:    "stars",
+	domain.SortCreated:  "created_at",
+}
+
+// sortOrder 返回 q.Sort 对应的 ORDER BY，同值时按评分从高到低；没有指定排序字段时返回 def
+func sortOrder(q domain.SearchQuery, def string) string {
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		return def
+	}
+	direction := " DESC"
+	if q.Ascending {
+		direction = " ASC"
+	}
+	if column == "llm_score" {
+		return column + direction
+	}
+	return column + direction + ", llm_score DESC"
+}
+
+// applySearchFilters 添加分类、挖矿方向、语言、评分和创建时间过滤条件
+func applySearchFilters(db *gorm.DB, q domain.SearchQuery) *gorm.DB {
+	if len(q.Categories) > 0 {
+		// categories 以 JSON 数组形式存储，按带引号的分类名匹配
+		or := db.Session(&gorm.Session{NewDB: true})
+		for i, c := range q.Categories {
+			pattern := `%"` + escapeLike(c) + `"%`
+			if i == 0 {
+				or = or.Where(`categories LIKE ? ESCAPE '\'`, pattern)
+			} else {
+				or = or.Or(`categories LIKE ? ESCAPE '\'`, pattern)
+			}
+		}
+		db = db.Where(or)
+	}
+	if q.Profile != "" {
+		// profiles 同样是 JSON 数组
+		db = db.Where(`profiles LIKE ? ESCAPE '\'`, `%"`+escapeLike(q.Profile)+`"%`)
+	}
+	if q.Language != "" {
+		db = db.Where("LOWER(language) = LOWER(?)", q.Language)
+	}
+	if q.MinScore > 0 {
+		db = db.Where("llm_score >= ?", q.MinScore)
+	}
+	if q.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *q.CreatedAfter)
+	}
+	if q.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *q.CreatedBefore)
+	}
+	return db
+}
+
+func findPage(db *gorm.DB, q domain.SearchQuery) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	if err := db.Limit(q.Limit).Offset(q.Offset).Find(&repos).Error; err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// escapeLike 转义 LIKE 通配符，用户输入的 % 和 _ 按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
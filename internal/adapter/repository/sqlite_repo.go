@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github-gold-miner/internal/common"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryPath SQLite 内存数据库，进程退出后数据丢失，适合测试
+const memoryPath = ":memory:"
+
+// NewSQLiteRepo 打开 (必要时创建) SQLite 数据库文件并执行未完成的迁移
+// 使用纯 Go 实现的驱动，不依赖 cgo
+func NewSQLiteRepo(path string) (*GormRepo, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return newGormRepo(db)
+}
+
+func openSQLite(path string) (*gorm.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite 数据库路径为空")
+	}
+	if path != memoryPath {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+		}
+	}
+
+	// busy_timeout 避免并发写入时立即报 database is locked
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	if path != memoryPath {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, common.WrapError(common.ErrCodeDBUnavailable, "连接数据库失败", err)
+	}
+
+	// SQLite 同一时间只允许一个写入者；内存数据库每个连接都是独立的库，必须只用一个连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, common.WrapError(common.ErrCodeDBUnavailable, "连接数据库失败", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github-gold-miner/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddUsage 把用量累加到同一日期、挖矿方向和模型的汇总上
+func (r *GormRepo) AddUsage(ctx context.Context, record *domain.UsageRecord) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "profile"}, {Name: "model"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"prompt_tokens":     gorm.Expr("usage_records.prompt_tokens + excluded.prompt_tokens"),
+			"completion_tokens": gorm.Expr("usage_records.completion_tokens + excluded.completion_tokens"),
+			"cost_usd":          gorm.Expr("usage_records.cost_usd + excluded.cost_usd"),
+			"updated_at":        gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(record).Error
+}
+
+// ListUsage 返回 sinceDay (含当天) 之后的用量汇总，按日期、挖矿方向和模型排序
+func (r *GormRepo) ListUsage(ctx context.Context, sinceDay string) ([]*domain.UsageRecord, error) {
+	var records []*domain.UsageRecord
+	err := r.db.WithContext(ctx).Where("day >= ?", sinceDay).
+		Order("day").Order("profile").Order("model").Find(&records).Error
+	return records, err
+}
+
+// AddUsage 把用量累加到同一日期、挖矿方向和模型的汇总上
+func (m *MemoryRepo) AddUsage(ctx context.Context, record *domain.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = m.nowFunc()
+	}
+	for _, existing := range m.usage {
+		if existing.Day == record.Day && existing.Profile == record.Profile && existing.Model == record.Model {
+			existing.Add(record.TokenUsage)
+			existing.UpdatedAt = record.UpdatedAt
+			return nil
+		}
+	}
+	copied := *record
+	m.usage = append(m.usage, &copied)
+	return nil
+}
+
+// ListUsage 返回 sinceDay (含当天) 之后的用量汇总，按日期、挖矿方向和模型排序
+func (m *MemoryRepo) ListUsage(ctx context.Context, sinceDay string) ([]*domain.UsageRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var records []*domain.UsageRecord
+	for _, record := range m.usage {
+		if record.Day >= sinceDay {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Model < b.Model
+	})
+	return records, nil
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/domain"
+
+	"gorm.io/gorm/clause"
+)
+
+// vectorHit 一条向量检索命中
+type vectorHit struct {
+	RepoID     string
+	Similarity float64
+}
+
+// vectorIndex 进程内的暴力检索索引，向量归一化后保存，余弦相似度即点积
+// 几万个项目的规模下全量扫描只需几毫秒，不需要近似索引
+type vectorIndex struct {
+	mu      sync.RWMutex
+	vectors map[string]map[string][]float32 // model -> repoID -> 单位向量
+}
+
+func newVectorIndex() *vectorIndex {
+	return &vectorIndex{vectors: make(map[string]map[string][]float32)}
+}
+
+// loaded 该模型的向量是否已经加载
+func (idx *vectorIndex) loaded(model string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	_, ok := idx.vectors[model]
+	return ok
+}
+
+// load 用完整的向量集合替换该模型的索引
+func (idx *vectorIndex) load(model string, vectors map[string][]float32) {
+	normalized := make(map[string][]float32, len(vectors))
+	for id, v := range vectors {
+		if n := normalize(v); n != nil {
+			normalized[id] = n
+		}
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.vectors[model] = normalized
+}
+
+func (idx *vectorIndex) put(model, repoID string, vector []float32) {
+	n := normalize(vector)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	byID, ok := idx.vectors[model]
+	if !ok {
+		byID = make(map[string][]float32)
+		idx.vectors[model] = byID
+	}
+	if n == nil {
+		delete(byID, repoID)
+		return
+	}
+	byID[repoID] = n
+}
+
+func (idx *vectorIndex) has(model, repoID string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	_, ok := idx.vectors[model][repoID]
+	return ok
+}
+
+// nearest 返回相似度最高的 k 个项目，维度不一致的向量被忽略
+func (idx *vectorIndex) nearest(model string, query []float32, k int) []vectorHit {
+	q := normalize(query)
+	if q == nil || k <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	hits := make([]vectorHit, 0, len(idx.vectors[model]))
+	for id, v := range idx.vectors[model] {
+		if len(v) != len(q) {
+			continue
+		}
+		var dot float64
+		for i := range v {
+			dot += float64(v[i]) * float64(q[i])
+		}
+		hits = append(hits, vectorHit{RepoID: id, Similarity: dot})
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Similarity != hits[j].Similarity {
+			return hits[i].Similarity > hits[j].Similarity
+		}
+		return hits[i].RepoID < hits[j].RepoID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// normalize 返回单位向量，零向量返回 nil
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// SaveEmbedding 保存项目的向量
+// 有 pgvector 时写入 pgvector 列；否则以 float32 小端字节保存，同时更新进程内索引
+func (r *GormRepo) SaveEmbedding(ctx context.Context, repoID, model, contentHash string, vector []float32) error {
+	if len(vector) == 0 {
+		return fmt.Errorf("项目 %s 的向量为空", repoID)
+	}
+	pgvector, err := r.usePgvector(ctx)
+	if err != nil {
+		return err
+	}
+
+	var value interface{} = encodeVector(vector)
+	placeholder := "?"
+	if pgvector {
+		value, placeholder = vectorLiteral(vector), "CAST(? AS vector)"
+	}
+	err = r.db.WithContext(ctx).Exec(
+		`INSERT INTO repo_embeddings (repo_id, model, embedding, content_hash, updated_at) VALUES (?, ?, `+placeholder+`, ?, ?)
+		ON CONFLICT (repo_id, model) DO UPDATE SET embedding = excluded.embedding, content_hash = excluded.content_hash, updated_at = excluded.updated_at`,
+		repoID, model, value, contentHash, time.Now(),
+	).Error
+	if err != nil {
+		return err
+	}
+
+	if !pgvector && r.vectorIndex().loaded(model) {
+		r.vectorIndex().put(model, repoID, vector)
+	}
+	return nil
+}
+
+// ReposNeedingEmbedding 获取还没有该模型向量、或生成向量后内容发生变化的项目
+// 迁移 0011 之前的项目和向量摘要都为空，视为未变化，项目下次保存时重新计算摘要
+func (r *GormRepo) ReposNeedingEmbedding(ctx context.Context, model string, limit int) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	err := r.db.WithContext(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM repo_embeddings e WHERE e.repo_id = repos.id AND e.model = ? AND e.content_hash = repos.embedding_hash)", model).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&repos).Error
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// NearestRepos 按余弦相似度返回最相近的 k 个项目
+// 有 pgvector 时使用 <=> (余弦距离) 在数据库中排序；否则首次查询时把向量加载到进程内索引
+func (r *GormRepo) NearestRepos(ctx context.Context, model string, vector []float32, k int) ([]*domain.ScoredRepo, error) {
+	pgvector, err := r.usePgvector(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []vectorHit
+	if pgvector {
+		literal := vectorLiteral(vector)
+		err := r.db.WithContext(ctx).
+			Table("repo_embeddings").
+			Select("repo_id, 1 - (embedding <=> CAST(? AS vector)) AS similarity", literal).
+			Where("model = ?", model).
+			Order(clause.OrderBy{Expression: clause.Expr{SQL: "embedding <=> CAST(? AS vector)", Vars: []interface{}{literal}}}).
+			Limit(k).
+			Scan(&hits).Error
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := r.loadVectors(ctx, model); err != nil {
+			return nil, err
+		}
+		hits = r.vectorIndex().nearest(model, vector, k)
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.RepoID
+	}
+	var repos []*domain.Repo
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&repos).Error; err != nil {
+		return nil, err
+	}
+	return scoreRepos(hits, repos), nil
+}
+
+// loadVectors 把该模型的全部向量加载到进程内索引，已加载时跳过
+// 索引只在本进程内维护，其他进程写入的向量需要重启后才可见
+func (r *GormRepo) loadVectors(ctx context.Context, model string) error {
+	idx := r.vectorIndex()
+	if idx.loaded(model) {
+		return nil
+	}
+
+	var rows []struct {
+		RepoID    string
+		Embedding []byte
+	}
+	err := r.db.WithContext(ctx).
+		Table("repo_embeddings").
+		Select("repo_id, embedding").
+		Where("model = ?", model).
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("加载向量失败: %w", err)
+	}
+
+	vectors := make(map[string][]float32, len(rows))
+	for _, row := range rows {
+		v, err := decodeVector(row.Embedding)
+		if err != nil {
+			return fmt.Errorf("项目 %s 的向量损坏: %w", row.RepoID, err)
+		}
+		vectors[row.RepoID] = v
+	}
+	idx.load(model, vectors)
+	return nil
+}
+
+func (r *GormRepo) vectorIndex() *vectorIndex {
+	r.vectorsOnce.Do(func() { r.vectors = newVectorIndex() })
+	return r.vectors
+}
+
+// usePgvector 向量是否保存在 pgvector 列中并由数据库检索
+// Postgres 没有 pgvector 扩展时，迁移 0003 把 embedding 建为 bytea 列，与 SQLite 一样在进程内检索
+func (r *GormRepo) usePgvector(ctx context.Context) (bool, error) {
+	if !r.isPostgres() {
+		return false, nil
+	}
+
+	r.pgvectorMu.Lock()
+	defer r.pgvectorMu.Unlock()
+	if r.pgvector == nil {
+		var udt string
+		err := r.db.WithContext(ctx).Raw(
+			`SELECT udt_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'repo_embeddings' AND column_name = 'embedding'`,
+		).Scan(&udt).Error
+		if err != nil {
+			return false, fmt.Errorf("检测 pgvector 失败: %w", err)
+		}
+		enabled := udt == "vector"
+		if !enabled {
+			fmt.Println("🧮 数据库未安装 pgvector，向量检索在进程内完成")
+		}
+		r.pgvector = &enabled
+	}
+	return *r.pgvector, nil
+}
+
+func (r *GormRepo) isPostgres() bool {
+	return r.db.Dialector.Name() == "postgres"
+}
+
+// scoreRepos 按命中顺序组装结果，检索后被删除的项目直接跳过
+func scoreRepos(hits []vectorHit, repos []*domain.Repo) []*domain.ScoredRepo {
+	byID := make(map[string]*domain.Repo, len(repos))
+	for _, repo := range repos {
+		byID[repo.ID] = repo
+	}
+	result := make([]*domain.ScoredRepo, 0, len(hits))
+	for _, h := range hits {
+		if repo, ok := byID[h.RepoID]; ok {
+			result = append(result, &domain.ScoredRepo{Repo: repo, Similarity: h.Similarity})
+		}
+	}
+	return result
+}
+
+// vectorLiteral 转换为 pgvector 的文本格式: [0.1,0.2,...]
+func vectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, x := range v {
+		parts[i] = strconv.FormatFloat(float64(x), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("长度 %d 不是 4 的倍数", len(b))
+	}
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresRepo_SaveEmbedding(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	expectEmbeddingColumn(mock, "vector")
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO repo_embeddings (repo_id, model, embedding, content_hash, updated_at) VALUES ($1, $2, CAST($3 AS vector), $4, $5)`)).
+		WithArgs("github-1", "text-embedding-004", "[0.5,-1,0.25]", "abc", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &GormRepo{db: gormDB}
+	require.NoError(t, repo.SaveEmbedding(context.Background(), "github-1", "text-embedding-004", "abc", []float32{0.5, -1, 0.25}))
+	assert.Error(t, repo.SaveEmbedding(context.Background(), "github-1", "text-embedding-004", "abc", nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_NearestRepos(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	expectEmbeddingColumn(mock, "vector")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT repo_id, 1 - (embedding <=> CAST($1 AS vector)) AS similarity FROM "repo_embeddings" WHERE model = $2 ORDER BY embedding <=> CAST($3 AS vector) LIMIT $4`)).
+		WithArgs("[1,0]", "m", "[1,0]", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"repo_id", "similarity"}).
+			AddRow("github-2", 0.9).
+			AddRow("github-1", 0.5))
+	// 数据库返回的顺序与相似度无关，结果按命中顺序重新排列
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos" WHERE id IN ($1,$2)`)).
+		WithArgs("github-2", "github-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow("github-1", "a/one").
+			AddRow("github-2", "a/two"))
+
+	repo := &GormRepo{db: gormDB}
+	result, err := repo.NearestRepos(context.Background(), "m", []float32{1, 0}, 2)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "github-2", result[0].Repo.ID)
+	assert.Equal(t, 0.9, result[0].Similarity)
+	assert.Equal(t, "github-1", result[1].Repo.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_WithoutPgvector(t *testing.T) {
+	gormDB, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 没有 pgvector 时 embedding 为 bytea 列，只检测一次列类型
+	expectEmbeddingColumn(mock, "bytea")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT repo_id, embedding FROM "repo_embeddings" WHERE model = $1`)).
+		WithArgs("m").
+		WillReturnRows(sqlmock.NewRows([]string{"repo_id", "embedding"}).
+			AddRow("github-1", encodeVector([]float32{1, 0})).
+			AddRow("github-2", encodeVector([]float32{0, 1})))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos" WHERE id IN ($1)`)).
+		WithArgs("github-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("github-2", "a/two"))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO repo_embeddings (repo_id, model, embedding, content_hash, updated_at) VALUES ($1, $2, $3, $4, $5)`)).
+		WithArgs("github-3", "m", encodeVector([]float32{1, 2}), "abc", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "repos" WHERE id IN ($1)`)).
+		WithArgs("github-3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("github-3", "a/three"))
+
+	repo := &GormRepo{db: gormDB}
+	ctx := context.Background()
+	result, err := repo.NearestRepos(ctx, "m", []float32{0, 1}, 1)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "github-2", result[0].Repo.ID)
+
+	// 新写入的向量同时进入进程内索引
+	require.NoError(t, repo.SaveEmbedding(ctx, "github-3", "m", "abc", []float32{1, 2}))
+	result, err = repo.NearestRepos(ctx, "m", []float32{1, 2}, 1)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "github-3", result[0].Repo.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectEmbeddingColumn 模拟检测 repo_embeddings.embedding 的列类型
+func expectEmbeddingColumn(mock sqlmock.Sqlmock, udt string) {
+	mock.ExpectQuery(`SELECT udt_name FROM information_schema.columns`).
+		WillReturnRows(sqlmock.NewRows([]string{"udt_name"}).AddRow(udt))
+}
+
+func TestVectorIndex(t *testing.T) {
+	idx := newVectorIndex()
+	idx.put("m", "a", []float32{1, 0})
+	idx.put("m", "b", []float32{1, 1})
+	idx.put("m", "wrong-dim", []float32{1, 0, 0})
+	idx.put("m", "zero", []float32{0, 0})
+
+	hits := idx.nearest("m", []float32{3, 0}, 10)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "a", hits[0].RepoID)
+	assert.InDelta(t, 1.0, hits[0].Similarity, 1e-6)
+	assert.InDelta(t, 0.7071, hits[1].Similarity, 1e-4)
+
+	assert.Empty(t, idx.nearest("m", []float32{0, 0}, 10))
+	assert.Empty(t, idx.nearest("other", []float32{1, 0}, 10))
+	assert.Len(t, idx.nearest("m", []float32{1, 0}, 1), 1)
+}
+
+func TestEncodeVector(t *testing.T) {
+	v := []float32{0.5, -1.25, 3e-8}
+	decoded, err := decodeVector(encodeVector(v))
+	require.NoError(t, err)
+	assert.Equal(t, v, decoded)
+
+	_, err = decodeVector([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultChannel 未在路由中显式配置时使用的渠道名 (即 FEISHU_WEBHOOK)
+const DefaultChannel = "default"
+
+// Config 路由规则配置
+type Config struct {
+	// Channels 渠道名 -> Webhook 地址，值支持 ${ENV} 形式引用环境变量
+	Channels map[string]string `yaml:"channels" json:"channels"`
+	// Routes 按顺序匹配的路由规则，一个项目可以命中多条路由
+	Routes []Route `yaml:"routes" json:"routes"`
+	// AlertChannels 接收告警消息的渠道，为空时使用 default
+	AlertChannels []string `yaml:"alert_channels" json:"alert_channels"`
+}
+
+// Route 一条路由规则：满足 Match 的项目会被推送到 Channels
+type Route struct {
+	Name     string   `yaml:"name" json:"name"`
+	Match    Match    `yaml:"match" json:"match"`
+	Channels []string `yaml:"channels" json:"channels"`
+	// MinScore 该路由的最低评分，低于此分数的项目不推送
+	MinScore int `yaml:"min_score" json:"min_score"`
+	// QuietHours 免打扰时段，期间的消息会暂存到时段结束后再发送
+	QuietHours *QuietHours `yaml:"quiet_hours" json:"quiet_hours,omitempty"`
+	// Stop 命中后不再继续匹配后续路由
+	Stop bool `yaml:"stop" json:"stop"`
+}
+
+// Match 路由匹配条件，未设置的条件视为不限制，多个条件之间为"且"
+type Match struct {
+	Categories  []string `yaml:"categories" json:"categories"`     // 命中任意一个分类即可
+	Languages   []string `yaml:"languages" json:"languages"`       // 命中任意一个语言即可 (不区分大小写)
+	ScoreMin    int      `yaml:"score_min" json:"score_min"`       // 评分区间下限 (含)
+	ScoreMax    int      `yaml:"score_max" json:"score_max"`       // 评分区间上限 (含)，0 表示不限
+	VelocityMin float64  `yaml:"velocity_min" json:"velocity_min"` // Star 增速下限 (stars/天)
+	VelocityMax float64  `yaml:"velocity_max" json:"velocity_max"` // Star 增速上限，0 表示不限
+}
+
+// QuietHours 免打扰时段，格式 HH:MM，允许跨越午夜 (如 22:00-08:00)
+type QuietHours struct {
+	Start    string `yaml:"start" json:"start"`
+	End      string `yaml:"end" json:"end"`
+	Timezone string `yaml:"timezone" json:"timezone"`
+
+	start, end int // 自午夜起的分钟数
+	loc        *time.Location
+}
+
+// LoadConfig 从 YAML 文件读取路由配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取路由配置失败: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析路由配置失败: %w", err)
+	}
+	for name, url := range cfg.Channels {
+		cfg.Channels[name] = os.ExpandEnv(url)
+	}
+	return &cfg, nil
+}
+
+// Validate 检查配置的完整性，available 为已注册的渠道名
+func (c *Config) Validate(available map[string]bool) error {
+	var problems []string
+	names := make(map[string]bool)
+
+	for i := range c.Routes {
+		route := &c.Routes[i]
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route-%d", i+1)
+		}
+		if names[route.Name] {
+			problems = append(problems, fmt.Sprintf("路由名重复: %s", route.Name))
+		}
+		names[route.Name] = true
+
+		if len(route.Channels) == 0 {
+			problems = append(problems, fmt.Sprintf("路由 %s 未配置渠道", route.Name))
+		}
+		for _, ch := range route.Channels {
+			if !available[ch] {
+				problems = append(problems, fmt.Sprintf("路由 %s 引用了未知渠道 %s", route.Name, ch))
+			}
+		}
+		if route.Match.ScoreMax > 0 && route.Match.ScoreMax < route.Match.ScoreMin {
+			problems = append(problems, fmt.Sprintf("路由 %s 的评分区间无效", route.Name))
+		}
+		if route.QuietHours != nil {
+			if err := route.QuietHours.parse(); err != nil {
+				problems = append(problems, fmt.Sprintf("路由 %s 的免打扰时段无效: %v", route.Name, err))
+			}
+		}
+	}
+	for _, ch := range c.AlertChannels {
+		if !available[ch] {
+			problems = append(problems, fmt.Sprintf("告警引用了未知渠道 %s", ch))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("路由配置无效: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (q *QuietHours) parse() error {
+	var err error
+	if q.start, err = parseClock(q.Start); err != nil {
+		return err
+	}
+	if q.end, err = parseClock(q.End); err != nil {
+		return err
+	}
+	q.loc = time.Local
+	if q.Timezone != "" {
+		if q.loc, err = time.LoadLocation(q.Timezone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains 判断某一时刻是否处于免打扰时段
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil || q.loc == nil || q.start == q.end {
+		return false
+	}
+	local := t.In(q.loc)
+	minute := local.Hour()*60 + local.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	// 跨午夜，如 22:00-08:00
+	return minute >= q.start || minute < q.end
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
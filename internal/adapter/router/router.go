@@ -0,0 +1,424 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// Router 实现了 port.Notifier 接口，按规则把项目分发到不同渠道
+type Router struct {
+	routes        []Route
+	channels      map[string]port.Notifier
+	alertChannels []string
+	nowFunc       func() time.Time
+
+	// 免打扰期间暂存的消息：设置了 store 时保存在数据库中，否则只保存在内存里
+	store  port.DeferredStore
+	owner  string
+	flush  sync.Mutex // 串行补发，避免同一条消息被并发补发两次
+	mu     sync.Mutex
+	nextID int64
+	queue  []*domain.DeferredMessage
+}
+
+// New 根据配置创建路由器，channels 为渠道名到通知器的映射
+func New(cfg *Config, channels map[string]port.Notifier) (*Router, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	available := make(map[string]bool, len(channels))
+	for name := range channels {
+		available[name] = true
+	}
+	if err := cfg.Validate(available); err != nil {
+		return nil, err
+	}
+
+	routes := cfg.Routes
+	if len(routes) == 0 {
+		// 没有任何路由时，所有项目都推送到默认渠道，与未启用路由时的行为一致
+		if _, ok := channels[DefaultChannel]; !ok {
+			return nil, fmt.Errorf("未配置路由且缺少 %s 渠道", DefaultChannel)
+		}
+		routes = []Route{{Name: DefaultChannel, Channels: []string{DefaultChannel}}}
+	}
+
+	alertChannels := cfg.AlertChannels
+	if len(alertChannels) == 0 {
+		if _, ok := channels[DefaultChannel]; ok {
+			alertChannels = []string{DefaultChannel}
+		}
+	}
+
+	return &Router{
+		routes:        routes,
+		channels:      channels,
+		alertChannels: alertChannels,
+		nowFunc:       time.Now,
+	}, nil
+}
+
+// SetStore 把免打扰期间暂存的消息保存到 store，进程重启或单次运行退出后，下一轮仍会补发
+// owner 区分共用同一数据库的多个路由器 (如不同挖矿方向)，各自只补发自己暂存的消息
+func (r *Router) SetStore(store port.DeferredStore, owner string) {
+	r.store = store
+	r.owner = owner
+}
+
+// CheckMinScore 检查路由的评分条件与挖矿的最低评分 minScore 是否冲突
+// 低于 minScore 的项目在挖矿的筛选阶段就被丢弃，不会到达路由，低于它的 min_score 永远不会生效
+func (r *Router) CheckMinScore(minScore int) error {
+	var problems []string
+	for _, route := range r.routes {
+		if route.MinScore > 0 && route.MinScore < minScore {
+			problems = append(problems, fmt.Sprintf("路由 %s 的 min_score %d 低于挖矿的最低评分 %d", route.Name, route.MinScore, minScore))
+		}
+		if route.Match.ScoreMax > 0 && route.Match.ScoreMax < minScore {
+			problems = append(problems, fmt.Sprintf("路由 %s 的 score_max %d 低于挖矿的最低评分 %d，永远不会命中", route.Name, route.Match.ScoreMax, minScore))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("路由配置无效: %s (请调低 mining.min_score)", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Match 返回项目命中的所有路由 (已考虑路由的最低评分)
+func (r *Router) Match(repo *domain.Repo) []Route {
+	var matched []Route
+	for _, route := range r.routes {
+		if !route.Match.matches(repo) || repo.LLMScore < route.MinScore {
+			continue
+		}
+		matched = append(matched, route)
+		if route.Stop {
+			break
+		}
+	}
+	return matched
+}
+
+// Notify 把项目推送到命中的所有渠道，同一渠道只推送一次
+// 部分渠道失败时返回包装了 port.ErrPartiallyDelivered 的错误；暂存的消息由每轮挖矿开始时的 Flush 补发
+func (r *Router) Notify(ctx context.Context, repo *domain.Repo) error {
+	routes := r.Match(repo)
+	if len(routes) == 0 {
+		log.Printf("[Router] 项目 %s 未命中任何路由，跳过推送", repo.Name)
+		return nil
+	}
+
+	now := r.nowFunc()
+	sent := make(map[string]bool)
+	delivered := 0
+	var errs []error
+	for _, route := range routes {
+		for _, ch := range route.Channels {
+			if sent[ch] {
+				continue
+			}
+			sent[ch] = true
+
+			if route.QuietHours.Contains(now) {
+				// 暂存失败时返回错误，避免项目被标记为已推送后消息丢失
+				if err := r.enqueue(ctx, route.Name, ch, repo, nil); err != nil {
+					errs = append(errs, fmt.Errorf("暂存到渠道 %s 失败: %w", ch, err))
+					continue
+				}
+				log.Printf("[Router] 路由 %s 处于免打扰时段，项目 %s 暂存待发", route.Name, repo.Name)
+				delivered++
+				continue
+			}
+			if err := r.channels[ch].Notify(ctx, repo); err != nil {
+				errs = append(errs, fmt.Errorf("渠道 %s: %w", ch, err))
+				continue
+			}
+			delivered++
+		}
+	}
+	return deliveryError(delivered, errs)
+}
+
+// NotifyDigest 按渠道分组发送汇总消息，不支持汇总的渠道逐条发送
+func (r *Router) NotifyDigest(ctx context.Context, title string, repos []*domain.Repo) error {
+	now := r.nowFunc()
+	perChannel := make(map[string][]*domain.Repo)
+	var errs []error
+	for _, repo := range repos {
+		sent := make(map[string]bool)
+		for _, route := range r.Match(repo) {
+			for _, ch := range route.Channels {
+				if sent[ch] {
+					continue
+				}
+				sent[ch] = true
+				if route.QuietHours.Contains(now) {
+					if err := r.enqueue(ctx, route.Name, ch, repo, nil); err != nil {
+						errs = append(errs, fmt.Errorf("暂存到渠道 %s 失败: %w", ch, err))
+					}
+					continue
+				}
+				perChannel[ch] = append(perChannel[ch], repo)
+			}
+		}
+	}
+
+	for _, ch := range sortedKeys(perChannel) {
+		notifier := r.channels[ch]
+		if digest, ok := notifier.(port.DigestNotifier); ok {
+			if err := digest.NotifyDigest(ctx, title, perChannel[ch]); err != nil {
+				errs = append(errs, fmt.Errorf("渠道 %s: %w", ch, err))
+			}
+			continue
+		}
+		for _, repo := range perChannel[ch] {
+			if err := notifier.Notify(ctx, repo); err != nil {
+				errs = append(errs, fmt.Errorf("渠道 %s: %w", ch, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyUpdate 追踪更新沿用项目原来命中的路由，保证爆发/撤回消息发到当初推送的群
+func (r *Router) NotifyUpdate(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error {
+	now := r.nowFunc()
+	sent := make(map[string]bool)
+	delivered := 0
+	var errs []error
+	for _, route := range r.Match(repo) {
+		for _, ch := range route.Channels {
+			if sent[ch] {
+				continue
+			}
+			sent[ch] = true
+
+			if route.QuietHours.Contains(now) {
+				if err := r.enqueue(ctx, route.Name, ch, repo, event); err != nil {
+					errs = append(errs, fmt.Errorf("暂存到渠道 %s 失败: %w", ch, err))
+					continue
+				}
+				delivered++
+				continue
+			}
+			if err := r.deliver(ctx, ch, repo, event); err != nil {
+				errs = append(errs, fmt.Errorf("渠道 %s: %w", ch, err))
+				continue
+			}
+			delivered++
+		}
+	}
+	return deliveryError(delivered, errs)
+}
+
+// NotifyAlert 告警不受路由规则和免打扰限制，直接发送到告警渠道
+func (r *Router) NotifyAlert(ctx context.Context, level, title, message string) error {
+	var errs []error
+	delivered := false
+	for _, ch := range r.alertChannels {
+		alerter, ok := r.channels[ch].(port.AlertNotifier)
+		if !ok {
+			continue
+		}
+		delivered = true
+		if err := alerter.NotifyAlert(ctx, level, title, message); err != nil {
+			errs = append(errs, fmt.Errorf("渠道 %s: %w", ch, err))
+		}
+	}
+	if !delivered {
+		log.Printf("[Router] 没有可用的告警渠道: [%s] %s", level, title)
+	}
+	return errors.Join(errs...)
+}
+
+// Flush 补发免打扰时段已经结束的暂存消息，发送失败的消息保留到下次
+func (r *Router) Flush(ctx context.Context) error {
+	r.flush.Lock()
+	defer r.flush.Unlock()
+
+	pending, err := r.pending(ctx)
+	if err != nil {
+		return fmt.Errorf("读取暂存消息失败: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	now := r.nowFunc()
+	quiet := make(map[string]bool)
+	for _, route := range r.routes {
+		quiet[route.Name] = route.QuietHours.Contains(now)
+	}
+
+	var errs []error
+	for _, msg := range pending {
+		if quiet[msg.Route] {
+			continue
+		}
+		if _, ok := r.channels[msg.Channel]; !ok {
+			// 渠道已从配置中移除，消息无处可发
+			log.Printf("[Router] 渠道 %s 已不存在，丢弃暂存的项目 %s", msg.Channel, msg.Repo.Name)
+		} else if err := r.deliver(ctx, msg.Channel, msg.Repo, msg.Event); err != nil {
+			errs = append(errs, fmt.Errorf("补发 %s 到渠道 %s 失败: %w", msg.Repo.Name, msg.Channel, err))
+			continue
+		} else {
+			log.Printf("[Router] 已补发免打扰期间的项目 %s 到渠道 %s", msg.Repo.Name, msg.Channel)
+		}
+		if err := r.remove(ctx, msg.ID); err != nil {
+			errs = append(errs, fmt.Errorf("删除已补发的消息失败: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Pending 返回暂存待发的消息数量
+func (r *Router) Pending(ctx context.Context) (int, error) {
+	pending, err := r.pending(ctx)
+	return len(pending), err
+}
+
+// deliver 发送单条消息，event 非空时发送追踪更新；渠道不支持更新消息时跳过
+func (r *Router) deliver(ctx context.Context, channel string, repo *domain.Repo, event *domain.TrackingEvent) error {
+	notifier := r.channels[channel]
+	if event == nil {
+		return notifier.Notify(ctx, repo)
+	}
+	updater, ok := notifier.(port.UpdateNotifier)
+	if !ok {
+		return nil
+	}
+	return updater.NotifyUpdate(ctx, repo, event)
+}
+
+// enqueue 暂存一条消息，同一渠道、项目和事件只暂存一次
+func (r *Router) enqueue(ctx context.Context, route, channel string, repo *domain.Repo, event *domain.TrackingEvent) error {
+	r.flush.Lock()
+	defer r.flush.Unlock()
+
+	pending, err := r.pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if p.Channel == channel && p.RepoID == repo.ID && sameEvent(p.Event, event) {
+			return nil
+		}
+	}
+
+	msg := &domain.DeferredMessage{
+		Owner:     r.owner,
+		Route:     route,
+		Channel:   channel,
+		RepoID:    repo.ID,
+		Repo:      repo,
+		Event:     event,
+		CreatedAt: r.nowFunc(),
+	}
+	if r.store != nil {
+		return r.store.SaveDeferred(ctx, msg)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	msg.ID = r.nextID
+	r.queue = append(r.queue, msg)
+	return nil
+}
+
+// pending 按暂存顺序返回所有暂存消息
+func (r *Router) pending(ctx context.Context) ([]*domain.DeferredMessage, error) {
+	if r.store != nil {
+		return r.store.ListDeferred(ctx, r.owner)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*domain.DeferredMessage(nil), r.queue...), nil
+}
+
+// remove 删除已经补发的消息
+func (r *Router) remove(ctx context.Context, id int64) error {
+	if r.store != nil {
+		return r.store.DeleteDeferred(ctx, id)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, msg := range r.queue {
+		if msg.ID == id {
+			r.queue = append(r.queue[:i], r.queue[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// deliveryError 汇总各渠道的错误：至少一个渠道已送达 (或已暂存) 时包装 port.ErrPartiallyDelivered
+func deliveryError(delivered int, errs []error) error {
+	err := errors.Join(errs...)
+	if err == nil || delivered == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (%d 个渠道已送达): %w", port.ErrPartiallyDelivered, delivered, err)
+}
+
+// sameEvent 判断两条暂存消息是否为同一个追踪事件 (都为空表示普通推送)
+func sameEvent(a, b *domain.TrackingEvent) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && a.Milestone == b.Milestone
+}
+
+// matches 判断项目是否满足匹配条件
+func (m Match) matches(repo *domain.Repo) bool {
+	if len(m.Categories) > 0 {
+		hit := false
+		for _, c := range m.Categories {
+			if repo.HasCategory(c) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+
+	if len(m.Languages) > 0 {
+		hit := false
+		for _, lang := range m.Languages {
+			if strings.EqualFold(lang, repo.Language) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+
+	if repo.LLMScore < m.ScoreMin || (m.ScoreMax > 0 && repo.LLMScore > m.ScoreMax) {
+		return false
+	}
+	if repo.StarGrowthRate < m.VelocityMin || (m.VelocityMax > 0 && repo.StarGrowthRate > m.VelocityMax) {
+		return false
+	}
+	return true
+}
+
+func sortedKeys(m map[string][]*domain.Repo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,419 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier 记录收到的消息，用于断言路由结果
+type recordingNotifier struct {
+	mu      sync.Mutex
+	repos   []string
+	digests [][]string
+	alerts  []string
+	updates []string
+	err     error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, repo *domain.Repo) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return n.err
+	}
+	n.repos = append(n.repos, repo.Name)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyDigest(ctx context.Context, title string, repos []*domain.Repo) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+	}
+	n.digests = append(n.digests, names)
+	return nil
+}
+
+func (n *recordingNotifier) NotifyUpdate(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.updates = append(n.updates, repo.Name+":"+string(event.Type))
+	return nil
+}
+
+func (n *recordingNotifier) NotifyAlert(ctx context.Context, level, title, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, level+":"+title)
+	return nil
+}
+
+var (
+	ideRepo = &domain.Repo{ID: "github-1", Name: "test/ide-plugin", Language: "TypeScript", LLMScore: 82, StarGrowthRate: 30, Categories: []string{domain.CategoryIDEExtension}}
+	cliRepo = &domain.Repo{ID: "github-2", Name: "test/cli-agent", Language: "Go", LLMScore: 65, StarGrowthRate: 120, Categories: []string{domain.CategoryCLIAgent}}
+	libRepo = &domain.Repo{ID: "github-3", Name: "test/other-lib", Language: "Python", LLMScore: 55, StarGrowthRate: 5, Categories: []string{domain.CategoryOther}}
+)
+
+func newTestRouter(t *testing.T, cfg *Config) (*Router, map[string]*recordingNotifier) {
+	recorders := map[string]*recordingNotifier{
+		DefaultChannel: {},
+		"ide-team":     {},
+		"infra-team":   {},
+	}
+	channels := make(map[string]port.Notifier)
+	for name, rec := range recorders {
+		channels[name] = rec
+	}
+	r, err := New(cfg, channels)
+	require.NoError(t, err)
+	return r, recorders
+}
+
+func TestRouter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    Route
+		repo     *domain.Repo
+		expected bool
+	}{
+		{name: "分类命中", route: Route{Match: Match{Categories: []string{domain.CategoryIDEExtension}}}, repo: ideRepo, expected: true},
+		{name: "分类未命中", route: Route{Match: Match{Categories: []string{domain.CategoryIDEExtension}}}, repo: cliRepo, expected: false},
+		{name: "语言不区分大小写", route: Route{Match: Match{Languages: []string{"go"}}}, repo: cliRepo, expected: true},
+		{name: "评分区间内", route: Route{Match: Match{ScoreMin: 60, ScoreMax: 70}}, repo: cliRepo, expected: true},
+		{name: "评分超出区间上限", route: Route{Match: Match{ScoreMin: 60, ScoreMax: 70}}, repo: ideRepo, expected: false},
+		{name: "增速下限", route: Route{Match: Match{VelocityMin: 100}}, repo: cliRepo, expected: true},
+		{name: "增速不足", route: Route{Match: Match{VelocityMin: 100}}, repo: ideRepo, expected: false},
+		{name: "路由最低评分", route: Route{MinScore: 70}, repo: cliRepo, expected: false},
+		{name: "空条件匹配所有项目", route: Route{}, repo: libRepo, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.route.Name = "r"
+			tt.route.Channels = []string{DefaultChannel}
+			r, _ := newTestRouter(t, &Config{Routes: []Route{tt.route}})
+			assert.Equal(t, tt.expected, len(r.Match(tt.repo)) == 1)
+		})
+	}
+}
+
+func TestRouter_Notify(t *testing.T) {
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Match: Match{Categories: []string{domain.CategoryIDEExtension}}, Channels: []string{"ide-team"}, MinScore: 70},
+		{Name: "infra", Match: Match{Categories: []string{domain.CategoryCLIAgent, domain.CategoryLLMInfra}}, Channels: []string{"infra-team", DefaultChannel}},
+		{Name: "all", Channels: []string{DefaultChannel}},
+	}}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	require.NoError(t, r.Notify(ctx, cliRepo))
+	require.NoError(t, r.Notify(ctx, libRepo))
+
+	assert.Equal(t, []string{"test/ide-plugin"}, rec["ide-team"].repos)
+	assert.Equal(t, []string{"test/cli-agent"}, rec["infra-team"].repos)
+	// 同一个渠道即使被多条路由命中也只推送一次
+	assert.Equal(t, []string{"test/ide-plugin", "test/cli-agent", "test/other-lib"}, rec[DefaultChannel].repos)
+}
+
+func TestRouter_StopAndNoMatch(t *testing.T) {
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Match: Match{Categories: []string{domain.CategoryIDEExtension}}, Channels: []string{"ide-team"}, Stop: true},
+		{Name: "high", Match: Match{ScoreMin: 80}, Channels: []string{DefaultChannel}},
+	}}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	require.NoError(t, r.Notify(ctx, libRepo)) // 不命中任何路由，不视为错误
+
+	assert.Equal(t, []string{"test/ide-plugin"}, rec["ide-team"].repos)
+	assert.Empty(t, rec[DefaultChannel].repos)
+}
+
+func TestRouter_QuietHours(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Channels: []string{"ide-team"}, QuietHours: &QuietHours{Start: "22:00", End: "08:00", Timezone: "Asia/Shanghai"}},
+		{Name: "all", Channels: []string{DefaultChannel}},
+	}}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	now := time.Date(2025, 12, 27, 23, 30, 0, 0, shanghai)
+	r.nowFunc = func() time.Time { return now }
+
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	assert.Empty(t, rec["ide-team"].repos)
+	assert.Equal(t, []string{"test/ide-plugin"}, rec[DefaultChannel].repos)
+	assertPending(t, r, 1)
+
+	// 同一项目重复推送只暂存一次
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	assertPending(t, r, 1)
+
+	// 仍在免打扰时段内，Flush 不发送
+	now = time.Date(2025, 12, 28, 7, 59, 0, 0, shanghai)
+	require.NoError(t, r.Flush(ctx))
+	assertPending(t, r, 1)
+
+	// 免打扰结束后补发
+	now = time.Date(2025, 12, 28, 8, 0, 0, 0, shanghai)
+	require.NoError(t, r.Flush(ctx))
+	assertPending(t, r, 0)
+	assert.Equal(t, []string{"test/ide-plugin"}, rec["ide-team"].repos)
+}
+
+func TestRouter_QuietHoursPersisted(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Channels: []string{"ide-team"}, QuietHours: &QuietHours{Start: "22:00", End: "08:00", Timezone: "Asia/Shanghai"}},
+	}}
+	store := repository.NewMemoryRepo()
+	ctx := context.Background()
+
+	now := time.Date(2025, 12, 27, 23, 30, 0, 0, shanghai)
+	r, rec := newTestRouter(t, cfg)
+	r.SetStore(store, "")
+	r.nowFunc = func() time.Time { return now }
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	require.NoError(t, r.NotifyUpdate(ctx, ideRepo, &domain.TrackingEvent{Type: domain.EventBreakout, RepoID: ideRepo.ID, Milestone: 1000}))
+	assert.Empty(t, rec["ide-team"].repos)
+
+	// 其他挖矿方向的路由器不会补发这些消息
+	other, otherRec := newTestRouter(t, cfg)
+	other.SetStore(store, "rust")
+	other.nowFunc = func() time.Time { return time.Date(2025, 12, 28, 9, 0, 0, 0, shanghai) }
+	require.NoError(t, other.Flush(ctx))
+	assert.Empty(t, otherRec["ide-team"].repos)
+
+	// 模拟进程重启：新的路由器从数据库中读取暂存消息并补发
+	now = time.Date(2025, 12, 28, 8, 0, 0, 0, shanghai)
+	restarted, restartedRec := newTestRouter(t, cfg)
+	restarted.SetStore(store, "")
+	restarted.nowFunc = func() time.Time { return now }
+	assertPending(t, restarted, 2)
+	require.NoError(t, restarted.Flush(ctx))
+	assert.Equal(t, []string{"test/ide-plugin"}, restartedRec["ide-team"].repos)
+	assert.Equal(t, []string{"test/ide-plugin:breakout"}, restartedRec["ide-team"].updates)
+	assertPending(t, restarted, 0)
+}
+
+func TestRouter_QuietHoursStoreError(t *testing.T) {
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Channels: []string{"ide-team"}, QuietHours: &QuietHours{Start: "00:00", End: "23:59"}},
+	}}
+	r, _ := newTestRouter(t, cfg)
+	r.SetStore(failingStore{}, "")
+	r.nowFunc = func() time.Time { return time.Date(2025, 12, 27, 12, 0, 0, 0, time.UTC) }
+
+	// 暂存失败必须返回错误，否则项目会被标记为已推送而消息丢失
+	assert.Error(t, r.Notify(context.Background(), ideRepo))
+}
+
+// failingStore 总是失败的暂存消息存储
+type failingStore struct{}
+
+func (failingStore) SaveDeferred(ctx context.Context, msg *domain.DeferredMessage) error {
+	return errors.New("database is locked")
+}
+
+func (failingStore) ListDeferred(ctx context.Context, owner string) ([]*domain.DeferredMessage, error) {
+	return nil, nil
+}
+
+func (failingStore) DeleteDeferred(ctx context.Context, id int64) error {
+	return nil
+}
+
+func assertPending(t *testing.T, r *Router, want int) {
+	t.Helper()
+	n, err := r.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, n)
+}
+
+func TestRouter_CheckMinScore(t *testing.T) {
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Channels: []string{"ide-team"}, MinScore: 70},
+		{Name: "all", Channels: []string{DefaultChannel}},
+	}}
+	r, _ := newTestRouter(t, cfg)
+	assert.NoError(t, r.CheckMinScore(50))
+	assert.NoError(t, r.CheckMinScore(70))
+
+	// 低于挖矿最低评分的项目不会到达路由
+	err := r.CheckMinScore(80)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "路由 ide 的 min_score 70")
+
+	cfg = &Config{Routes: []Route{{Name: "low", Match: Match{ScoreMax: 40}, Channels: []string{DefaultChannel}}}}
+	r, _ = newTestRouter(t, cfg)
+	assert.Error(t, r.CheckMinScore(50))
+}
+
+func TestRouter_NotifyDigestAndAlert(t *testing.T) {
+	cfg := &Config{
+		Routes: []Route{
+			{Name: "ide", Match: Match{Categories: []string{domain.CategoryIDEExtension}}, Channels: []string{"ide-team"}},
+			{Name: "all", Channels: []string{DefaultChannel}},
+		},
+		AlertChannels: []string{"infra-team"},
+	}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	require.NoError(t, r.NotifyDigest(ctx, "本周精选", []*domain.Repo{ideRepo, cliRepo}))
+	assert.Equal(t, [][]string{{"test/ide-plugin"}}, rec["ide-team"].digests)
+	assert.Equal(t, [][]string{{"test/ide-plugin", "test/cli-agent"}}, rec[DefaultChannel].digests)
+
+	require.NoError(t, r.NotifyAlert(ctx, "critical", "Webhook 失效", "403"))
+	assert.Equal(t, []string{"critical:Webhook 失效"}, rec["infra-team"].alerts)
+	assert.Empty(t, rec[DefaultChannel].alerts)
+}
+
+func TestRouter_NotifyUpdate(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Match: Match{Categories: []string{domain.CategoryIDEExtension}}, Channels: []string{"ide-team"}, QuietHours: &QuietHours{Start: "22:00", End: "08:00", Timezone: "Asia/Shanghai"}},
+		{Name: "all", Channels: []string{DefaultChannel}},
+	}}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	now := time.Date(2025, 12, 27, 23, 30, 0, 0, shanghai)
+	r.nowFunc = func() time.Time { return now }
+
+	event := &domain.TrackingEvent{Type: domain.EventBreakout, RepoID: ideRepo.ID, Milestone: 1000}
+	require.NoError(t, r.NotifyUpdate(ctx, ideRepo, event))
+	assert.Equal(t, []string{"test/ide-plugin:breakout"}, rec[DefaultChannel].updates)
+	assert.Empty(t, rec["ide-team"].updates)
+	// 追踪更新不能被当作普通推送
+	assert.Empty(t, rec[DefaultChannel].repos)
+
+	// 免打扰结束后补发的仍然是追踪更新
+	now = time.Date(2025, 12, 28, 8, 0, 0, 0, shanghai)
+	require.NoError(t, r.Flush(ctx))
+	assert.Equal(t, []string{"test/ide-plugin:breakout"}, rec["ide-team"].updates)
+	assert.Empty(t, rec["ide-team"].repos)
+}
+
+func TestRouter_ChannelError(t *testing.T) {
+	cfg := &Config{Routes: []Route{{Name: "all", Channels: []string{"ide-team", DefaultChannel}}}}
+	r, rec := newTestRouter(t, cfg)
+	rec["ide-team"].err = errors.New("webhook 403")
+
+	err := r.Notify(context.Background(), ideRepo)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ide-team")
+	// 一个渠道失败不影响其他渠道，已送达部分渠道时调用方应视为已推送
+	assert.ErrorIs(t, err, port.ErrPartiallyDelivered)
+	assert.Equal(t, []string{"test/ide-plugin"}, rec[DefaultChannel].repos)
+
+	// 全部渠道失败
+	rec[DefaultChannel].err = errors.New("webhook 403")
+	err = r.Notify(context.Background(), ideRepo)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, port.ErrPartiallyDelivered)
+}
+
+func TestRouter_NotifyIgnoresPendingFailures(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	cfg := &Config{Routes: []Route{
+		{Name: "ide", Match: Match{Categories: []string{domain.CategoryIDEExtension}}, Channels: []string{"ide-team"}, QuietHours: &QuietHours{Start: "22:00", End: "08:00", Timezone: "Asia/Shanghai"}},
+		{Name: "all", Channels: []string{DefaultChannel}},
+	}}
+	r, rec := newTestRouter(t, cfg)
+	ctx := context.Background()
+
+	now := time.Date(2025, 12, 27, 23, 30, 0, 0, shanghai)
+	r.nowFunc = func() time.Time { return now }
+	require.NoError(t, r.Notify(ctx, ideRepo))
+	assertPending(t, r, 1)
+
+	// 免打扰结束后暂存消息补发失败，不影响新项目的推送结果
+	now = time.Date(2025, 12, 28, 9, 0, 0, 0, shanghai)
+	rec["ide-team"].err = errors.New("webhook 403")
+	require.NoError(t, r.Notify(ctx, cliRepo))
+	assertPending(t, r, 1)
+	assert.Error(t, r.Flush(ctx))
+}
+
+func TestNew_Validation(t *testing.T) {
+	channels := map[string]port.Notifier{DefaultChannel: &recordingNotifier{}}
+
+	tests := []struct {
+		name   string
+		cfg    *Config
+		errStr string
+	}{
+		{name: "未知渠道", cfg: &Config{Routes: []Route{{Name: "a", Channels: []string{"nope"}}}}, errStr: "未知渠道"},
+		{name: "缺少渠道", cfg: &Config{Routes: []Route{{Name: "a"}}}, errStr: "未配置渠道"},
+		{name: "路由名重复", cfg: &Config{Routes: []Route{{Name: "a", Channels: []string{DefaultChannel}}, {Name: "a", Channels: []string{DefaultChannel}}}}, errStr: "路由名重复"},
+		{name: "评分区间无效", cfg: &Config{Routes: []Route{{Name: "a", Channels: []string{DefaultChannel}, Match: Match{ScoreMin: 80, ScoreMax: 60}}}}, errStr: "评分区间无效"},
+		{name: "免打扰格式错误", cfg: &Config{Routes: []Route{{Name: "a", Channels: []string{DefaultChannel}, QuietHours: &QuietHours{Start: "25:00", End: "08:00"}}}}, errStr: "免打扰时段无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, channels)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errStr)
+		})
+	}
+
+	t.Run("无路由时回退到默认渠道", func(t *testing.T) {
+		r, err := New(nil, channels)
+		require.NoError(t, err)
+		assert.Len(t, r.Match(libRepo), 1)
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("IDE_WEBHOOK", "https://open.feishu.cn/open-apis/bot/v2/hook/ide")
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+channels:
+  ide-team: ${IDE_WEBHOOK}
+routes:
+  - name: ide
+    match:
+      categories: [ide-extension]
+      score_min: 60
+    channels: [ide-team]
+    min_score: 70
+    quiet_hours:
+      start: "22:00"
+      end: "08:00"
+      timezone: Asia/Shanghai
+`), 0o644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://open.feishu.cn/open-apis/bot/v2/hook/ide", cfg.Channels["ide-team"])
+	require.Len(t, cfg.Routes, 1)
+	assert.Equal(t, []string{"ide-extension"}, cfg.Routes[0].Match.Categories)
+	assert.Equal(t, 70, cfg.Routes[0].MinScore)
+	assert.Equal(t, "22:00", cfg.Routes[0].QuietHours.Start)
+}
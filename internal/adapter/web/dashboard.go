@@ -0,0 +1,423 @@
+// Package web 服务端渲染的项目看板：项目列表 (排序、分类筛选、Star 走势)、
+// 项目详情 (评估历史、用户反馈) 和语义搜索。模板和静态文件通过 embed.FS 打包进二进制
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github-gold-miner/internal/service"
+)
+
+//go:embed templates static
+var assets embed.FS
+
+const (
+	// defaultPageSize 列表每页项目数
+	defaultPageSize = 30
+
+	// searchTimeout 语义搜索的超时时间 (含补齐向量索引)
+	searchTimeout = 2 * time.Minute
+
+	// maxCommentLength 反馈备注的最大长度 (字符)
+	maxCommentLength = 2000
+)
+
+// Config 看板依赖
+type Config struct {
+	Token    string                 // 访问令牌，浏览器以 Basic Auth 登录，用户名任意、密码为令牌
+	Repos    port.Repository        // 项目存储
+	Searcher *service.SearchService // 语义搜索，为 nil 时搜索框不可用
+	PageSize int                    // 列表每页项目数，默认 30
+}
+
+// Dashboard 项目看板
+type Dashboard struct {
+	cfg   Config
+	mux   *http.ServeMux
+	pages map[string]*template.Template
+}
+
+// New 创建看板，必须配置访问令牌
+func New(cfg Config) (*Dashboard, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("未配置看板访问令牌")
+	}
+	if cfg.Repos == nil {
+		return nil, errors.New("未配置项目存储")
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = defaultPageSize
+	}
+
+	d := &Dashboard{cfg: cfg, mux: http.NewServeMux(), pages: make(map[string]*template.Template)}
+	for _, page := range []string{"list.html", "detail.html", "search.html"} {
+		tmpl, err := template.New("").Funcs(templateFuncs).ParseFS(assets, "templates/layout.html", "templates/"+page)
+		if err != nil {
+			return nil, fmt.Errorf("解析看板模板 %s 失败: %w", page, err)
+		}
+		d.pages[page] = tmpl
+	}
+
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		return nil, err
+	}
+	d.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	d.mux.HandleFunc("GET /{$}", d.handleList)
+	d.mux.HandleFunc("GET /repos/{id}", d.handleDetail)
+	d.mux.HandleFunc("POST /repos/{id}/feedback", d.handleFeedback)
+	d.mux.HandleFunc("GET /search", d.handleSearch)
+	return d, nil
+}
+
+// ServeHTTP 校验 Basic Auth 后分发请求
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(d.cfg.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="gold-miner", charset="UTF-8"`)
+		http.Error(w, "需要登录：密码为 API_TOKEN", http.StatusUnauthorized)
+		return
+	}
+	// 浏览器会自动带上 Basic Auth，拒绝其他站点发起的表单提交
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		http.Error(w, "拒绝跨站请求", http.StatusForbidden)
+		return
+	}
+	d.mux.ServeHTTP(w, r)
+}
+
+// sameOrigin 请求带 Origin 时必须与当前站点一致
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// listState 列表页的筛选和排序状态
+type listState struct {
+	Sort       domain.SortField
+	Ascending  bool
+	Categories []string
+	Page       int
+}
+
+// url 生成列表页链接
+func (s listState) url() string {
+	values := url.Values{}
+	if s.Sort != domain.SortDefault {
+		values.Set("sort", string(s.Sort))
+	}
+	if s.Ascending {
+		values.Set("order", "asc")
+	}
+	for _, c := range s.Categories {
+		values.Add("category", c)
+	}
+	if s.Page > 1 {
+		values.Set("page", strconv.Itoa(s.Page))
+	}
+	if len(values) == 0 {
+		return "/"
+	}
+	return "/?" + values.Encode()
+}
+
+func (s listState) hasCategory(category string) bool {
+	for _, c := range s.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// column 可排序的列
+type column struct {
+	Label     string
+	URL       string
+	Active    bool
+	Ascending bool
+}
+
+// categoryChip 分类筛选按钮，点击切换选中状态
+type categoryChip struct {
+	Name     string
+	URL      string
+	Selected bool
+}
+
+// repoRow 列表中的一行
+type repoRow struct {
+	Repo  *domain.Repo
+	Spark *Sparkline
+}
+
+type listPage struct {
+	Title      string
+	Rows       []repoRow
+	Columns    []column
+	Categories []categoryChip
+	Page       int
+	PrevURL    string
+	NextURL    string
+}
+
+func (d *Dashboard) handleList(w http.ResponseWriter, r *http.Request) {
+	state, err := parseListState(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	// 多取一条判断是否还有下一页
+	repos, err := d.cfg.Repos.Search(ctx, domain.SearchQuery{
+		Categories: state.Categories,
+		Sort:       state.Sort,
+		Ascending:  state.Ascending,
+		Limit:      d.cfg.PageSize + 1,
+		Offset:     (state.Page - 1) * d.cfg.PageSize,
+	})
+	if err != nil {
+		d.serverError(w, err)
+		return
+	}
+	hasNext := len(repos) > d.cfg.PageSize
+	if hasNext {
+		repos = repos[:d.cfg.PageSize]
+	}
+
+	page := listPage{Title: "项目看板", Page: state.Page}
+	for _, repo := range repos {
+		snapshots, err := d.cfg.Repos.GetSnapshots(ctx, repo.ID)
+		if err != nil {
+			d.serverError(w, err)
+			return
+		}
+		page.Rows = append(page.Rows, repoRow{Repo: repo, Spark: NewSparkline(snapshots, 120, 24)})
+	}
+
+	for _, col := range []struct {
+		label string
+		field domain.SortField
+	}{{"评分", domain.SortScore}, {"增长率", domain.SortVelocity}, {"Star", domain.SortStars}, {"创建时间", domain.SortCreated}} {
+		active := state.Sort == col.field || (state.Sort == domain.SortDefault && col.field == domain.SortScore)
+		next := state
+		next.Sort, next.Page = col.field, 1
+		// 再次点击当前列时切换升降序
+		next.Ascending = active && !state.Ascending
+		page.Columns = append(page.Columns, column{Label: col.label, URL: next.url(), Active: active, Ascending: active && state.Ascending})
+	}
+
+	for _, c := range domain.Categories() {
+		next := state
+		next.Page = 1
+		next.Categories = nil
+		for _, selected := range state.Categories {
+			if selected != c {
+				next.Categories = append(next.Categories, selected)
+			}
+		}
+		if !state.hasCategory(c) {
+			next.Categories = append(next.Categories, c)
+		}
+		page.Categories = append(page.Categories, categoryChip{Name: c, URL: next.url(), Selected: state.hasCategory(c)})
+	}
+
+	if state.Page > 1 {
+		prev := state
+		prev.Page--
+		page.PrevURL = prev.url()
+	}
+	if hasNext {
+		next := state
+		next.Page++
+		page.NextURL = next.url()
+	}
+	d.render(w, "list.html", page)
+}
+
+// parseListState 解析 sort、order、category、page 参数
+func parseListState(values url.Values) (listState, error) {
+	var state listState
+	var err error
+	if state.Sort, err = domain.ParseSortField(values.Get("sort")); err != nil {
+		return state, err
+	}
+	switch values.Get("order") {
+	case "", "desc":
+	case "asc":
+		state.Ascending = true
+	default:
+		return state, fmt.Errorf("不支持的排序方向: %s (可选 asc、desc)", values.Get("order"))
+	}
+	for _, c := range values["category"] {
+		if c = strings.TrimSpace(c); c != "" && !state.hasCategory(c) {
+			state.Categories = append(state.Categories, c)
+		}
+	}
+	state.Page = 1
+	if p := values.Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			return state, fmt.Errorf("无效的页码: %s", p)
+		}
+		state.Page = n
+	}
+	return state, nil
+}
+
+type detailPage struct {
+	Title      string
+	Repo       *domain.Repo
+	Spark      *Sparkline
+	Snapshots  []*domain.StarSnapshot
+	Appraisals []*domain.Appraisal
+	Feedback   []*domain.Feedback
+	Useful     int
+	NotUseful  int
+}
+
+func (d *Dashboard) handleDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	repo, err := d.cfg.Repos.Get(ctx, r.PathValue("id"))
+	if errors.Is(err, port.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		d.serverError(w, err)
+		return
+	}
+
+	page := detailPage{Title: repo.Name, Repo: repo}
+	if page.Snapshots, err = d.cfg.Repos.GetSnapshots(ctx, repo.ID); err != nil {
+		d.serverError(w, err)
+		return
+	}
+	if page.Appraisals, err = d.cfg.Repos.GetAppraisals(ctx, repo.ID); err != nil {
+		d.serverError(w, err)
+		return
+	}
+	if page.Feedback, err = d.cfg.Repos.GetFeedback(ctx, repo.ID); err != nil {
+		d.serverError(w, err)
+		return
+	}
+	for _, f := range page.Feedback {
+		if f.Verdict == domain.FeedbackUseful {
+			page.Useful++
+		} else {
+			page.NotUseful++
+		}
+	}
+	page.Spark = NewSparkline(page.Snapshots, 480, 80)
+	d.render(w, "detail.html", page)
+}
+
+// handleFeedback 保存反馈后重定向回详情页，作者为 Basic Auth 用户名
+func (d *Dashboard) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	repo, err := d.cfg.Repos.Get(ctx, r.PathValue("id"))
+	if errors.Is(err, port.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		d.serverError(w, err)
+		return
+	}
+
+	verdict := domain.FeedbackVerdict(r.PostFormValue("verdict"))
+	if verdict != domain.FeedbackUseful && verdict != domain.FeedbackNotUseful {
+		http.Error(w, "请选择 有用 或 没用", http.StatusBadRequest)
+		return
+	}
+	comment := strings.TrimSpace(r.PostFormValue("comment"))
+	if len([]rune(comment)) > maxCommentLength {
+		http.Error(w, fmt.Sprintf("备注不能超过 %d 个字符", maxCommentLength), http.StatusBadRequest)
+		return
+	}
+	author, _, _ := r.BasicAuth()
+
+	err = d.cfg.Repos.AddFeedback(ctx, &domain.Feedback{
+		RepoID:    repo.ID,
+		Verdict:   verdict,
+		Comment:   comment,
+		Author:    author,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		d.serverError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/repos/"+url.PathEscape(repo.ID)+"#feedback", http.StatusSeeOther)
+}
+
+type searchPage struct {
+	Title   string
+	Query   string
+	Enabled bool
+	Results []*domain.SearchResult
+	Error   string
+}
+
+func (d *Dashboard) handleSearch(w http.ResponseWriter, r *http.Request) {
+	page := searchPage{Title: "语义搜索", Query: strings.TrimSpace(r.URL.Query().Get("q")), Enabled: d.cfg.Searcher != nil}
+	if page.Query != "" && page.Enabled {
+		ctx, cancel := context.WithTimeout(r.Context(), searchTimeout)
+		defer cancel()
+		results, _, err := d.cfg.Searcher.Search(ctx, page.Query)
+		if err != nil {
+			log.Printf("❌ 看板搜索失败: %v", err)
+			page.Error = err.Error()
+		}
+		page.Results = results
+	}
+	d.render(w, "search.html", page)
+}
+
+// render 先渲染到缓冲区，模板出错时不会输出半个页面
+func (d *Dashboard) render(w http.ResponseWriter, name string, data interface{}) {
+	var b strings.Builder
+	if err := d.pages[name].ExecuteTemplate(&b, "layout", data); err != nil {
+		d.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func (d *Dashboard) serverError(w http.ResponseWriter, err error) {
+	log.Printf("❌ 看板请求失败: %v", err)
+	http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+}
+
+// templateFuncs 模板辅助函数
+var templateFuncs = template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	},
+	"datetime":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"rate":       func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) },
+	"pathEscape": url.PathEscape,
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// fakeAppraiser 语义搜索返回第一个候选项目 (最新创建的项目)
+type fakeAppraiser struct{}
+
+func (fakeAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error) {
+	return repo, nil
+}
+
+func (fakeAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, query string) ([]*domain.SearchResult, error) {
+	return []*domain.SearchResult{{RepoID: repos[0].ID, Reason: "命令行 <Agent>", Relevance: 88, Action: "试用"}}, nil
+}
+
+func newTestDashboard(t *testing.T) (*Dashboard, *repository.MemoryRepo) {
+	t.Helper()
+	ctx := context.Background()
+	repo := repository.NewMemoryRepo()
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	appraised := base.Add(time.Hour)
+	for _, r := range []*domain.Repo{
+		{ID: "github-1", Name: "acme/agent", Description: "CLI coding agent", Stars: 500, StarGrowthRate: 10, LLMScore: 90, LLMReview: "很好用", Categories: []string{domain.CategoryCLIAgent}, CreatedAt: base, AppraisedAt: &appraised, AppraisalModel: "gemini"},
+		{ID: "github-2", Name: "acme/review", Description: "review bot", Stars: 900, StarGrowthRate: 30, LLMScore: 70, Categories: []string{domain.CategoryCodeReview}, CreatedAt: base.AddDate(0, 0, 1)},
+	} {
+		require.NoError(t, repo.Save(ctx, r))
+	}
+	for i, stars := range []int{100, 300, 500} {
+		require.NoError(t, repo.AddSnapshot(ctx, &domain.StarSnapshot{RepoID: "github-1", Stars: stars, CapturedAt: base.AddDate(0, 0, i)}))
+	}
+
+	d, err := New(Config{
+		Token:    testToken,
+		Repos:    repo,
+		Searcher: service.NewSearchService(repo, fakeAppraiser{}, nil, service.SearchConfig{}),
+	})
+	require.NoError(t, err)
+	return d, repo
+}
+
+func get(t *testing.T, d *Dashboard, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.SetBasicAuth("alice", testToken)
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDashboard_RequiresLogin(t *testing.T) {
+	d, _ := newTestDashboard(t)
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Basic")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "wrong")
+	rec = httptest.NewRecorder()
+	d.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestDashboard_List(t *testing.T) {
+	d, _ := newTestDashboard(t)
+
+	rec := get(t, d, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	// 默认按评分排序，有快照的项目显示走势图
+	assert.Less(t, strings.Index(body, "acme/agent"), strings.Index(body, "acme/review"))
+	assert.Contains(t, body, "<polyline")
+	assert.Contains(t, body, `href="/?order=asc&amp;sort=score"`)
+
+	rec = get(t, d, "/?sort=stars")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body = rec.Body.String()
+	assert.Less(t, strings.Index(body, "acme/review"), strings.Index(body, "acme/agent"))
+
+	rec = get(t, d, "/?category="+domain.CategoryCodeReview)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "acme/agent")
+	assert.Contains(t, rec.Body.String(), "acme/review")
+
+	assert.Equal(t, http.StatusBadRequest, get(t, d, "/?sort=name").Code)
+	assert.Equal(t, http.StatusBadRequest, get(t, d, "/?page=0").Code)
+}
+
+func TestDashboard_Pagination(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.cfg.PageSize = 1
+
+	body := get(t, d, "/").Body.String()
+	assert.Contains(t, body, `href="/?page=2"`)
+
+	body = get(t, d, "/?page=2").Body.String()
+	assert.Contains(t, body, "acme/review")
+	assert.Contains(t, body, `href="/"`)
+	assert.NotContains(t, body, `page=3`)
+}
+
+func TestDashboard_DetailAndFeedback(t *testing.T) {
+	d, repo := newTestDashboard(t)
+
+	rec := get(t, d, "/repos/github-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "很好用")
+	assert.Contains(t, body, "gemini")
+	assert.Contains(t, body, "⭐ 100 → 500 (+400)")
+
+	form := url.Values{"verdict": {"not_useful"}, "comment": {"<b>不是编程工具</b>"}}
+	req := httptest.NewRequest(http.MethodPost, "/repos/github-1/feedback", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("alice", testToken)
+	rec = httptest.NewRecorder()
+	d.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/repos/github-1#feedback", rec.Header().Get("Location"))
+
+	feedback, err := repo.GetFeedback(context.Background(), "github-1")
+	require.NoError(t, err)
+	require.Len(t, feedback, 1)
+	assert.Equal(t, domain.FeedbackNotUseful, feedback[0].Verdict)
+	assert.Equal(t, "alice", feedback[0].Author)
+
+	// 反馈内容需要转义
+	body = get(t, d, "/repos/github-1").Body.String()
+	assert.Contains(t, body, "&lt;b&gt;不是编程工具&lt;/b&gt;")
+	assert.Contains(t, body, "👎 1")
+
+	assert.Equal(t, http.StatusNotFound, get(t, d, "/repos/github-404").Code)
+}
+
+func TestDashboard_FeedbackValidation(t *testing.T) {
+	d, _ := newTestDashboard(t)
+
+	post := func(form url.Values, origin string) int {
+		req := httptest.NewRequest(http.MethodPost, "/repos/github-1/feedback", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		req.SetBasicAuth("alice", testToken)
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post(url.Values{"verdict": {"maybe"}}, ""))
+	assert.Equal(t, http.StatusForbidden, post(url.Values{"verdict": {"useful"}}, "https://evil.example"))
+	assert.Equal(t, http.StatusSeeOther, post(url.Values{"verdict": {"useful"}}, "http://example.com"))
+}
+
+func TestDashboard_Search(t *testing.T) {
+	d, _ := newTestDashboard(t)
+
+	rec := get(t, d, "/search?q=agent")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `value="agent"`)
+	assert.Contains(t, body, "命令行 &lt;Agent&gt;")
+	assert.Contains(t, body, `href="/repos/github-2"`)
+
+	d.cfg.Searcher = nil
+	assert.Contains(t, get(t, d, "/search?q=agent").Body.String(), "未配置语义搜索")
+}
+
+func TestDashboard_Static(t *testing.T) {
+	d, _ := newTestDashboard(t)
+
+	rec := get(t, d, "/static/style.css")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/css")
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+
+	"github-gold-miner/internal/domain"
+)
+
+// sparkPadding 留给线宽的边距 (像素)
+const sparkPadding = 2
+
+// Sparkline Star 走势迷你图，Points 为 SVG polyline 坐标
+type Sparkline struct {
+	Width  int
+	Height int
+	Points string
+	First  int // 第一次快照的 Star 数
+	Last   int // 最近一次快照的 Star 数
+}
+
+// Delta 快照期间的 Star 增量
+func (s *Sparkline) Delta() int {
+	return s.Last - s.First
+}
+
+// NewSparkline 按时间比例把快照映射到 width x height 的坐标，少于两个快照时返回 nil
+func NewSparkline(snapshots []*domain.StarSnapshot, width, height int) *Sparkline {
+	if len(snapshots) < 2 {
+		return nil
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	minStars, maxStars := first.Stars, first.Stars
+	for _, s := range snapshots {
+		minStars = min(minStars, s.Stars)
+		maxStars = max(maxStars, s.Stars)
+	}
+	span := last.CapturedAt.Sub(first.CapturedAt).Seconds()
+
+	innerW := float64(width - 2*sparkPadding)
+	innerH := float64(height - 2*sparkPadding)
+	points := make([]string, len(snapshots))
+	for i, s := range snapshots {
+		// 快照时间相同时按顺序等距排列
+		xRatio := float64(i) / float64(len(snapshots)-1)
+		if span > 0 {
+			xRatio = s.CapturedAt.Sub(first.CapturedAt).Seconds() / span
+		}
+		// Star 数不变时画在中间
+		yRatio := 0.5
+		if maxStars > minStars {
+			yRatio = float64(s.Stars-minStars) / float64(maxStars-minStars)
+		}
+		x := sparkPadding + xRatio*innerW
+		y := sparkPadding + (1-yRatio)*innerH
+		points[i] = strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+	}
+
+	return &Sparkline{
+		Width:  width,
+		Height: height,
+		Points: strings.Join(points, " "),
+		First:  first.Stars,
+		Last:   last.Stars,
+	}
+}
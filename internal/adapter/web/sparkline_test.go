@@ -0,0 +1,33 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSparkline(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snap := func(day, stars int) *domain.StarSnapshot {
+		return &domain.StarSnapshot{Stars: stars, CapturedAt: base.AddDate(0, 0, day)}
+	}
+
+	assert.Nil(t, NewSparkline(nil, 100, 20))
+	assert.Nil(t, NewSparkline([]*domain.StarSnapshot{snap(0, 10)}, 100, 20))
+
+	// 横轴按时间比例，纵轴最高点在顶部
+	s := NewSparkline([]*domain.StarSnapshot{snap(0, 100), snap(1, 200), snap(4, 300)}, 104, 24)
+	assert.Equal(t, "2.0,22.0 27.0,12.0 102.0,2.0", s.Points)
+	assert.Equal(t, 200, s.Delta())
+
+	// Star 数不变时画在中间
+	s = NewSparkline([]*domain.StarSnapshot{snap(0, 50), snap(2, 50)}, 104, 24)
+	assert.Equal(t, "2.0,12.0 102.0,12.0", s.Points)
+
+	// 快照时间相同时按顺序等距排列
+	s = NewSparkline([]*domain.StarSnapshot{snap(0, 1), snap(0, 2), snap(0, 3)}, 104, 24)
+	assert.Equal(t, "2.0,22.0 52.0,12.0 102.0,2.0", s.Points)
+}
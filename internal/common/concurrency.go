@@ -0,0 +1,209 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// aimdDecreaseInterval 两次降低并发上限之间的最小间隔，同一波限流错误只减半一次
+const aimdDecreaseInterval = 5 * time.Second
+
+// CallOutcome 一次调用的结果，决定自适应并发上限如何调整
+type CallOutcome int
+
+const (
+	CallSucceeded  CallOutcome = iota // 成功：上限缓慢增加
+	CallOverloaded                    // 限流 (429) 或超时：上限减半
+	CallFailed                        // 其他错误：上限不变
+)
+
+// AdaptiveLimiter 按 AIMD (加性增、乘性减) 调整并发上限的限流器，可以并发使用
+// 从最大并发数开始；每次成功上限增加 1/上限 (约每轮并发增加 1)，限流或超时时减半，不低于最小并发数
+type AdaptiveLimiter struct {
+	clock    Clock
+	min, max float64
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	lastDecrease time.Time
+	wake         chan struct{} // 有调用结束时关闭，唤醒等待的调用
+}
+
+// NewAdaptiveLimiter 创建并发上限在 [minLimit, maxLimit] 之间的限流器；clock 为 nil 时使用 SystemClock
+func NewAdaptiveLimiter(minLimit, maxLimit int, clock Clock) *AdaptiveLimiter {
+	if clock == nil {
+		clock = SystemClock
+	}
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	return &AdaptiveLimiter{
+		clock: clock,
+		min:   float64(minLimit),
+		max:   float64(maxLimit),
+		limit: float64(maxLimit),
+		wake:  make(chan struct{}),
+	}
+}
+
+// Limit 返回当前的并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Acquire 等到正在进行的调用数低于并发上限，ctx 取消时返回 ctx.Err()；成功后必须调用 Release
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release 结束一次调用，并按结果调整并发上限；返回调整前和调整后的上限
+func (l *AdaptiveLimiter) Release(outcome CallOutcome) (before, after int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	before = int(l.limit)
+	l.inFlight--
+	switch outcome {
+	case CallSucceeded:
+		l.limit = math.Min(l.max, l.limit+1/l.limit)
+	case CallOverloaded:
+		now := l.clock.Now()
+		if now.Sub(l.lastDecrease) >= aimdDecreaseInterval {
+			l.lastDecrease = now
+			l.limit = math.Max(l.min, math.Floor(l.limit/2))
+		}
+	}
+	close(l.wake)
+	l.wake = make(chan struct{})
+	return before, int(l.limit)
+}
+
+// IsOverloaded 判断错误是否说明服务商过载：限流 (HTTP 429、gRPC ResourceExhausted、错误码 LLM_RATE_LIMITED /
+// GITHUB_RATE_LIMITED) 或单次请求超时；ctx 为发起请求的上级 context，它已经结束时 (阶段或整轮超时、取消)
+// 请求超时是上级的超时造成的，不算过载
+func IsOverloaded(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) ||
+		HasCode(err, ErrCodeLLMRateLimited) || HasCode(err, ErrCodeGitHubRateLimited) {
+		return true
+	}
+	if code, ok := HTTPStatus(err); ok && code == http.StatusTooManyRequests {
+		return true
+	}
+	grpcCode := codes.OK
+	if s, ok := status.FromError(err); ok {
+		grpcCode = s.Code()
+	}
+	if grpcCode == codes.ResourceExhausted {
+		return true
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || grpcCode == codes.DeadlineExceeded ||
+		HasCode(err, ErrCodeLLMTimeout) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// LLMLimiterConfig 一个 LLM 服务商的限额，0 表示不限
+type LLMLimiterConfig struct {
+	RPM            int // 每分钟请求数
+	TPM            int // 每分钟 Token 数 (输入加输出)
+	MaxConcurrency int // 自适应并发的上限
+}
+
+// LLMLimiter 同一个服务商的所有 LLM 调用共用的限流器：先按自适应并发上限排队，再按 RPM / TPM 令牌桶等待
+// 服务商限流时降低并发上限并清空请求额度，避免所有调用同时退避、同时重试
+type LLMLimiter struct {
+	name        string
+	rate        *RateLimiter
+	concurrency *AdaptiveLimiter // MaxConcurrency 为 0 时为 nil
+}
+
+// NewLLMLimiter 创建服务商 name 的限流器；clock 为 nil 时使用 SystemClock
+func NewLLMLimiter(name string, cfg LLMLimiterConfig, clock Clock) *LLMLimiter {
+	l := &LLMLimiter{name: name, rate: NewRateLimiter(cfg.RPM, cfg.TPM, clock)}
+	if cfg.MaxConcurrency > 0 {
+		l.concurrency = NewAdaptiveLimiter(1, cfg.MaxConcurrency, clock)
+	}
+	return l
+}
+
+// Concurrency 返回当前的并发上限，不限并发时返回 0
+func (l *LLMLimiter) Concurrency() int {
+	if l == nil || l.concurrency == nil {
+		return 0
+	}
+	return l.concurrency.Limit()
+}
+
+// Do 等待限流后调用一次 fn (不重试)，l 为 nil 时直接调用
+// estimatedTokens 为预估的 Token 用量，fn 返回实际用量 (未知时返回 0，不修正)
+func (l *LLMLimiter) Do(ctx context.Context, estimatedTokens int64, fn func() (int64, error)) error {
+	if l == nil {
+		_, err := fn()
+		return err
+	}
+	if l.concurrency != nil {
+		if err := l.concurrency.Acquire(ctx); err != nil {
+			return err
+		}
+	}
+	if err := l.rate.Wait(ctx, estimatedTokens); err != nil {
+		if l.concurrency != nil {
+			l.concurrency.Release(CallFailed)
+		}
+		return err
+	}
+
+	used, err := fn()
+	if used > 0 {
+		l.rate.Adjust(used - estimatedTokens)
+	}
+	outcome := CallSucceeded
+	switch {
+	case IsOverloaded(ctx, err):
+		outcome = CallOverloaded
+		l.rate.Throttle()
+	case err != nil:
+		outcome = CallFailed
+	}
+	if l.concurrency != nil {
+		if before, after := l.concurrency.Release(outcome); after < before {
+			log.Printf("🐢 %s 限流或超时，并发上限从 %d 降到 %d", l.name, before, after)
+		}
+	}
+	return err
+}
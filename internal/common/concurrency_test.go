@@ -0,0 +1,176 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpError 带 HTTP 状态码的错误，与 googleapi / apierror 的错误类型相同
+type httpError int
+
+func (e httpError) Error() string { return fmt.Sprintf("status %d", int(e)) }
+func (e httpError) HTTPCode() int { return int(e) }
+
+func TestAdaptiveLimiter_AIMD(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewAdaptiveLimiter(1, 8, clock)
+	ctx := context.Background()
+
+	call := func(outcome CallOutcome) {
+		t.Helper()
+		if err := limiter.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		limiter.Release(outcome)
+	}
+
+	if got := limiter.Limit(); got != 8 {
+		t.Fatalf("初始上限 = %d, want 8", got)
+	}
+	// 限流时减半，同一波限流只减一次
+	call(CallOverloaded)
+	call(CallOverloaded)
+	if got := limiter.Limit(); got != 4 {
+		t.Errorf("限流后上限 = %d, want 4", got)
+	}
+	clock.Advance(aimdDecreaseInterval)
+	call(CallOverloaded)
+	clock.Advance(aimdDecreaseInterval)
+	call(CallOverloaded)
+	clock.Advance(aimdDecreaseInterval)
+	call(CallOverloaded)
+	if got := limiter.Limit(); got != 1 {
+		t.Errorf("上限 = %d, want 不低于最小值 1", got)
+	}
+
+	// 其他错误不影响上限；成功时约每轮并发增加 1
+	call(CallFailed)
+	if got := limiter.Limit(); got != 1 {
+		t.Errorf("上限 = %d, want 1", got)
+	}
+	call(CallSucceeded)
+	if got := limiter.Limit(); got != 2 {
+		t.Errorf("上限 = %d, want 2", got)
+	}
+	// 上限为 2 时大约两次成功增加 1：2 → 2.5 → 2.9 → 3.24
+	call(CallSucceeded)
+	call(CallSucceeded)
+	call(CallSucceeded)
+	if got := limiter.Limit(); got != 3 {
+		t.Errorf("上限 = %d, want 3", got)
+	}
+	for i := 0; i < 100; i++ {
+		call(CallSucceeded)
+	}
+	if got := limiter.Limit(); got != 8 {
+		t.Errorf("上限 = %d, want 不超过最大值 8", got)
+	}
+}
+
+func TestAdaptiveLimiter_Blocks(t *testing.T) {
+	limiter := NewAdaptiveLimiter(1, 2, newFakeClock())
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := limiter.Acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 达到上限后等待，直到有调用结束
+	acquired := make(chan error)
+	go func() { acquired <- limiter.Acquire(ctx) }()
+	select {
+	case <-acquired:
+		t.Fatal("达到并发上限时 Acquire 应该等待")
+	case <-time.After(20 * time.Millisecond):
+	}
+	limiter.Release(CallSucceeded)
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+
+	// ctx 取消时放弃等待
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(cancelled); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestIsOverloaded(t *testing.T) {
+	live := context.Background()
+	expired, cancel := context.WithTimeout(live, -time.Second)
+	defer cancel()
+	timeout := fmt.Errorf("评估超时: %w", context.DeadlineExceeded)
+	tests := []struct {
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{live, nil, false},
+		{live, errors.New("AI 返回内容为空"), false},
+		{live, httpError(500), false},
+		{live, httpError(429), true},
+		{live, fmt.Errorf("调用失败: %w", httpError(429)), true},
+		{live, WrapError(ErrCodeLLMRateLimited, "AI 调用失败", errors.New("quota")), true},
+		{expired, httpError(429), true},
+		// 只看错误类型和错误码，不匹配错误信息
+		{live, errors.New("rpc error: code = ResourceExhausted desc = Quota exceeded"), false},
+		{live, errors.New("openai: 429 Too Many Requests"), false},
+		// 上级 context 仍然有效时是单次请求超时，已经结束时是阶段或整轮超时
+		{live, timeout, true},
+		{live, status.Error(codes.DeadlineExceeded, "deadline"), true},
+		{expired, timeout, false},
+		{expired, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsOverloaded(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("IsOverloaded(%v) with ctx err %v = %v, want %v", tt.err, tt.ctx.Err(), got, tt.want)
+		}
+	}
+}
+
+func TestLLMLimiter_Do(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLLMLimiter("Gemini", LLMLimiterConfig{RPM: 60, TPM: 6000, MaxConcurrency: 4}, clock)
+	ctx := context.Background()
+
+	// 实际用量修正 Token 额度
+	if err := limiter.Do(ctx, 1000, func() (int64, error) { return 5000, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := limiter.rate.tokens.available; got != 1000 {
+		t.Errorf("tokens.available = %v, want 1000", got)
+	}
+
+	// 限流时并发上限减半，请求额度清空
+	err := limiter.Do(ctx, 100, func() (int64, error) { return 0, httpError(429) })
+	if !IsOverloaded(ctx, err) {
+		t.Fatalf("Do() = %v, want 429", err)
+	}
+	if got := limiter.Concurrency(); got != 2 {
+		t.Errorf("Concurrency() = %d, want 2", got)
+	}
+	if err := limiter.Do(ctx, 100, func() (int64, error) { return 100, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := clock.slept(); got != time.Second {
+		t.Errorf("slept = %v, want 1s", got)
+	}
+
+	// nil 限流器直接调用
+	var none *LLMLimiter
+	calls := 0
+	if err := none.Do(ctx, 100, func() (int64, error) { calls++; return 0, nil }); err != nil || calls != 1 {
+		t.Errorf("nil.Do() = %v, calls = %d", err, calls)
+	}
+	if got := none.Concurrency(); got != 0 {
+		t.Errorf("nil.Concurrency() = %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package common
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// RequestCounter 统计经过的 HTTP 请求数 (含重试)，可以并发使用
+type RequestCounter struct {
+	n atomic.Int64
+}
+
+// Wrap 返回统计请求数的 RoundTripper，base 为 nil 时使用 http.DefaultTransport
+func (c *RequestCounter) Wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return countingTransport{base: base, counter: c}
+}
+
+// Count 返回累计请求数
+func (c *RequestCounter) Count() int64 {
+	return c.n.Load()
+}
+
+type countingTransport struct {
+	base    http.RoundTripper
+	counter *RequestCounter
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.counter.n.Add(1)
+	return t.base.RoundTrip(req)
+}
@@ -1,6 +1,16 @@
 package common
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 // AppError 应用级错误结构
 type AppError struct {
@@ -20,6 +30,17 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// ErrorCode 返回错误码，执行报告通过这个方法按错误码统计
+func (e *AppError) ErrorCode() string {
+	return e.Code
+}
+
+// Is 错误码相同的 AppError 视为同一种错误，errors.Is(err, common.NewError(code, "")) 判断错误链中是否有该错误码
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
 // WrapError 包装错误
 func WrapError(code, message string, err error) error {
 	return &AppError{
@@ -46,4 +67,141 @@ const (
 	ErrCodeInvalidInput  = "INVALID_INPUT"
 	ErrCodeNotFound      = "NOT_FOUND"
 	ErrCodeInternal      = "INTERNAL_ERROR"
-)
\ No newline at end of file
+)
+
+// 错误码目录：适配器按失败类型包装错误，调用方可以按错误码分支，执行报告按错误码统计
+const (
+	// GitHub
+	ErrCodeGitHubRateLimited  = "GITHUB_RATE_LIMITED" // 触发 GitHub 限流
+	ErrCodeGitHubUnauthorized = "GITHUB_UNAUTHORIZED" // Token 无效或没有权限
+	ErrCodeGitHubNotFound     = "GITHUB_NOT_FOUND"    // 仓库已删除或转为私有
+	ErrCodeGitHubUnavailable  = "GITHUB_UNAVAILABLE"  // 网络错误、超时或 GitHub 5xx
+
+	// LLM (评估、对话和 Embedding)
+	ErrCodeLLMRateLimited    = "LLM_RATE_LIMITED"    // 服务商限流或配额用尽
+	ErrCodeLLMUnauthorized   = "LLM_UNAUTHORIZED"    // API Key 无效或没有权限
+	ErrCodeLLMTimeout        = "LLM_TIMEOUT"         // 调用超时
+	ErrCodeLLMInvalidJSON    = "LLM_INVALID_JSON"    // 返回内容不是约定格式的 JSON
+	ErrCodeLLMEmptyResponse  = "LLM_EMPTY_RESPONSE"  // 返回内容为空
+	ErrCodeLLMSafetyBlocked  = "LLM_SAFETY_BLOCKED"  // 被服务商的安全策略拦截
+	ErrCodeLLMBudgetExceeded = "LLM_BUDGET_EXCEEDED" // 费用超出预算，没有调用
+
+	// 通知
+	ErrCodeNotifyRejected    = "NOTIFY_REJECTED"    // 通知服务拒绝了消息 (4xx)
+	ErrCodeNotifyUnavailable = "NOTIFY_UNAVAILABLE" // 网络错误、超时或通知服务 5xx
+
+	// 数据库
+	ErrCodeDBUnavailable = "DB_UNAVAILABLE" // 连不上数据库或连接已断开
+)
+
+// AsAppError 返回错误链中第一个 AppError
+func AsAppError(err error) (*AppError, bool) {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// Code 返回错误链中第一个 AppError 的错误码，没有时返回空字符串
+func Code(err error) string {
+	if appErr, ok := AsAppError(err); ok {
+		return appErr.Code
+	}
+	return ""
+}
+
+// HasCode 错误链中是否有错误码为 code 的 AppError
+func HasCode(err error, code string) bool {
+	return errors.Is(err, &AppError{Code: code})
+}
+
+// wrapClassified 用 code 包装 err；err 已经带有错误码时只追加 message，保留更具体的错误码
+func wrapClassified(code, message string, err error) error {
+	if Code(err) != "" {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	return WrapError(code, message, err)
+}
+
+// GitHubError 按 GitHub API 错误的类型选择错误码并包装，err 为 nil 时返回 nil
+func GitHubError(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := ErrCodeGitHubAPI
+	statusCode, hasStatus := HTTPStatus(err)
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	switch {
+	case errors.As(err, &rateErr), errors.As(err, &abuseErr), statusCode == http.StatusTooManyRequests:
+		code = ErrCodeGitHubRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		code = ErrCodeGitHubUnauthorized
+	case statusCode == http.StatusNotFound:
+		code = ErrCodeGitHubNotFound
+	case statusCode >= 500, !hasStatus && IsRetryable(err):
+		code = ErrCodeGitHubUnavailable
+	}
+	return wrapClassified(code, message, err)
+}
+
+// LLMError 按 LLM 服务商错误的类型选择错误码并包装，err 为 nil 时返回 nil
+// err 已经带有错误码 (如 LLM_SAFETY_BLOCKED、LLM_BUDGET_EXCEEDED) 时保留原错误码
+func LLMError(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := ErrCodeAIProcessing
+	statusCode, _ := HTTPStatus(err)
+	grpcCode := codes.OK
+	if s, ok := status.FromError(err); ok {
+		grpcCode = s.Code()
+	}
+	var ae *apierror.APIError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), grpcCode == codes.DeadlineExceeded:
+		code = ErrCodeLLMTimeout
+	case statusCode == http.StatusTooManyRequests, grpcCode == codes.ResourceExhausted:
+		code = ErrCodeLLMRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden,
+		grpcCode == codes.Unauthenticated, grpcCode == codes.PermissionDenied,
+		errors.As(err, &ae) && ae.Reason() == "API_KEY_INVALID":
+		code = ErrCodeLLMUnauthorized
+	}
+	return wrapClassified(code, message, err)
+}
+
+// NotifyError 按通知服务错误的类型选择错误码并包装，err 为 nil 时返回 nil
+func NotifyError(message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := ErrCodeNotification
+	if statusCode, ok := HTTPStatus(err); ok {
+		if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests {
+			code = ErrCodeNotifyRejected
+		} else if statusCode >= 500 || statusCode == http.StatusTooManyRequests {
+			code = ErrCodeNotifyUnavailable
+		}
+	} else if IsRetryable(err) {
+		code = ErrCodeNotifyUnavailable
+	}
+	return wrapClassified(code, message, err)
+}
+
+// errorRecorderKey 见 WithErrorRecorder
+type errorRecorderKey struct{}
+
+// WithErrorRecorder 返回带有 record 的 context，适配器通过 RecordError 上报没有作为返回值传出的错误
+// (如并发评估中单个项目的失败)，执行报告据此按错误码统计；record 可能被并发调用
+func WithErrorRecorder(ctx context.Context, record func(error)) context.Context {
+	return context.WithValue(ctx, errorRecorderKey{}, record)
+}
+
+// RecordError 把错误交给 ctx 中的记录函数，没有记录函数或 err 为 nil 时忽略
+func RecordError(ctx context.Context, err error) {
+	if record, ok := ctx.Value(errorRecorderKey{}).(func(error)); ok && err != nil {
+		record(err)
+	}
+}
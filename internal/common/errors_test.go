@@ -0,0 +1,129 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/google/go-github/v53/github"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAppError_CodeHelpers(t *testing.T) {
+	inner := WrapError(ErrCodeLLMInvalidJSON, "解析响应失败", errors.New("unexpected end of JSON input"))
+	err := fmt.Errorf("分析 acme/coder 失败: %w", inner)
+
+	if got := Code(err); got != ErrCodeLLMInvalidJSON {
+		t.Errorf("Code = %q, want %q", got, ErrCodeLLMInvalidJSON)
+	}
+	if !HasCode(err, ErrCodeLLMInvalidJSON) || HasCode(err, ErrCodeLLMTimeout) {
+		t.Error("HasCode 应该只匹配错误链中的错误码")
+	}
+	if !errors.Is(err, NewError(ErrCodeLLMInvalidJSON, "")) {
+		t.Error("错误码相同的 AppError 应该被 errors.Is 匹配")
+	}
+	if appErr, ok := AsAppError(err); !ok || appErr.Message != "解析响应失败" {
+		t.Errorf("AsAppError = %v, %v", appErr, ok)
+	}
+	if got := Code(errors.New("plain")); got != "" {
+		t.Errorf("没有错误码时 Code = %q, want 空字符串", got)
+	}
+	if got := inner.(*AppError).ErrorCode(); got != ErrCodeLLMInvalidJSON {
+		t.Errorf("ErrorCode() = %q", got)
+	}
+}
+
+func TestGitHubError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"限流", &github.RateLimitError{Response: ghResponse(403, nil)}, ErrCodeGitHubRateLimited},
+		{"二级限流", &github.AbuseRateLimitError{Response: ghResponse(403, nil)}, ErrCodeGitHubRateLimited},
+		{"认证失败", &github.ErrorResponse{Response: ghResponse(401, nil)}, ErrCodeGitHubUnauthorized},
+		{"仓库已删除", &github.ErrorResponse{Response: ghResponse(404, nil)}, ErrCodeGitHubNotFound},
+		{"服务端错误", fmt.Errorf("retry failed after 4 attempts: %w", &github.ErrorResponse{Response: ghResponse(502, nil)}), ErrCodeGitHubUnavailable},
+		{"网络错误", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrCodeGitHubUnavailable},
+		{"参数错误", &github.ErrorResponse{Response: ghResponse(422, nil)}, ErrCodeGitHubAPI},
+		{"ctx 取消", context.Canceled, ErrCodeGitHubAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := GitHubError("GitHub API 调用失败", tt.err)
+			if got := Code(err); got != tt.want {
+				t.Errorf("Code = %q, want %q (%v)", got, tt.want, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("应该保留原始错误")
+			}
+		})
+	}
+	if GitHubError("GitHub API 调用失败", nil) != nil {
+		t.Error("err 为 nil 时应该返回 nil")
+	}
+}
+
+func TestLLMError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"超时", fmt.Errorf("retry aborted: %w", context.DeadlineExceeded), ErrCodeLLMTimeout},
+		{"gRPC 超时", status.Error(codes.DeadlineExceeded, "deadline"), ErrCodeLLMTimeout},
+		{"配额用尽", quotaError(t, 0), ErrCodeLLMRateLimited},
+		{"HTTP 429", &HTTPError{Service: "OpenAI API", StatusCode: 429}, ErrCodeLLMRateLimited},
+		{"API Key 无效", &googleapi.Error{Code: 401}, ErrCodeLLMUnauthorized},
+		{"没有权限", status.Error(codes.PermissionDenied, "denied"), ErrCodeLLMUnauthorized},
+		{"其他错误", errors.New("boom"), ErrCodeAIProcessing},
+		// 已有的错误码更具体，保留
+		{"安全策略拦截", Permanent(WrapError(ErrCodeLLMSafetyBlocked, "AI 拒绝回答", errors.New("blocked"))), ErrCodeLLMSafetyBlocked},
+		{"超出预算", fmt.Errorf("%w: 今日 $5.00", NewError(ErrCodeLLMBudgetExceeded, "LLM 费用超出预算")), ErrCodeLLMBudgetExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(LLMError("AI 调用失败", tt.err)); got != tt.want {
+				t.Errorf("Code = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Webhook 拒绝", &HTTPError{Service: "飞书 API", StatusCode: 400}, ErrCodeNotifyRejected},
+		{"服务端错误", fmt.Errorf("retry failed after 4 attempts: %w", &HTTPError{Service: "飞书 API", StatusCode: 503}), ErrCodeNotifyUnavailable},
+		{"被限流", &HTTPError{Service: "飞书 API", StatusCode: 429}, ErrCodeNotifyUnavailable},
+		{"网络错误", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrCodeNotifyUnavailable},
+		{"ctx 取消", context.Canceled, ErrCodeNotification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(NotifyError("发送请求失败", tt.err)); got != tt.want {
+				t.Errorf("Code = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	// 没有记录函数时忽略
+	RecordError(context.Background(), errors.New("boom"))
+
+	var recorded []error
+	ctx := WithErrorRecorder(context.Background(), func(err error) { recorded = append(recorded, err) })
+	RecordError(ctx, NewError(ErrCodeLLMEmptyResponse, "AI 返回内容为空"))
+	RecordError(ctx, nil)
+	if len(recorded) != 1 || !HasCode(recorded[0], ErrCodeLLMEmptyResponse) {
+		t.Errorf("recorded = %v", recorded)
+	}
+}
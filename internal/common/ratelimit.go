@@ -0,0 +1,136 @@
+package common
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Clock 限流器使用的时间来源，测试时可以替换为假时钟
+type Clock interface {
+	Now() time.Time
+	// Sleep 等待 d，ctx 取消时提前返回 ctx.Err()
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock 真实时间
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimiter 按每分钟请求数 (RPM) 和每分钟 Token 数 (TPM) 限流的令牌桶，可以并发使用
+// 两个桶的容量都是一分钟的额度，按时间匀速补充；限额为 0 的桶不限制
+type RateLimiter struct {
+	clock Clock
+
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+}
+
+// bucket 令牌桶，available 可以为负数 (实际用量超过预估时欠下的额度)
+type bucket struct {
+	perMinute float64
+	available float64
+	last      time.Time
+}
+
+// NewRateLimiter 创建限流器，rpm、tpm 为 0 表示不限；clock 为 nil 时使用 SystemClock
+func NewRateLimiter(rpm, tpm int, clock Clock) *RateLimiter {
+	if clock == nil {
+		clock = SystemClock
+	}
+	now := clock.Now()
+	return &RateLimiter{
+		clock:    clock,
+		requests: bucket{perMinute: float64(rpm), available: float64(rpm), last: now},
+		tokens:   bucket{perMinute: float64(tpm), available: float64(tpm), last: now},
+	}
+}
+
+// refill 按经过的时间补充额度，最多补满一分钟的额度
+func (b *bucket) refill(now time.Time) {
+	if b.perMinute <= 0 {
+		return
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.available = math.Min(b.perMinute, b.available+elapsed.Minutes()*b.perMinute)
+	}
+	b.last = now
+}
+
+// delay 还要等多久才有 n 个额度，n 超过容量时按容量计算
+func (b *bucket) delay(n float64) time.Duration {
+	if b.perMinute <= 0 {
+		return 0
+	}
+	n = math.Min(n, b.perMinute)
+	if b.available >= n {
+		return 0
+	}
+	return time.Duration(math.Ceil((n - b.available) / b.perMinute * float64(time.Minute)))
+}
+
+// Wait 等到有一个请求和 tokens 个 Token 的额度后扣除，ctx 取消时返回 ctx.Err()
+// tokens 是本次调用的预估用量，调用完成后用 Adjust 修正为实际用量
+func (l *RateLimiter) Wait(ctx context.Context, tokens int64) error {
+	for {
+		l.mu.Lock()
+		now := l.clock.Now()
+		l.requests.refill(now)
+		l.tokens.refill(now)
+		wait := max(l.requests.delay(1), l.tokens.delay(float64(tokens)))
+		if wait == 0 {
+			if l.requests.perMinute > 0 {
+				l.requests.available--
+			}
+			if l.tokens.perMinute > 0 {
+				l.tokens.available -= float64(tokens)
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		if err := l.clock.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Adjust 按实际用量与预估的差额修正 Token 额度，delta 为正时多扣，为负时退还
+func (l *RateLimiter) Adjust(delta int64) {
+	if delta == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tokens.perMinute > 0 {
+		l.tokens.refill(l.clock.Now())
+		l.tokens.available = math.Min(l.tokens.perMinute, l.tokens.available-float64(delta))
+	}
+}
+
+// Throttle 服务商返回限流错误时清空请求额度，之后的请求按 RPM 匀速发出，而不是同时重试
+func (l *RateLimiter) Throttle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.requests.perMinute > 0 {
+		l.requests.refill(l.clock.Now())
+		l.requests.available = math.Min(l.requests.available, 0)
+	}
+}
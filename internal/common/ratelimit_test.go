@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock Sleep 立即把时间拨快 d，并记录每次等待的时长
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) slept() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var total time.Duration
+	for _, d := range c.sleeps {
+		total += d
+	}
+	return total
+}
+
+func TestRateLimiter_RPM(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	limiter := NewRateLimiter(60, 0, clock)
+
+	// 一分钟的额度可以立即使用
+	for i := 0; i < 60; i++ {
+		if err := limiter.Wait(ctx, 1000); err != nil {
+			t.Fatalf("Wait() = %v", err)
+		}
+	}
+	if got := clock.slept(); got != 0 {
+		t.Fatalf("前 60 个请求不应等待，等待了 %v", got)
+	}
+
+	// 之后每秒补充一个
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, 1000); err != nil {
+			t.Fatalf("Wait() = %v", err)
+		}
+	}
+	if got := clock.slept(); got != 3*time.Second {
+		t.Errorf("slept = %v, want 3s", got)
+	}
+
+	// 空闲后最多补满一分钟的额度
+	clock.Advance(time.Hour)
+	limiter.requests.refill(clock.Now())
+	if limiter.requests.available != 60 {
+		t.Errorf("available = %v, want 60", limiter.requests.available)
+	}
+}
+
+func TestRateLimiter_TPM(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	limiter := NewRateLimiter(0, 6000, clock) // 每秒补充 100 个 Token
+
+	if err := limiter.Wait(ctx, 4000); err != nil {
+		t.Fatal(err)
+	}
+	// 还剩 2000，需要 3000，等 10 秒
+	if err := limiter.Wait(ctx, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if got := clock.slept(); got != 10*time.Second {
+		t.Errorf("slept = %v, want 10s", got)
+	}
+
+	// 实际用量比预估多 1000，下一次调用要多等
+	limiter.Adjust(1000)
+	if err := limiter.Wait(ctx, 500); err != nil {
+		t.Fatal(err)
+	}
+	if got := clock.slept(); got != 25*time.Second {
+		t.Errorf("slept = %v, want 25s", got)
+	}
+
+	// 超过容量的请求按容量计算，不会永远等待
+	if err := limiter.Wait(ctx, 100000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimiter_ThrottleAndCancel(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(120, 0, clock)
+
+	// 限流后清空额度，下一个请求等半秒
+	limiter.Throttle()
+	if err := limiter.Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := clock.slept(); got != 500*time.Millisecond {
+		t.Errorf("slept = %v, want 500ms", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, 0); err != context.Canceled {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+
+	// 不限流时不等待
+	unlimited := NewRateLimiter(0, 0, clock)
+	for i := 0; i < 1000; i++ {
+		if err := unlimited.Wait(context.Background(), 1e6); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
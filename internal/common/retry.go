@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"math/rand/v2"
 	"time"
 )
 
@@ -18,8 +20,33 @@ type Config struct {
 	initialDelay time.Duration
 	maxDelay     time.Duration
 	multiplier   float64
+
+	isRetryable   func(error) bool
+	jitter        Jitter
+	onRetry       OnRetryFunc
+	maxRetryAfter time.Duration
+	clock         Clock
 }
 
+// Jitter selects how the backoff delay is randomised so that parallel
+// workers hitting the same failure do not retry in lockstep.
+type Jitter int
+
+const (
+	// NoJitter uses the plain exponential delay.
+	NoJitter Jitter = iota
+	// FullJitter picks a random delay between 0 and the exponential delay.
+	FullJitter
+	// DecorrelatedJitter picks a random delay between the initial delay and
+	// three times the previous delay, capped at the maximum delay.
+	DecorrelatedJitter
+)
+
+// OnRetryFunc is called before sleeping for each retry, with the context passed
+// to Do, the retry number (starting at 1), the error that caused it and the
+// delay before the retry.
+type OnRetryFunc func(ctx context.Context, attempt int, err error, delay time.Duration)
+
 // Option is a functional option for configuring retry behavior.
 type Option func(*Config)
 
@@ -63,6 +90,83 @@ func WithMultiplier(m float64) Option {
 	}
 }
 
+// WithIsRetryable sets the classifier that decides whether an error is worth
+// retrying. Errors it rejects are returned immediately without wrapping.
+// Default is IsRetryable.
+func WithIsRetryable(fn func(error) bool) Option {
+	return func(c *Config) {
+		if fn != nil {
+			c.isRetryable = fn
+		}
+	}
+}
+
+// WithJitter sets the jitter strategy applied to backoff delays.
+// Default is NoJitter.
+func WithJitter(j Jitter) Option {
+	return func(c *Config) {
+		c.jitter = j
+	}
+}
+
+// WithOnRetry sets a hook called before each retry, e.g. for logging or metrics.
+func WithOnRetry(fn OnRetryFunc) Option {
+	return func(c *Config) {
+		c.onRetry = fn
+	}
+}
+
+// WithMaxRetryAfter sets the longest server-requested wait (Retry-After or
+// rate-limit reset) that is honoured. If the server asks for longer, Do gives
+// up instead of blocking. Default is 1 minute.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.maxRetryAfter = d
+		}
+	}
+}
+
+// WithClock sets the clock used for backoff sleeps, mainly for tests.
+// Default is SystemClock.
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// LogRetry returns an option that logs every retry of the named service and
+// reports it to the retry recorder in ctx (see WithRetryRecorder). classify
+// wraps the error with an error code, e.g. GitHubError or LLMError; nil
+// reports the error as is.
+func LogRetry(service string, classify func(message string, err error) error) Option {
+	return WithOnRetry(func(ctx context.Context, attempt int, err error, delay time.Duration) {
+		log.Printf("🔁 %s 调用失败，%s 后第 %d 次重试: %v", service, delay.Round(time.Millisecond), attempt, err)
+		if classify != nil {
+			err = classify(service+" 调用失败", err)
+		}
+		RecordRetry(ctx, err)
+	})
+}
+
+// retryRecorderKey 见 WithRetryRecorder
+type retryRecorderKey struct{}
+
+// WithRetryRecorder 返回带有 record 的 context，LogRetry 把每次重试的错误交给 record，
+// 执行报告据此按错误码统计重试次数；record 可能被并发调用
+func WithRetryRecorder(ctx context.Context, record func(error)) context.Context {
+	return context.WithValue(ctx, retryRecorderKey{}, record)
+}
+
+// RecordRetry 把一次重试的错误交给 ctx 中的记录函数，没有记录函数或 err 为 nil 时忽略
+func RecordRetry(ctx context.Context, err error) {
+	if record, ok := ctx.Value(retryRecorderKey{}).(func(error)); ok && err != nil {
+		record(err)
+	}
+}
+
 // defaultConfig returns the default retry configuration.
 func defaultConfig() *Config {
 	return &Config{
@@ -70,6 +174,10 @@ func defaultConfig() *Config {
 		initialDelay: 1 * time.Second,
 		maxDelay:     30 * time.Second,
 		multiplier:   2.0,
+
+		isRetryable:   IsRetryable,
+		maxRetryAfter: time.Minute,
+		clock:         SystemClock,
 	}
 }
 
@@ -78,8 +186,10 @@ func defaultConfig() *Config {
 //
 // The function will:
 // - Execute immediately on the first attempt
-// - Retry on failure with exponential backoff
+// - Retry on failure with exponential backoff, optionally jittered
+// - Wait at least as long as the server asks via Retry-After or rate-limit reset headers
 // - Return nil if any attempt succeeds
+// - Return a non-retryable error (see IsRetryable) immediately and unwrapped
 // - Return the last error if all attempts fail
 // - Return context.Canceled or context.DeadlineExceeded if context is cancelled
 //
@@ -116,8 +226,18 @@ func Do(ctx context.Context, fn RetryableFunc, opts ...Option) error {
 		lastErr = err
 	}
 
-	// Retry attempts
-	for attempt := 1; attempt <= cfg.maxRetries; attempt++ {
+	prevDelay := cfg.initialDelay
+
+	// Retry attempts. Non-retryable errors are returned as is, also when no
+	// retries are allowed at all.
+	for attempt := 1; ; attempt++ {
+		if !cfg.isRetryable(lastErr) {
+			return unwrapPermanent(lastErr)
+		}
+		if attempt > cfg.maxRetries {
+			return fmt.Errorf("retry failed after %d attempts: %w", attempt, unwrapPermanent(lastErr))
+		}
+
 		// Check context before sleeping
 		select {
 		case <-ctx.Done():
@@ -125,17 +245,23 @@ func Do(ctx context.Context, fn RetryableFunc, opts ...Option) error {
 		default:
 		}
 
-		// Calculate delay with exponential backoff
-		delay := calculateDelay(attempt, cfg.initialDelay, cfg.maxDelay, cfg.multiplier)
+		// Calculate delay with exponential backoff, never shorter than the server asks for
+		delay := cfg.backoff(attempt, prevDelay)
+		if wait, ok := retryAfter(lastErr, cfg.clock.Now()); ok {
+			if wait > cfg.maxRetryAfter {
+				return fmt.Errorf("retry aborted after %d attempts: server asked to wait %s: %w", attempt, wait.Round(time.Second), lastErr)
+			}
+			delay = max(delay, wait)
+		}
+		prevDelay = delay
+
+		if cfg.onRetry != nil {
+			cfg.onRetry(ctx, attempt, lastErr, delay)
+		}
 
 		// Sleep with context cancellation support
-		timer := time.NewTimer(delay)
-		select {
-		case <-ctx.Done():
-			timer.Stop()
-			return fmt.Errorf("retry aborted during backoff (attempt %d/%d): %w", attempt, cfg.maxRetries, ctx.Err())
-		case <-timer.C:
-			// Continue to next attempt
+		if err := cfg.clock.Sleep(ctx, delay); err != nil {
+			return fmt.Errorf("retry aborted during backoff (attempt %d/%d): %w", attempt, cfg.maxRetries, err)
 		}
 
 		// Execute the function
@@ -145,9 +271,35 @@ func Do(ctx context.Context, fn RetryableFunc, opts ...Option) error {
 			lastErr = err
 		}
 	}
+}
+
+// unwrapPermanent strips the Permanent marker so callers see the original error.
+func unwrapPermanent(err error) error {
+	if p, ok := err.(*permanentError); ok {
+		return p.err
+	}
+	return err
+}
 
-	// All retries exhausted
-	return fmt.Errorf("retry failed after %d attempts: %w", cfg.maxRetries+1, lastErr)
+// backoff computes the delay before the given retry according to the jitter strategy.
+// prevDelay is the delay used before the previous retry (initialDelay for the first).
+func (c *Config) backoff(attempt int, prevDelay time.Duration) time.Duration {
+	switch c.jitter {
+	case FullJitter:
+		return randomDuration(0, calculateDelay(attempt, c.initialDelay, c.maxDelay, c.multiplier))
+	case DecorrelatedJitter:
+		return min(c.maxDelay, randomDuration(c.initialDelay, 3*prevDelay))
+	default:
+		return calculateDelay(attempt, c.initialDelay, c.maxDelay, c.multiplier)
+	}
+}
+
+// randomDuration returns a random duration in [lo, hi], or lo if hi <= lo.
+func randomDuration(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Int64N(int64(hi-lo)+1))
 }
 
 // calculateDelay computes the delay for the current attempt using exponential backoff.
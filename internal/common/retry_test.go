@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -380,6 +381,212 @@ func TestDo_InvalidOptions(t *testing.T) {
 	}
 }
 
+func TestDo_NonRetryableError(t *testing.T) {
+	permanentErr := errors.New("bad credentials")
+	attempts := 0
+
+	err := Do(context.Background(), func() error {
+		attempts++
+		return &HTTPError{Service: "GitHub API", StatusCode: 401}
+	}, WithInitialDelay(1*time.Millisecond))
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 401 {
+		t.Errorf("expected the 401 error to be returned, got: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt for a non-retryable error, got %d", attempts)
+	}
+
+	// Permanent errors are returned unwrapped
+	err = Do(context.Background(), func() error {
+		return Permanent(permanentErr)
+	}, WithInitialDelay(1*time.Millisecond))
+	if err != permanentErr {
+		t.Errorf("expected the original error, got: %v", err)
+	}
+
+	// A custom classifier replaces the default one
+	attempts = 0
+	err = Do(context.Background(), func() error {
+		attempts++
+		return &HTTPError{Service: "GitHub API", StatusCode: 401}
+	}, WithInitialDelay(1*time.Millisecond), WithMaxRetries(2), WithIsRetryable(func(error) bool { return true }))
+	if err == nil || attempts != 3 {
+		t.Errorf("expected 3 attempts with a custom classifier, got %d (err: %v)", attempts, err)
+	}
+}
+
+func TestDo_NonRetryableErrorWithoutRetries(t *testing.T) {
+	httpErr := &HTTPError{Service: "GitHub API", StatusCode: 401}
+	err := Do(context.Background(), func() error {
+		return httpErr
+	}, WithMaxRetries(0))
+	if err != httpErr {
+		t.Errorf("expected the 401 error to be returned unwrapped, got: %v", err)
+	}
+
+	permanentErr := errors.New("bad credentials")
+	err = Do(context.Background(), func() error {
+		return Permanent(permanentErr)
+	}, WithMaxRetries(0))
+	if err != permanentErr {
+		t.Errorf("expected the original error, got: %v", err)
+	}
+
+	failure := errors.New("temporary failure")
+	err = Do(context.Background(), func() error {
+		return failure
+	}, WithMaxRetries(0))
+	if !errors.Is(err, failure) || !contains(err.Error(), "retry failed after 1 attempts") {
+		t.Errorf("expected wrapped failure after 1 attempt, got: %v", err)
+	}
+}
+
+func TestLogRetry_RecordsRetries(t *testing.T) {
+	var recorded []string
+	ctx := WithRetryRecorder(context.Background(), func(err error) {
+		recorded = append(recorded, Code(err))
+	})
+	attempts := 0
+
+	err := Do(ctx, func() error {
+		attempts++
+		if attempts < 3 {
+			return &HTTPError{Service: "GitHub API", StatusCode: 502}
+		}
+		return nil
+	}, WithClock(newFakeClock()), LogRetry("GitHub API", GitHubError))
+
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	want := []string{ErrCodeGitHubUnavailable, ErrCodeGitHubUnavailable}
+	if len(recorded) != len(want) || recorded[0] != want[0] || recorded[1] != want[1] {
+		t.Errorf("recorded retries = %v, want %v", recorded, want)
+	}
+}
+
+func TestDo_HonoursRetryAfter(t *testing.T) {
+	clock := newFakeClock()
+	attempts := 0
+
+	err := Do(context.Background(), func() error {
+		attempts++
+		if attempts == 1 {
+			return &HTTPError{StatusCode: 429, Header: http.Header{"Retry-After": {"20"}}}
+		}
+		return nil
+	}, WithClock(clock), WithInitialDelay(time.Second))
+
+	if err != nil {
+		t.Fatalf("expected success, got: %v", err)
+	}
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 20*time.Second {
+		t.Errorf("expected a single 20s wait, got %v", clock.sleeps)
+	}
+
+	// Waits longer than the limit abort instead of blocking
+	attempts = 0
+	err = Do(context.Background(), func() error {
+		attempts++
+		return &HTTPError{StatusCode: 429, Header: http.Header{"Retry-After": {"3600"}}}
+	}, WithClock(clock), WithMaxRetryAfter(time.Minute))
+	if err == nil || !contains(err.Error(), "server asked to wait 1h0m0s") {
+		t.Errorf("expected abort because of the long Retry-After, got: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestDo_OnRetry(t *testing.T) {
+	type call struct {
+		attempt int
+		delay   time.Duration
+	}
+	var calls []call
+	failure := errors.New("temporary failure")
+
+	err := Do(context.Background(), func() error {
+		return failure
+	},
+		WithClock(newFakeClock()),
+		WithMaxRetries(3),
+		WithInitialDelay(time.Second),
+		WithOnRetry(func(ctx context.Context, attempt int, err error, delay time.Duration) {
+			if err != failure {
+				t.Errorf("unexpected error passed to hook: %v", err)
+			}
+			calls = append(calls, call{attempt, delay})
+		}),
+	)
+
+	if !errors.Is(err, failure) {
+		t.Errorf("expected wrapped failure, got: %v", err)
+	}
+	want := []call{{1, time.Second}, {2, 2 * time.Second}, {3, 4 * time.Second}}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d hook calls, got %v", len(want), calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("hook call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestDo_Jitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		jitter Jitter
+		lo, hi func(attempt int, prev time.Duration) time.Duration
+	}{
+		{
+			name:   "full jitter",
+			jitter: FullJitter,
+			lo:     func(int, time.Duration) time.Duration { return 0 },
+			hi: func(attempt int, _ time.Duration) time.Duration {
+				return calculateDelay(attempt, 100*time.Millisecond, 2*time.Second, 2)
+			},
+		},
+		{
+			name:   "decorrelated jitter",
+			jitter: DecorrelatedJitter,
+			lo:     func(int, time.Duration) time.Duration { return 100 * time.Millisecond },
+			hi: func(_ int, prev time.Duration) time.Duration {
+				return min(2*time.Second, 3*prev)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for run := 0; run < 50; run++ {
+				clock := newFakeClock()
+				_ = Do(context.Background(), func() error {
+					return errors.New("temporary failure")
+				},
+					WithClock(clock),
+					WithMaxRetries(6),
+					WithInitialDelay(100*time.Millisecond),
+					WithMaxDelay(2*time.Second),
+					WithJitter(tt.jitter),
+				)
+
+				prev := 100 * time.Millisecond
+				for i, d := range clock.sleeps {
+					lo, hi := tt.lo(i+1, prev), tt.hi(i+1, prev)
+					if d < lo || d > hi {
+						t.Fatalf("retry %d delay %v outside [%v, %v]", i+1, d, lo, hi)
+					}
+					prev = d
+				}
+			}
+		})
+	}
+}
+
 // Benchmark tests
 func BenchmarkDo_Success(b *testing.B) {
 	ctx := context.Background()
@@ -0,0 +1,215 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPError HTTP 接口返回的非 2xx 响应，保留状态码和响应头，供 IsRetryable、RetryAfter 判断
+type HTTPError struct {
+	Service    string // 出错的服务，如 "OpenAI API"
+	StatusCode int
+	Header     http.Header
+	Body       string // 响应体的开头部分，便于排查
+}
+
+// NewHTTPError 根据响应创建 HTTPError，最多读取 512 字节响应体，不关闭 resp.Body
+func NewHTTPError(service string, resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &HTTPError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s 报错: 状态码 %d", e.Service, e.StatusCode)
+	}
+	return fmt.Sprintf("%s 报错: 状态码 %d: %s", e.Service, e.StatusCode, e.Body)
+}
+
+// HTTPCode 返回 HTTP 状态码，与 apierror.APIError 的方法同名
+func (e *HTTPError) HTTPCode() int {
+	return e.StatusCode
+}
+
+// permanentError 调用方明确标记为不可重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 把 err 标记为不可重试，Do 立即返回 err 本身；err 为 nil 时返回 nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsRetryable 默认的错误分类，判断重试能否解决 err:
+//   - 可以重试: GitHub 限流、HTTP 408 / 425 / 429 / 5xx (501 除外)、gRPC Unavailable / ResourceExhausted 等临时错误、
+//     调用超时，以及网络错误等无法识别的错误
+//   - 不可重试: 其他 HTTP 4xx (认证失败、仓库已删除等)、gRPC InvalidArgument / PermissionDenied 等、
+//     JSON 解析错误、ctx 取消，以及用 Permanent 标记的错误
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+		return true
+	}
+	if code, ok := HTTPStatus(err); ok {
+		return retryableStatus(code)
+	}
+	if s, ok := status.FromError(err); ok {
+		return retryableCode(s.Code())
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return false
+	}
+	return true
+}
+
+// HTTPStatus 从 GitHub、googleapi 以及带 HTTPCode 方法的错误中取出 HTTP 状态码
+func HTTPStatus(err error) (int, bool) {
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.StatusCode, true
+	}
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) && rateErr.Response != nil {
+		return rateErr.Response.StatusCode, true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.Response != nil {
+		return abuseErr.Response.StatusCode, true
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, true
+	}
+	// apierror.APIError 包装 gRPC 错误时返回 -1
+	var coded interface{ HTTPCode() int }
+	if errors.As(err, &coded) && coded.HTTPCode() > 0 {
+		return coded.HTTPCode(), true
+	}
+	return 0, false
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+	return code >= 500
+}
+
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// RetryAfter 返回服务端要求的等待时间：GitHub 限流的重置时间、Retry-After 响应头、
+// X-RateLimit-Reset 响应头 (剩余额度为 0 时) 或 Google API 的 RetryInfo
+func RetryAfter(err error) (time.Duration, bool) {
+	return retryAfter(err, time.Now())
+}
+
+func retryAfter(err error, now time.Time) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) && !rateErr.Rate.Reset.IsZero() {
+		return max(rateErr.Rate.Reset.Sub(now), 0), true
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.RetryAfter != nil {
+		return max(*abuseErr.RetryAfter, 0), true
+	}
+	var ae *apierror.APIError
+	if errors.As(err, &ae) {
+		if info := ae.Details().RetryInfo; info.GetRetryDelay() != nil {
+			return max(info.GetRetryDelay().AsDuration(), 0), true
+		}
+	}
+	return headerRetryAfter(errorHeader(err), now)
+}
+
+// errorHeader 取出错误对应的 HTTP 响应头，没有时返回 nil
+func errorHeader(err error) http.Header {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Header
+	}
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.Header
+	}
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) && rateErr.Response != nil {
+		return rateErr.Response.Header
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.Response != nil {
+		return abuseErr.Response.Header
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Header
+	}
+	return nil
+}
+
+// headerRetryAfter 解析 Retry-After (秒数或 HTTP 日期)；没有时在剩余额度为 0 的情况下使用 X-RateLimit-Reset (Unix 时间)
+func headerRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if h == nil {
+		return 0, false
+	}
+	if v := strings.TrimSpace(h.Get("Retry-After")); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return max(at.Sub(now), 0), true
+		}
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
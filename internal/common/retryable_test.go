@@ -0,0 +1,151 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v53/github"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ghResponse 构造 go-github 错误里的响应，Error() 需要 Request
+func ghResponse(code int, header http.Header) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/acme/coder", nil)
+	return &http.Response{StatusCode: code, Header: header, Request: req}
+}
+
+// quotaError Gemini 配额用尽时的 gRPC 错误，带 RetryInfo
+func quotaError(t *testing.T, delay time.Duration) error {
+	t.Helper()
+	st, err := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ae, ok := apierror.FromError(st.Err())
+	if !ok {
+		t.Fatal("apierror.FromError 失败")
+	}
+	return ae
+}
+
+func TestIsRetryable(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"网络错误", errors.New("connection reset by peer"), true},
+		{"调用超时", context.DeadlineExceeded, true},
+		{"ctx 取消", fmt.Errorf("调用失败: %w", context.Canceled), false},
+		{"Permanent", Permanent(errors.New("safety")), false},
+		{"JSON 解析错误", syntaxErr, false},
+		{"GitHub 认证失败", &github.ErrorResponse{Response: ghResponse(401, nil)}, false},
+		{"GitHub 仓库已删除", fmt.Errorf("GitHub API 调用失败: %w", &github.ErrorResponse{Response: ghResponse(404, nil)}), false},
+		{"GitHub 参数错误", &github.ErrorResponse{Response: ghResponse(422, nil)}, false},
+		{"GitHub 服务端错误", &github.ErrorResponse{Response: ghResponse(502, nil)}, true},
+		{"GitHub 限流", &github.RateLimitError{Response: ghResponse(403, nil)}, true},
+		{"GitHub 二级限流", &github.AbuseRateLimitError{Response: ghResponse(403, nil)}, true},
+		{"googleapi 429", &googleapi.Error{Code: 429}, true},
+		{"googleapi 400", &googleapi.Error{Code: 400}, false},
+		{"googleapi 501", &googleapi.Error{Code: 501}, false},
+		{"HTTPCode 503", httpError(503), true},
+		{"HTTPCode 408", httpError(408), true},
+		{"HTTPError 401", &HTTPError{Service: "OpenAI API", StatusCode: 401}, false},
+		{"gRPC Unavailable", status.Error(codes.Unavailable, "unavailable"), true},
+		{"gRPC InvalidArgument", status.Error(codes.InvalidArgument, "bad request"), false},
+		{"gRPC PermissionDenied", fmt.Errorf("AI 调用失败: %w", status.Error(codes.PermissionDenied, "api key")), false},
+		{"apierror ResourceExhausted", quotaError(t, time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	abuseWait := 90 * time.Second
+	tests := []struct {
+		name   string
+		err    error
+		want   time.Duration
+		wantOK bool
+	}{
+		{"没有提示", errors.New("boom"), 0, false},
+		{"GitHub 限流重置时间", &github.RateLimitError{
+			Rate:     github.Rate{Reset: github.Timestamp{Time: now.Add(42 * time.Second)}},
+			Response: ghResponse(403, nil),
+		}, 42 * time.Second, true},
+		{"重置时间已过", &github.RateLimitError{
+			Rate:     github.Rate{Reset: github.Timestamp{Time: now.Add(-time.Second)}},
+			Response: ghResponse(403, nil),
+		}, 0, true},
+		{"GitHub 二级限流", &github.AbuseRateLimitError{Response: ghResponse(403, nil), RetryAfter: &abuseWait}, abuseWait, true},
+		{"Retry-After 秒数", &github.ErrorResponse{Response: ghResponse(503, http.Header{"Retry-After": {"7"}})}, 7 * time.Second, true},
+		{"Retry-After 日期", &googleapi.Error{Code: 429, Header: http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}}, time.Minute, true},
+		{"X-RateLimit-Reset", &HTTPError{StatusCode: 429, Header: http.Header{
+			"X-Ratelimit-Remaining": {"0"},
+			"X-Ratelimit-Reset":     {fmt.Sprint(now.Add(30 * time.Second).Unix())},
+		}}, 30 * time.Second, true},
+		{"还有剩余额度时忽略 X-RateLimit-Reset", &HTTPError{StatusCode: 500, Header: http.Header{
+			"X-Ratelimit-Remaining": {"10"},
+			"X-Ratelimit-Reset":     {fmt.Sprint(now.Add(30 * time.Second).Unix())},
+		}}, 0, false},
+		{"RetryInfo", fmt.Errorf("AI 调用失败: %w", quotaError(t, 37*time.Second)), 37 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfter(tt.err, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewHTTPError(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 401,
+		Header:     http.Header{"Retry-After": {"3"}},
+		Body:       io.NopCloser(strings.NewReader(" invalid key \n")),
+	}
+	err := NewHTTPError("OpenAI API", resp)
+	if got, want := err.Error(), "OpenAI API 报错: 状态码 401: invalid key"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if code, ok := HTTPStatus(fmt.Errorf("向量化失败: %w", err)); !ok || code != 401 {
+		t.Errorf("HTTPStatus = (%d, %v), want (401, true)", code, ok)
+	}
+	if got := (&HTTPError{Service: "飞书 API", StatusCode: 500}).Error(); got != "飞书 API 报错: 状态码 500" {
+		t.Errorf("没有响应体时 Error() = %q", got)
+	}
+}
+
+func TestIsOverloaded_StatusCodes(t *testing.T) {
+	if !IsOverloaded(context.Background(), status.Error(codes.ResourceExhausted, "quota")) {
+		t.Error("gRPC ResourceExhausted 应视为限流")
+	}
+	if !IsOverloaded(context.Background(), &googleapi.Error{Code: 429}) {
+		t.Error("googleapi 429 应视为限流")
+	}
+	if IsOverloaded(context.Background(), &github.ErrorResponse{Response: ghResponse(404, nil), Message: "Not Found"}) {
+		t.Error("404 不是限流")
+	}
+}
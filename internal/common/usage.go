@@ -0,0 +1,45 @@
+package common
+
+import "context"
+
+// UsageMeter 统计 LLM 用量并执行费用预算，通过 context 传给 LLM 适配器，可以并发使用
+type UsageMeter interface {
+	// Allow 调用 LLM 前检查预算，超出预算时返回错误；items 为这次调用评估的项目数
+	Allow(ctx context.Context, items int) error
+
+	// Record 记录一次调用的输入、输出 Token 数 (重试的调用同样计费)
+	Record(ctx context.Context, model string, promptTokens, completionTokens int64)
+}
+
+type usageMeterKey struct{}
+
+// WithUsageMeter 返回带有 meter 的 context，之后的 LLM 调用用量都记录到 meter
+func WithUsageMeter(ctx context.Context, meter UsageMeter) context.Context {
+	return context.WithValue(ctx, usageMeterKey{}, meter)
+}
+
+// HasUsageMeter ctx 中是否已经有 UsageMeter
+func HasUsageMeter(ctx context.Context) bool {
+	_, ok := ctx.Value(usageMeterKey{}).(UsageMeter)
+	return ok
+}
+
+// AllowLLMCall 按 ctx 中的 UsageMeter 检查预算，没有 UsageMeter 时总是允许
+func AllowLLMCall(ctx context.Context) error {
+	return AllowLLMBatch(ctx, 1)
+}
+
+// AllowLLMBatch 与 AllowLLMCall 相同，用于一次调用评估 items 个项目的批量评估
+func AllowLLMBatch(ctx context.Context, items int) error {
+	if meter, ok := ctx.Value(usageMeterKey{}).(UsageMeter); ok {
+		return meter.Allow(ctx, items)
+	}
+	return nil
+}
+
+// RecordUsage 把一次 LLM 调用的用量记录到 ctx 中的 UsageMeter，没有 UsageMeter 时忽略
+func RecordUsage(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	if meter, ok := ctx.Value(usageMeterKey{}).(UsageMeter); ok {
+		meter.Record(ctx, model, promptTokens, completionTokens)
+	}
+}
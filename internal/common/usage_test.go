@@ -0,0 +1,45 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// budgetMeter 累计 Token 数，达到 limit 后拒绝调用
+type budgetMeter struct {
+	limit, used int64
+}
+
+func (m *budgetMeter) Allow(ctx context.Context, items int) error {
+	if m.used >= m.limit {
+		return errors.New("超出预算")
+	}
+	return nil
+}
+
+func (m *budgetMeter) Record(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	m.used += promptTokens + completionTokens
+}
+
+func TestUsageMeter(t *testing.T) {
+	// 没有 UsageMeter 时总是允许，用量被忽略
+	ctx := context.Background()
+	if err := AllowLLMCall(ctx); err != nil {
+		t.Fatalf("AllowLLMCall() = %v, want nil", err)
+	}
+	RecordUsage(ctx, "gemini-2.5-pro", 100, 20)
+
+	meter := &budgetMeter{limit: 150}
+	ctx = WithUsageMeter(ctx, meter)
+	if err := AllowLLMCall(ctx); err != nil {
+		t.Fatalf("AllowLLMCall() = %v, want nil", err)
+	}
+	RecordUsage(ctx, "gemini-2.5-pro", 100, 50)
+	if meter.used != 150 {
+		t.Errorf("used = %d, want 150", meter.used)
+	}
+	if err := AllowLLMCall(ctx); err == nil {
+		t.Error("超出预算后 AllowLLMCall() 应返回错误")
+	}
+}
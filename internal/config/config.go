@@ -0,0 +1,531 @@
+// Package config 读取程序配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath 未通过 -config 指定时，当前目录下存在该文件则自动读取
+const DefaultPath = "gold-miner.yaml"
+
+// DefaultDSN 未配置数据库时使用的本地数据库，无需安装 Postgres
+const DefaultDSN = "sqlite://gold_miner.db"
+
+// redacted 密钥在 config print 中的显示
+const redacted = "******"
+
+// Config 程序配置
+type Config struct {
+	Database  DatabaseConfig  `yaml:"database" json:"database"`
+	GitHub    GitHubConfig    `yaml:"github" json:"github"`
+	Gemini    GeminiConfig    `yaml:"gemini" json:"gemini"`
+	LLMCache  LLMCacheConfig  `yaml:"llm_cache" json:"llm_cache"`
+	LLMBudget LLMBudgetConfig `yaml:"llm_budget" json:"llm_budget"`
+	OpenAI    OpenAIConfig    `yaml:"openai" json:"openai"`
+	Ollama    OllamaConfig    `yaml:"ollama" json:"ollama"`
+	Notify    NotifyConfig    `yaml:"notify" json:"notify"`
+	Mining    MiningConfig    `yaml:"mining" json:"mining"`
+	Tracking  TrackingConfig  `yaml:"tracking" json:"tracking"`
+	Search    SearchConfig    `yaml:"search" json:"search"`
+	Serve     ServeConfig     `yaml:"serve" json:"serve"`
+
+	// Profiles 挖矿方向，共用同一个数据库；为空时只有一个使用 mining 和 notify 配置的默认方向
+	Profiles []ProfileConfig `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+}
+
+// DatabaseConfig 数据库配置
+type DatabaseConfig struct {
+	URL string `yaml:"url" json:"url"` // DATABASE_URL，postgres://... 或 sqlite://文件路径
+}
+
+// GitHubConfig GitHub API 配置
+type GitHubConfig struct {
+	Token string `yaml:"token" json:"token"` // GITHUB_TOKEN
+}
+
+// GeminiConfig Gemini 配置，用于项目评估和语义搜索
+type GeminiConfig struct {
+	APIKey         string `yaml:"api_key" json:"api_key"`                 // GEMINI_API_KEY
+	RPM            int    `yaml:"rpm" json:"rpm"`                         // 每分钟请求数上限，0 表示不限
+	TPM            int    `yaml:"tpm" json:"tpm"`                         // 每分钟 Token 数上限，0 表示不限
+	MaxConcurrency int    `yaml:"max_concurrency" json:"max_concurrency"` // 所有挖矿方向共用的自适应并发上限，0 表示与 mining.concurrency 相同
+}
+
+// LLMCacheConfig LLM 评估结果缓存配置，缓存保存在数据库中
+type LLMCacheConfig struct {
+	TTL        time.Duration `yaml:"ttl" json:"ttl"`                 // 缓存有效期，0 表示不使用缓存
+	MaxEntries int           `yaml:"max_entries" json:"max_entries"` // 最多保留的缓存条数
+}
+
+// LLMBudgetConfig LLM 费用预算和模型单价，金额单位为美元，0 表示不限
+type LLMBudgetConfig struct {
+	DailyUSD   float64               `yaml:"daily_usd" json:"daily_usd"`               // 所有挖矿方向每天的费用上限
+	MonthlyUSD float64               `yaml:"monthly_usd" json:"monthly_usd"`           // 所有挖矿方向每月的费用上限
+	Prices     map[string]ModelPrice `yaml:"prices,omitempty" json:"prices,omitempty"` // 模型单价，覆盖或补充内置的单价表
+}
+
+// ModelPrice 模型单价，单位为美元 / 百万 Token
+type ModelPrice struct {
+	Prompt     float64 `yaml:"prompt" json:"prompt"`
+	Completion float64 `yaml:"completion" json:"completion"`
+}
+
+// OpenAIConfig OpenAI 兼容接口配置，用于 Embedding
+type OpenAIConfig struct {
+	APIKey  string `yaml:"api_key" json:"api_key"`   // OPENAI_API_KEY
+	BaseURL string `yaml:"base_url" json:"base_url"` // OPENAI_BASE_URL
+}
+
+// OllamaConfig 本地 Ollama 配置，用于 Embedding
+type OllamaConfig struct {
+	Host string `yaml:"host" json:"host"` // OLLAMA_HOST
+}
+
+// NotifyConfig 通知配置
+type NotifyConfig struct {
+	FeishuWebhook string `yaml:"feishu_webhook" json:"feishu_webhook"` // FEISHU_WEBHOOK，默认推送渠道
+	Routes        string `yaml:"routes" json:"routes"`                 // 通知路由规则文件 (YAML)
+	Templates     string `yaml:"templates" json:"templates"`           // 自定义通知模板目录
+}
+
+// MiningConfig 挖矿配置
+type MiningConfig struct {
+	Topics          []string      `yaml:"topics" json:"topics"`                           // 除 Trending 外额外抓取的 GitHub topics
+	Languages       []string      `yaml:"languages,omitempty" json:"languages,omitempty"` // 按编程语言抓取 Trending 并过滤，为空表示不限语言
+	Criteria        string        `yaml:"criteria,omitempty" json:"criteria,omitempty"`   // LLM 评估标准，为空时判断是否为 AI 编程工具
+	MaxAgeDays      int           `yaml:"max_age_days" json:"max_age_days"`               // 只保留创建时间在这么多天内的项目
+	MinScore        int           `yaml:"min_score" json:"min_score"`                     // 推送所需的最低 LLM 评分
+	CycleTimeout    time.Duration `yaml:"cycle_timeout" json:"cycle_timeout"`             // 一轮挖矿的最长执行时间
+	AppraiseTimeout time.Duration `yaml:"appraise_timeout" json:"appraise_timeout"`       // 单个项目 LLM 评估的超时时间
+	Concurrency     int           `yaml:"concurrency" json:"concurrency"`                 // LLM 评估并发数
+	BatchSize       int           `yaml:"batch_size" json:"batch_size"`                   // 每次 LLM 调用评估的项目数，1 表示逐个评估
+	Pipeline        string        `yaml:"pipeline" json:"pipeline"`                       // 流水线配置文件 (YAML)
+	Schedule        string        `yaml:"schedule" json:"schedule"`                       // 定时执行的 cron 表达式
+	Interval        time.Duration `yaml:"interval" json:"interval"`                       // 按间隔执行，0 表示只执行一次
+	DailyBudgetUSD  float64       `yaml:"daily_budget_usd" json:"daily_budget_usd"`       // 每个挖矿方向每天的 LLM 费用上限 (美元)，0 表示不限
+}
+
+// TrackingConfig 推送后追踪配置
+type TrackingConfig struct {
+	Milestones []int         `yaml:"milestones" json:"milestones"`   // Star 里程碑
+	MinGrowth  float64       `yaml:"min_growth" json:"min_growth"`   // 越过里程碑时 Star 数至少是推送时的多少倍才发送爆发通知
+	Retract    []string      `yaml:"retract" json:"retract"`         // 发送撤回通知的状态变化: archived、deleted
+	Window     time.Duration `yaml:"window" json:"window"`           // 只追踪这段时间内推送过的项目
+	CheckEvery time.Duration `yaml:"check_every" json:"check_every"` // 同一项目两次检查的最小间隔
+}
+
+// ProfileTrackingConfig 挖矿方向单独的爆发通知阈值，未设置的字段沿用 tracking 中的全局配置
+type ProfileTrackingConfig struct {
+	Milestones []int   `yaml:"milestones,omitempty" json:"milestones,omitempty"` // Star 里程碑
+	MinGrowth  float64 `yaml:"min_growth,omitempty" json:"min_growth,omitempty"` // 最小增长倍数
+}
+
+// SearchConfig 语义搜索配置
+type SearchConfig struct {
+	Embedder   string `yaml:"embedder" json:"embedder"`       // gemini、openai、ollama、none 或 auto
+	EmbedModel string `yaml:"embed_model" json:"embed_model"` // 为空时使用各服务商的默认模型
+	TopK       int    `yaml:"top_k" json:"top_k"`             // 向量检索召回的项目数
+}
+
+// ServeConfig REST API 服务配置
+type ServeConfig struct {
+	Listen   string `yaml:"listen" json:"listen"`       // 监听地址
+	APIToken string `yaml:"api_token" json:"api_token"` // API_TOKEN
+}
+
+// ProfileConfig 挖矿方向：有自己的数据源、过滤条件、评估标准、推送阈值、执行计划和通知渠道
+// 未设置的字段沿用 mining 和 notify 中的全局配置
+type ProfileConfig struct {
+	Name       string        `yaml:"name" json:"name"`                                     // 方向名称，项目按名称标记发现它的方向
+	Topics     []string      `yaml:"topics,omitempty" json:"topics,omitempty"`             // 额外抓取的 GitHub topics
+	Languages  []string      `yaml:"languages,omitempty" json:"languages,omitempty"`       // 按编程语言抓取 Trending 并过滤
+	Criteria   string        `yaml:"criteria,omitempty" json:"criteria,omitempty"`         // LLM 评估标准
+	MaxAgeDays int           `yaml:"max_age_days,omitempty" json:"max_age_days,omitempty"` // 只保留创建时间在这么多天内的项目
+	MinScore   *int          `yaml:"min_score,omitempty" json:"min_score,omitempty"`       // 推送所需的最低 LLM 评分
+	Pipeline   string        `yaml:"pipeline,omitempty" json:"pipeline,omitempty"`         // 流水线配置文件 (YAML)
+	Schedule   string        `yaml:"schedule,omitempty" json:"schedule,omitempty"`         // 定时执行的 cron 表达式
+	Interval   time.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`         // 按间隔执行
+	Notify     NotifyConfig  `yaml:"notify,omitempty" json:"notify,omitempty"`             // 通知渠道
+
+	Tracking       ProfileTrackingConfig `yaml:"tracking,omitempty" json:"tracking,omitempty"`                 // 推送后追踪的爆发阈值
+	DailyBudgetUSD float64               `yaml:"daily_budget_usd,omitempty" json:"daily_budget_usd,omitempty"` // 本方向每天的 LLM 费用上限 (美元)
+}
+
+// Profile 合并了全局配置后的挖矿方向
+type Profile struct {
+	Name     string // 未配置挖矿方向时为空
+	Mining   MiningConfig
+	Notify   NotifyConfig
+	Tracking TrackingConfig
+}
+
+// MaxBatchSize 批量评估每批最多的项目数，过大的批次容易让 LLM 漏掉项目或超出输出长度
+const MaxBatchSize = 50
+
+// profileName 挖矿方向名称只能包含小写字母、数字、- 和 _
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Default 默认配置，与不使用配置文件时的行为一致
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{URL: DefaultDSN},
+		LLMCache: LLMCacheConfig{TTL: 7 * 24 * time.Hour, MaxEntries: 10000},
+		Mining: MiningConfig{
+			Topics:          []string{"ai-coding", "ide-extension", "dev-tools"},
+			MaxAgeDays:      10,
+			MinScore:        50,
+			CycleTimeout:    5 * time.Minute,
+			AppraiseTimeout: 30 * time.Second,
+			Concurrency:     3,
+			BatchSize:       1,
+		},
+		Tracking: TrackingConfig{
+			Milestones: []int{1000, 5000, 10000, 50000},
+			MinGrowth:  2,
+			Retract:    []string{"archived", "deleted"},
+			Window:     30 * 24 * time.Hour,
+			CheckEvery: 6 * time.Hour,
+		},
+		Search: SearchConfig{Embedder: "auto", TopK: 30},
+		Serve:  ServeConfig{Listen: ":8080"},
+	}
+}
+
+// Load 依次应用默认值、配置文件 (path 为空时跳过) 和环境变量
+func Load(path string) (*Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		if err := decode(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	}
+	for _, b := range cfg.envBindings() {
+		if v, ok := lookupEnv(b.name); ok && v != "" {
+			*b.target = v
+		}
+	}
+	return cfg, nil
+}
+
+// decode 解析 YAML，拼错的字段名视为错误而不是静默忽略
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// envBinding 可以用环境变量覆盖的配置项
+type envBinding struct {
+	name   string
+	target *string
+	secret bool
+}
+
+func (c *Config) envBindings() []envBinding {
+	return []envBinding{
+		{"DATABASE_URL", &c.Database.URL, false}, // 密码在 Redacted 中单独处理
+		{"GITHUB_TOKEN", &c.GitHub.Token, true},
+		{"GEMINI_API_KEY", &c.Gemini.APIKey, true},
+		{"OPENAI_API_KEY", &c.OpenAI.APIKey, true},
+		{"OPENAI_BASE_URL", &c.OpenAI.BaseURL, false},
+		{"OLLAMA_HOST", &c.Ollama.Host, false},
+		{"FEISHU_WEBHOOK", &c.Notify.FeishuWebhook, true},
+		{"API_TOKEN", &c.Serve.APIToken, true},
+	}
+}
+
+// ResolveProfiles 返回所有挖矿方向，未设置的字段使用全局配置；没有配置挖矿方向时返回一个名称为空的默认方向
+func (c *Config) ResolveProfiles() []Profile {
+	if len(c.Profiles) == 0 {
+		return []Profile{{Mining: c.Mining, Notify: c.Notify, Tracking: c.Tracking}}
+	}
+	profiles := make([]Profile, 0, len(c.Profiles))
+	for _, p := range c.Profiles {
+		m := c.Mining
+		if len(p.Topics) > 0 {
+			m.Topics = p.Topics
+		}
+		if len(p.Languages) > 0 {
+			m.Languages = p.Languages
+		}
+		if p.Criteria != "" {
+			m.Criteria = p.Criteria
+		}
+		if p.MaxAgeDays > 0 {
+			m.MaxAgeDays = p.MaxAgeDays
+		}
+		if p.MinScore != nil {
+			m.MinScore = *p.MinScore
+		}
+		if p.Pipeline != "" {
+			m.Pipeline = p.Pipeline
+		}
+		if p.DailyBudgetUSD > 0 {
+			m.DailyBudgetUSD = p.DailyBudgetUSD
+		}
+		// 执行计划整体覆盖，避免方向的 cron 和全局的间隔同时生效
+		if p.Schedule != "" || p.Interval > 0 {
+			m.Schedule, m.Interval = p.Schedule, p.Interval
+		}
+
+		n := c.Notify
+		if p.Notify.FeishuWebhook != "" {
+			n.FeishuWebhook = p.Notify.FeishuWebhook
+		}
+		if p.Notify.Routes != "" {
+			n.Routes = p.Notify.Routes
+		}
+		if p.Notify.Templates != "" {
+			n.Templates = p.Notify.Templates
+		}
+		t := c.Tracking
+		if len(p.Tracking.Milestones) > 0 {
+			t.Milestones = p.Tracking.Milestones
+		}
+		if p.Tracking.MinGrowth > 0 {
+			t.MinGrowth = p.Tracking.MinGrowth
+		}
+		profiles = append(profiles, Profile{Name: p.Name, Mining: m, Notify: n, Tracking: t})
+	}
+	return profiles
+}
+
+// Validate 检查配置是否合法，返回所有问题
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Database.URL == "" {
+		add("database.url 不能为空")
+	} else if scheme, _, ok := strings.Cut(c.Database.URL, "://"); ok {
+		// 没有 scheme 的是 "host=... user=..." 形式的 Postgres 连接串
+		switch strings.ToLower(scheme) {
+		case "sqlite", "sqlite3", "postgres", "postgresql":
+		default:
+			add("database.url 不支持的数据库类型 %s (可选 postgres://、sqlite://)", scheme)
+		}
+	}
+
+	if c.Gemini.RPM < 0 || c.Gemini.TPM < 0 || c.Gemini.MaxConcurrency < 0 {
+		add("gemini.rpm、gemini.tpm 和 gemini.max_concurrency 不能为负数")
+	}
+	if c.LLMCache.TTL < 0 {
+		add("llm_cache.ttl 不能为负数")
+	} else if c.LLMCache.TTL > 0 && c.LLMCache.MaxEntries <= 0 {
+		add("llm_cache.max_entries 必须大于 0")
+	}
+
+	b := c.LLMBudget
+	if b.DailyUSD < 0 || b.MonthlyUSD < 0 {
+		add("llm_budget 的预算不能为负数")
+	}
+	for model, price := range b.Prices {
+		if price.Prompt < 0 || price.Completion < 0 {
+			add("llm_budget.prices.%s 的单价不能为负数", model)
+		}
+	}
+
+	m := c.Mining
+	if len(m.Topics) == 0 {
+		add("mining.topics 至少需要一个 topic")
+	}
+	for _, topic := range m.Topics {
+		if strings.TrimSpace(topic) == "" {
+			add("mining.topics 不能包含空字符串")
+			break
+		}
+	}
+	if m.MaxAgeDays <= 0 {
+		add("mining.max_age_days 必须大于 0")
+	}
+	if m.MinScore < 0 || m.MinScore > 100 {
+		add("mining.min_score 必须在 0-100 之间")
+	}
+	if m.CycleTimeout <= 0 {
+		add("mining.cycle_timeout 必须大于 0")
+	}
+	if m.AppraiseTimeout <= 0 {
+		add("mining.appraise_timeout 必须大于 0")
+	} else if m.CycleTimeout > 0 && m.AppraiseTimeout > m.CycleTimeout {
+		add("mining.appraise_timeout 不能超过 mining.cycle_timeout")
+	}
+	if m.Concurrency <= 0 {
+		add("mining.concurrency 必须大于 0")
+	}
+	if m.BatchSize <= 0 || m.BatchSize > MaxBatchSize {
+		add("mining.batch_size 必须在 1-%d 之间", MaxBatchSize)
+	}
+	if m.DailyBudgetUSD < 0 {
+		add("mining.daily_budget_usd 不能为负数")
+	}
+	validateSchedule("mining", m.Schedule, m.Interval, add)
+	validateLanguages("mining", m.Languages, add)
+
+	seen := make(map[string]bool, len(c.Profiles))
+	for i, p := range c.Profiles {
+		key := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case !profileName.MatchString(p.Name):
+			add("%s.name %q 无效 (只能包含小写字母、数字、- 和 _)", key, p.Name)
+		case seen[p.Name]:
+			add("%s.name %q 重复", key, p.Name)
+		default:
+			key = "profiles." + p.Name
+		}
+		seen[p.Name] = true
+
+		for _, topic := range p.Topics {
+			if strings.TrimSpace(topic) == "" {
+				add("%s.topics 不能包含空字符串", key)
+				break
+			}
+		}
+		validateLanguages(key, p.Languages, add)
+		if p.MaxAgeDays < 0 {
+			add("%s.max_age_days 不能为负数", key)
+		}
+		if p.DailyBudgetUSD < 0 {
+			add("%s.daily_budget_usd 不能为负数", key)
+		}
+		if p.MinScore != nil && (*p.MinScore < 0 || *p.MinScore > 100) {
+			add("%s.min_score 必须在 0-100 之间", key)
+		}
+		validateSchedule(key, p.Schedule, p.Interval, add)
+		validateMilestones(key+".tracking", p.Tracking.Milestones, add)
+		if p.Tracking.MinGrowth != 0 && p.Tracking.MinGrowth < 1 {
+			add("%s.tracking.min_growth 不能小于 1", key)
+		}
+	}
+
+	t := c.Tracking
+	validateMilestones("tracking", t.Milestones, add)
+	if t.MinGrowth < 1 {
+		add("tracking.min_growth 不能小于 1")
+	}
+	for _, event := range t.Retract {
+		switch event {
+		case "archived", "deleted":
+		default:
+			add("tracking.retract %q 无效 (可选 archived、deleted)", event)
+		}
+	}
+	if t.Window <= 0 {
+		add("tracking.window 必须大于 0")
+	}
+	if t.CheckEvery <= 0 {
+		add("tracking.check_every 必须大于 0")
+	}
+
+	switch c.Search.Embedder {
+	case "auto", "gemini", "openai", "ollama", "none", "":
+	default:
+		add("search.embedder %q 无效 (可选 auto、gemini、openai、ollama、none)", c.Search.Embedder)
+	}
+	if c.Search.TopK <= 0 {
+		add("search.top_k 必须大于 0")
+	}
+
+	if c.Serve.Listen == "" {
+		add("serve.listen 不能为空")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("配置无效: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateSchedule 检查 cron 表达式和执行间隔
+func validateSchedule(key, schedule string, interval time.Duration, add func(string, ...interface{})) {
+	if schedule != "" {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			add("%s.schedule 不是有效的 cron 表达式: %v", key, err)
+		}
+	}
+	if interval < 0 {
+		add("%s.interval 不能为负数", key)
+	} else if interval > 0 && interval < time.Minute {
+		add("%s.interval 不能小于 1 分钟", key)
+	}
+}
+
+// validateLanguages 编程语言不能为空字符串
+func validateLanguages(key string, languages []string, add func(string, ...interface{})) {
+	for _, language := range languages {
+		if strings.TrimSpace(language) == "" {
+			add("%s.languages 不能包含空字符串", key)
+			return
+		}
+	}
+}
+
+// validateMilestones 里程碑必须是从小到大排列的正整数
+func validateMilestones(key string, milestones []int, add func(string, ...interface{})) {
+	for i, milestone := range milestones {
+		if milestone <= 0 {
+			add("%s.milestones 必须是正整数", key)
+			return
+		}
+		if i > 0 && milestone <= milestones[i-1] {
+			add("%s.milestones 必须从小到大排列", key)
+			return
+		}
+	}
+}
+
+// Redacted 返回隐藏了密钥的副本，用于打印；数据库地址只隐藏密码
+func (c *Config) Redacted() *Config {
+	cp := *c
+	cp.Mining.Topics = append([]string(nil), c.Mining.Topics...)
+	cp.Tracking.Milestones = append([]int(nil), c.Tracking.Milestones...)
+	cp.Tracking.Retract = append([]string(nil), c.Tracking.Retract...)
+	for _, b := range cp.envBindings() {
+		if b.secret && *b.target != "" {
+			*b.target = redacted
+		}
+	}
+	cp.Profiles = append([]ProfileConfig(nil), c.Profiles...)
+	for i := range cp.Profiles {
+		if cp.Profiles[i].Notify.FeishuWebhook != "" {
+			cp.Profiles[i].Notify.FeishuWebhook = redacted
+		}
+	}
+	if u, err := url.Parse(cp.Database.URL); err == nil {
+		cp.Database.URL = u.Redacted()
+	}
+	return &cp
+}
+
+// WriteYAML 以 YAML 格式输出配置
+func (c *Config) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
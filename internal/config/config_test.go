@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gold-miner.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func envOf(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestDefault_IsValid(t *testing.T) {
+	cfg, err := load("", envOf(nil))
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, DefaultDSN, cfg.Database.URL)
+	assert.Equal(t, []string{"ai-coding", "ide-extension", "dev-tools"}, cfg.Mining.Topics)
+	assert.Equal(t, 5*time.Minute, cfg.Mining.CycleTimeout)
+	assert.Equal(t, 30*time.Second, cfg.Mining.AppraiseTimeout)
+}
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	path := writeFile(t, `
+database:
+  url: postgres://miner:filepass@db/gold
+gemini:
+  api_key: from-file
+mining:
+  topics: [ai-agent]
+  min_score: 70
+  cycle_timeout: 10m
+tracking:
+  window: 72h
+`)
+	cfg, err := load(path, envOf(map[string]string{
+		"GEMINI_API_KEY": "from-env",
+		"FEISHU_WEBHOOK": "https://open.feishu.cn/hook/abc",
+		"GITHUB_TOKEN":   "", // 空的环境变量不覆盖
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "from-env", cfg.Gemini.APIKey)
+	assert.Equal(t, "https://open.feishu.cn/hook/abc", cfg.Notify.FeishuWebhook)
+	assert.Equal(t, []string{"ai-agent"}, cfg.Mining.Topics)
+	assert.Equal(t, 70, cfg.Mining.MinScore)
+	assert.Equal(t, 10*time.Minute, cfg.Mining.CycleTimeout)
+	assert.Equal(t, 72*time.Hour, cfg.Tracking.Window)
+	// 文件中没有的字段保留默认值
+	assert.Equal(t, 10, cfg.Mining.MaxAgeDays)
+	assert.Equal(t, 3, cfg.Mining.Concurrency)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := load(filepath.Join(t.TempDir(), "missing.yaml"), envOf(nil))
+	assert.ErrorContains(t, err, "读取配置文件失败")
+
+	// 拼错的字段名
+	_, err = load(writeFile(t, "mining:\n  min_scroe: 60\n"), envOf(nil))
+	assert.ErrorContains(t, err, "min_scroe")
+
+	_, err = load(writeFile(t, "mining:\n  cycle_timeout: soon\n"), envOf(nil))
+	assert.Error(t, err)
+
+	// 空文件等同于默认配置
+	cfg, err := load(writeFile(t, ""), envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"数据库地址", func(c *Config) { c.Database.URL = "mysql://db" }, "database.url"},
+		{"Gemini 限流", func(c *Config) { c.Gemini.TPM = -1 }, "gemini.tpm"},
+		{"缓存有效期", func(c *Config) { c.LLMCache.TTL = -time.Hour }, "llm_cache.ttl"},
+		{"缓存条数", func(c *Config) { c.LLMCache.MaxEntries = 0 }, "llm_cache.max_entries"},
+		{"预算", func(c *Config) { c.LLMBudget.DailyUSD = -1 }, "llm_budget"},
+		{"单价", func(c *Config) {
+			c.LLMBudget.Prices = map[string]ModelPrice{"gemini-2.5-pro": {Prompt: -1}}
+		}, "llm_budget.prices.gemini-2.5-pro"},
+		{"方向预算", func(c *Config) { c.Profiles = []ProfileConfig{{Name: "rust", DailyBudgetUSD: -1}} }, "profiles.rust.daily_budget_usd"},
+		{"没有 topic", func(c *Config) { c.Mining.Topics = nil }, "mining.topics"},
+		{"空 topic", func(c *Config) { c.Mining.Topics = []string{"ai-coding", " "} }, "空字符串"},
+		{"天数", func(c *Config) { c.Mining.MaxAgeDays = 0 }, "mining.max_age_days"},
+		{"评分", func(c *Config) { c.Mining.MinScore = 101 }, "mining.min_score"},
+		{"周期超时", func(c *Config) { c.Mining.CycleTimeout = 0 }, "mining.cycle_timeout"},
+		{"评估超时", func(c *Config) { c.Mining.AppraiseTimeout = 10 * time.Minute }, "不能超过"},
+		{"并发数", func(c *Config) { c.Mining.Concurrency = 0 }, "mining.concurrency"},
+		{"批大小为 0", func(c *Config) { c.Mining.BatchSize = 0 }, "mining.batch_size"},
+		{"批大小过大", func(c *Config) { c.Mining.BatchSize = MaxBatchSize + 1 }, "mining.batch_size"},
+		{"cron", func(c *Config) { c.Mining.Schedule = "every day" }, "mining.schedule"},
+		{"间隔", func(c *Config) { c.Mining.Interval = time.Second }, "mining.interval"},
+		{"里程碑", func(c *Config) { c.Tracking.Milestones = []int{5000, 1000} }, "从小到大"},
+		{"增长倍数", func(c *Config) { c.Tracking.MinGrowth = 0.5 }, "tracking.min_growth"},
+		{"撤回事件", func(c *Config) { c.Tracking.Retract = []string{"renamed"} }, "tracking.retract"},
+		{"方向里程碑", func(c *Config) {
+			c.Profiles = []ProfileConfig{{Name: "rust", Tracking: ProfileTrackingConfig{Milestones: []int{0, 500}}}}
+		}, "profiles.rust.tracking.milestones"},
+		{"方向增长倍数", func(c *Config) {
+			c.Profiles = []ProfileConfig{{Name: "rust", Tracking: ProfileTrackingConfig{MinGrowth: 0.8}}}
+		}, "profiles.rust.tracking.min_growth"},
+		{"向量模型", func(c *Config) { c.Search.Embedder = "bert" }, "search.embedder"},
+		{"召回数", func(c *Config) { c.Search.TopK = 0 }, "search.top_k"},
+		{"监听地址", func(c *Config) { c.Serve.Listen = "" }, "serve.listen"},
+		{"空语言", func(c *Config) { c.Mining.Languages = []string{""} }, "mining.languages"},
+		{"方向名称", func(c *Config) { c.Profiles = []ProfileConfig{{Name: "AI Agents"}} }, "profiles[0].name"},
+		{"方向重名", func(c *Config) { c.Profiles = []ProfileConfig{{Name: "agents"}, {Name: "agents"}} }, "重复"},
+		{"方向评分", func(c *Config) {
+			score := 120
+			c.Profiles = []ProfileConfig{{Name: "agents", MinScore: &score}}
+		}, "profiles.agents.min_score"},
+		{"方向 cron", func(c *Config) { c.Profiles = []ProfileConfig{{Name: "rust", Schedule: "daily"}} }, "profiles.rust.schedule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			assert.ErrorContains(t, cfg.Validate(), tt.want)
+		})
+	}
+
+	// 所有问题一次性返回
+	cfg := Default()
+	cfg.Mining.MinScore = -1
+	cfg.Search.TopK = 0
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "mining.min_score")
+	assert.ErrorContains(t, err, "search.top_k")
+}
+
+func TestResolveProfiles(t *testing.T) {
+	// 没有配置挖矿方向时只有一个默认方向
+	cfg := Default()
+	assert.Equal(t, []Profile{{Mining: cfg.Mining, Notify: cfg.Notify, Tracking: cfg.Tracking}}, cfg.ResolveProfiles())
+
+	path := writeFile(t, `
+mining:
+  min_score: 60
+  schedule: "0 9 * * *"
+  daily_budget_usd: 2
+notify:
+  routes: routes.yaml
+profiles:
+  - name: agents
+    topics: [ai-agent]
+    criteria: 面向开发者的 AI Agent 框架
+    min_score: 0
+  - name: rust
+    languages: [rust]
+    max_age_days: 30
+    interval: 2h
+    daily_budget_usd: 0.5
+    notify:
+      feishu_webhook: https://open.feishu.cn/hook/rust
+    tracking:
+      milestones: [300, 1000]
+      min_growth: 1.5
+`)
+	cfg, err := load(path, envOf(map[string]string{"FEISHU_WEBHOOK": "https://open.feishu.cn/hook/default"}))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	profiles := cfg.ResolveProfiles()
+	require.Len(t, profiles, 2)
+	agents, rust := profiles[0], profiles[1]
+
+	assert.Equal(t, "agents", agents.Name)
+	assert.Equal(t, []string{"ai-agent"}, agents.Mining.Topics)
+	assert.Equal(t, "面向开发者的 AI Agent 框架", agents.Mining.Criteria)
+	assert.Equal(t, 0, agents.Mining.MinScore) // 显式设置的 0 也覆盖全局配置
+	assert.Equal(t, 10, agents.Mining.MaxAgeDays)
+	assert.Equal(t, "0 9 * * *", agents.Mining.Schedule)
+	assert.Equal(t, 2.0, agents.Mining.DailyBudgetUSD)
+	assert.Equal(t, "https://open.feishu.cn/hook/default", agents.Notify.FeishuWebhook)
+
+	assert.Equal(t, []string{"ai-coding", "ide-extension", "dev-tools"}, rust.Mining.Topics)
+	assert.Equal(t, []string{"rust"}, rust.Mining.Languages)
+	assert.Equal(t, 60, rust.Mining.MinScore)
+	assert.Equal(t, 30, rust.Mining.MaxAgeDays)
+	assert.Equal(t, 0.5, rust.Mining.DailyBudgetUSD)
+	// 方向的执行间隔替换全局的 cron
+	assert.Empty(t, rust.Mining.Schedule)
+	assert.Equal(t, 2*time.Hour, rust.Mining.Interval)
+	assert.Equal(t, "https://open.feishu.cn/hook/rust", rust.Notify.FeishuWebhook)
+	assert.Equal(t, "routes.yaml", rust.Notify.Routes)
+
+	// 爆发阈值按方向覆盖，未设置的方向沿用全局配置
+	assert.Equal(t, cfg.Tracking, agents.Tracking)
+	assert.Equal(t, []int{300, 1000}, rust.Tracking.Milestones)
+	assert.Equal(t, 1.5, rust.Tracking.MinGrowth)
+	assert.Equal(t, cfg.Tracking.Window, rust.Tracking.Window)
+
+	// 方向的 Webhook 同样隐藏
+	r := cfg.Redacted()
+	assert.Equal(t, redacted, r.Profiles[1].Notify.FeishuWebhook)
+	assert.Equal(t, "https://open.feishu.cn/hook/rust", cfg.Profiles[1].Notify.FeishuWebhook)
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.URL = "postgres://miner:s3cret@db:5432/gold?sslmode=disable"
+	cfg.GitHub.Token = "ghp_xxx"
+	cfg.Gemini.APIKey = "AIza"
+	cfg.Notify.FeishuWebhook = "https://open.feishu.cn/hook/abc"
+	cfg.OpenAI.BaseURL = "https://api.example.com/v1"
+
+	r := cfg.Redacted()
+	assert.Equal(t, "postgres://miner:xxxxx@db:5432/gold?sslmode=disable", r.Database.URL)
+	assert.Equal(t, redacted, r.GitHub.Token)
+	assert.Equal(t, redacted, r.Gemini.APIKey)
+	assert.Equal(t, redacted, r.Notify.FeishuWebhook)
+	// 未设置的密钥和非密钥字段原样输出
+	assert.Empty(t, r.Serve.APIToken)
+	assert.Equal(t, "https://api.example.com/v1", r.OpenAI.BaseURL)
+	// 原配置不受影响
+	assert.Equal(t, "ghp_xxx", cfg.GitHub.Token)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteYAML(&buf))
+	assert.NotContains(t, buf.String(), "s3cret")
+	assert.Contains(t, buf.String(), "cycle_timeout: 5m0s")
+
+	// 输出的配置可以重新读取
+	back, err := load(writeFile(t, buf.String()), envOf(nil))
+	require.NoError(t, err)
+	assert.Equal(t, r, back)
+}
@@ -1,6 +1,11 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -23,7 +28,540 @@ type Repo struct {
 	IsAIProgrammingTool bool   `json:"is_ai_programming_tool"` // 是否为AI编程工具
 	LLMScore           int    `json:"llm_score"`              // LLM评分(1-100)
 	LLMReview          string `json:"llm_review" gorm:"type:text"` // LLM简评
+	Categories         []string `json:"categories" gorm:"serializer:json;type:text"` // 项目分类 (见 Category* 常量)
+	AppraisalModel     string     `json:"appraisal_model,omitempty"` // 最近一次评估使用的模型
+	PromptVersion      string     `json:"prompt_version,omitempty"`  // 最近一次评估使用的 Prompt 版本
+	AppraisedAt        *time.Time `json:"appraised_at,omitempty"`    // 最近一次评估时间
+	ReadmeSHA          string     `json:"readme_sha,omitempty"`      // 最近一次评估时 README 的 Git blob SHA，README 未变化时复用评估结果
+	EmbeddingHash      string     `json:"-"`                         // EmbeddingText 的摘要，由仓库在保存时计算，与向量记录的摘要不同时重新生成向量
 	
+	// 发现该项目的挖矿方向 (profile)，未配置方向时为空
+	Profiles []string `json:"profiles,omitempty" gorm:"serializer:json;type:text"`
+
 	// 推送信息
-	AlreadyNotified bool `json:"already_notified" gorm:"index"` // 是否已推送
+	AlreadyNotified bool       `json:"already_notified" gorm:"index"` // 是否已推送
+	NotifiedAt      *time.Time `json:"notified_at,omitempty"`         // 推送时间
+	NotifiedStars   int        `json:"notified_stars"`                // 推送时的 Star 数
+
+	// 推送后追踪
+	Archived         bool       `json:"archived"`                  // 仓库是否已归档
+	Retracted        bool       `json:"retracted"`                 // 是否已发送撤回通知
+	TrackedMilestone int        `json:"tracked_milestone"`         // 已通知过的最高 Star 里程碑
+	LastCheckedAt    *time.Time `json:"last_checked_at,omitempty"` // 最近一次追踪检查时间
+}
+
+// Appraisal 一次 LLM 评估记录，repos 表只保留最新结果，历史记录保存在 appraisals 表
+type Appraisal struct {
+	ID                  uint      `json:"id" gorm:"primaryKey"`
+	RepoID              string    `json:"repo_id" gorm:"uniqueIndex:idx_appraisals_repo_time"`
+	Model               string    `json:"model"`
+	PromptVersion       string    `json:"prompt_version"`
+	IsAIProgrammingTool bool      `json:"is_ai_programming_tool"`
+	Score               int       `json:"score"`
+	Review              string    `json:"review" gorm:"type:text"`
+	Categories          []string  `json:"categories" gorm:"serializer:json;type:text"`
+	AppraisedAt         time.Time `json:"appraised_at" gorm:"uniqueIndex:idx_appraisals_repo_time"`
+}
+
+// Appraisal 返回项目当前评估结果对应的历史记录，未经过 LLM 评估时返回 nil
+func (r *Repo) Appraisal() *Appraisal {
+	if r.AppraisedAt == nil {
+		return nil
+	}
+	return &Appraisal{
+		RepoID:              r.ID,
+		Model:               r.AppraisalModel,
+		PromptVersion:       r.PromptVersion,
+		IsAIProgrammingTool: r.IsAIProgrammingTool,
+		Score:               r.LLMScore,
+		Review:              r.LLMReview,
+		Categories:          r.Categories,
+		AppraisedAt:         *r.AppraisedAt,
+	}
+}
+
+// HasProfile 项目是否被该挖矿方向发现过
+func (r *Repo) HasProfile(profile string) bool {
+	for _, p := range r.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeProfiles 合并两组挖矿方向，保持先后顺序并去重
+func MergeProfiles(a, b []string) []string {
+	var merged []string
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, p := range append(append([]string(nil), a...), b...) {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// 搜索分页默认值
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 100
+)
+
+// SearchQuery 项目搜索条件，零值字段表示不过滤
+type SearchQuery struct {
+	Text          string     // 关键词，支持 websearch 语法 ("短语"、-排除、or)
+	Categories    []string   // 命中任一分类即可
+	Profile       string     // 发现项目的挖矿方向
+	Language      string     // 编程语言，不区分大小写
+	MinScore      int        // 最低 LLM 评分
+	CreatedAfter  *time.Time // 项目创建时间下限 (含)
+	CreatedBefore *time.Time // 项目创建时间上限 (不含)
+	Limit         int        // 每页数量，默认 DefaultSearchLimit，最大 MaxSearchLimit
+	Offset        int        // 跳过的结果数
+	Sort          SortField  // 排序字段，为空时有关键词按相关度、否则按评分排序
+	Ascending     bool       // 按 Sort 升序排列，默认降序
+}
+
+// SortField 项目列表的排序字段
+type SortField string
+
+const (
+	SortDefault  SortField = ""
+	SortScore    SortField = "score"    // LLM 评分
+	SortVelocity SortField = "velocity" // Star 增长率
+	SortStars    SortField = "stars"    // Star 数
+	SortCreated  SortField = "created"  // 创建时间
+)
+
+// ParseSortField 解析排序字段，不支持的字段返回错误
+func ParseSortField(s string) (SortField, error) {
+	switch f := SortField(s); f {
+	case SortDefault, SortScore, SortVelocity, SortStars, SortCreated:
+		return f, nil
+	default:
+		return SortDefault, fmt.Errorf("不支持的排序字段: %s (可选 score、velocity、stars、created)", s)
+	}
+}
+
+// Normalize 填充分页默认值并修正越界的参数
+func (q SearchQuery) Normalize() SearchQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return q
+}
+
+// RunStatus 挖矿执行状态
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+// RunTrigger 挖矿的触发方式
+type RunTrigger string
+
+const (
+	TriggerCron     RunTrigger = "cron"     // Cron 表达式定时执行
+	TriggerInterval RunTrigger = "interval" // 按固定间隔执行
+	TriggerManual   RunTrigger = "manual"   // 命令行手动执行一次
+	TriggerAPI      RunTrigger = "api"      // 通过 REST API 触发
+)
+
+// MiningRun 一次挖矿执行的记录
+type MiningRun struct {
+	ID          int64      `json:"id" gorm:"primaryKey"`
+	Trigger     RunTrigger `json:"trigger"`
+	Profile     string     `json:"profile,omitempty"` // 挖矿方向，未配置方向时为空
+	Status      RunStatus  `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	ResumedFrom int64      `json:"resumed_from,omitempty"` // 从哪次中断的执行的检查点续跑，0 表示从头执行
+
+	MiningReport `gorm:"embedded"`
+}
+
+// CachedAppraisal 缓存的 LLM 评估结果，缓存键由模型、Prompt 版本和项目内容 (名称、描述、README 版本) 摘要而来
+type CachedAppraisal struct {
+	CacheKey            string    `json:"cache_key" gorm:"primaryKey"`
+	RepoID              string    `json:"repo_id"`
+	IsAIProgrammingTool bool      `json:"is_ai_programming_tool"`
+	LLMScore            int       `json:"llm_score"`
+	LLMReview           string    `json:"llm_review" gorm:"type:text"`
+	Categories          []string  `json:"categories" gorm:"serializer:json;type:text"`
+	AppraisalModel      string    `json:"appraisal_model"`
+	PromptVersion       string    `json:"prompt_version"`
+	CreatedAt           time.Time `json:"created_at"` // 评估时间，超过有效期后不再使用
+}
+
+// TokenUsage LLM 调用的输入、输出 Token 数和按模型单价换算的费用
+type TokenUsage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"` // 包括思考过程的 Token
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Total 输入和输出的 Token 总数
+func (u TokenUsage) Total() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add 累加另一次调用的用量
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CostUSD += other.CostUSD
+}
+
+// ModelPrice 模型单价，单位为美元 / 百万 Token
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// Cost 按单价换算一次调用的费用 (美元)
+func (p ModelPrice) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// UsageRecord 按日期、挖矿方向和模型汇总的 LLM 用量
+type UsageRecord struct {
+	Day        string `json:"day" gorm:"primaryKey"`     // 本地日期，格式 2006-01-02
+	Profile    string `json:"profile" gorm:"primaryKey"` // 挖矿方向，未配置方向时为空
+	Model      string `json:"model" gorm:"primaryKey"`
+	TokenUsage `gorm:"embedded"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// 预算的统计范围
+const (
+	BudgetDaily        = "daily"         // 所有挖矿方向当天的费用
+	BudgetMonthly      = "monthly"       // 所有挖矿方向当月的费用
+	BudgetProfileDaily = "profile_daily" // 本挖矿方向当天的费用
+)
+
+// BudgetStatus 一项 LLM 费用预算在当前周期的用量
+type BudgetStatus struct {
+	Scope    string  `json:"scope"`
+	Period   string  `json:"period"` // 统计周期，如 2025-03-01 (按天) 或 2025-03 (按月)
+	LimitUSD float64 `json:"limit_usd"`
+	SpentUSD float64 `json:"spent_usd"`
+}
+
+// Exceeded 费用是否已经达到预算
+func (s *BudgetStatus) Exceeded() bool {
+	return s.SpentUSD >= s.LimitUSD
+}
+
+// RunCheckpoint 挖矿执行中一个阶段的输出，执行中断后下一次执行从检查点续跑
+type RunCheckpoint struct {
+	ID        int64     `json:"id" gorm:"primaryKey"`
+	RunID     int64     `json:"run_id"`
+	Seq       int       `json:"seq"` // 阶段在流水线中的位置，从 0 开始
+	Stage     string    `json:"stage"`
+	Complete  bool      `json:"complete"` // 阶段是否执行完成；未完成时只包含已经处理过的项目 (如已完成 LLM 评估的项目)
+	Repos     []*Repo   `json:"repos" gorm:"serializer:json;type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 内置挖矿阶段的名称，按默认执行顺序
+const (
+	StageFetch          = "fetch"           // 从 Trending 和 Topic 抓取
+	StageDedup          = "dedup"           // 去掉多个数据源重复返回的项目
+	StageFilterLanguage = "filter_language" // 按挖矿方向配置的编程语言过滤
+	StageFilterAge      = "filter_age"      // 时效性过滤
+	StageFilterActivity = "filter_activity" // 活跃度过滤
+	StageVelocity       = "velocity"        // 计算 Star 增长率
+	StageAppraise       = "appraise"        // LLM 评估
+	StageSelect         = "select"          // 按是否为 AI 编程工具和评分筛选
+	StageStore          = "store"           // 入库，已存在的项目只更新不计入输出
+	StageNotify         = "notify"          // 推送
+)
+
+// MiningReport 一次挖矿的执行报告
+type MiningReport struct {
+	Stages        []*StageReport  `json:"stages" gorm:"serializer:json;type:text"`
+	APIRequests   int64           `json:"api_requests"`                                       // 消耗的 GitHub API 配额 (请求次数)
+	LLMTokens     int64           `json:"llm_tokens"`                                         // 消耗的 LLM Token 数
+	Reused        int             `json:"reused"`                                             // 复用已有评估结果、没有调用 LLM 的项目数
+	CacheHits     int64           `json:"cache_hits"`                                         // LLM 评估缓存命中次数
+	CacheMisses   int64           `json:"cache_misses"`                                       // LLM 评估缓存未命中次数 (实际调用了 LLM)
+	Usage         TokenUsage      `json:"usage" gorm:"embedded"`                              // 按调用记录的 LLM 输入、输出 Token 数和费用
+	BudgetBlocked int             `json:"budget_blocked"`                                     // 超出预算后没有评估的项目数
+	Budgets       []*BudgetStatus `json:"budgets,omitempty" gorm:"serializer:json;type:text"` // 执行结束时各项预算的用量
+	Pushed        []string        `json:"pushed" gorm:"serializer:json;type:text"`            // 推送成功的项目 (owner/name)
+	Fates         []*RepoFate     `json:"fates,omitempty" gorm:"-"`                           // 每个项目的去向，只在本次执行中可用，不保存
+}
+
+// RepoFate 一个项目在流水线中的去向
+type RepoFate struct {
+	RepoID string `json:"repo_id"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Stage  string `json:"stage,omitempty"`  // 丢弃该项目的阶段，为空表示通过了全部阶段
+	Reason string `json:"reason,omitempty"` // 被丢弃的原因
+}
+
+// StageReport 一个阶段的输入输出数量、耗时和错误
+type StageReport struct {
+	Name       string         `json:"name"`
+	In         int            `json:"in"`
+	Out        int            `json:"out"`
+	DurationMS int64          `json:"duration_ms"`
+	Skipped    bool           `json:"skipped,omitempty"` // 出错后按 skip 策略丢弃了该阶段的输出
+	Resumed    bool           `json:"resumed,omitempty"` // 输出来自中断执行的检查点，本次没有执行
+	Errors     []string       `json:"errors,omitempty"`
+	ErrorCodes map[string]int `json:"error_codes,omitempty"` // 按错误码统计的错误数，没有错误码的错误计入 ErrorCodeUnknown
+	Retries    map[string]int `json:"retries,omitempty"`     // 按错误码统计的外部调用重试次数 (见 common.LogRetry)
+
+	dropped map[string]string // 项目 ID → 被本阶段丢弃的原因
+}
+
+// ErrorCodeUnknown 没有错误码的错误在执行报告中的统计键
+const ErrorCodeUnknown = "UNKNOWN"
+
+// AddStage 追加一个阶段并返回，供执行过程中填写输出和错误
+func (r *MiningReport) AddStage(name string, in int) *StageReport {
+	stage := &StageReport{Name: name, In: in}
+	r.Stages = append(r.Stages, stage)
+	return stage
+}
+
+// Stage 按名称查找阶段，不存在时返回 nil
+func (r *MiningReport) Stage(name string) *StageReport {
+	for _, s := range r.Stages {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddError 记录阶段中的一个错误，并按错误码 (common.AppError 的 ErrorCode 方法) 计数
+func (s *StageReport) AddError(err error) {
+	s.Errors = append(s.Errors, err.Error())
+	if s.ErrorCodes == nil {
+		s.ErrorCodes = make(map[string]int)
+	}
+	s.ErrorCodes[errorCode(err)]++
+}
+
+// AddRetry 按错误码记录阶段中外部调用的一次重试
+func (s *StageReport) AddRetry(err error) {
+	if s.Retries == nil {
+		s.Retries = make(map[string]int)
+	}
+	s.Retries[errorCode(err)]++
+}
+
+// errorCode 错误链中的错误码 (common.AppError 的 ErrorCode 方法)，没有时返回 ErrorCodeUnknown
+func errorCode(err error) string {
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) && coded.ErrorCode() != "" {
+		return coded.ErrorCode()
+	}
+	return ErrorCodeUnknown
+}
+
+// ErrorCodes 汇总各阶段按错误码统计的错误数，没有错误时返回空 map
+func (r *MiningReport) ErrorCodes() map[string]int {
+	counts := make(map[string]int)
+	for _, s := range r.Stages {
+		for code, n := range s.ErrorCodes {
+			counts[code] += n
+		}
+	}
+	return counts
+}
+
+// Retries 汇总各阶段按错误码统计的重试次数，没有重试时返回空 map
+func (r *MiningReport) Retries() map[string]int {
+	counts := make(map[string]int)
+	for _, s := range r.Stages {
+		for code, n := range s.Retries {
+			counts[code] += n
+		}
+	}
+	return counts
+}
+
+// Drop 记录项目被本阶段丢弃的原因
+func (s *StageReport) Drop(repoID, reason string) {
+	if s.dropped == nil {
+		s.dropped = make(map[string]string)
+	}
+	s.dropped[repoID] = reason
+}
+
+// DropReason 返回项目被本阶段丢弃的原因，没有记录时返回空字符串
+func (s *StageReport) DropReason(repoID string) string {
+	return s.dropped[repoID]
+}
+
+// ScoredRepo 向量检索结果
+type ScoredRepo struct {
+	Repo       *Repo   `json:"repo"`
+	Similarity float64 `json:"similarity"` // 与查询的余弦相似度，越大越相关
+}
+
+// SearchResult 语义搜索的一条匹配结果
+type SearchResult struct {
+	RepoID    string `json:"repo_id"`
+	Repo      *Repo  `json:"repo,omitempty"` // 校验通过后回填的项目信息
+	Reason    string `json:"reason"`         // 匹配理由
+	Relevance int    `json:"relevance"`      // 相关度 0-100
+	Action    string `json:"action"`         // 行动建议
+}
+
+// ChatRole 对话消息的发送方
+type ChatRole string
+
+const (
+	ChatRoleUser      ChatRole = "user"
+	ChatRoleAssistant ChatRole = "assistant"
+)
+
+// ChatMessage 多轮对话中的一条消息
+type ChatMessage struct {
+	Role    ChatRole        `json:"role"`
+	Content string          `json:"content"`
+	Results []*SearchResult `json:"results,omitempty"` // 助手回复中推荐的项目
+	At      time.Time       `json:"at"`
+}
+
+// ChatReply 模型对一轮对话的回复
+type ChatReply struct {
+	Message string          `json:"message"` // 回答正文 (Markdown)
+	Results []*SearchResult `json:"results"` // 本轮提到的项目，按相关度排序
+}
+
+// EmbeddingText 生成项目用于向量化的文本：名称、描述、分类和 LLM 评价
+func (r *Repo) EmbeddingText() string {
+	parts := []string{r.Name}
+	if r.Description != "" {
+		parts = append(parts, r.Description)
+	}
+	if len(r.Categories) > 0 {
+		parts = append(parts, strings.Join(r.Categories, ", "))
+	}
+	if r.LLMReview != "" {
+		parts = append(parts, r.LLMReview)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// EmbeddingTextHash 返回 EmbeddingText 的 SHA-256，描述、分类或评价变化后随之变化
+func (r *Repo) EmbeddingTextHash() string {
+	sum := sha256.Sum256([]byte(r.EmbeddingText()))
+	return hex.EncodeToString(sum[:])
+}
+
+// StarSnapshot Star 数快照，用于观察推送后的增长曲线
+type StarSnapshot struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RepoID     string    `json:"repo_id" gorm:"index"`
+	Stars      int       `json:"stars"`
+	CapturedAt time.Time `json:"captured_at" gorm:"index"`
+}
+
+// FeedbackVerdict 用户对项目的评价
+type FeedbackVerdict string
+
+const (
+	FeedbackUseful    FeedbackVerdict = "useful"     // 有用
+	FeedbackNotUseful FeedbackVerdict = "not_useful" // 没用 (误判、不感兴趣)
+)
+
+// Feedback 用户对项目的反馈，用于校准评分
+type Feedback struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	RepoID    string          `json:"repo_id" gorm:"index"`
+	Verdict   FeedbackVerdict `json:"verdict"`
+	Comment   string          `json:"comment" gorm:"type:text"`
+	Author    string          `json:"author"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TrackingEventType 追踪事件类型
+type TrackingEventType string
+
+const (
+	EventBreakout TrackingEventType = "breakout" // 推送后 Star 爆发，越过里程碑
+	EventArchived TrackingEventType = "archived" // 仓库被归档
+	EventDeleted  TrackingEventType = "deleted"  // 仓库被删除或转为私有
+)
+
+// TrackingEvent 已推送项目的状态变化
+type TrackingEvent struct {
+	Type          TrackingEventType `json:"type"`
+	RepoID        string            `json:"repo_id"`
+	PreviousStars int               `json:"previous_stars"` // 推送时的 Star 数
+	CurrentStars  int               `json:"current_stars"`
+	Milestone     int               `json:"milestone,omitempty"` // 越过的里程碑 (仅 breakout)
+	DetectedAt    time.Time         `json:"detected_at"`
+}
+
+// IsRetraction 是否为撤回类事件
+func (e *TrackingEvent) IsRetraction() bool {
+	return e.Type == EventArchived || e.Type == EventDeleted
+}
+
+// DeferredMessage 免打扰时段暂存的通知，时段结束后补发
+// 保存在数据库中，进程重启或单次运行退出后，下一次补发时仍会发送
+type DeferredMessage struct {
+	ID        int64          `json:"id" gorm:"primaryKey"`
+	Owner     string         `json:"owner"` // 暂存消息的通知器 (挖矿方向)，共用数据库的多个通知器只补发自己的消息
+	Route     string         `json:"route"`
+	Channel   string         `json:"channel"`
+	RepoID    string         `json:"repo_id"`
+	Repo      *Repo          `json:"repo" gorm:"serializer:json;type:text"`
+	Event     *TrackingEvent `json:"event,omitempty" gorm:"serializer:json;type:text"` // 非空表示追踪更新消息
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// 项目分类，由 LLM 在鉴定时给出，用于通知路由和筛选
+const (
+	CategoryIDEExtension   = "ide-extension"   // IDE 插件 / 编辑器集成
+	CategoryCLIAgent       = "cli-agent"       // 命令行编程 Agent
+	CategoryCodeCompletion = "code-completion" // 代码补全 / 生成
+	CategoryCodeReview     = "code-review"     // 代码审查
+	CategoryTesting        = "testing"         // 测试生成 / 质量
+	CategoryAgentFramework = "agent-framework" // Agent 框架 / SDK
+	CategoryLLMInfra       = "llm-infra"       // 模型推理 / 网关等基础设施
+	CategoryOther          = "other"
+)
+
+// Categories 返回所有已知分类，顺序固定
+func Categories() []string {
+	return []string{
+		CategoryIDEExtension,
+		CategoryCLIAgent,
+		CategoryCodeCompletion,
+		CategoryCodeReview,
+		CategoryTesting,
+		CategoryAgentFramework,
+		CategoryLLMInfra,
+		CategoryOther,
+	}
+}
+
+// HasCategory 判断项目是否属于某个分类
+func (r *Repo) HasCategory(category string) bool {
+	for _, c := range r.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
 }
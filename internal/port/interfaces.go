@@ -2,9 +2,19 @@ package port
 
 import (
 	"context"
+	"errors"
+	"time"
+
 	"github-gold-miner/internal/domain"
 )
 
+// ErrNotFound 查询的项目不存在 (例如 GitHub 仓库已被删除或转为私有)
+var ErrNotFound = errors.New("项目不存在")
+
+// ErrPartiallyDelivered 消息已送达 (或暂存到) 部分渠道，其余渠道失败
+// 调用方应视为已推送并记录错误，重试会把消息重复发到已送达的渠道
+var ErrPartiallyDelivered = errors.New("部分渠道推送失败")
+
 // Scouter (侦察兵): 负责去 GitHub 发现新项目
 type Scouter interface {
 	// 获取GitHub Trending项目
@@ -14,6 +24,12 @@ type Scouter interface {
 	GetReposByTopic(ctx context.Context, topic string) ([]*domain.Repo, error)
 }
 
+// Inspector (巡检员): 负责查询已推送项目的最新状态
+type Inspector interface {
+	// 按 owner/name 获取项目当前信息，仓库不存在时返回 ErrNotFound
+	GetRepo(ctx context.Context, fullName string) (*domain.Repo, error)
+}
+
 // Filter (过滤器): 负责按规则过滤项目
 type Filter interface {
 	// 过滤掉创建时间超过指定天数的项目
@@ -39,7 +55,56 @@ type Analyzer interface {
 type Appraiser interface {
 	// 输入原始项目，输出包含评分和分析的完整项目
 	Appraise(ctx context.Context, repo *domain.Repo) (*domain.Repo, error)
-	SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) (string, error)
+
+	// 从候选项目中挑选符合用户意图的项目，返回的 RepoID 必须来自 repos
+	SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error)
+}
+
+// CriteriaAppraiser 支持自定义评估标准的鉴定师 (可选能力)，用于不同的挖矿方向
+type CriteriaAppraiser interface {
+	// 返回按 criteria 判断项目是否符合要求的鉴定师，criteria 为空时使用默认标准
+	WithCriteria(criteria string) Appraiser
+}
+
+// BatchAppraiser 一次 LLM 调用评估多个项目的鉴定师 (可选能力)，多个项目共用一份评估说明以节省 Token
+type BatchAppraiser interface {
+	// 返回按项目 ID 索引的评估结果；LLM 漏掉或返回无效结果的项目不在结果中，由调用方重新评估
+	// 整个调用失败 (如 API 出错、超出预算) 时返回错误
+	AppraiseBatch(ctx context.Context, repos []*domain.Repo) (map[string]*domain.Repo, error)
+}
+
+// PromptVersioner 报告当前评估 Prompt 版本的鉴定师 (可选能力)，Prompt 变化后不复用旧的评估结果
+type PromptVersioner interface {
+	PromptVersion() string
+}
+
+// ModelNamer 报告所用 LLM 模型名称的鉴定师 (可选能力)
+type ModelNamer interface {
+	Model() string
+}
+
+// Chatter 支持多轮对话检索的鉴定师 (可选能力)
+type Chatter interface {
+	// 基于候选项目开启一个对话，对话内保留历史消息，追问时不需要重新检索
+	StartChat(candidates []*domain.Repo) ChatSession
+}
+
+// ChatSession 一次多轮对话
+type ChatSession interface {
+	// 发送一条消息，返回的 RepoID 必须来自候选项目
+	Send(ctx context.Context, message string) (*domain.ChatReply, error)
+
+	// 追加候选项目 (例如用户换了一个话题重新检索)，已有的历史消息保留
+	AddCandidates(repos []*domain.Repo)
+}
+
+// Embedder (向量化): 把文本转换为向量，用于语义检索
+type Embedder interface {
+	// 批量向量化，返回的向量与 texts 一一对应
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// 模型名称，不同模型生成的向量不能混用
+	Model() string
 }
 
 // Notifier (信使): 负责推送到手机 (飞书/钉钉)
@@ -48,23 +113,160 @@ type Notifier interface {
 	Notify(ctx context.Context, repo *domain.Repo) error
 }
 
+// DigestNotifier 支持把多个项目汇总成一条消息的通知渠道 (可选能力)
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, title string, repos []*domain.Repo) error
+}
+
+// AlertNotifier 支持发送系统告警的通知渠道 (可选能力)
+// level 取值 info / warning / critical
+type AlertNotifier interface {
+	NotifyAlert(ctx context.Context, level, title, message string) error
+}
+
+// UpdateNotifier 支持发送追踪更新 (爆发/撤回) 的通知渠道 (可选能力)
+type UpdateNotifier interface {
+	NotifyUpdate(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error
+}
+
+// PendingFlusher 会暂存消息的通知渠道 (如免打扰期间)，每轮挖矿开始时补发
+type PendingFlusher interface {
+	Flush(ctx context.Context) error
+}
+
 // Repository (仓库管理员): 负责存储和查询
 type Repository interface {
-	// 保存项目
+	// 保存项目 (Upsert)，不覆盖推送和追踪状态；带评估结果时同时记录评估历史
 	Save(ctx context.Context, repo *domain.Repo) error
 
+	// 获取项目的评估历史，按时间顺序
+	GetAppraisals(ctx context.Context, repoID string) ([]*domain.Appraisal, error)
+
+	// 按 ID 获取项目，不存在时返回 ErrNotFound
+	Get(ctx context.Context, repoID string) (*domain.Repo, error)
+
 	// 判断是否已经处理过 (防重)
 	Exists(ctx context.Context, repoID string) (bool, error)
 
 	// 标记为已推送
 	MarkAsNotified(ctx context.Context, repoID string) error
 
-	// Search 对应你的"提问查询"功能
-	// MVP 阶段：可以是 SQL 的 LIKE 查询
-	// 进阶阶段：可以是 Vector 向量搜索
-	Search(ctx context.Context, query string) ([]*domain.Repo, error)
+	// Search 对应你的"提问查询"功能，按关键词和过滤条件分页搜索
+	// Postgres 使用全文检索 + 模糊匹配，其他实现可以退化为 LIKE 查询
+	Search(ctx context.Context, query domain.SearchQuery) ([]*domain.Repo, error)
 	GetAllCandidates(ctx context.Context) ([]*domain.Repo, error)
 
 	// 获取未推送的项目
 	GetUnnotifiedRepos(ctx context.Context) ([]*domain.Repo, error)
-}
\ No newline at end of file
+
+	// 获取某个时间之后推送过的项目，供推送后追踪使用
+	GetNotifiedRepos(ctx context.Context, since time.Time) ([]*domain.Repo, error)
+
+	// 更新追踪相关字段 (Star、归档、里程碑、检查时间等)
+	UpdateTracking(ctx context.Context, repo *domain.Repo) error
+
+	// 记录 / 读取 Star 快照
+	AddSnapshot(ctx context.Context, snapshot *domain.StarSnapshot) error
+	GetSnapshots(ctx context.Context, repoID string) ([]*domain.StarSnapshot, error)
+
+	// 记录 / 读取用户反馈，读取时按时间顺序
+	AddFeedback(ctx context.Context, feedback *domain.Feedback) error
+	GetFeedback(ctx context.Context, repoID string) ([]*domain.Feedback, error)
+}
+
+// VectorStore 支持向量检索的仓库 (可选能力)
+// 向量按模型分别存储，检索时只比较同一模型的向量
+type VectorStore interface {
+	// 保存项目的向量，已有时覆盖；contentHash 为生成向量时项目的 EmbeddingHash
+	SaveEmbedding(ctx context.Context, repoID, model, contentHash string, vector []float32) error
+
+	// 获取还没有该模型向量、或生成向量后内容 (EmbeddingHash) 发生变化的项目，按创建时间倒序，用于补齐索引
+	ReposNeedingEmbedding(ctx context.Context, model string, limit int) ([]*domain.Repo, error)
+
+	// 按余弦相似度返回最相近的 k 个项目
+	NearestRepos(ctx context.Context, model string, vector []float32, k int) ([]*domain.ScoredRepo, error)
+}
+
+// RunStore (运行记录): 保存每次挖矿的执行记录和阶段报告
+type RunStore interface {
+	// 新建执行记录并回填 ID
+	CreateRun(ctx context.Context, run *domain.MiningRun) error
+
+	// 更新执行记录 (状态、结束时间、报告)
+	UpdateRun(ctx context.Context, run *domain.MiningRun) error
+
+	// 按 ID 获取执行记录，不存在时返回 ErrNotFound
+	GetRun(ctx context.Context, id int64) (*domain.MiningRun, error)
+
+	// 按开始时间倒序返回最近 limit 条执行记录
+	ListRuns(ctx context.Context, limit int) ([]*domain.MiningRun, error)
+}
+
+// CheckpointStore (检查点): 保存挖矿执行中每个阶段的输出 (可选能力)，用于中断后续跑
+type CheckpointStore interface {
+	// 保存检查点，同一次执行同一位置 (Seq) 的检查点覆盖已有的
+	SaveCheckpoint(ctx context.Context, checkpoint *domain.RunCheckpoint) error
+
+	// 按阶段位置顺序返回一次执行的所有检查点
+	GetCheckpoints(ctx context.Context, runID int64) ([]*domain.RunCheckpoint, error)
+
+	// 删除一次执行的所有检查点
+	DeleteCheckpoints(ctx context.Context, runID int64) error
+}
+
+// AppraisalCache (评估缓存): 持久化保存 LLM 评估结果 (可选能力)，相同内容的项目不再重复调用 LLM
+type AppraisalCache interface {
+	// 按缓存键获取 since 之后写入的缓存，不存在或已过期时返回 ErrNotFound
+	GetCachedAppraisal(ctx context.Context, key string, since time.Time) (*domain.CachedAppraisal, error)
+
+	// 写入缓存，同一个键覆盖已有的
+	PutCachedAppraisal(ctx context.Context, entry *domain.CachedAppraisal) error
+
+	// 删除 before 之前写入的缓存，并且只保留最新的 keep 条，返回删除的条数
+	PruneAppraisalCache(ctx context.Context, before time.Time, keep int) (int64, error)
+}
+
+// DeferredStore (暂存消息): 持久化保存免打扰时段暂存的通知 (可选能力)，进程退出后仍能补发
+type DeferredStore interface {
+	// 保存暂存消息并回填 ID
+	SaveDeferred(ctx context.Context, msg *domain.DeferredMessage) error
+
+	// 按暂存顺序返回 owner 的暂存消息
+	ListDeferred(ctx context.Context, owner string) ([]*domain.DeferredMessage, error)
+
+	// 删除已经补发的消息
+	DeleteDeferred(ctx context.Context, id int64) error
+}
+
+// UsageStore (用量账本): 按日期、挖矿方向和模型累计 LLM 用量 (可选能力)，用于统计费用和执行预算
+type UsageStore interface {
+	// 把用量累加到同一日期、挖矿方向和模型的汇总上
+	AddUsage(ctx context.Context, record *domain.UsageRecord) error
+
+	// 返回 sinceDay (格式 2006-01-02，含当天) 之后的用量汇总，按日期、挖矿方向和模型排序
+	ListUsage(ctx context.Context, sinceDay string) ([]*domain.UsageRecord, error)
+}
+
+// CacheCounter 统计缓存命中情况的组件 (可选能力)，用于记录每轮挖矿的缓存命中率
+type CacheCounter interface {
+	// 进程启动以来累计的命中和未命中次数
+	CacheStats() (hits, misses int64)
+}
+
+// ReadmeInspector 查询项目 README 版本的组件 (可选能力)，README 未变化时可以复用已有的评估结果
+type ReadmeInspector interface {
+	// 返回 README 的 Git blob SHA，项目没有 README 时返回空字符串
+	ReadmeSHA(ctx context.Context, fullName string) (string, error)
+}
+
+// RequestCounter 统计外部 API 请求次数的组件 (可选能力)，用于记录每轮挖矿消耗的配额
+type RequestCounter interface {
+	// 进程启动以来的累计请求次数 (含重试)
+	Requests() int64
+}
+
+// TokenCounter 统计 LLM Token 用量的组件 (可选能力)
+type TokenCounter interface {
+	// 进程启动以来累计消耗的 Token 数 (输入 + 输出)
+	TokensUsed() int64
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// ErrBudgetExceeded LLM 费用达到预算，预算周期结束前不再调用 LLM 评估
+var ErrBudgetExceeded = common.NewError(common.ErrCodeLLMBudgetExceeded, "LLM 费用超出预算")
+
+// DefaultPrices 常用模型的标准单价 (美元 / 百万 Token)，可以在配置中覆盖或补充
+var DefaultPrices = map[string]domain.ModelPrice{
+	"gemini-2.5-pro":         {Prompt: 1.25, Completion: 10},
+	"gemini-2.5-flash":       {Prompt: 0.30, Completion: 2.50},
+	"gemini-2.5-flash-lite":  {Prompt: 0.10, Completion: 0.40},
+	"gemini-2.0-flash":       {Prompt: 0.10, Completion: 0.40},
+	"text-embedding-3-small": {Prompt: 0.02},
+	"text-embedding-3-large": {Prompt: 0.13},
+}
+
+// budgetLabels 报告和告警中各项预算的中文名称
+var budgetLabels = map[string]string{
+	domain.BudgetDaily:        "每日总预算",
+	domain.BudgetMonthly:      "每月总预算",
+	domain.BudgetProfileDaily: "本方向每日预算",
+}
+
+// BudgetConfig LLM 费用预算，金额单位为美元，0 表示不限
+type BudgetConfig struct {
+	DailyUSD   float64                      // 所有挖矿方向每天的费用上限
+	MonthlyUSD float64                      // 所有挖矿方向每月的费用上限
+	Prices     map[string]domain.ModelPrice // 模型单价，与 DefaultPrices 合并，同名时覆盖
+}
+
+// Budget 把 LLM 用量按模型单价换算成费用记入用量账本，并按预算限制 LLM 评估，多个挖矿方向共用
+type Budget struct {
+	store   port.UsageStore
+	cfg     BudgetConfig
+	prices  map[string]domain.ModelPrice
+	nowFunc func() time.Time
+
+	mu       sync.Mutex
+	unpriced map[string]bool // 已经提示过没有单价的模型
+	alerted  map[string]bool // 已经告警过的预算，键为挖矿方向、范围和周期
+}
+
+// NewBudget 创建预算，用量记入 store
+func NewBudget(store port.UsageStore, cfg BudgetConfig) *Budget {
+	prices := make(map[string]domain.ModelPrice, len(DefaultPrices)+len(cfg.Prices))
+	for model, price := range DefaultPrices {
+		prices[model] = price
+	}
+	for model, price := range cfg.Prices {
+		prices[model] = price
+	}
+	return &Budget{
+		store:    store,
+		cfg:      cfg,
+		prices:   prices,
+		nowFunc:  time.Now,
+		unpriced: make(map[string]bool),
+		alerted:  make(map[string]bool),
+	}
+}
+
+// Meter 返回记录 LLM 用量并执行预算的 context，用于挖矿以外的 LLM 调用 (重新评估、搜索、对话和向量化)
+// 用量记在挖矿方向 profile 下；ctx 中已有 UsageMeter 时原样返回，b 为 nil 时不记账也不限制
+func (b *Budget) Meter(ctx context.Context, profile string) context.Context {
+	if b == nil || common.HasUsageMeter(ctx) {
+		return ctx
+	}
+	return common.WithUsageMeter(ctx, &usageMeter{budget: b, profile: profile})
+}
+
+// cost 按模型单价换算费用，没有单价的模型按 0 计算并提示一次
+func (b *Budget) cost(model string, promptTokens, completionTokens int64) float64 {
+	price, ok := b.prices[model]
+	if !ok {
+		b.mu.Lock()
+		if !b.unpriced[model] {
+			b.unpriced[model] = true
+			log.Printf("⚠️ 没有配置模型 %s 的单价，费用按 0 计算 (见配置 llm_budget.prices)", model)
+		}
+		b.mu.Unlock()
+	}
+	return price.Cost(promptTokens, completionTokens)
+}
+
+// record 把一次调用的用量记入当天的账本，写入失败只记录日志
+func (b *Budget) record(ctx context.Context, profile, model string, usage domain.TokenUsage) {
+	now := b.nowFunc()
+	err := b.store.AddUsage(context.WithoutCancel(ctx), &domain.UsageRecord{
+		Day: now.Format(time.DateOnly), Profile: profile, Model: model, TokenUsage: usage, UpdatedAt: now,
+	})
+	if err != nil {
+		log.Printf("⚠️ 记录 LLM 用量失败: %v", err)
+	}
+}
+
+// Status 返回挖矿方向的各项预算在当前周期的用量，profileDailyUSD 为该方向每天的预算 (0 表示不限)
+// 没有配置任何预算时返回 nil
+func (b *Budget) Status(ctx context.Context, profile string, profileDailyUSD float64) ([]*domain.BudgetStatus, error) {
+	if b.cfg.DailyUSD <= 0 && b.cfg.MonthlyUSD <= 0 && profileDailyUSD <= 0 {
+		return nil, nil
+	}
+	now := b.nowFunc()
+	day, month := now.Format(time.DateOnly), now.Format("2006-01")
+	since := day
+	if b.cfg.MonthlyUSD > 0 {
+		since = month + "-01"
+	}
+	records, err := b.store.ListUsage(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var daily, monthly, profileDaily float64
+	for _, r := range records {
+		monthly += r.CostUSD
+		if r.Day == day {
+			daily += r.CostUSD
+			if r.Profile == profile {
+				profileDaily += r.CostUSD
+			}
+		}
+	}
+
+	var statuses []*domain.BudgetStatus
+	if b.cfg.DailyUSD > 0 {
+		statuses = append(statuses, &domain.BudgetStatus{Scope: domain.BudgetDaily, Period: day, LimitUSD: b.cfg.DailyUSD, SpentUSD: daily})
+	}
+	if b.cfg.MonthlyUSD > 0 {
+		statuses = append(statuses, &domain.BudgetStatus{Scope: domain.BudgetMonthly, Period: month, LimitUSD: b.cfg.MonthlyUSD, SpentUSD: monthly})
+	}
+	if profileDailyUSD > 0 {
+		statuses = append(statuses, &domain.BudgetStatus{Scope: domain.BudgetProfileDaily, Period: day, LimitUSD: profileDailyUSD, SpentUSD: profileDaily})
+	}
+	return statuses, nil
+}
+
+// markAlerted 记录预算在本周期已经告警，第一次调用时返回 true
+func (b *Budget) markAlerted(profile string, status *domain.BudgetStatus) bool {
+	key := status.Scope + "/" + status.Period
+	if status.Scope == domain.BudgetProfileDaily {
+		key = profile + "/" + key
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.alerted[key] {
+		return false
+	}
+	b.alerted[key] = true
+	return true
+}
+
+// DescribeBudget 预算用量的中文描述，如 "每日总预算 $5.00，已用 $5.12 (2025-03-01)"
+func DescribeBudget(status *domain.BudgetStatus) string {
+	label := budgetLabels[status.Scope]
+	if label == "" {
+		label = status.Scope
+	}
+	return fmt.Sprintf("%s $%.2f，已用 $%.2f (%s)", label, status.LimitUSD, status.SpentUSD, status.Period)
+}
+
+// usageMeter 一次挖矿的 LLM 用量，实现 common.UsageMeter
+type usageMeter struct {
+	budget   *Budget // 为 nil 时按默认单价统计费用，不记账也不限制
+	profile  string
+	dailyUSD float64 // 本方向每天的预算
+
+	mu      sync.Mutex
+	usage   domain.TokenUsage
+	blocked int // 因超出预算没有评估的项目数
+}
+
+// Allow 任何一项预算用完时拒绝调用；查询账本失败时不限制
+func (u *usageMeter) Allow(ctx context.Context, items int) error {
+	if u.budget == nil {
+		return nil
+	}
+	statuses, err := u.budget.Status(ctx, u.profile, u.dailyUSD)
+	if err != nil {
+		log.Printf("⚠️ 查询 LLM 用量失败，本次评估不受预算限制: %v", err)
+		return nil
+	}
+	for _, status := range statuses {
+		if status.Exceeded() {
+			u.mu.Lock()
+			u.blocked += items
+			u.mu.Unlock()
+			return fmt.Errorf("%w: %s", ErrBudgetExceeded, DescribeBudget(status))
+		}
+	}
+	return nil
+}
+
+// Record 换算费用后累加到本次挖矿的用量，并记入账本
+func (u *usageMeter) Record(ctx context.Context, model string, promptTokens, completionTokens int64) {
+	usage := domain.TokenUsage{PromptTokens: promptTokens, CompletionTokens: completionTokens}
+	if u.budget != nil {
+		usage.CostUSD = u.budget.cost(model, promptTokens, completionTokens)
+		u.budget.record(ctx, u.profile, model, usage)
+	} else {
+		usage.CostUSD = DefaultPrices[model].Cost(promptTokens, completionTokens)
+	}
+	u.mu.Lock()
+	u.usage.Add(usage)
+	u.mu.Unlock()
+}
+
+// totals 返回本次挖矿的用量和因超出预算没有评估的项目数
+func (u *usageMeter) totals() (domain.TokenUsage, int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usage, u.blocked
+}
+
+// checkBudget 在报告中记录各项预算的用量；预算用完时通过告警渠道通知，同一预算每个周期只告警一次
+func (m *MiningService) checkBudget(ctx context.Context, report *domain.MiningReport) {
+	if m.budget == nil {
+		return
+	}
+	statuses, err := m.budget.Status(ctx, m.cfg.Profile, m.cfg.DailyBudgetUSD)
+	if err != nil {
+		log.Printf("⚠️ 查询 LLM 预算失败: %v", err)
+		return
+	}
+	report.Budgets = statuses
+
+	var exceeded []string
+	for _, status := range statuses {
+		if status.Exceeded() && m.budget.markAlerted(m.cfg.Profile, status) {
+			exceeded = append(exceeded, DescribeBudget(status))
+		}
+	}
+	if len(exceeded) == 0 {
+		return
+	}
+	log.Printf("💸 LLM 费用超出预算: %s", strings.Join(exceeded, "；"))
+
+	alerter, ok := m.notifier.(port.AlertNotifier)
+	if !ok {
+		return
+	}
+	message := strings.Join(exceeded, "\n")
+	if m.cfg.Profile != "" {
+		message = "挖矿方向: " + m.cfg.Profile + "\n" + message
+	}
+	if report.BudgetBlocked > 0 {
+		message += fmt.Sprintf("\n本轮有 %d 个项目因超出预算没有评估", report.BudgetBlocked)
+	}
+	message += "\n预算周期结束前不再调用 LLM 评估"
+	if err := alerter.NotifyAlert(ctx, "warning", "LLM 费用超出预算", message); err != nil {
+		log.Printf("⚠️ 发送预算告警失败: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// llmAnalyzer 像 LLM 适配器一样逐个评估：调用前检查预算，调用后记录用量
+type llmAnalyzer struct {
+	model              string
+	prompt, completion int64 // 每次调用的 Token 数
+	calls              int
+}
+
+func (a *llmAnalyzer) CalculateStarGrowthRate(repos []*domain.Repo) []*domain.Repo { return repos }
+func (a *llmAnalyzer) SetMaxGoroutines(max int)                                    {}
+
+func (a *llmAnalyzer) AnalyzeWithLLM(ctx context.Context, repos []*domain.Repo) ([]*domain.Repo, error) {
+	for _, repo := range repos {
+		if err := common.AllowLLMCall(ctx); err != nil {
+			continue
+		}
+		a.calls++
+		common.RecordUsage(ctx, a.model, a.prompt, a.completion)
+		repo.IsAIProgrammingTool, repo.LLMScore = true, 80
+	}
+	return repos, nil
+}
+
+// alertNotifier 记录告警的通知渠道
+type alertNotifier struct {
+	*MockNotifier
+	alerts []string
+}
+
+func (n *alertNotifier) NotifyAlert(ctx context.Context, level, title, message string) error {
+	n.alerts = append(n.alerts, title+": "+message)
+	return nil
+}
+
+func TestBudget_Status(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	for _, r := range []*domain.UsageRecord{
+		{Day: "2025-02-28", Model: "gemini-2.5-pro", TokenUsage: domain.TokenUsage{CostUSD: 10}},
+		{Day: "2025-03-01", Model: "gemini-2.5-pro", TokenUsage: domain.TokenUsage{CostUSD: 2}},
+		{Day: "2025-03-02", Profile: "rust", Model: "gemini-2.5-pro", TokenUsage: domain.TokenUsage{CostUSD: 1.5}},
+		{Day: "2025-03-02", Model: "gemini-2.5-pro", TokenUsage: domain.TokenUsage{CostUSD: 1}},
+	} {
+		require.NoError(t, store.AddUsage(ctx, r))
+	}
+
+	budget := NewBudget(store, BudgetConfig{DailyUSD: 5, MonthlyUSD: 4.5})
+	budget.nowFunc = func() time.Time { return time.Date(2025, 3, 2, 12, 0, 0, 0, time.Local) }
+	statuses, err := budget.Status(ctx, "rust", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.BudgetStatus{
+		{Scope: domain.BudgetDaily, Period: "2025-03-02", LimitUSD: 5, SpentUSD: 2.5},
+		{Scope: domain.BudgetMonthly, Period: "2025-03", LimitUSD: 4.5, SpentUSD: 4.5},
+		{Scope: domain.BudgetProfileDaily, Period: "2025-03-02", LimitUSD: 1.5, SpentUSD: 1.5},
+	}, statuses)
+	assert.False(t, statuses[0].Exceeded())
+	assert.True(t, statuses[1].Exceeded())
+	assert.Equal(t, "每月总预算 $4.50，已用 $4.50 (2025-03)", DescribeBudget(statuses[1]))
+
+	// 没有配置预算时不查询账本
+	statuses, err = NewBudget(store, BudgetConfig{}).Status(ctx, "rust", 0)
+	require.NoError(t, err)
+	assert.Nil(t, statuses)
+}
+
+func TestBudget_DryRunUsageStore(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	require.NoError(t, store.AddUsage(ctx, &domain.UsageRecord{Day: "2025-03-02", Model: "gemini-2.5-pro", TokenUsage: domain.TokenUsage{CostUSD: 6}}))
+
+	budget := NewBudget(NewDryRunUsageStore(store), BudgetConfig{DailyUSD: 5})
+	budget.nowFunc = func() time.Time { return time.Date(2025, 3, 2, 12, 0, 0, 0, time.Local) }
+
+	// 试运行的用量不记账
+	common.RecordUsage(budget.Meter(ctx, ""), "gemini-2.5-pro", 1000, 1000)
+	records, err := store.ListUsage(ctx, "2025-03-01")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 6.0, records[0].CostUSD)
+
+	// 预算仍按已有用量判断
+	statuses, err := budget.Status(ctx, "", 0)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Exceeded())
+}
+
+func TestBudget_Cost(t *testing.T) {
+	budget := NewBudget(repository.NewMemoryRepo(), BudgetConfig{Prices: map[string]domain.ModelPrice{
+		"gemini-2.5-flash": {Prompt: 0.15, Completion: 0.6},
+		"my-model":         {Prompt: 1, Completion: 2},
+	}})
+	assert.InDelta(t, 1.25+10, budget.cost("gemini-2.5-pro", 1e6, 1e6), 1e-9)
+	assert.InDelta(t, 0.15+0.6, budget.cost("gemini-2.5-flash", 1e6, 1e6), 1e-9, "配置的单价覆盖默认单价")
+	assert.InDelta(t, 0.001+0.002, budget.cost("my-model", 1000, 1000), 1e-9)
+	assert.Zero(t, budget.cost("unknown", 1000, 1000))
+}
+
+func TestMiningService_Budget(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	budget := NewBudget(store, BudgetConfig{DailyUSD: 4})
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	budget.nowFunc = func() time.Time { return now }
+
+	var fetched []*domain.Repo
+	for i := 1; i <= 5; i++ {
+		fetched = append(fetched, &domain.Repo{ID: fmt.Sprintf("github-%d", i), Name: fmt.Sprintf("acme/tool-%d", i)})
+	}
+	// 每次调用 $1.5
+	analyzer := &llmAnalyzer{model: "gemini-2.5-pro", prompt: 400_000, completion: 100_000}
+	notifier := &alertNotifier{MockNotifier: new(MockNotifier)}
+	notifier.On("Notify", mock.Anything, mock.Anything).Return(nil)
+	newService := func() *MiningService {
+		scouter := new(MockScouter)
+		scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return(fetched, nil)
+		service := NewMiningService(scouter, new(MockFilter), analyzer, store, new(MockAppraiser), notifier)
+		service.SetConfig(MiningConfig{Profile: "agents", MinScore: 50})
+		service.SetBudget(budget)
+		service.notifyInterval = 0
+		require.NoError(t, service.SetPipeline(PipelineConfig{Stages: []StageConfig{
+			{Name: domain.StageFetch}, {Name: domain.StageAppraise}, {Name: domain.StageSelect}, {Name: domain.StageNotify},
+		}}))
+		return service
+	}
+
+	// 第三次调用后超出每日预算，剩下的项目不再评估
+	report, err := newService().ExecuteMiningCycle(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, analyzer.calls)
+	assert.Equal(t, domain.TokenUsage{PromptTokens: 1_200_000, CompletionTokens: 300_000, CostUSD: 4.5}, report.Usage)
+	assert.Equal(t, int64(1_500_000), report.LLMTokens)
+	assert.Equal(t, 2, report.BudgetBlocked)
+	assert.Equal(t, []*domain.BudgetStatus{{Scope: domain.BudgetDaily, Period: "2025-03-01", LimitUSD: 4, SpentUSD: 4.5}}, report.Budgets)
+	assert.Contains(t, report.Stage(domain.StageAppraise).Errors[0], "跳过了 2 个项目的评估")
+	require.Len(t, notifier.alerts, 1)
+	assert.Contains(t, notifier.alerts[0], "挖矿方向: agents")
+	assert.Contains(t, notifier.alerts[0], "每日总预算 $4.00，已用 $4.50")
+
+	records, err := store.ListUsage(ctx, "2025-03-01")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "agents", records[0].Profile)
+	assert.Equal(t, int64(1_200_000), records[0].PromptTokens)
+
+	// 同一天内不再调用 LLM，也不重复告警
+	report, err = newService().ExecuteMiningCycle(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, analyzer.calls)
+	assert.Equal(t, 5, report.BudgetBlocked)
+	assert.Len(t, notifier.alerts, 1)
+
+	// 第二天重新开始计算
+	now = now.Add(24 * time.Hour)
+	report, err = newService().ExecuteMiningCycle(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 6, analyzer.calls)
+	assert.Equal(t, 2, report.BudgetBlocked)
+	assert.Len(t, notifier.alerts, 2)
+}
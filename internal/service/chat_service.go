@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// ErrNoSession 还没有开始对话
+var ErrNoSession = errors.New("还没有开始对话")
+
+// ChatService 对话式检索：首轮检索候选项目并缓存，之后的追问复用同一批候选项目和对话历史
+type ChatService struct {
+	searcher *SearchService
+	chatter  port.Chatter
+	nowFunc  func() time.Time
+
+	session     port.ChatSession
+	candidates  []*domain.Repo
+	known       map[string]bool
+	messages    []*domain.ChatMessage
+	lastResults []*domain.SearchResult
+	shortlist   []*domain.Repo
+}
+
+// NewChatService 创建对话服务
+func NewChatService(searcher *SearchService, chatter port.Chatter) *ChatService {
+	c := &ChatService{searcher: searcher, chatter: chatter, nowFunc: time.Now}
+	c.Reset()
+	return c
+}
+
+// Reset 结束当前对话，清空候选项目和历史，保留收藏
+func (c *ChatService) Reset() {
+	c.session = nil
+	c.candidates = nil
+	c.known = make(map[string]bool)
+	c.messages = nil
+	c.lastResults = nil
+}
+
+// Retrieve 按 query 检索候选项目并加入当前对话，返回新增的项目数
+func (c *ChatService) Retrieve(ctx context.Context, query string) (int, error) {
+	repos, err := c.searcher.Candidates(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	var added []*domain.Repo
+	for _, r := range repos {
+		if !c.known[r.ID] {
+			c.known[r.ID] = true
+			added = append(added, r)
+		}
+	}
+	c.candidates = append(c.candidates, added...)
+
+	if c.session == nil {
+		// 没有候选项目时不开始对话，下一条消息重新检索
+		if len(c.candidates) > 0 {
+			c.session = c.chatter.StartChat(c.candidates)
+		}
+	} else if len(added) > 0 {
+		c.session.AddCandidates(added)
+	}
+	return len(added), nil
+}
+
+// Ask 发送一条消息，还没有开始对话时先用消息内容检索候选项目
+func (c *ChatService) Ask(ctx context.Context, message string) (*domain.ChatReply, error) {
+	// 对话的用量与检索一样记入搜索服务的预算
+	ctx = c.searcher.budget.Meter(ctx, "")
+	if c.session == nil {
+		if _, err := c.Retrieve(ctx, message); err != nil {
+			return nil, fmt.Errorf("检索候选项目失败: %w", err)
+		}
+	}
+	if len(c.candidates) == 0 {
+		return &domain.ChatReply{Message: "数据库里还没有项目，请先运行 -mode=mine 抓取一些项目"}, nil
+	}
+
+	reply, err := c.session.Send(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	reply.Results = validateSearchResults(reply.Results, c.candidates)
+
+	now := c.nowFunc()
+	c.messages = append(c.messages,
+		&domain.ChatMessage{Role: domain.ChatRoleUser, Content: message, At: now},
+		&domain.ChatMessage{Role: domain.ChatRoleAssistant, Content: reply.Message, Results: reply.Results, At: now},
+	)
+	// 没有推荐项目的回复 (如对比、解释) 不覆盖编号，用户可以继续按上一次的序号操作
+	if len(reply.Results) > 0 {
+		c.lastResults = reply.Results
+	}
+	return reply, nil
+}
+
+// Resolve 把用户输入的引用解析为项目：序号 (上一次推荐列表中的位置)、项目 ID 或 owner/name
+func (c *ChatService) Resolve(ref string) (*domain.Repo, error) {
+	ref = strings.TrimSpace(ref)
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(c.lastResults) {
+			return nil, fmt.Errorf("序号 %d 超出范围，上一次推荐了 %d 个项目", n, len(c.lastResults))
+		}
+		return c.lastResults[n-1].Repo, nil
+	}
+	for _, r := range c.candidates {
+		if r.ID == ref || strings.EqualFold(r.Name, ref) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("找不到项目: %s", ref)
+}
+
+// Save 把项目加入收藏，refs 为空时收藏上一次推荐的全部项目，返回新加入的项目
+func (c *ChatService) Save(refs ...string) ([]*domain.Repo, error) {
+	var repos []*domain.Repo
+	if len(refs) == 0 {
+		if len(c.lastResults) == 0 {
+			return nil, errors.New("还没有推荐过项目")
+		}
+		for _, r := range c.lastResults {
+			repos = append(repos, r.Repo)
+		}
+	}
+	for _, ref := range refs {
+		repo, err := c.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+
+	var added []*domain.Repo
+	for _, repo := range repos {
+		if !c.inShortlist(repo.ID) {
+			c.shortlist = append(c.shortlist, repo)
+			added = append(added, repo)
+		}
+	}
+	return added, nil
+}
+
+func (c *ChatService) inShortlist(id string) bool {
+	for _, r := range c.shortlist {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Shortlist 返回收藏的项目
+func (c *ChatService) Shortlist() []*domain.Repo {
+	return c.shortlist
+}
+
+// Messages 返回当前对话的全部消息
+func (c *ChatService) Messages() []*domain.ChatMessage {
+	return c.messages
+}
+
+// WriteShortlist 以 Markdown 列表写出收藏
+func (c *ChatService) WriteShortlist(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# 收藏的项目\n\n")
+	for _, r := range c.shortlist {
+		fmt.Fprintf(&b, "- [%s](%s) ⭐ %d", r.Name, r.URL, r.Stars)
+		if r.Description != "" {
+			fmt.Fprintf(&b, " — %s", r.Description)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Export 导出对话，format 为 markdown 或 json
+func (c *ChatService) Export(w io.Writer, format string) error {
+	if len(c.messages) == 0 {
+		return ErrNoSession
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c.messages)
+	case "markdown", "md":
+		var b strings.Builder
+		b.WriteString("# 项目检索对话\n")
+		for _, m := range c.messages {
+			if m.Role == domain.ChatRoleUser {
+				fmt.Fprintf(&b, "\n## 🙋 %s\n\n_%s_\n", m.Content, m.At.Format("2006-01-02 15:04"))
+				continue
+			}
+			fmt.Fprintf(&b, "\n%s\n", m.Content)
+			for i, r := range m.Results {
+				fmt.Fprintf(&b, "\n%d. [%s](%s) (相关度 %d)：%s", i+1, r.Repo.Name, r.Repo.URL, r.Relevance, r.Reason)
+				if r.Action != "" {
+					fmt.Fprintf(&b, " 建议：%s", r.Action)
+				}
+			}
+			if len(m.Results) > 0 {
+				b.WriteString("\n")
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChatter 按顺序返回预设回复，记录每轮收到的消息和追加的候选项目
+type fakeChatter struct {
+	replies    []*domain.ChatReply
+	started    []*domain.Repo
+	added      [][]*domain.Repo
+	sent       []string
+	startCount int
+}
+
+func (f *fakeChatter) StartChat(candidates []*domain.Repo) port.ChatSession {
+	f.startCount++
+	f.started = candidates
+	return f
+}
+
+func (f *fakeChatter) AddCandidates(repos []*domain.Repo) {
+	f.added = append(f.added, repos)
+}
+
+func (f *fakeChatter) Send(ctx context.Context, message string) (*domain.ChatReply, error) {
+	f.sent = append(f.sent, message)
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return reply, nil
+}
+
+func TestChatService_Conversation(t *testing.T) {
+	ctx := context.Background()
+	goRepo := &domain.Repo{ID: "github-1", Name: "ai/go-agent", URL: "https://github.com/ai/go-agent", Language: "Go"}
+	pyRepo := &domain.Repo{ID: "github-2", Name: "ai/py-agent", URL: "https://github.com/ai/py-agent", Language: "Python"}
+	reviewer := &domain.Repo{ID: "github-3", Name: "ai/reviewer", URL: "https://github.com/ai/reviewer"}
+
+	repoStore := new(MockRepository)
+	repoStore.On("GetAllCandidates", mock.Anything).Return([]*domain.Repo{goRepo, pyRepo}, nil).Once()
+	repoStore.On("GetAllCandidates", mock.Anything).Return([]*domain.Repo{pyRepo, reviewer}, nil).Once()
+
+	chatter := &fakeChatter{replies: []*domain.ChatReply{
+		{Message: "推荐两个", Results: []*domain.SearchResult{
+			{RepoID: "github-2", Relevance: 70, Reason: "Python"},
+			{RepoID: "github-1", Relevance: 90, Reason: "Go"},
+			{RepoID: "github-404", Relevance: 99},
+		}},
+		{Message: "| 对比 |", Results: nil},
+		{Message: "只有这个是 Go 的", Results: []*domain.SearchResult{{RepoID: "github-1", Relevance: 95}}},
+	}}
+
+	chat := NewChatService(NewSearchService(repoStore, new(MockAppraiser), nil, SearchConfig{}), chatter)
+	chat.nowFunc = func() time.Time { return time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC) }
+
+	// 首轮：检索候选项目并开启对话，编造的项目被丢弃
+	reply, err := chat.Ask(ctx, "命令行 Agent")
+	require.NoError(t, err)
+	require.Len(t, reply.Results, 2)
+	assert.Equal(t, "github-1", reply.Results[0].RepoID)
+	assert.Equal(t, []*domain.Repo{goRepo, pyRepo}, chatter.started)
+
+	// 追问：复用候选项目，不重新检索；没有推荐项目的回复不影响序号
+	_, err = chat.Ask(ctx, "对比前两个")
+	require.NoError(t, err)
+	repo, err := chat.Resolve("2")
+	require.NoError(t, err)
+	assert.Equal(t, pyRepo, repo)
+	_, err = chat.Resolve("3")
+	assert.Error(t, err)
+
+	_, err = chat.Ask(ctx, "只要 Go 的")
+	require.NoError(t, err)
+	assert.Equal(t, 1, chatter.startCount)
+	assert.Equal(t, []string{"命令行 Agent", "对比前两个", "只要 Go 的"}, chatter.sent)
+
+	// 重新检索只追加新项目
+	added, err := chat.Retrieve(ctx, "代码审查")
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, [][]*domain.Repo{{reviewer}}, chatter.added)
+
+	// 收藏：默认收藏上一次推荐的全部项目，支持 ID 和项目名，去重
+	saved, err := chat.Save()
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Repo{goRepo}, saved)
+	saved, err = chat.Save("ai/Reviewer", "github-1")
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Repo{reviewer}, saved)
+	assert.Equal(t, []*domain.Repo{goRepo, reviewer}, chat.Shortlist())
+
+	var shortlist bytes.Buffer
+	require.NoError(t, chat.WriteShortlist(&shortlist))
+	assert.Contains(t, shortlist.String(), "- [ai/go-agent](https://github.com/ai/go-agent)")
+
+	// 导出对话
+	var md bytes.Buffer
+	require.NoError(t, chat.Export(&md, "markdown"))
+	assert.Contains(t, md.String(), "## 🙋 命令行 Agent")
+	assert.Contains(t, md.String(), "1. [ai/go-agent](https://github.com/ai/go-agent) (相关度 90)：Go")
+	assert.Contains(t, md.String(), "| 对比 |")
+
+	var js bytes.Buffer
+	require.NoError(t, chat.Export(&js, "json"))
+	var messages []*domain.ChatMessage
+	require.NoError(t, json.Unmarshal(js.Bytes(), &messages))
+	require.Len(t, messages, 6)
+	assert.Equal(t, domain.ChatRoleAssistant, messages[1].Role)
+	assert.Equal(t, "github-1", messages[1].Results[0].RepoID)
+
+	assert.Error(t, chat.Export(&js, "pdf"))
+
+	// 重置后收藏保留
+	chat.Reset()
+	assert.ErrorIs(t, chat.Export(&js, "json"), ErrNoSession)
+	assert.Len(t, chat.Shortlist(), 2)
+	repoStore.AssertExpectations(t)
+}
+
+func TestChatService_EmptyDatabase(t *testing.T) {
+	repo := &domain.Repo{ID: "github-1", Name: "ai/go-agent"}
+	repoStore := new(MockRepository)
+	repoStore.On("GetAllCandidates", mock.Anything).Return([]*domain.Repo{}, nil).Once()
+	repoStore.On("GetAllCandidates", mock.Anything).Return([]*domain.Repo{repo}, nil).Once()
+	chatter := &fakeChatter{replies: []*domain.ChatReply{{Message: "推荐", Results: []*domain.SearchResult{{RepoID: "github-1", Relevance: 90}}}}}
+
+	chat := NewChatService(NewSearchService(repoStore, new(MockAppraiser), nil, SearchConfig{}), chatter)
+	reply, err := chat.Ask(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Contains(t, reply.Message, "-mode=mine")
+	assert.Empty(t, chatter.sent)
+	assert.Zero(t, chatter.startCount)
+
+	// 没有候选项目时不开始对话，下一条消息重新检索
+	reply, err = chat.Ask(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "推荐", reply.Message)
+	assert.Equal(t, 1, chatter.startCount)
+	assert.Equal(t, []*domain.Repo{repo}, chatter.started)
+	repoStore.AssertExpectations(t)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// appraiseBatch 启用检查点时每评估完这么多个项目保存一次进度
+const appraiseBatch = 10
+
+// checkpointer 把流水线每个阶段的输出保存为执行记录的检查点
+type checkpointer struct {
+	store port.CheckpointStore
+	run   *domain.MiningRun
+	seqs  map[string]int // 阶段名称 → 在流水线中的位置
+}
+
+// EnableCheckpoints 每个阶段结束后把输出保存为 run 的检查点；
+// run.ResumedFrom 不为 0 时从那次执行的检查点续跑，跳过已完成的阶段并复用已完成的评估
+func (m *MiningService) EnableCheckpoints(store port.CheckpointStore, run *domain.MiningRun) {
+	m.checkpoints = &checkpointer{store: store, run: run}
+}
+
+// begin 读取中断执行的检查点并复制到本次执行，本次执行再中断时可以继续续跑
+// 返回可以跳过的阶段的输出，以及中断前已经完成评估的项目 (项目 ID → 项目)
+func (c *checkpointer) begin(ctx context.Context, names []string) ([][]*domain.Repo, map[string]*domain.Repo) {
+	c.seqs = make(map[string]int, len(names))
+	for i, name := range names {
+		c.seqs[name] = i
+	}
+	if c.run.ResumedFrom == 0 {
+		return nil, nil
+	}
+
+	checkpoints, err := c.store.GetCheckpoints(ctx, c.run.ResumedFrom)
+	if err != nil {
+		log.Printf("⚠️ 读取执行记录 #%d 的检查点失败，从头执行: %v", c.run.ResumedFrom, err)
+		return nil, nil
+	}
+	var outputs [][]*domain.Repo
+	appraised := make(map[string]*domain.Repo)
+	for _, checkpoint := range checkpoints {
+		// 流水线配置变化或中间缺少检查点时，之后的检查点不再可用
+		if checkpoint.Seq != len(outputs) || checkpoint.Seq >= len(names) || names[checkpoint.Seq] != checkpoint.Stage {
+			break
+		}
+		c.save(ctx, checkpoint.Stage, checkpoint.Repos, checkpoint.Complete)
+		if !checkpoint.Complete {
+			for _, repo := range checkpoint.Repos {
+				if repo.AppraisedAt != nil {
+					appraised[repo.ID] = repo
+				}
+			}
+			break
+		}
+		outputs = append(outputs, checkpoint.Repos)
+	}
+	if err := c.store.DeleteCheckpoints(ctx, c.run.ResumedFrom); err != nil {
+		log.Printf("⚠️ 删除执行记录 #%d 的检查点失败: %v", c.run.ResumedFrom, err)
+	}
+	return outputs, appraised
+}
+
+// save 保存阶段的输出，实现 StageHook；保存失败只记录日志，不影响挖矿
+func (c *checkpointer) save(ctx context.Context, stage string, repos []*domain.Repo, complete bool) {
+	checkpoint := &domain.RunCheckpoint{
+		RunID:    c.run.ID,
+		Seq:      c.seqs[stage],
+		Stage:    stage,
+		Complete: complete,
+		Repos:    repos,
+	}
+	// 阶段因为整轮超时结束时 ctx 已经结束，检查点仍然需要写入
+	if err := c.store.SaveCheckpoint(context.WithoutCancel(ctx), checkpoint); err != nil {
+		log.Printf("⚠️ 保存阶段 %s 的检查点失败: %v", stage, err)
+	}
+}
+
+// reuseAppraisal 复用中断前已完成的评估，或 README、描述和 Prompt 版本都没有变化的已入库项目的评估
+// 只有已入库且评估过的项目才查询 README 版本，并记录到 repo.ReadmeSHA，入库后供下次比较
+func (m *MiningService) reuseAppraisal(ctx context.Context, repo *domain.Repo) bool {
+	if prev := m.resumed[repo.ID]; prev != nil {
+		copyAppraisal(repo, prev)
+		return true
+	}
+
+	inspector, ok := m.fetcher.(port.ReadmeInspector)
+	if !ok {
+		return false
+	}
+	// 先查已有评估，没有可比较的评估时不消耗 GitHub 配额
+	stored, err := m.repoStore.Get(ctx, repo.ID)
+	if err != nil {
+		if !errors.Is(err, port.ErrNotFound) {
+			log.Printf("⚠️ 读取 %s 的已有评估失败: %v", repo.Name, err)
+		}
+		return false
+	}
+	if stored.AppraisedAt == nil {
+		return false
+	}
+
+	sha, err := inspector.ReadmeSHA(ctx, repo.Name)
+	if err != nil {
+		log.Printf("⚠️ 获取 %s 的 README 版本失败: %v", repo.Name, err)
+		return false
+	}
+	repo.ReadmeSHA = sha
+	versioner, ok := m.appraiser.(port.PromptVersioner)
+	if !ok || sha == "" || stored.ReadmeSHA != sha ||
+		stored.Description != repo.Description || stored.PromptVersion != versioner.PromptVersion() {
+		return false
+	}
+	copyAppraisal(repo, stored)
+	return true
+}
+
+// copyAppraisal 把 from 的评估结果复制到 repo
+func copyAppraisal(repo, from *domain.Repo) {
+	repo.IsAIProgrammingTool = from.IsAIProgrammingTool
+	repo.LLMScore = from.LLMScore
+	repo.LLMReview = from.LLMReview
+	repo.Categories = append([]string(nil), from.Categories...)
+	repo.AppraisalModel = from.AppraisalModel
+	repo.PromptVersion = from.PromptVersion
+	repo.AppraisedAt = from.AppraisedAt
+	repo.ReadmeSHA = from.ReadmeSHA
+}
+
+// resumeSummary 续跑时的提示
+func resumeSummary(run *domain.MiningRun, skipped, appraised int) string {
+	return fmt.Sprintf("♻️ 从执行记录 #%d 的检查点续跑: 跳过 %d 个已完成的阶段，复用 %d 个已完成的评估", run.ResumedFrom, skipped, appraised)
+}
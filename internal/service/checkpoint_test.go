@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// readmeScouter 能查询 README 版本的数据源
+type readmeScouter struct {
+	*MockScouter
+	shas    map[string]string
+	queried []string
+}
+
+func (s *readmeScouter) ReadmeSHA(ctx context.Context, fullName string) (string, error) {
+	s.queried = append(s.queried, fullName)
+	return s.shas[fullName], nil
+}
+
+// versionedAppraiser 报告 Prompt 版本的鉴定师
+type versionedAppraiser struct {
+	*MockAppraiser
+	version string
+}
+
+func (a *versionedAppraiser) PromptVersion() string { return a.version }
+
+// funcAnalyzer 按函数评估项目，记录每次调用的输入
+type funcAnalyzer struct {
+	analyze func(n int, repos []*domain.Repo) ([]*domain.Repo, error) // n 为第几次调用，从 0 开始
+	calls   [][]*domain.Repo
+}
+
+func (a *funcAnalyzer) CalculateStarGrowthRate(repos []*domain.Repo) []*domain.Repo { return repos }
+func (a *funcAnalyzer) SetMaxGoroutines(max int)                                    {}
+
+func (a *funcAnalyzer) AnalyzeWithLLM(ctx context.Context, repos []*domain.Repo) ([]*domain.Repo, error) {
+	a.calls = append(a.calls, repos)
+	return a.analyze(len(a.calls)-1, repos)
+}
+
+func TestMiningService_ResumeFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	runs := NewRunHistory(store)
+	pipeline := PipelineConfig{Stages: []StageConfig{
+		{Name: domain.StageFetch, OnError: PolicyFail},
+		{Name: domain.StageAppraise, OnError: PolicyFail},
+		{Name: domain.StageStore},
+	}}
+	appraisedAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	appraise := func(repos []*domain.Repo) []*domain.Repo {
+		for _, repo := range repos {
+			repo.IsAIProgrammingTool, repo.LLMScore, repo.AppraisedAt = true, 70, &appraisedAt
+		}
+		return repos
+	}
+	newService := func(scouter *MockScouter, analyzer *funcAnalyzer) *MiningService {
+		service := NewMiningService(scouter, new(MockFilter), analyzer, store, new(MockAppraiser), nil)
+		service.SetConfig(MiningConfig{})
+		require.NoError(t, service.SetPipeline(pipeline))
+		return service
+	}
+
+	var fetched []*domain.Repo
+	for i := 1; i <= appraiseBatch+2; i++ {
+		fetched = append(fetched, &domain.Repo{ID: fmt.Sprintf("github-%d", i), Name: fmt.Sprintf("acme/tool-%d", i)})
+	}
+
+	// 第一次执行评估完第一批后超时
+	scouter := new(MockScouter)
+	scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return(fetched, nil)
+	first := newService(scouter, &funcAnalyzer{analyze: func(n int, repos []*domain.Repo) ([]*domain.Repo, error) {
+		if n == 0 {
+			return appraise(repos), nil
+		}
+		return repos, context.DeadlineExceeded
+	}})
+	interrupted, err := runs.Run(ctx, domain.TriggerCron, "", func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		first.EnableCheckpoints(store, run)
+		return first.ExecuteMiningCycle(ctx, 1)
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 第二次执行跳过抓取，只评估剩下的项目
+	scouter = new(MockScouter)
+	analyzer := &funcAnalyzer{analyze: func(n int, repos []*domain.Repo) ([]*domain.Repo, error) {
+		return appraise(repos), nil
+	}}
+	second := newService(scouter, analyzer)
+	var report *domain.MiningReport
+	resumed, err := runs.Run(ctx, domain.TriggerCron, "", func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		second.EnableCheckpoints(store, run)
+		report, err = second.ExecuteMiningCycle(ctx, 1)
+		return report, err
+	})
+	require.NoError(t, err)
+	scouter.AssertNotCalled(t, "GetTrendingRepos", mock.Anything, mock.Anything, mock.Anything)
+	require.Len(t, analyzer.calls, 1)
+	require.Len(t, analyzer.calls[0], 2)
+	assert.Equal(t, "github-11", analyzer.calls[0][0].ID)
+
+	assert.Equal(t, interrupted.ID, resumed.ResumedFrom)
+	assert.True(t, report.Stage(domain.StageFetch).Resumed)
+	assert.Equal(t, len(fetched), report.Stage(domain.StageFetch).Out)
+	assert.False(t, report.Stage(domain.StageAppraise).Resumed)
+	assert.Equal(t, appraiseBatch, report.Reused)
+	assert.Equal(t, len(fetched), report.Stage(domain.StageStore).In)
+	for _, repo := range fetched {
+		got, err := store.Get(ctx, repo.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 70, got.LLMScore, repo.ID)
+	}
+
+	// 续跑成功后两次执行的检查点都已删除
+	for _, id := range []int64{interrupted.ID, resumed.ID} {
+		saved, err := store.GetCheckpoints(ctx, id)
+		require.NoError(t, err)
+		assert.Empty(t, saved, "执行记录 #%d", id)
+	}
+}
+
+func TestMiningService_ReuseAppraisal(t *testing.T) {
+	ctx := context.Background()
+	appraisedAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	stored := func(id, sha string) *domain.Repo {
+		return &domain.Repo{
+			ID: id, Name: "acme/" + id, Description: "agent", ReadmeSHA: sha,
+			IsAIProgrammingTool: true, LLMScore: 90, LLMReview: "上次的评估", PromptVersion: "v2", AppraisedAt: &appraisedAt,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		repo    *domain.Repo // 本次抓取到的项目
+		reused  bool
+	}{
+		{name: "README 和描述都没有变化", version: "v2", repo: &domain.Repo{ID: "same", Name: "acme/same", Description: "agent"}, reused: true},
+		{name: "README 变化", version: "v2", repo: &domain.Repo{ID: "readme", Name: "acme/readme", Description: "agent"}},
+		{name: "描述变化", version: "v2", repo: &domain.Repo{ID: "same", Name: "acme/same", Description: "coding agent"}},
+		{name: "Prompt 版本变化", version: "v3", repo: &domain.Repo{ID: "same", Name: "acme/same", Description: "agent"}},
+		{name: "新项目", version: "v2", repo: &domain.Repo{ID: "new", Name: "acme/new", Description: "agent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemoryRepo()
+			require.NoError(t, store.Save(ctx, stored("same", "sha-same")))
+			require.NoError(t, store.Save(ctx, stored("readme", "sha-old")))
+
+			scouter := &readmeScouter{MockScouter: new(MockScouter), shas: map[string]string{
+				"acme/same": "sha-same", "acme/readme": "sha-new", "acme/new": "sha-new",
+			}}
+			scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return([]*domain.Repo{tt.repo}, nil)
+			analyzer := &funcAnalyzer{analyze: func(n int, repos []*domain.Repo) ([]*domain.Repo, error) {
+				return repos, nil
+			}}
+
+			service := NewMiningService(scouter, new(MockFilter), analyzer, store, &versionedAppraiser{new(MockAppraiser), tt.version}, nil)
+			service.SetConfig(MiningConfig{})
+			require.NoError(t, service.SetPipeline(PipelineConfig{Stages: []StageConfig{
+				{Name: domain.StageFetch}, {Name: domain.StageAppraise}, {Name: domain.StageStore},
+			}}))
+			report, err := service.ExecuteMiningCycle(ctx, 1)
+			require.NoError(t, err)
+
+			require.Len(t, analyzer.calls, 1)
+			if tt.reused {
+				assert.Equal(t, 1, report.Reused)
+				assert.Empty(t, analyzer.calls[0])
+				assert.Equal(t, 90, tt.repo.LLMScore)
+				assert.Equal(t, "上次的评估", tt.repo.LLMReview)
+			} else {
+				assert.Zero(t, report.Reused)
+				assert.Equal(t, []*domain.Repo{tt.repo}, analyzer.calls[0])
+			}
+			// 已入库的项目查询 README 版本并随项目入库，供下次比较；新项目没有可比较的评估，不查询
+			got, err := store.Get(ctx, tt.repo.ID)
+			require.NoError(t, err)
+			if tt.repo.ID == "new" {
+				assert.Empty(t, scouter.queried)
+				assert.Empty(t, got.ReadmeSHA)
+			} else {
+				assert.Equal(t, []string{tt.repo.Name}, scouter.queried)
+				assert.Equal(t, scouter.shas[tt.repo.Name], got.ReadmeSHA)
+			}
+		})
+	}
+}
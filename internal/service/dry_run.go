@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// DryRunRecorder 记录试运行中本应写入数据库和推送的项目
+type DryRunRecorder struct {
+	mu       sync.Mutex
+	saved    []string
+	notified []string
+}
+
+// Saved 本应入库 (新增或更新) 的项目，按调用顺序
+func (r *DryRunRecorder) Saved() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.saved...)
+}
+
+// Notified 本应推送的项目，按调用顺序
+func (r *DryRunRecorder) Notified() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.notified...)
+}
+
+func (r *DryRunRecorder) recordSave(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, name)
+}
+
+func (r *DryRunRecorder) recordNotify(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notified = append(r.notified, name)
+}
+
+// EnableDryRun 切换为试运行：流水线照常执行，抓取和 LLM 评估照常调用，
+// 但入库和推送只记录在返回的 DryRunRecorder 中，不写数据库也不发消息，也不补齐向量索引
+// 是否已入库仍然查询真实数据库，因此报告与正式运行的结果一致
+func (m *MiningService) EnableDryRun() *DryRunRecorder {
+	recorder := &DryRunRecorder{}
+	m.repoStore = &dryRunRepository{Repository: m.repoStore, recorder: recorder, saved: make(map[string]*domain.Repo)}
+	m.notifier = dryRunNotifier{recorder: recorder}
+	m.notifyInterval = 0
+	m.indexer = nil
+	return recorder
+}
+
+// dryRunRepository 读操作转发到真实仓库，写操作只记录
+type dryRunRepository struct {
+	port.Repository
+	recorder *DryRunRecorder
+
+	mu    sync.Mutex
+	saved map[string]*domain.Repo
+}
+
+func (r *dryRunRepository) Save(ctx context.Context, repo *domain.Repo) error {
+	r.mu.Lock()
+	copied := *repo
+	if stored := r.saved[repo.ID]; stored != nil {
+		copied.Profiles = domain.MergeProfiles(stored.Profiles, repo.Profiles)
+	}
+	r.saved[repo.ID] = &copied
+	r.mu.Unlock()
+	r.recorder.recordSave(repo.Name)
+	return nil
+}
+
+// Exists 试运行中"保存"过的项目也视为已存在
+func (r *dryRunRepository) Exists(ctx context.Context, repoID string) (bool, error) {
+	r.mu.Lock()
+	saved := r.saved[repoID]
+	r.mu.Unlock()
+	if saved != nil {
+		return true, nil
+	}
+	return r.Repository.Exists(ctx, repoID)
+}
+
+// Get 优先返回试运行中"保存"过的项目
+func (r *dryRunRepository) Get(ctx context.Context, repoID string) (*domain.Repo, error) {
+	r.mu.Lock()
+	saved := r.saved[repoID]
+	r.mu.Unlock()
+	if saved != nil {
+		copied := *saved
+		return &copied, nil
+	}
+	return r.Repository.Get(ctx, repoID)
+}
+
+func (r *dryRunRepository) MarkAsNotified(ctx context.Context, repoID string) error {
+	return nil
+}
+
+func (r *dryRunRepository) UpdateTracking(ctx context.Context, repo *domain.Repo) error {
+	return nil
+}
+
+func (r *dryRunRepository) AddSnapshot(ctx context.Context, snapshot *domain.StarSnapshot) error {
+	return nil
+}
+
+func (r *dryRunRepository) AddFeedback(ctx context.Context, feedback *domain.Feedback) error {
+	return nil
+}
+
+// dryRunUsageStore 读取真实的用量账本以执行预算，用量不写入
+type dryRunUsageStore struct {
+	port.UsageStore
+}
+
+// NewDryRunUsageStore 试运行使用的用量账本：预算照常按已有用量判断，本次的用量不记账
+func NewDryRunUsageStore(store port.UsageStore) port.UsageStore {
+	return dryRunUsageStore{UsageStore: store}
+}
+
+func (s dryRunUsageStore) AddUsage(ctx context.Context, record *domain.UsageRecord) error {
+	return nil
+}
+
+// dryRunNotifier 只记录推送的项目
+type dryRunNotifier struct {
+	recorder *DryRunRecorder
+}
+
+func (n dryRunNotifier) Notify(ctx context.Context, repo *domain.Repo) error {
+	n.recorder.recordNotify(repo.Name)
+	return nil
+}
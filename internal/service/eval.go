@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// EvalCase 评估数据集中的一个项目，Expected 为人工标注的是否应该推送
+type EvalCase struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Expected    bool   `json:"expected"`
+	Note        string `json:"note,omitempty"` // 标注理由
+}
+
+// LoadEvalCases 读取 JSON 格式的评估数据集，项目 ID 不能重复
+func LoadEvalCases(path string) ([]EvalCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取评估数据集失败: %w", err)
+	}
+	var cases []EvalCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("解析评估数据集 %s 失败: %w", path, err)
+	}
+	seen := make(map[string]bool, len(cases))
+	for i, c := range cases {
+		if c.ID == "" || c.Name == "" {
+			return nil, fmt.Errorf("评估数据集第 %d 项缺少 id 或 name", i+1)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("评估数据集中的项目 ID %s 重复", c.ID)
+		}
+		seen[c.ID] = true
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("评估数据集 %s 为空", path)
+	}
+	return cases, nil
+}
+
+// EvalResult 一种评估方式在数据集上的结果，"推送" 与挖矿的评分筛选阶段一致：
+// 被识别为 AI 编程工具 (或符合自定义标准) 且评分不低于 MinScore
+type EvalResult struct {
+	Mode      string `json:"mode"`       // single 或 batch
+	BatchSize int    `json:"batch_size"` // 每次 LLM 调用评估的项目数
+	MinScore  int    `json:"min_score"`
+
+	Cases          int `json:"cases"`
+	Failed         int `json:"failed"` // 没有得到评估结果的项目
+	TruePositives  int `json:"true_positives"`
+	FalsePositives int `json:"false_positives"`
+	TrueNegatives  int `json:"true_negatives"`
+	FalseNegatives int `json:"false_negatives"`
+
+	Usage    domain.TokenUsage `json:"usage"`
+	Duration time.Duration     `json:"duration"`
+
+	Scores   map[string]int  `json:"scores"`   // 按项目 ID 的评分，不含评估失败的项目
+	Selected map[string]bool `json:"selected"` // 按项目 ID 是否推送，不含评估失败的项目
+}
+
+// Accuracy 评估成功的项目中，是否推送的判断与标注一致的比例
+func (r *EvalResult) Accuracy() float64 {
+	return ratio(r.TruePositives+r.TrueNegatives, r.Cases-r.Failed)
+}
+
+// Precision 推送的项目中标注为应该推送的比例
+func (r *EvalResult) Precision() float64 {
+	return ratio(r.TruePositives, r.TruePositives+r.FalsePositives)
+}
+
+// Recall 标注为应该推送的项目中实际推送的比例
+func (r *EvalResult) Recall() float64 {
+	return ratio(r.TruePositives, r.TruePositives+r.FalseNegatives)
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// EvaluateAppraisals 用 analyzer 评估数据集中的所有项目，与标注对比，并统计 LLM 用量
+// 每次评估使用新的项目副本，同一个数据集可以交给不同配置的 analyzer 评估
+func EvaluateAppraisals(ctx context.Context, analyzer port.Analyzer, cases []EvalCase, minScore int) (*EvalResult, error) {
+	repos := make([]*domain.Repo, 0, len(cases))
+	expected := make(map[string]bool, len(cases))
+	for _, c := range cases {
+		repos = append(repos, &domain.Repo{ID: c.ID, Name: c.Name, Description: c.Description, URL: c.URL})
+		expected[c.ID] = c.Expected
+	}
+
+	meter := &usageMeter{}
+	start := time.Now()
+	analyzed, err := analyzer.AnalyzeWithLLM(common.WithUsageMeter(ctx, meter), repos)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EvalResult{
+		MinScore: minScore,
+		Cases:    len(cases),
+		Duration: time.Since(start),
+		Scores:   make(map[string]int, len(cases)),
+		Selected: make(map[string]bool, len(cases)),
+	}
+	result.Usage, _ = meter.totals()
+	for _, repo := range analyzed {
+		if repo.AppraisedAt == nil {
+			result.Failed++
+			continue
+		}
+		selected := repo.IsAIProgrammingTool && repo.LLMScore >= minScore
+		result.Scores[repo.ID] = repo.LLMScore
+		result.Selected[repo.ID] = selected
+		switch {
+		case selected && expected[repo.ID]:
+			result.TruePositives++
+		case selected:
+			result.FalsePositives++
+		case expected[repo.ID]:
+			result.FalseNegatives++
+		default:
+			result.TrueNegatives++
+		}
+	}
+	return result, nil
+}
+
+// EvalComparison 两种评估方式在同一数据集上的一致性
+type EvalComparison struct {
+	Compared      int      `json:"compared"`        // 两种方式都评估成功的项目数
+	Agreement     float64  `json:"agreement"`       // 是否推送的判断一致的比例
+	MeanScoreDiff float64  `json:"mean_score_diff"` // 评分差的绝对值的平均数
+	MaxScoreDiff  int      `json:"max_score_diff"`
+	Disagreements []string `json:"disagreements"` // 是否推送的判断不一致的项目
+}
+
+// CompareEvals 以 base 为基准比较 other 的评估结果，按 cases 的顺序列出判断不一致的项目
+func CompareEvals(cases []EvalCase, base, other *EvalResult) *EvalComparison {
+	cmp := &EvalComparison{Disagreements: []string{}}
+	agreed, totalDiff := 0, 0
+	for _, c := range cases {
+		a, okA := base.Scores[c.ID]
+		b, okB := other.Scores[c.ID]
+		if !okA || !okB {
+			continue
+		}
+		cmp.Compared++
+		diff := int(math.Abs(float64(a - b)))
+		totalDiff += diff
+		cmp.MaxScoreDiff = max(cmp.MaxScoreDiff, diff)
+		if base.Selected[c.ID] == other.Selected[c.ID] {
+			agreed++
+		} else {
+			cmp.Disagreements = append(cmp.Disagreements, c.Name)
+		}
+	}
+	cmp.Agreement = ratio(agreed, cmp.Compared)
+	if cmp.Compared > 0 {
+		cmp.MeanScoreDiff = float64(totalDiff) / float64(cmp.Compared)
+	}
+	return cmp
+}
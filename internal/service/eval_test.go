@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scoringAnalyzer 按预设评分评估项目，没有预设评分的项目评估失败；每次评估消耗 tokens 个输入 Token
+type scoringAnalyzer struct {
+	scores map[string]int
+	tokens int64
+}
+
+func (a *scoringAnalyzer) CalculateStarGrowthRate(repos []*domain.Repo) []*domain.Repo { return repos }
+func (a *scoringAnalyzer) SetMaxGoroutines(max int)                                    {}
+
+func (a *scoringAnalyzer) AnalyzeWithLLM(ctx context.Context, repos []*domain.Repo) ([]*domain.Repo, error) {
+	now := time.Now()
+	for _, repo := range repos {
+		score, ok := a.scores[repo.ID]
+		if !ok {
+			continue
+		}
+		common.RecordUsage(ctx, "gemini-2.5-flash", a.tokens, 0)
+		repo.IsAIProgrammingTool, repo.LLMScore, repo.AppraisedAt = score > 0, score, &now
+	}
+	return repos, nil
+}
+
+func TestEvaluateAppraisals(t *testing.T) {
+	cases := []EvalCase{
+		{ID: "r1", Name: "acme/coder", Expected: true},
+		{ID: "r2", Name: "acme/agent", Expected: true},
+		{ID: "r3", Name: "acme/theme", Expected: false},
+		{ID: "r4", Name: "acme/notes", Expected: false},
+		{ID: "r5", Name: "acme/lost", Expected: true},
+	}
+	single, err := EvaluateAppraisals(context.Background(), &scoringAnalyzer{
+		scores: map[string]int{"r1": 90, "r2": 40, "r3": 10, "r4": 70},
+		tokens: 1_000_000,
+	}, cases, 50)
+	require.NoError(t, err)
+	assert.Equal(t, 5, single.Cases)
+	assert.Equal(t, 1, single.Failed)
+	assert.Equal(t, 1, single.TruePositives)
+	assert.Equal(t, 1, single.FalsePositives)
+	assert.Equal(t, 1, single.TrueNegatives)
+	assert.Equal(t, 1, single.FalseNegatives)
+	assert.InDelta(t, 0.5, single.Accuracy(), 1e-9)
+	assert.InDelta(t, 0.5, single.Precision(), 1e-9)
+	assert.InDelta(t, 0.5, single.Recall(), 1e-9)
+	assert.Equal(t, int64(4_000_000), single.Usage.PromptTokens)
+	assert.InDelta(t, 4*0.30, single.Usage.CostUSD, 1e-9, "按默认单价计算费用")
+
+	batch, err := EvaluateAppraisals(context.Background(), &scoringAnalyzer{
+		scores: map[string]int{"r1": 85, "r2": 60, "r3": 15, "r4": 70, "r5": 80},
+		tokens: 250_000,
+	}, cases, 50)
+	require.NoError(t, err)
+
+	cmp := CompareEvals(cases, single, batch)
+	assert.Equal(t, 4, cmp.Compared, "只比较两种方式都评估成功的项目")
+	assert.InDelta(t, 0.75, cmp.Agreement, 1e-9)
+	assert.InDelta(t, (5+20+5+0)/4.0, cmp.MeanScoreDiff, 1e-9)
+	assert.Equal(t, 20, cmp.MaxScoreDiff)
+	assert.Equal(t, []string{"acme/agent"}, cmp.Disagreements)
+}
+
+func TestLoadEvalCases(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	cases, err := LoadEvalCases(write("ok.json", `[{"id": "github-1", "name": "acme/coder", "expected": true, "note": "代码助手"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []EvalCase{{ID: "github-1", Name: "acme/coder", Expected: true, Note: "代码助手"}}, cases)
+
+	_, err = LoadEvalCases(write("dup.json", `[{"id": "a", "name": "x"}, {"id": "a", "name": "y"}]`))
+	assert.ErrorContains(t, err, "重复")
+	_, err = LoadEvalCases(write("missing.json", `[{"name": "x"}]`))
+	assert.ErrorContains(t, err, "缺少 id")
+	_, err = LoadEvalCases(write("empty.json", `[]`))
+	assert.ErrorContains(t, err, "为空")
+}
+
+// 数据集随代码一起维护，保证可以被 eval 子命令读取
+func TestLoadEvalCases_Dataset(t *testing.T) {
+	cases, err := LoadEvalCases("../../eval/appraisal.json")
+	require.NoError(t, err)
+	expected := 0
+	for _, c := range cases {
+		if c.Expected {
+			expected++
+		}
+	}
+	assert.Positive(t, expected)
+	assert.Less(t, expected, len(cases), "数据集中需要同时有正例和反例")
+}
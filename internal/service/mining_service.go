@@ -2,14 +2,40 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
 	"github-gold-miner/internal/port"
 )
 
+// MiningConfig 挖矿规则
+type MiningConfig struct {
+	Profile    string   // 挖矿方向，入库的项目打上该标记；为空表示未配置方向
+	Topics     []string // 除 Trending 外额外抓取的 GitHub topics
+	Languages  []string // 按编程语言抓取 Trending 并过滤，为空表示不限语言
+	MaxAgeDays int      // 只保留创建时间在这么多天内的项目
+	MinScore   int      // 推送所需的最低 LLM 评分
+
+	DailyBudgetUSD float64 // 本方向每天的 LLM 费用预算 (美元)，0 表示不限
+}
+
+// DefaultMiningConfig 默认抓取 ai-coding、ide-extension、dev-tools 三个 topic，
+// 只保留 10 天内创建、评分不低于 50 的 AI 编程工具
+func DefaultMiningConfig() MiningConfig {
+	return MiningConfig{
+		Topics:     []string{"ai-coding", "ide-extension", "dev-tools"},
+		MaxAgeDays: 10,
+		MinScore:   50,
+	}
+}
+
 // MiningService 处理挖矿逻辑
 type MiningService struct {
 	fetcher    port.Scouter
@@ -18,9 +44,19 @@ type MiningService struct {
 	repoStore  port.Repository
 	appraiser  port.Appraiser
 	notifier   port.Notifier
+
+	cfg            MiningConfig
+	pipeline       PipelineConfig // 阶段顺序、超时和错误策略
+	extra          []Stage        // 通过 RegisterStage 注册的阶段
+	notifyInterval time.Duration  // 两次推送之间的间隔，避免触发 API 限制
+
+	checkpoints *checkpointer           // 为 nil 时不保存检查点
+	resumed     map[string]*domain.Repo // 续跑时中断前已完成评估的项目
+	budget      *Budget                 // 为 nil 时不限制 LLM 费用
+	indexer     *SearchService          // 入库后为新项目和内容变化的项目补齐向量，为 nil 时不补齐
 }
 
-// NewMiningService 创建新的挖矿服务
+// NewMiningService 创建新的挖矿服务，使用默认流水线
 func NewMiningService(
 	fetcher port.Scouter,
 	filter port.Filter,
@@ -36,126 +72,461 @@ func NewMiningService(
 		repoStore: repoStore,
 		appraiser: appraiser,
 		notifier:  notifier,
+		cfg:       DefaultMiningConfig(),
+		pipeline:  DefaultPipelineConfig(),
+
+		notifyInterval: 3 * time.Second,
 	}
 }
 
-// ExecuteMiningCycle 执行一次挖矿周期
-func (m *MiningService) ExecuteMiningCycle(ctx context.Context, concurrency int) error {
+// SetConfig 设置挖矿规则
+func (m *MiningService) SetConfig(cfg MiningConfig) {
+	m.cfg = cfg
+}
+
+// SetBudget 设置 LLM 费用预算，多个挖矿方向共用同一个 Budget；本方向每天的预算见 MiningConfig.DailyBudgetUSD
+func (m *MiningService) SetBudget(budget *Budget) {
+	m.budget = budget
+}
+
+// SetIndexer 设置入库后补齐向量索引的搜索服务，搜索时只读取已有的向量
+func (m *MiningService) SetIndexer(indexer *SearchService) {
+	m.indexer = indexer
+}
+
+// RegisterStage 注册自定义阶段，在流水线配置中按名称引用；与内置阶段同名时替换内置阶段
+func (m *MiningService) RegisterStage(stage Stage) {
+	m.extra = append(m.extra, stage)
+}
+
+// SetPipeline 设置流水线配置，引用了未注册的阶段等配置错误时返回错误
+func (m *MiningService) SetPipeline(cfg PipelineConfig) error {
+	if _, err := NewPipeline(cfg, m.stages(&domain.MiningReport{})); err != nil {
+		return err
+	}
+	m.pipeline = cfg
+	return nil
+}
+
+// ValidatePipeline 用内置阶段校验流水线配置，不包含通过 RegisterStage 注册的阶段
+func ValidatePipeline(cfg PipelineConfig) error {
+	_, err := NewPipeline(cfg, (&MiningService{}).stages(&domain.MiningReport{}))
+	return err
+}
+
+// ExecuteMiningCycle 按流水线配置执行一次挖矿周期，返回各阶段的输入输出数量、耗时、错误和外部 API 用量
+// 阶段出错时按配置的错误策略处理；流水线被终止或超时时返回错误，报告仍然有效
+func (m *MiningService) ExecuteMiningCycle(ctx context.Context, concurrency int) (*domain.MiningReport, error) {
+	report := &domain.MiningReport{}
+	// LLM 适配器通过 ctx 中的 meter 记录本轮的用量，并在调用前检查预算
+	meter := &usageMeter{budget: m.budget, profile: m.cfg.Profile, dailyUSD: m.cfg.DailyBudgetUSD}
+	ctx = common.WithUsageMeter(ctx, meter)
+	requestsBefore, tokensBefore := m.apiRequests(), m.llmTokens()
+	hitsBefore, missesBefore := m.cacheStats()
+	defer func() {
+		report.APIRequests = m.apiRequests() - requestsBefore
+		report.LLMTokens = m.llmTokens() - tokensBefore
+		hits, misses := m.cacheStats()
+		report.CacheHits, report.CacheMisses = hits-hitsBefore, misses-missesBefore
+
+		// 按调用记录的用量不受同时执行的其他挖矿方向影响，有记录时优先使用
+		report.Usage, report.BudgetBlocked = meter.totals()
+		if total := report.Usage.Total(); total > 0 {
+			report.LLMTokens = total
+		}
+		if stage := report.Stage(domain.StageAppraise); stage != nil && report.BudgetBlocked > 0 {
+			stage.AddError(fmt.Errorf("%w，跳过了 %d 个项目的评估", ErrBudgetExceeded, report.BudgetBlocked))
+		}
+		m.checkBudget(context.WithoutCancel(ctx), report)
+	}()
+
+	pipeline, err := NewPipeline(m.pipeline, m.stages(report))
+	if err != nil {
+		return report, err
+	}
+
 	// 设置并发数
 	m.analyzer.SetMaxGoroutines(concurrency)
 
 	fmt.Println("🚀 [挖矿模式] 开始搜寻AI编程工具金矿...")
 
-	// 1. 数据源 (Fetcher)
-	fmt.Println("📥 正在抓取 GitHub Trending 项目...")
-	trendingRepos, err := m.fetcher.GetTrendingRepos(ctx, "all", "weekly")
-	if err != nil {
-		log.Printf("❌ 获取 trending repos 失败: %v", err)
-	} else {
+	// 补发免打扰期间暂存的消息
+	if flusher, ok := m.notifier.(port.PendingFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			log.Printf("⚠️ 补发暂存消息失败: %v", err)
+		}
+	}
+
+	var outputs [][]*domain.Repo
+	if m.checkpoints != nil {
+		outputs, m.resumed = m.checkpoints.begin(ctx, pipeline.StageNames())
+		if m.checkpoints.run.ResumedFrom != 0 {
+			fmt.Println(resumeSummary(m.checkpoints.run, len(outputs), len(m.resumed)))
+		}
+		pipeline.OnStageDone(m.checkpoints.save)
+	}
+
+	if _, err := pipeline.Resume(ctx, report, outputs); err != nil {
+		return report, err
+	}
+	fmt.Printf("🎉 本轮挖矿完成，共推送 %d 个项目\n", len(report.Pushed))
+	return report, nil
+}
+
+// stages 内置阶段和注册的阶段，推送阶段把推送成功的项目记入 report
+func (m *MiningService) stages(report *domain.MiningReport) []Stage {
+	builtin := []Stage{
+		NewStage(domain.StageFetch, m.fetchStage),
+		NewStage(domain.StageDedup, dedupStage),
+		NewStage(domain.StageFilterLanguage, m.filterLanguageStage),
+		NewStage(domain.StageFilterAge, m.filterAgeStage),
+		NewStage(domain.StageFilterActivity, m.filterActivityStage),
+		NewStage(domain.StageVelocity, m.velocityStage),
+		NewStage(domain.StageAppraise, func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+			analyzed, reused, err := m.appraiseStage(ctx, repos, stage)
+			report.Reused += reused
+			return analyzed, err
+		}),
+		NewStage(domain.StageSelect, m.selectStage),
+		NewStage(domain.StageStore, m.storeStage),
+		NewStage(domain.StageNotify, func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+			pushed, err := m.notifyStage(ctx, repos, stage)
+			for _, repo := range pushed {
+				report.Pushed = append(report.Pushed, repo.Name)
+			}
+			return pushed, err
+		}),
+	}
+	return append(builtin, m.extra...)
+}
+
+// fetchStage 从 GitHub Trending (配置了语言时按语言抓取) 和指定 topics 抓取项目，追加到输入之后
+// 单个数据源失败只记录错误，所有数据源都失败时返回错误
+func (m *MiningService) fetchStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	languages := m.cfg.Languages
+	if len(languages) == 0 {
+		languages = []string{"all"}
+	}
+	for _, language := range languages {
+		fmt.Printf("📥 正在抓取 GitHub Trending 项目 (%s)...\n", language)
+		trendingRepos, err := m.fetcher.GetTrendingRepos(ctx, language, "weekly")
+		if err != nil {
+			log.Printf("❌ 获取 trending repos (%s) 失败: %v", language, err)
+			if len(m.cfg.Languages) == 0 {
+				stage.AddError(fmt.Errorf("trending: %w", err))
+			} else {
+				stage.AddError(fmt.Errorf("trending %s: %w", language, err))
+			}
+			continue
+		}
 		fmt.Printf("✅ 成功获取 %d 个 trending 项目\n", len(trendingRepos))
+		repos = append(repos, trendingRepos...)
 	}
 
 	// 获取指定 topics 的项目
-	topics := []string{"ai-coding", "ide-extension", "dev-tools"}
-	var topicRepos []*domain.Repo
-	for _, topic := range topics {
+	for _, topic := range m.cfg.Topics {
 		fmt.Printf("📥 正在抓取 topic '%s' 的项目...\n", topic)
-		repos, err := m.fetcher.GetReposByTopic(ctx, topic)
+		topicRepos, err := m.fetcher.GetReposByTopic(ctx, topic)
 		if err != nil {
 			log.Printf("❌ 获取 topic '%s' 的 repos 失败: %v", topic, err)
+			stage.AddError(fmt.Errorf("topic %s: %w", topic, err))
+			continue
+		}
+		repos = append(repos, topicRepos...)
+		fmt.Printf("✅ 成功获取 %d 个 '%s' topic 项目\n", len(topicRepos), topic)
+	}
+
+	if len(stage.Errors) == len(languages)+len(m.cfg.Topics) {
+		return repos, fmt.Errorf("所有数据源都抓取失败: %s", stage.Errors[0])
+	}
+	return repos, nil
+}
+
+// dedupStage 去掉多个数据源重复返回的项目，保留第一次出现的
+func dedupStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	seen := make(map[string]bool, len(repos))
+	var result []*domain.Repo
+	for _, repo := range repos {
+		if seen[repo.ID] {
 			continue
 		}
-		topicRepos = append(topicRepos, repos...)
-		fmt.Printf("✅ 成功获取 %d 个 '%s' topic 项目\n", len(repos), topic)
+		seen[repo.ID] = true
+		result = append(result, repo)
 	}
+	return result, nil
+}
 
-	// 合并所有项目
-	allRepos := append(trendingRepos, topicRepos...)
+// filterLanguageStage 只保留 Languages 中的编程语言 (不区分大小写)，未配置语言时全部保留
+func (m *MiningService) filterLanguageStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	if len(m.cfg.Languages) == 0 {
+		return repos, nil
+	}
+	var result []*domain.Repo
+	for _, repo := range repos {
+		if slices.ContainsFunc(m.cfg.Languages, func(l string) bool { return strings.EqualFold(l, repo.Language) }) {
+			result = append(result, repo)
+			continue
+		}
+		language := repo.Language
+		if language == "" {
+			language = "未知"
+		}
+		stage.Drop(repo.ID, fmt.Sprintf("语言 %s 不在 %s 中", language, strings.Join(m.cfg.Languages, "、")))
+	}
+	fmt.Printf("✅ 语言过滤后剩余 %d 个项目\n", len(result))
+	return result, nil
+}
 
-	// 2. 初筛漏斗 (Hard Filter)
+// filterAgeStage 时效性过滤：创建时间在 MaxAgeDays 天内
+func (m *MiningService) filterAgeStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
 	fmt.Println("🔍 开始初筛...")
-	// 时效性过滤：创建时间在10天内
-	filteredRepos := m.filter.FilterByCreatedAt(allRepos, 10)
-	fmt.Printf("✅ 时效性过滤后剩余 %d 个项目\n", len(filteredRepos))
+	filtered := m.filter.FilterByCreatedAt(repos, m.cfg.MaxAgeDays)
+	for _, repo := range droppedRepos(repos, filtered) {
+		stage.Drop(repo.ID, fmt.Sprintf("创建于 %s，超过 %d 天", repo.CreatedAt.Format("2006-01-02"), m.cfg.MaxAgeDays))
+	}
+	fmt.Printf("✅ 时效性过滤后剩余 %d 个项目\n", len(filtered))
+	return filtered, nil
+}
 
-	// 活跃度过滤：近期有commit提交
-	filteredRepos, err = m.filter.FilterByRecentCommit(ctx, filteredRepos)
+// filterActivityStage 活跃度过滤：近期有commit提交
+// 出错时过滤器返回已经检查过的项目，默认继续处理这些项目
+func (m *MiningService) filterActivityStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	filtered, err := m.filter.FilterByRecentCommit(ctx, repos)
+	reason := "近期没有代码提交"
 	if err != nil {
 		log.Printf("⚠️ 活跃度过滤出错: %v", err)
-		// 如果活跃度过滤出错，我们仍然可以继续处理已有的项目
+		reason = fmt.Sprintf("活跃度检查未完成: %v", err)
 	}
-	fmt.Printf("✅ 活跃度过滤后剩余 %d 个项目\n", len(filteredRepos))
+	for _, repo := range droppedRepos(repos, filtered) {
+		stage.Drop(repo.ID, reason)
+	}
+	fmt.Printf("✅ 活跃度过滤后剩余 %d 个项目\n", len(filtered))
+	return filtered, err
+}
 
-	// 3. 深度分析 (Analyzer)
+// velocityStage 数学模型分析：计算Star增长速率
+func (m *MiningService) velocityStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
 	fmt.Println("🧠 开始深度分析...")
-	// 数学模型分析：计算Star增长速率
-	reposWithGrowthRate := m.analyzer.CalculateStarGrowthRate(filteredRepos)
-	fmt.Printf("✅ 已计算 %d 个项目的Star增长速率\n", len(reposWithGrowthRate))
+	result := m.analyzer.CalculateStarGrowthRate(repos)
+	fmt.Printf("✅ 已计算 %d 个项目的Star增长速率\n", len(result))
+	return result, nil
+}
 
-	// LLM分析：判断是否为AI编程工具并评分
-	analyzedRepos, err := m.analyzer.AnalyzeWithLLM(ctx, reposWithGrowthRate)
+// appraiseStage LLM分析：判断是否为AI编程工具并评分，返回复用已有评估结果的项目数
+// 可以复用评估结果的项目不再调用 LLM (见 reuseAppraisal)；启用检查点时分批评估，每批完成后保存进度
+func (m *MiningService) appraiseStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, int, error) {
+	var analyzed, pending []*domain.Repo
+	for _, repo := range repos {
+		if m.reuseAppraisal(ctx, repo) {
+			analyzed = append(analyzed, repo)
+		} else {
+			pending = append(pending, repo)
+		}
+	}
+	reused := len(analyzed)
+	if reused > 0 {
+		fmt.Printf("♻️ %d 个项目复用已有的评估结果\n", reused)
+	}
+
+	batch := len(pending)
+	if m.checkpoints != nil {
+		batch = appraiseBatch
+	}
+	// 单个项目的评估失败不作为阶段的返回值，通过 ctx 上报后记入阶段报告；
+	// 超出预算的项目在执行结束时汇总记录一次 (见 ExecuteMiningCycle)
+	var mu sync.Mutex
+	ctx = common.WithErrorRecorder(ctx, func(err error) {
+		if common.HasCode(err, common.ErrCodeLLMBudgetExceeded) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		stage.AddError(err)
+	})
+
+	var err error
+	for start := 0; ; start += batch {
+		end := min(start+batch, len(pending))
+		var done []*domain.Repo
+		done, err = m.analyzer.AnalyzeWithLLM(ctx, pending[start:end])
+		analyzed = append(analyzed, done...)
+		if err != nil || end == len(pending) {
+			break
+		}
+		m.checkpoints.save(ctx, domain.StageAppraise, analyzed, false)
+	}
 	if err != nil {
 		log.Printf("⚠️ LLM分析出错: %v", err)
 	}
-	fmt.Printf("✅ 已完成 %d 个项目的LLM分析\n", len(analyzedRepos))
+	for _, repo := range droppedRepos(repos, analyzed) {
+		stage.Drop(repo.ID, "LLM 评估失败")
+	}
+	fmt.Printf("✅ 已完成 %d 个项目的LLM分析\n", len(analyzed)-reused)
+	return analyzed, reused, err
+}
 
-	// 4. 存储和推送
-	fmt.Println("💾 开始存储和推送...")
-	successCount := 0
-	for _, repo := range analyzedRepos {
-		// 检查context是否已超时或取消
-		select {
-		case <-ctx.Done():
-			fmt.Println("⏰ 执行时间过长，提前结束存储和推送阶段")
-			goto finish
+// selectStage 只保留被识别为AI编程工具且评分不低于 MinScore 的项目
+func (m *MiningService) selectStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	var result []*domain.Repo
+	for _, repo := range repos {
+		switch {
+		case !repo.IsAIProgrammingTool:
+			stage.Drop(repo.ID, "不是 AI 编程工具")
+		case repo.LLMScore < m.cfg.MinScore:
+			stage.Drop(repo.ID, fmt.Sprintf("评分 %d 低于 %d", repo.LLMScore, m.cfg.MinScore))
 		default:
+			result = append(result, repo)
 		}
+	}
+	return result, nil
+}
 
-		// 只处理被识别为AI编程工具且评分较高的项目
-		// 降低阈值以便更容易推送项目进行测试
-		if !repo.IsAIProgrammingTool || repo.LLMScore < 50 {
-			continue
+// storeStage 保存项目并打上挖矿方向的标记，输出新入库的项目；已存在的项目只刷新元数据并记录本次评估
+// 配置了挖矿方向时，其他方向已入库的项目对本方向来说也是新项目，会在本方向的通知渠道推送
+func (m *MiningService) storeStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	fmt.Println("💾 开始存储和推送...")
+	var saved []*domain.Repo
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			fmt.Println("⏰ 执行时间过长，提前结束存储阶段")
+			return saved, err
+		}
+		if m.cfg.Profile != "" {
+			repo.Profiles = domain.MergeProfiles(repo.Profiles, []string{m.cfg.Profile})
 		}
 
 		// 检查是否已存在
-		exists, err := m.repoStore.Exists(ctx, repo.ID)
+		exists, err := m.discovered(ctx, repo.ID)
 		if err != nil {
 			log.Printf("❌ 检查项目 %s 是否存在时出错: %v，跳过该项目", repo.Name, err)
+			stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+			stage.Drop(repo.ID, fmt.Sprintf("检查是否已入库失败: %v", err))
 			continue
 		}
 		if exists {
+			// Save 不会覆盖推送状态，这里只刷新元数据并记录本次评估
+			if err := m.repoStore.Save(ctx, repo); err != nil {
+				log.Printf("⚠️ 更新已存在项目 %s 失败: %v", repo.Name, err)
+				stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+			}
 			fmt.Printf("⏭️ 项目 %s 已存在\n", repo.Name)
+			stage.Drop(repo.ID, "已入库过，只更新元数据")
 			continue
 		}
 
 		// 保存到数据库
 		if err := m.repoStore.Save(ctx, repo); err != nil {
 			log.Printf("❌ 保存项目 %s 失败: %v", repo.Name, err)
+			stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+			stage.Drop(repo.ID, fmt.Sprintf("保存失败: %v", err))
 			continue
 		}
+		saved = append(saved, repo)
+	}
+	m.index(ctx, stage)
+	return saved, nil
+}
 
-		if m.notifier == nil {
-			log.Printf("⚠️ 未配置通知通道，跳过推送项目 %s", repo.Name)
-			continue
+// index 为本轮入库和更新的项目补齐向量，失败只记录在阶段报告中，下一轮入库后会再次补齐
+func (m *MiningService) index(ctx context.Context, stage *domain.StageReport) {
+	if m.indexer == nil || !m.indexer.VectorEnabled() || ctx.Err() != nil {
+		return
+	}
+	indexed, err := m.indexer.Index(ctx)
+	if err != nil {
+		log.Printf("⚠️ 补齐向量索引失败: %v", err)
+		stage.AddError(fmt.Errorf("补齐向量索引失败: %w", err))
+	}
+	if indexed > 0 {
+		fmt.Printf("🧮 已为 %d 个项目生成向量\n", indexed)
+	}
+}
+
+// discovered 项目是否已经入库过；配置了挖矿方向时，只有本方向发现过的项目才算
+// 引入挖矿方向之前入库的项目没有方向标记，视为已被所有方向发现，避免升级后重复推送
+func (m *MiningService) discovered(ctx context.Context, repoID string) (bool, error) {
+	if m.cfg.Profile == "" {
+		return m.repoStore.Exists(ctx, repoID)
+	}
+	stored, err := m.repoStore.Get(ctx, repoID)
+	if errors.Is(err, port.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(stored.Profiles) == 0 || stored.HasProfile(m.cfg.Profile), nil
+}
+
+// notifyStage 推送项目并标记为已推送，输出推送成功的项目
+func (m *MiningService) notifyStage(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+	if m.notifier == nil {
+		if len(repos) > 0 {
+			log.Printf("⚠️ 未配置通知通道，跳过推送 %d 个项目", len(repos))
+		}
+		for _, repo := range repos {
+			stage.Drop(repo.ID, "未配置通知通道")
+		}
+		return nil, nil
+	}
+
+	var pushed []*domain.Repo
+	for i, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			fmt.Println("⏰ 执行时间过长，提前结束推送阶段")
+			return pushed, err
+		}
+		// 避免触发 API 限制
+		if i > 0 {
+			time.Sleep(m.notifyInterval)
 		}
 
-		if err := m.notifier.Notify(ctx, repo); err != nil {
+		if err := m.notifier.Notify(ctx, repo); errors.Is(err, port.ErrPartiallyDelivered) {
+			// 已送达部分渠道，仍然标记为已推送，避免下一轮重复推送到已送达的渠道
+			log.Printf("⚠️ 推送项目 %s 时部分渠道失败: %v", repo.Name, err)
+			stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+		} else if err != nil {
 			log.Printf("❌ 推送项目 %s 到通知通道失败: %v", repo.Name, err)
+			stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+			stage.Drop(repo.ID, fmt.Sprintf("推送失败: %v", err))
 			continue
 		}
 
 		if err := m.repoStore.MarkAsNotified(ctx, repo.ID); err != nil {
 			log.Printf("⚠️ 标记项目 %s 为已通知失败: %v", repo.Name, err)
+			stage.AddError(fmt.Errorf("%s: %w", repo.Name, err))
+			stage.Drop(repo.ID, fmt.Sprintf("已推送但标记失败: %v", err))
 			continue
 		}
 		fmt.Printf("📲 已处理项目 %s\n", repo.Name)
-		successCount++
+		pushed = append(pushed, repo)
+	}
+	return pushed, nil
+}
 
-		// 避免触发 API 限制
-		time.Sleep(3 * time.Second)
+// apiRequests 汇总抓取和过滤组件的累计 GitHub API 请求次数
+func (m *MiningService) apiRequests() int64 {
+	var total int64
+	for _, c := range []interface{}{m.fetcher, m.filter} {
+		if counter, ok := c.(port.RequestCounter); ok {
+			total += counter.Requests()
+		}
 	}
+	return total
+}
 
-finish:
-	fmt.Printf("🎉 本轮挖矿完成，共处理 %d 个项目\n", successCount)
-	return nil
+// cacheStats 返回鉴定师累计的评估缓存命中和未命中次数，未实现 port.CacheCounter 时为 0
+func (m *MiningService) cacheStats() (hits, misses int64) {
+	if counter, ok := m.appraiser.(port.CacheCounter); ok {
+		return counter.CacheStats()
+	}
+	return 0, 0
+}
+
+// llmTokens 返回鉴定师累计消耗的 Token 数，未实现 port.TokenCounter 时为 0
+func (m *MiningService) llmTokens() int64 {
+	if counter, ok := m.appraiser.(port.TokenCounter); ok {
+		return counter.TokensUsed()
+	}
+	return 0
 }
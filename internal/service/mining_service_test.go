@@ -3,12 +3,17 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/common"
 	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations for testing
@@ -69,9 +74,10 @@ func (m *MockAppraiser) Appraise(ctx context.Context, repo *domain.Repo) (*domai
 	return args.Get(0).(*domain.Repo), args.Error(1)
 }
 
-func (m *MockAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) (string, error) {
+func (m *MockAppraiser) SemanticSearch(ctx context.Context, repos []*domain.Repo, userQuery string) ([]*domain.SearchResult, error) {
 	args := m.Called(ctx, repos, userQuery)
-	return args.String(0), args.Error(1)
+	results, _ := args.Get(0).([]*domain.SearchResult)
+	return results, args.Error(1)
 }
 
 type MockRepository struct {
@@ -83,6 +89,17 @@ func (m *MockRepository) Save(ctx context.Context, repo *domain.Repo) error {
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetAppraisals(ctx context.Context, repoID string) ([]*domain.Appraisal, error) {
+	args := m.Called(ctx, repoID)
+	return args.Get(0).([]*domain.Appraisal), args.Error(1)
+}
+
+func (m *MockRepository) Get(ctx context.Context, repoID string) (*domain.Repo, error) {
+	args := m.Called(ctx, repoID)
+	repo, _ := args.Get(0).(*domain.Repo)
+	return repo, args.Error(1)
+}
+
 func (m *MockRepository) Exists(ctx context.Context, repoID string) (bool, error) {
 	args := m.Called(ctx, repoID)
 	return args.Bool(0), args.Error(1)
@@ -93,7 +110,7 @@ func (m *MockRepository) MarkAsNotified(ctx context.Context, repoID string) erro
 	return args.Error(0)
 }
 
-func (m *MockRepository) Search(ctx context.Context, query string) ([]*domain.Repo, error) {
+func (m *MockRepository) Search(ctx context.Context, query domain.SearchQuery) ([]*domain.Repo, error) {
 	args := m.Called(ctx, query)
 	return args.Get(0).([]*domain.Repo), args.Error(1)
 }
@@ -108,6 +125,36 @@ func (m *MockRepository) GetUnnotifiedRepos(ctx context.Context) ([]*domain.Repo
 	return args.Get(0).([]*domain.Repo), args.Error(1)
 }
 
+func (m *MockRepository) GetNotifiedRepos(ctx context.Context, since time.Time) ([]*domain.Repo, error) {
+	args := m.Called(ctx, since)
+	return args.Get(0).([]*domain.Repo), args.Error(1)
+}
+
+func (m *MockRepository) UpdateTracking(ctx context.Context, repo *domain.Repo) error {
+	args := m.Called(ctx, repo)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddSnapshot(ctx context.Context, snapshot *domain.StarSnapshot) error {
+	args := m.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetSnapshots(ctx context.Context, repoID string) ([]*domain.StarSnapshot, error) {
+	args := m.Called(ctx, repoID)
+	return args.Get(0).([]*domain.StarSnapshot), args.Error(1)
+}
+
+func (m *MockRepository) AddFeedback(ctx context.Context, feedback *domain.Feedback) error {
+	args := m.Called(ctx, feedback)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetFeedback(ctx context.Context, repoID string) ([]*domain.Feedback, error) {
+	args := m.Called(ctx, repoID)
+	return args.Get(0).([]*domain.Feedback), args.Error(1)
+}
+
 type MockNotifier struct {
 	mock.Mock
 }
@@ -117,6 +164,23 @@ func (m *MockNotifier) Notify(ctx context.Context, repo *domain.Repo) error {
 	return args.Error(0)
 }
 
+func (m *MockNotifier) NotifyUpdate(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error {
+	args := m.Called(ctx, repo, event)
+	return args.Error(0)
+}
+
+type MockInspector struct {
+	mock.Mock
+}
+
+func (m *MockInspector) GetRepo(ctx context.Context, fullName string) (*domain.Repo, error) {
+	args := m.Called(ctx, fullName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Repo), args.Error(1)
+}
+
 func TestNewMiningService(t *testing.T) {
 	mockScouter := new(MockScouter)
 	mockFilter := new(MockFilter)
@@ -185,6 +249,42 @@ func TestMiningService_ExecuteMiningCycle(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "部分渠道推送失败仍标记为已推送",
+			setupMocks: func(ms *MockScouter, mf *MockFilter, ma *MockAnalyzer, mr *MockRepository, ma2 *MockAppraiser, notifier *MockNotifier) {
+				ms.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return([]*domain.Repo{testRepo}, nil)
+				ms.On("GetReposByTopic", mock.Anything, mock.Anything).Return([]*domain.Repo{}, nil)
+				mf.On("FilterByCreatedAt", mock.Anything, 10).Return([]*domain.Repo{testRepo})
+				mf.On("FilterByRecentCommit", mock.Anything, mock.Anything).Return([]*domain.Repo{testRepo}, nil)
+				ma.On("SetMaxGoroutines", mock.Anything).Return()
+				ma.On("CalculateStarGrowthRate", mock.Anything).Return([]*domain.Repo{testRepo})
+				ma.On("AnalyzeWithLLM", mock.Anything, mock.Anything).Return([]*domain.Repo{testRepo}, nil)
+				mr.On("Exists", mock.Anything, testRepo.ID).Return(false, nil)
+				mr.On("Save", mock.Anything, testRepo).Return(nil)
+				// 已送达部分渠道，重试会重复推送，因此仍然标记
+				mr.On("MarkAsNotified", mock.Anything, testRepo.ID).Return(nil)
+				notifier.On("Notify", mock.Anything, testRepo).Return(fmt.Errorf("%w: 渠道 ide-team: webhook 403", port.ErrPartiallyDelivered))
+			},
+			expectError: false,
+		},
+		{
+			name: "已存在的项目只更新不推送",
+			setupMocks: func(ms *MockScouter, mf *MockFilter, ma *MockAnalyzer, mr *MockRepository, ma2 *MockAppraiser, notifier *MockNotifier) {
+				ms.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return([]*domain.Repo{testRepo}, nil)
+				ms.On("GetReposByTopic", mock.Anything, "ai-coding").Return([]*domain.Repo{}, nil)
+				ms.On("GetReposByTopic", mock.Anything, "ide-extension").Return([]*domain.Repo{}, nil)
+				ms.On("GetReposByTopic", mock.Anything, "dev-tools").Return([]*domain.Repo{}, nil)
+				mf.On("FilterByCreatedAt", mock.Anything, 10).Return([]*domain.Repo{testRepo})
+				mf.On("FilterByRecentCommit", mock.Anything, mock.Anything).Return([]*domain.Repo{testRepo}, nil)
+				ma.On("SetMaxGoroutines", mock.Anything).Return()
+				ma.On("CalculateStarGrowthRate", mock.Anything).Return([]*domain.Repo{testRepo})
+				ma.On("AnalyzeWithLLM", mock.Anything, mock.Anything).Return([]*domain.Repo{testRepo}, nil)
+				mr.On("Exists", mock.Anything, testRepo.ID).Return(true, nil)
+				mr.On("Save", mock.Anything, testRepo).Return(nil)
+				// 不应该再次推送或修改推送状态
+			},
+			expectError: false,
+		},
 		{
 			name: "获取Trending Repos失败",
 			setupMocks: func(ms *MockScouter, mf *MockFilter, ma *MockAnalyzer, mr *MockRepository, ma2 *MockAppraiser, notifier *MockNotifier) {
@@ -200,6 +300,16 @@ func TestMiningService_ExecuteMiningCycle(t *testing.T) {
 			},
 			expectError: false, // 不应该返回错误，只是记录日志
 		},
+		{
+			name: "所有数据源都失败",
+			setupMocks: func(ms *MockScouter, mf *MockFilter, ma *MockAnalyzer, mr *MockRepository, ma2 *MockAppraiser, notifier *MockNotifier) {
+				ms.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return([]*domain.Repo{}, errors.New("network error"))
+				ms.On("GetReposByTopic", mock.Anything, mock.Anything).Return([]*domain.Repo{}, errors.New("network error"))
+				ma.On("SetMaxGoroutines", mock.Anything).Return()
+				// 没有数据可以处理，不进入后续阶段
+			},
+			expectError: true,
+		},
 		{
 			name: "活跃度过滤失败",
 			setupMocks: func(ms *MockScouter, mf *MockFilter, ma *MockAnalyzer, mr *MockRepository, ma2 *MockAppraiser, notifier *MockNotifier) {
@@ -275,7 +385,8 @@ func TestMiningService_ExecuteMiningCycle(t *testing.T) {
 			ctx := context.Background()
 			
 			// 执行测试
-			err := service.ExecuteMiningCycle(ctx, 3)
+			report, err := service.ExecuteMiningCycle(ctx, 3)
+			assert.NotNil(t, report)
 
 			// 验证结果
 			if tt.expectError {
@@ -294,3 +405,224 @@ func TestMiningService_ExecuteMiningCycle(t *testing.T) {
 		})
 	}
 }
+
+// meteredScouter 记录 API 请求次数的抓取器
+type meteredScouter struct {
+	*MockScouter
+	requests int64
+}
+
+func (m *meteredScouter) Requests() int64 { return m.requests }
+
+// meteredAppraiser 记录 Token 用量和缓存命中的鉴定师
+type meteredAppraiser struct {
+	*MockAppraiser
+	tokens       int64
+	hits, misses int64
+}
+
+func (m *meteredAppraiser) TokensUsed() int64 { return m.tokens }
+
+func (m *meteredAppraiser) CacheStats() (int64, int64) { return m.hits, m.misses }
+
+func TestMiningService_Report(t *testing.T) {
+	newRepo := &domain.Repo{ID: "github-1", Name: "acme/agent", IsAIProgrammingTool: true, LLMScore: 80}
+	existing := &domain.Repo{ID: "github-2", Name: "acme/old", IsAIProgrammingTool: true, LLMScore: 90}
+	notTool := &domain.Repo{ID: "github-3", Name: "acme/blog", LLMScore: 95}
+	all := []*domain.Repo{newRepo, existing, notTool}
+
+	scouter := &meteredScouter{MockScouter: new(MockScouter), requests: 100}
+	appraiser := &meteredAppraiser{MockAppraiser: new(MockAppraiser), tokens: 5000, hits: 7, misses: 4}
+	mf, ma, mr, notifier := new(MockFilter), new(MockAnalyzer), new(MockRepository), new(MockNotifier)
+
+	scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").
+		Run(func(mock.Arguments) { scouter.requests += 3 }).
+		Return(all, nil)
+	scouter.On("GetReposByTopic", mock.Anything, "ai-coding").Return([]*domain.Repo{}, errors.New("rate limited"))
+	// 多个数据源返回的重复项目在去重阶段合并
+	scouter.On("GetReposByTopic", mock.Anything, "ide-extension").Return([]*domain.Repo{newRepo}, nil)
+	scouter.On("GetReposByTopic", mock.Anything, mock.Anything).Return([]*domain.Repo{}, nil)
+	mf.On("FilterByCreatedAt", all, 10).Return(all)
+	mf.On("FilterByRecentCommit", mock.Anything, all).Return(all, nil)
+	ma.On("SetMaxGoroutines", 2).Return()
+	ma.On("CalculateStarGrowthRate", all).Return(all)
+	ma.On("AnalyzeWithLLM", mock.Anything, all).
+		Run(func(args mock.Arguments) {
+			appraiser.tokens, appraiser.hits, appraiser.misses = appraiser.tokens+1200, appraiser.hits+2, appraiser.misses+1
+			// 单个项目评估失败不影响整体，只记入报告
+			common.RecordError(args.Get(0).(context.Context), common.WrapError(common.ErrCodeLLMInvalidJSON, "解析响应失败", errors.New("unexpected end of JSON input")))
+		}).
+		Return(all, nil)
+	mr.On("Exists", mock.Anything, newRepo.ID).Return(false, nil)
+	mr.On("Exists", mock.Anything, existing.ID).Return(true, nil)
+	mr.On("Save", mock.Anything, mock.Anything).Return(nil)
+	mr.On("MarkAsNotified", mock.Anything, newRepo.ID).Return(nil)
+	notifier.On("Notify", mock.Anything, newRepo).Return(nil)
+
+	service := NewMiningService(scouter, mf, ma, mr, appraiser, notifier)
+	report, err := service.ExecuteMiningCycle(context.Background(), 2)
+	assert.NoError(t, err)
+
+	type counts struct{ in, out, errors int }
+	got := make(map[string]counts)
+	var names []string
+	for _, stage := range report.Stages {
+		names = append(names, stage.Name)
+		got[stage.Name] = counts{stage.In, stage.Out, len(stage.Errors)}
+	}
+	assert.Equal(t, []string{
+		domain.StageFetch, domain.StageDedup, domain.StageFilterLanguage, domain.StageFilterAge, domain.StageFilterActivity,
+		domain.StageVelocity, domain.StageAppraise, domain.StageSelect, domain.StageStore, domain.StageNotify,
+	}, names)
+	assert.Equal(t, counts{0, 4, 1}, got[domain.StageFetch])
+	assert.Equal(t, counts{4, 3, 0}, got[domain.StageDedup])
+	assert.Equal(t, counts{3, 3, 1}, got[domain.StageAppraise])
+	assert.Equal(t, counts{3, 2, 0}, got[domain.StageSelect])
+	// 已存在的项目只更新，不计入入库和推送
+	assert.Equal(t, counts{2, 1, 0}, got[domain.StageStore])
+	assert.Equal(t, counts{1, 1, 0}, got[domain.StageNotify])
+	assert.Equal(t, []string{"acme/agent"}, report.Pushed)
+	assert.Contains(t, report.Stage(domain.StageFetch).Errors[0], "topic ai-coding")
+	assert.Equal(t, map[string]int{common.ErrCodeLLMInvalidJSON: 1}, report.Stage(domain.StageAppraise).ErrorCodes)
+	assert.Equal(t, map[string]int{domain.ErrorCodeUnknown: 1, common.ErrCodeLLMInvalidJSON: 1}, report.ErrorCodes())
+
+	// 只统计本轮的增量
+	assert.Equal(t, int64(3), report.APIRequests)
+	assert.Equal(t, int64(1200), report.LLMTokens)
+	assert.Equal(t, int64(2), report.CacheHits)
+	assert.Equal(t, int64(1), report.CacheMisses)
+
+	// 每个项目的去向
+	assert.Equal(t, []*domain.RepoFate{
+		{RepoID: "github-1", Name: "acme/agent", Score: 80},
+		{RepoID: "github-2", Name: "acme/old", Score: 90, Stage: domain.StageStore, Reason: "已入库过，只更新元数据"},
+		{RepoID: "github-3", Name: "acme/blog", Score: 95, Stage: domain.StageSelect, Reason: "不是 AI 编程工具"},
+	}, report.Fates)
+}
+
+func TestMiningService_DryRun(t *testing.T) {
+	ctx := context.Background()
+	fresh := &domain.Repo{ID: "github-1", Name: "acme/agent", IsAIProgrammingTool: true, LLMScore: 80}
+	low := &domain.Repo{ID: "github-2", Name: "acme/toy", IsAIProgrammingTool: true, LLMScore: 30}
+	stale := &domain.Repo{ID: "github-3", Name: "acme/legacy", CreatedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	all := []*domain.Repo{fresh, low, stale}
+	young := []*domain.Repo{fresh, low}
+
+	store := repository.NewMemoryRepo()
+	scouter, mf, ma, notifier := new(MockScouter), new(MockFilter), new(MockAnalyzer), new(MockNotifier)
+	scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return(all, nil)
+	scouter.On("GetReposByTopic", mock.Anything, mock.Anything).Return([]*domain.Repo{}, nil)
+	mf.On("FilterByCreatedAt", all, 10).Return(young)
+	mf.On("FilterByRecentCommit", mock.Anything, young).Return(young, nil)
+	ma.On("SetMaxGoroutines", 1).Return()
+	ma.On("CalculateStarGrowthRate", young).Return(young)
+	ma.On("AnalyzeWithLLM", mock.Anything, young).Return(young, nil)
+
+	service := NewMiningService(scouter, mf, ma, store, new(MockAppraiser), notifier)
+	recorder := service.EnableDryRun()
+	report, err := service.ExecuteMiningCycle(ctx, 1)
+	require.NoError(t, err)
+
+	// 入库和推送只记录，不写数据库也不调用真实通知渠道
+	assert.Equal(t, []string{"acme/agent"}, recorder.Saved())
+	assert.Equal(t, []string{"acme/agent"}, recorder.Notified())
+	assert.Equal(t, []string{"acme/agent"}, report.Pushed)
+	exists, err := store.Exists(ctx, fresh.ID)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+	assert.Equal(t, []*domain.RepoFate{
+		{RepoID: "github-1", Name: "acme/agent", Score: 80},
+		{RepoID: "github-2", Name: "acme/toy", Score: 30, Stage: domain.StageSelect, Reason: "评分 30 低于 50"},
+		{RepoID: "github-3", Name: "acme/legacy", Stage: domain.StageFilterAge, Reason: "创建于 2020-01-02，超过 10 天"},
+	}, report.Fates)
+}
+
+func TestMiningService_Profile(t *testing.T) {
+	ctx := context.Background()
+	fresh := &domain.Repo{ID: "github-1", Name: "acme/rs-agent", Language: "Rust", IsAIProgrammingTool: true, LLMScore: 80}
+	goRepo := &domain.Repo{ID: "github-2", Name: "acme/go-agent", Language: "Go", IsAIProgrammingTool: true, LLMScore: 80}
+	legacy := &domain.Repo{ID: "github-3", Name: "acme/legacy", Language: "rust", IsAIProgrammingTool: true, LLMScore: 80}
+	shared := &domain.Repo{ID: "github-4", Name: "acme/shared", Language: "Rust", IsAIProgrammingTool: true, LLMScore: 80}
+	rust := []*domain.Repo{fresh, legacy, shared}
+
+	store := repository.NewMemoryRepo()
+	// 引入挖矿方向之前入库的项目，和其他方向发现的项目
+	require.NoError(t, store.Save(ctx, &domain.Repo{ID: legacy.ID, Name: legacy.Name}))
+	require.NoError(t, store.Save(ctx, &domain.Repo{ID: shared.ID, Name: shared.Name, Profiles: []string{"agents"}}))
+
+	scouter, mf, ma, notifier := new(MockScouter), new(MockFilter), new(MockAnalyzer), new(MockNotifier)
+	scouter.On("GetTrendingRepos", mock.Anything, "rust", "weekly").Return([]*domain.Repo{fresh, goRepo, legacy, shared}, nil)
+	mf.On("FilterByCreatedAt", rust, 30).Return(rust)
+	mf.On("FilterByRecentCommit", mock.Anything, rust).Return(rust, nil)
+	ma.On("SetMaxGoroutines", 1).Return()
+	ma.On("CalculateStarGrowthRate", rust).Return(rust)
+	ma.On("AnalyzeWithLLM", mock.Anything, rust).Return(rust, nil)
+	notifier.On("Notify", mock.Anything, mock.Anything).Return(nil)
+
+	service := NewMiningService(scouter, mf, ma, store, new(MockAppraiser), notifier)
+	service.notifyInterval = 0
+	service.SetConfig(MiningConfig{Profile: "rust", Languages: []string{"rust"}, MaxAgeDays: 30, MinScore: 60})
+	report, err := service.ExecuteMiningCycle(ctx, 1)
+	require.NoError(t, err)
+
+	// 其他方向发现过的项目在本方向再推送一次，旧项目不重复推送
+	assert.Equal(t, []string{"acme/rs-agent", "acme/shared"}, report.Pushed)
+	assert.Equal(t, "语言 Go 不在 rust 中", report.Stage(domain.StageFilterLanguage).DropReason(goRepo.ID))
+	assert.Equal(t, "已入库过，只更新元数据", report.Stage(domain.StageStore).DropReason(legacy.ID))
+
+	for id, want := range map[string][]string{
+		fresh.ID:  {"rust"},
+		legacy.ID: {"rust"},
+		shared.ID: {"agents", "rust"},
+	} {
+		got, err := store.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, got.Profiles, id)
+	}
+	results, err := store.Search(ctx, domain.SearchQuery{Profile: "agents"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, shared.ID, results[0].ID)
+}
+
+func TestMiningService_CustomPipeline(t *testing.T) {
+	repos := []*domain.Repo{
+		{ID: "github-1", Name: "acme/agent", Stars: 10},
+		{ID: "github-2", Name: "acme/farm", Stars: 5000},
+	}
+	scouter := new(MockScouter)
+	scouter.On("GetTrendingRepos", mock.Anything, "all", "weekly").Return(repos, nil)
+	scouter.On("GetReposByTopic", mock.Anything, mock.Anything).Return([]*domain.Repo{}, nil)
+	ma := new(MockAnalyzer)
+	ma.On("SetMaxGoroutines", 1).Return()
+
+	service := NewMiningService(scouter, new(MockFilter), ma, new(MockRepository), new(MockAppraiser), nil)
+	// 新阶段只需要注册并写进配置，不需要修改服务
+	service.RegisterStage(NewStage("star_farm", func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+		var kept []*domain.Repo
+		for _, r := range repos {
+			if r.Stars < 1000 {
+				kept = append(kept, r)
+			}
+		}
+		return kept, nil
+	}))
+
+	assert.Error(t, service.SetPipeline(PipelineConfig{Stages: []StageConfig{{Name: "readme"}}}))
+	require.NoError(t, service.SetPipeline(PipelineConfig{Stages: []StageConfig{
+		{Name: domain.StageFetch},
+		{Name: "star_farm"},
+	}}))
+
+	report, err := service.ExecuteMiningCycle(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, report.Stages, 2)
+	assert.Equal(t, "star_farm", report.Stages[1].Name)
+	assert.Equal(t, 2, report.Stages[1].In)
+	assert.Equal(t, 1, report.Stages[1].Out)
+	// 没有配置的阶段不会执行
+	scouter.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Stage 挖矿流水线中的一个阶段：输入上一阶段输出的项目，返回交给下一阶段的项目
+type Stage interface {
+	// 阶段名称，与流水线配置中的 name 对应
+	Name() string
+
+	// 单个项目的错误通过 report.AddError 记录后继续处理；
+	// 返回 error 表示整个阶段出错，由配置的错误策略决定流水线如何继续
+	Run(ctx context.Context, repos []*domain.Repo, report *domain.StageReport) ([]*domain.Repo, error)
+}
+
+// StageFunc 把函数包装为 Stage
+type StageFunc func(ctx context.Context, repos []*domain.Repo, report *domain.StageReport) ([]*domain.Repo, error)
+
+// NewStage 用函数创建阶段
+func NewStage(name string, fn StageFunc) Stage {
+	return funcStage{name: name, fn: fn}
+}
+
+type funcStage struct {
+	name string
+	fn   StageFunc
+}
+
+func (s funcStage) Name() string { return s.name }
+
+func (s funcStage) Run(ctx context.Context, repos []*domain.Repo, report *domain.StageReport) ([]*domain.Repo, error) {
+	return s.fn(ctx, repos, report)
+}
+
+// ErrorPolicy 阶段返回错误时流水线的处理方式
+type ErrorPolicy string
+
+const (
+	// PolicyFail 终止流水线，本轮挖矿记为失败
+	PolicyFail ErrorPolicy = "fail"
+	// PolicySkip 丢弃该阶段的输出，把输入原样交给下一阶段
+	PolicySkip ErrorPolicy = "skip"
+	// PolicyContinue 使用该阶段已经产生的输出继续 (默认)
+	PolicyContinue ErrorPolicy = "continue"
+)
+
+// StageConfig 一个阶段在流水线中的配置
+type StageConfig struct {
+	Name    string        `yaml:"name" json:"name"`
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`   // 该阶段的最长执行时间，0 表示只受整轮挖矿的超时限制
+	OnError ErrorPolicy   `yaml:"on_error" json:"on_error"` // 出错时的处理方式，默认 continue
+}
+
+// PipelineConfig 流水线配置，阶段按列表顺序执行；不在列表中的阶段不会执行
+type PipelineConfig struct {
+	Stages []StageConfig `yaml:"stages" json:"stages"`
+}
+
+// DefaultPipelineConfig 默认流水线：抓取 → 去重 → 初筛 → 分析 → 入库 → 推送
+// 抓取失败 (所有数据源都失败) 时没有可处理的项目，终止本轮挖矿
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{Stages: []StageConfig{
+		{Name: domain.StageFetch, OnError: PolicyFail},
+		{Name: domain.StageDedup},
+		{Name: domain.StageFilterLanguage},
+		{Name: domain.StageFilterAge},
+		{Name: domain.StageFilterActivity},
+		{Name: domain.StageVelocity},
+		{Name: domain.StageAppraise},
+		{Name: domain.StageSelect},
+		{Name: domain.StageStore},
+		{Name: domain.StageNotify},
+	}}
+}
+
+// LoadPipelineConfig 从 YAML 文件读取流水线配置
+func LoadPipelineConfig(path string) (PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PipelineConfig{}, fmt.Errorf("读取流水线配置失败: %w", err)
+	}
+
+	var cfg PipelineConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return PipelineConfig{}, fmt.Errorf("解析流水线配置失败: %w", err)
+	}
+	return cfg, nil
+}
+
+// StageHook 在阶段执行结束后调用，out 为阶段的输出；complete 表示阶段没有出错，输出完整
+type StageHook func(ctx context.Context, stage string, out []*domain.Repo, complete bool)
+
+// Pipeline 按配置顺序执行的阶段
+type Pipeline struct {
+	stages      []configuredStage
+	onStageDone StageHook
+	nowFunc     func() time.Time
+}
+
+type configuredStage struct {
+	Stage
+	cfg StageConfig
+}
+
+// NewPipeline 按配置从 available 中挑选阶段组成流水线
+// 配置引用了未注册的阶段、阶段重复或错误策略无效时返回错误
+func NewPipeline(cfg PipelineConfig, available []Stage) (*Pipeline, error) {
+	byName := make(map[string]Stage, len(available))
+	for _, s := range available {
+		byName[s.Name()] = s
+	}
+
+	var problems []string
+	if len(cfg.Stages) == 0 {
+		problems = append(problems, "至少需要一个阶段")
+	}
+	seen := make(map[string]bool)
+	p := &Pipeline{nowFunc: time.Now}
+	for _, sc := range cfg.Stages {
+		stage, ok := byName[sc.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("未知阶段 %q", sc.Name))
+			continue
+		}
+		if seen[sc.Name] {
+			problems = append(problems, fmt.Sprintf("阶段 %s 重复", sc.Name))
+		}
+		seen[sc.Name] = true
+		switch sc.OnError {
+		case "":
+			sc.OnError = PolicyContinue
+		case PolicyFail, PolicySkip, PolicyContinue:
+		default:
+			problems = append(problems, fmt.Sprintf("阶段 %s 的错误策略 %q 无效 (可选 fail、skip、continue)", sc.Name, sc.OnError))
+		}
+		if sc.Timeout < 0 {
+			problems = append(problems, fmt.Sprintf("阶段 %s 的超时时间不能为负数", sc.Name))
+		}
+		p.stages = append(p.stages, configuredStage{Stage: stage, cfg: sc})
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("流水线配置无效: %s", strings.Join(problems, "; "))
+	}
+	return p, nil
+}
+
+// StageNames 按执行顺序返回阶段名称
+func (p *Pipeline) StageNames() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// OnStageDone 设置每个阶段结束后的回调，用于保存检查点
+func (p *Pipeline) OnStageDone(hook StageHook) {
+	p.onStageDone = hook
+}
+
+// Run 依次执行各阶段，每个阶段的输入输出数量、耗时和错误追加到 report 中，
+// 每个项目的去向 (在哪个阶段因为什么被丢弃) 记入 report.Fates
+// 返回最后一个阶段的输出；某个阶段按 fail 策略终止或 ctx 结束时返回错误
+func (p *Pipeline) Run(ctx context.Context, report *domain.MiningReport) ([]*domain.Repo, error) {
+	return p.Resume(ctx, report, nil)
+}
+
+// Resume 与 Run 相同，但前 len(outputs) 个阶段不再执行，outputs[i] 作为第 i 个阶段的输出 (来自检查点)
+func (p *Pipeline) Resume(ctx context.Context, report *domain.MiningReport, outputs [][]*domain.Repo) ([]*domain.Repo, error) {
+	if len(outputs) > len(p.stages) {
+		return nil, fmt.Errorf("检查点有 %d 个阶段，流水线只有 %d 个阶段", len(outputs), len(p.stages))
+	}
+
+	fates := newFateTracker(report)
+	var repos []*domain.Repo
+	for i, out := range outputs {
+		stage := report.AddStage(p.stages[i].Name(), len(repos))
+		stage.Out = len(out)
+		stage.Resumed = true
+		fates.record(repos, out, stage)
+		repos = out
+	}
+
+	for _, s := range p.stages[len(outputs):] {
+		if err := ctx.Err(); err != nil {
+			fates.abort(repos, s.Name(), "流水线在此阶段前中止")
+			return repos, fmt.Errorf("阶段 %s 未执行: %w", s.Name(), err)
+		}
+
+		stage := report.AddStage(s.Name(), len(repos))
+		started := p.nowFunc()
+		out, err := s.run(withRetryRecorder(ctx, stage), repos, stage)
+		stage.DurationMS = p.nowFunc().Sub(started).Milliseconds()
+
+		if err != nil {
+			stage.AddError(err)
+			switch s.cfg.OnError {
+			case PolicyFail:
+				stage.Out = len(out)
+				p.stageDone(ctx, s.Name(), out, false)
+				fates.record(repos, out, stage)
+				fates.abort(out, s.Name(), fmt.Sprintf("流水线在此阶段终止: %v", err))
+				return out, fmt.Errorf("阶段 %s 失败: %w", s.Name(), err)
+			case PolicySkip:
+				out = repos
+				stage.Skipped = true
+			}
+		}
+		stage.Out = len(out)
+		p.stageDone(ctx, s.Name(), out, err == nil)
+		fates.record(repos, out, stage)
+		repos = out
+	}
+
+	// 最后一个阶段可能因为整轮超时提前结束
+	if err := ctx.Err(); err != nil {
+		return repos, fmt.Errorf("流水线未完成: %w", err)
+	}
+	return repos, nil
+}
+
+// withRetryRecorder 把阶段中外部调用的重试按错误码记入阶段报告，阶段内可能并发重试
+func withRetryRecorder(ctx context.Context, stage *domain.StageReport) context.Context {
+	var mu sync.Mutex
+	return common.WithRetryRecorder(ctx, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		stage.AddRetry(err)
+	})
+}
+
+// stageDone 调用阶段结束的回调
+func (p *Pipeline) stageDone(ctx context.Context, stage string, out []*domain.Repo, complete bool) {
+	if p.onStageDone != nil {
+		p.onStageDone(ctx, stage, out, complete)
+	}
+}
+
+// fateTracker 根据每个阶段的输入输出推断项目的去向
+type fateTracker struct {
+	report *domain.MiningReport
+	byID   map[string]*domain.RepoFate
+}
+
+func newFateTracker(report *domain.MiningReport) *fateTracker {
+	return &fateTracker{report: report, byID: make(map[string]*domain.RepoFate)}
+}
+
+// record 登记阶段新产生的项目 (如抓取)，输入中有而输出中没有的项目记为被该阶段丢弃
+func (t *fateTracker) record(in, out []*domain.Repo, stage *domain.StageReport) {
+	for _, repo := range out {
+		fate, ok := t.byID[repo.ID]
+		if !ok {
+			fate = &domain.RepoFate{RepoID: repo.ID}
+			t.byID[repo.ID] = fate
+			t.report.Fates = append(t.report.Fates, fate)
+		}
+		fate.Name = repo.Name
+		fate.Score = repo.LLMScore
+	}
+	for _, repo := range droppedRepos(in, out) {
+		fate := t.byID[repo.ID]
+		if fate == nil || fate.Stage != "" {
+			continue
+		}
+		fate.Stage = stage.Name
+		fate.Reason = stage.DropReason(repo.ID)
+		if fate.Reason == "" {
+			fate.Reason = fmt.Sprintf("未通过 %s 阶段", stage.Name)
+		}
+	}
+}
+
+// abort 流水线提前结束时，把还在流水线中的项目记为停在该阶段
+func (t *fateTracker) abort(repos []*domain.Repo, stage, reason string) {
+	for _, repo := range repos {
+		if fate := t.byID[repo.ID]; fate != nil && fate.Stage == "" {
+			fate.Stage = stage
+			fate.Reason = reason
+		}
+	}
+}
+
+// droppedRepos 返回 in 中有而 out 中没有的项目 (按 ID 比较)
+func droppedRepos(in, out []*domain.Repo) []*domain.Repo {
+	kept := make(map[string]bool, len(out))
+	for _, repo := range out {
+		kept[repo.ID] = true
+	}
+	var dropped []*domain.Repo
+	for _, repo := range in {
+		if !kept[repo.ID] {
+			kept[repo.ID] = true // 重复的项目只返回一次
+			dropped = append(dropped, repo)
+		}
+	}
+	return dropped
+}
+
+// run 在阶段自己的超时时间内执行
+func (s configuredStage) run(ctx context.Context, repos []*domain.Repo, report *domain.StageReport) ([]*domain.Repo, error) {
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+	return s.Stage.Run(ctx, repos, report)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/common"
+	"github-gold-miner/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendStage 在输入后追加一个项目
+func appendStage(name, id string) Stage {
+	return NewStage(name, func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+		return append(repos, &domain.Repo{ID: id}), nil
+	})
+}
+
+// failingStage 丢掉一半输入后返回错误
+func failingStage(name string) Stage {
+	return NewStage(name, func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+		stage.AddError(errors.New("单个项目失败"))
+		return repos[:len(repos)/2], errors.New("服务不可用")
+	})
+}
+
+func ids(repos []*domain.Repo) []string {
+	var result []string
+	for _, r := range repos {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func TestPipeline_OrderFromConfig(t *testing.T) {
+	available := []Stage{appendStage("a", "1"), appendStage("b", "2"), appendStage("c", "3")}
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Name: "c"}, {Name: "a"}}}, available)
+	require.NoError(t, err)
+
+	report := &domain.MiningReport{}
+	out, err := p.Run(context.Background(), report)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "1"}, ids(out))
+	require.Len(t, report.Stages, 2)
+	assert.Equal(t, "c", report.Stages[0].Name)
+	assert.Equal(t, 1, report.Stages[1].In)
+	assert.Equal(t, 2, report.Stages[1].Out)
+}
+
+func TestPipeline_ErrorPolicies(t *testing.T) {
+	codes := map[string]int{domain.ErrorCodeUnknown: 2}
+	tests := []struct {
+		policy    ErrorPolicy
+		wantErr   bool
+		wantOut   []string
+		wantStage domain.StageReport
+	}{
+		{PolicyContinue, false, []string{"1", "9"}, domain.StageReport{Name: "flaky", In: 2, Out: 1, Errors: []string{"单个项目失败", "服务不可用"}, ErrorCodes: codes}},
+		{PolicySkip, false, []string{"1", "2", "9"}, domain.StageReport{Name: "flaky", In: 2, Out: 2, Skipped: true, Errors: []string{"单个项目失败", "服务不可用"}, ErrorCodes: codes}},
+		{PolicyFail, true, []string{"1"}, domain.StageReport{Name: "flaky", In: 2, Out: 1, Errors: []string{"单个项目失败", "服务不可用"}, ErrorCodes: codes}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			available := []Stage{appendStage("one", "1"), appendStage("two", "2"), failingStage("flaky"), appendStage("last", "9")}
+			p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+				{Name: "one"}, {Name: "two"}, {Name: "flaky", OnError: tt.policy}, {Name: "last"},
+			}}, available)
+			require.NoError(t, err)
+			p.nowFunc = func() time.Time { return time.Time{} }
+
+			report := &domain.MiningReport{}
+			out, err := p.Run(context.Background(), report)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "阶段 flaky 失败")
+				// 终止后不再执行后续阶段
+				assert.Len(t, report.Stages, 3)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOut, ids(out))
+			assert.Equal(t, tt.wantStage, *report.Stages[2])
+		})
+	}
+}
+
+func TestPipeline_RetryCounts(t *testing.T) {
+	// 第一次调用 502，重试后成功
+	flaky := NewStage("flaky", func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+		attempts := 0
+		err := common.Do(ctx, func() error {
+			attempts++
+			if attempts == 1 {
+				return &common.HTTPError{Service: "GitHub API", StatusCode: 502}
+			}
+			return nil
+		}, common.WithInitialDelay(time.Millisecond), common.LogRetry("GitHub API", common.GitHubError))
+		return repos, err
+	})
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Name: "flaky"}, {Name: "next"}}},
+		[]Stage{flaky, appendStage("next", "1")})
+	require.NoError(t, err)
+
+	report := &domain.MiningReport{}
+	_, err = p.Run(context.Background(), report)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{common.ErrCodeGitHubUnavailable: 1}, report.Stages[0].Retries)
+	assert.Empty(t, report.Stages[0].ErrorCodes)
+	assert.Empty(t, report.Stages[1].Retries)
+	assert.Equal(t, map[string]int{common.ErrCodeGitHubUnavailable: 1}, report.Retries())
+}
+
+func TestPipeline_StageTimeout(t *testing.T) {
+	slow := NewStage("slow", func(ctx context.Context, repos []*domain.Repo, stage *domain.StageReport) ([]*domain.Repo, error) {
+		<-ctx.Done()
+		return repos, ctx.Err()
+	})
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{Name: "slow", Timeout: 10 * time.Millisecond},
+		{Name: "next"},
+	}}, []Stage{slow, appendStage("next", "1")})
+	require.NoError(t, err)
+
+	// 单个阶段超时不影响后续阶段
+	report := &domain.MiningReport{}
+	out, err := p.Run(context.Background(), report)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids(out))
+	assert.Contains(t, report.Stages[0].Errors[0], "deadline exceeded")
+
+	// 整轮挖矿结束时不再执行后续阶段
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.Run(ctx, &domain.MiningReport{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPipeline_ResumeAndStageHook(t *testing.T) {
+	available := []Stage{appendStage("a", "1"), appendStage("b", "2"), failingStage("c"), appendStage("d", "4")}
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}}, available)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, p.StageNames())
+
+	var done []string
+	p.OnStageDone(func(ctx context.Context, stage string, out []*domain.Repo, complete bool) {
+		if complete {
+			done = append(done, stage)
+		} else {
+			done = append(done, stage+"(未完成)")
+		}
+	})
+
+	// 前两个阶段的输出来自检查点，不再执行
+	report := &domain.MiningReport{}
+	checkpoint := [][]*domain.Repo{{{ID: "x"}}, {{ID: "x"}, {ID: "y"}}}
+	out, err := p.Resume(context.Background(), report, checkpoint)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x", "4"}, ids(out))
+	assert.Equal(t, []string{"c(未完成)", "d"}, done)
+	require.Len(t, report.Stages, 4)
+	assert.Equal(t, domain.StageReport{Name: "b", In: 1, Out: 2, Resumed: true}, *report.Stages[1])
+	assert.False(t, report.Stages[2].Resumed)
+	assert.Equal(t, 2, report.Stages[2].In)
+
+	_, err = p.Resume(context.Background(), &domain.MiningReport{}, make([][]*domain.Repo, 5))
+	assert.Error(t, err)
+}
+
+func TestNewPipeline_Validation(t *testing.T) {
+	available := []Stage{appendStage("a", "1")}
+
+	_, err := NewPipeline(PipelineConfig{}, available)
+	assert.ErrorContains(t, err, "至少需要一个阶段")
+
+	_, err = NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{Name: "a"}, {Name: "a"}, {Name: "readme"}, {Name: "a", OnError: "retry"},
+	}}, available)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "阶段 a 重复")
+	assert.Contains(t, err.Error(), `未知阶段 "readme"`)
+	assert.Contains(t, err.Error(), `错误策略 "retry" 无效`)
+}
+
+func TestDefaultPipelineConfig_UsesBuiltinStages(t *testing.T) {
+	service := NewMiningService(nil, nil, nil, nil, nil, nil)
+	assert.NoError(t, service.SetPipeline(DefaultPipelineConfig()))
+}
+
+func TestLoadPipelineConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+stages:
+  - name: fetch
+    timeout: 2m
+    on_error: fail
+  - name: appraise
+    on_error: skip
+`), 0o644))
+
+	cfg, err := LoadPipelineConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []StageConfig{
+		{Name: "fetch", Timeout: 2 * time.Minute, OnError: PolicyFail},
+		{Name: "appraise", OnError: PolicySkip},
+	}, cfg.Stages)
+
+	_, err = LoadPipelineConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
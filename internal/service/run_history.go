@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// ErrRunInProgress 同一挖矿方向已有任务在执行
+var ErrRunInProgress = errors.New("已有挖矿任务在执行")
+
+// MineFunc 执行一次挖矿周期并返回执行报告，run 为本次执行的记录 (用于保存检查点和续跑)
+type MineFunc func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error)
+
+const (
+	// resumeWindow 中断的执行在这段时间内可以续跑，更早的检查点中的项目数据已经过时
+	resumeWindow = 6 * time.Hour
+	// staleRunAfter 超过这么久仍处于执行中的记录视为进程退出导致的中断
+	staleRunAfter = time.Hour
+	// resumeLookback 查找可续跑的执行时检查的最近执行记录数
+	resumeLookback = 20
+)
+
+// RunHistory 把每次挖矿的执行记录和报告保存到 RunStore
+// 同一进程内每个挖矿方向同一时间只允许一个任务运行，不同方向可以同时运行
+type RunHistory struct {
+	store   port.RunStore
+	mu      sync.Mutex
+	running map[string]bool // 正在执行的挖矿方向
+	nowFunc func() time.Time
+}
+
+// NewRunHistory 创建执行记录
+func NewRunHistory(store port.RunStore) *RunHistory {
+	return &RunHistory{store: store, running: make(map[string]bool), nowFunc: time.Now}
+}
+
+// Start 记录挖矿方向 profile (未配置方向时为空) 的一次新执行，该方向已有任务在执行时返回 ErrRunInProgress
+// 该方向最近一次执行中断 (失败或进程退出) 并留下了检查点时，新执行记为从它续跑 (run.ResumedFrom)
+func (h *RunHistory) Start(ctx context.Context, trigger domain.RunTrigger, profile string) (*domain.MiningRun, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running[profile] {
+		return nil, ErrRunInProgress
+	}
+
+	run := &domain.MiningRun{Trigger: trigger, Profile: profile, Status: domain.RunRunning, StartedAt: h.nowFunc()}
+	run.ResumedFrom = h.interrupted(ctx, profile)
+	if err := h.store.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	h.running[profile] = true
+	return run, nil
+}
+
+// Finish 记录执行结果和报告，report 可以为 nil
+// 执行记录写入失败只记录日志，不影响下一次执行
+func (h *RunHistory) Finish(ctx context.Context, run *domain.MiningRun, report *domain.MiningReport, err error) {
+	h.mu.Lock()
+	delete(h.running, run.Profile)
+	h.mu.Unlock()
+
+	now := h.nowFunc()
+	run.FinishedAt = &now
+	run.Status = domain.RunSucceeded
+	if err != nil {
+		run.Status = domain.RunFailed
+		run.Error = err.Error()
+	}
+	if report != nil {
+		run.MiningReport = *report
+	}
+	// 挖矿超时或被取消时 ctx 已经结束，执行记录仍然需要写入
+	if updateErr := h.store.UpdateRun(context.WithoutCancel(ctx), run); updateErr != nil {
+		log.Printf("⚠️ 保存挖矿执行记录 #%d 失败: %v", run.ID, updateErr)
+	}
+
+	// 成功的执行不会被续跑，检查点不再需要
+	if checkpoints, ok := h.store.(port.CheckpointStore); ok && err == nil {
+		if deleteErr := checkpoints.DeleteCheckpoints(context.WithoutCancel(ctx), run.ID); deleteErr != nil {
+			log.Printf("⚠️ 删除执行记录 #%d 的检查点失败: %v", run.ID, deleteErr)
+		}
+	}
+}
+
+// interrupted 返回挖矿方向最近一次执行的 ID，如果它在 resumeWindow 内中断并留下了检查点；否则返回 0
+func (h *RunHistory) interrupted(ctx context.Context, profile string) int64 {
+	checkpoints, ok := h.store.(port.CheckpointStore)
+	if !ok {
+		return 0
+	}
+	runs, err := h.store.ListRuns(ctx, resumeLookback)
+	if err != nil {
+		log.Printf("⚠️ 查找可续跑的执行记录失败: %v", err)
+		return 0
+	}
+
+	now := h.nowFunc()
+	for _, run := range runs {
+		if run.Profile != profile {
+			continue
+		}
+		age := now.Sub(run.StartedAt)
+		stale := run.Status == domain.RunRunning && age > staleRunAfter
+		if age > resumeWindow || (run.Status != domain.RunFailed && !stale) {
+			return 0
+		}
+		saved, err := checkpoints.GetCheckpoints(ctx, run.ID)
+		if err != nil || len(saved) == 0 {
+			return 0
+		}
+		return run.ID
+	}
+	return 0
+}
+
+// Run 执行挖矿方向 profile 的一次挖矿并记录结果
+func (h *RunHistory) Run(ctx context.Context, trigger domain.RunTrigger, profile string, mine MineFunc) (*domain.MiningRun, error) {
+	run, err := h.Start(ctx, trigger, profile)
+	if err != nil {
+		return nil, err
+	}
+	report, err := mine(ctx, run)
+	h.Finish(ctx, run, report, err)
+	return run, err
+}
+
+// List 按开始时间倒序返回最近 limit 条执行记录
+func (h *RunHistory) List(ctx context.Context, limit int) ([]*domain.MiningRun, error) {
+	return h.store.ListRuns(ctx, limit)
+}
+
+// Get 获取一次执行记录，不存在时返回 port.ErrNotFound
+func (h *RunHistory) Get(ctx context.Context, id int64) (*domain.MiningRun, error) {
+	return h.store.GetRun(ctx, id)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHistory(t *testing.T) {
+	ctx := context.Background()
+	h := NewRunHistory(repository.NewMemoryRepo())
+
+	first, err := h.Start(ctx, domain.TriggerAPI, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunRunning, first.Status)
+
+	// 同一时间只允许一个任务
+	_, err = h.Start(ctx, domain.TriggerAPI, "")
+	assert.ErrorIs(t, err, ErrRunInProgress)
+
+	h.Finish(ctx, first, nil, nil)
+	report := &domain.MiningReport{APIRequests: 12, Pushed: []string{"acme/agent"}}
+	report.AddStage(domain.StageFetch, 4).Out = 20
+	second, err := h.Run(ctx, domain.TriggerCron, "", func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		return report, errors.New("GitHub API 限流")
+	})
+	assert.EqualError(t, err, "GitHub API 限流")
+	third, err := h.Start(ctx, domain.TriggerManual, "")
+	require.NoError(t, err)
+
+	runs, err := h.List(ctx, 2)
+	require.NoError(t, err)
+	// 按开始时间倒序
+	require.Len(t, runs, 2)
+	assert.Equal(t, third.ID, runs[0].ID)
+	assert.Equal(t, domain.RunRunning, runs[0].Status)
+	assert.Equal(t, domain.RunFailed, runs[1].Status)
+	assert.Equal(t, "GitHub API 限流", runs[1].Error)
+	assert.NotNil(t, runs[1].FinishedAt)
+
+	got, err := h.Get(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TriggerCron, got.Trigger)
+	assert.Equal(t, int64(12), got.APIRequests)
+	assert.Equal(t, []string{"acme/agent"}, got.Pushed)
+	require.Len(t, got.Stages, 1)
+	assert.Equal(t, 20, got.Stages[0].Out)
+
+	got, err = h.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RunSucceeded, got.Status)
+
+	_, err = h.Get(ctx, 404)
+	assert.ErrorIs(t, err, port.ErrNotFound)
+}
+
+func TestRunHistory_Profiles(t *testing.T) {
+	ctx := context.Background()
+	h := NewRunHistory(repository.NewMemoryRepo())
+
+	agents, err := h.Start(ctx, domain.TriggerCron, "agents")
+	require.NoError(t, err)
+	assert.Equal(t, "agents", agents.Profile)
+
+	// 不同挖矿方向可以同时执行，同一方向不行
+	rust, err := h.Start(ctx, domain.TriggerCron, "rust")
+	require.NoError(t, err)
+	_, err = h.Start(ctx, domain.TriggerAPI, "agents")
+	assert.ErrorIs(t, err, ErrRunInProgress)
+
+	h.Finish(ctx, agents, nil, nil)
+	_, err = h.Start(ctx, domain.TriggerAPI, "agents")
+	assert.NoError(t, err)
+	_, err = h.Start(ctx, domain.TriggerAPI, "rust")
+	assert.ErrorIs(t, err, ErrRunInProgress)
+
+	got, err := h.Get(ctx, rust.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rust", got.Profile)
+}
+
+func TestRunHistory_Resume(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	h := NewRunHistory(store)
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	h.nowFunc = func() time.Time { return now }
+
+	checkpoint := func(run *domain.MiningRun) {
+		require.NoError(t, store.SaveCheckpoint(ctx, &domain.RunCheckpoint{RunID: run.ID, Stage: domain.StageFetch, Complete: true}))
+	}
+
+	// 没有检查点的失败执行不续跑
+	failed, err := h.Run(ctx, domain.TriggerCron, "", func(ctx context.Context, run *domain.MiningRun) (*domain.MiningReport, error) {
+		return nil, errors.New("超时")
+	})
+	require.Error(t, err)
+	next, err := h.Start(ctx, domain.TriggerCron, "")
+	require.NoError(t, err)
+	assert.Zero(t, next.ResumedFrom)
+
+	// 失败并留下检查点的执行由同一方向的下一次执行续跑，其他方向不受影响
+	checkpoint(next)
+	h.Finish(ctx, next, nil, errors.New("超时"))
+	now = now.Add(time.Minute)
+	other, err := h.Start(ctx, domain.TriggerCron, "agents")
+	require.NoError(t, err)
+	assert.Zero(t, other.ResumedFrom)
+	resumed, err := h.Start(ctx, domain.TriggerCron, "")
+	require.NoError(t, err)
+	assert.Equal(t, next.ID, resumed.ResumedFrom)
+	assert.NotEqual(t, failed.ID, resumed.ResumedFrom)
+
+	// 成功的执行删除自己的检查点
+	checkpoint(resumed)
+	h.Finish(ctx, resumed, nil, nil)
+	saved, err := store.GetCheckpoints(ctx, resumed.ID)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+	fresh, err := h.Start(ctx, domain.TriggerCron, "")
+	require.NoError(t, err)
+	assert.Zero(t, fresh.ResumedFrom)
+
+	// 进程退出后一直处于执行中的记录视为中断，但过期的检查点不再续跑
+	checkpoint(fresh)
+	h.running = make(map[string]bool)
+	now = now.Add(staleRunAfter + time.Minute)
+	stale, err := h.Start(ctx, domain.TriggerCron, "")
+	require.NoError(t, err)
+	assert.Equal(t, fresh.ID, stale.ResumedFrom)
+
+	checkpoint(stale)
+	h.Finish(ctx, stale, nil, errors.New("超时"))
+	now = now.Add(resumeWindow + time.Minute)
+	late, err := h.Start(ctx, domain.TriggerCron, "")
+	require.NoError(t, err)
+	assert.Zero(t, late.ResumedFrom)
+}
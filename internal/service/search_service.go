@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// SearchConfig 语义搜索配置
+type SearchConfig struct {
+	TopK       int // 向量检索召回的项目数，作为 LLM 的上下文
+	IndexBatch int // 补齐向量时每批处理的项目数
+}
+
+// DefaultSearchConfig 默认召回 30 个项目，每批向量化 50 个
+func DefaultSearchConfig() SearchConfig {
+	return SearchConfig{TopK: 30, IndexBatch: 50}
+}
+
+// SearchService 语义搜索：先用向量检索从全库召回最相关的项目，再交给 LLM 挑选
+// 没有配置 Embedder 或仓库不支持向量检索时，退化为把最近入库的候选项目交给 LLM
+type SearchService struct {
+	repoStore port.Repository
+	appraiser port.Appraiser
+	embedder  port.Embedder
+	vectors   port.VectorStore
+	budget    *Budget
+	cfg       SearchConfig
+}
+
+// NewSearchService 创建搜索服务，embedder 可以为 nil
+func NewSearchService(repoStore port.Repository, appraiser port.Appraiser, embedder port.Embedder, cfg SearchConfig) *SearchService {
+	def := DefaultSearchConfig()
+	if cfg.TopK <= 0 {
+		cfg.TopK = def.TopK
+	}
+	if cfg.IndexBatch <= 0 {
+		cfg.IndexBatch = def.IndexBatch
+	}
+
+	s := &SearchService{repoStore: repoStore, appraiser: appraiser, cfg: cfg}
+	if vectors, ok := repoStore.(port.VectorStore); ok && embedder != nil {
+		s.embedder = embedder
+		s.vectors = vectors
+	}
+	return s
+}
+
+// SetBudget 设置 LLM 费用预算：检索、对话和向量化的用量记入账本，超出预算时不再调用
+func (s *SearchService) SetBudget(budget *Budget) {
+	s.budget = budget
+}
+
+// VectorEnabled 是否使用向量检索
+func (s *SearchService) VectorEnabled() bool {
+	return s.vectors != nil
+}
+
+// Index 为还没有向量、或内容变化后向量已过期的项目生成向量，返回本次处理的项目数
+func (s *SearchService) Index(ctx context.Context) (int, error) {
+	if !s.VectorEnabled() {
+		return 0, nil
+	}
+	ctx = s.budget.Meter(ctx, "")
+
+	model := s.embedder.Model()
+	indexed := 0
+	for {
+		repos, err := s.vectors.ReposNeedingEmbedding(ctx, model, s.cfg.IndexBatch)
+		if err != nil {
+			return indexed, fmt.Errorf("读取待向量化项目失败: %w", err)
+		}
+		if len(repos) == 0 {
+			return indexed, nil
+		}
+
+		texts := make([]string, len(repos))
+		for i, repo := range repos {
+			texts[i] = repo.EmbeddingText()
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			return indexed, err
+		}
+		if len(vectors) != len(repos) {
+			return indexed, fmt.Errorf("向量数量 %d 与项目数量 %d 不一致", len(vectors), len(repos))
+		}
+
+		for i, repo := range repos {
+			if err := s.vectors.SaveEmbedding(ctx, repo.ID, model, repo.EmbeddingHash, vectors[i]); err != nil {
+				return indexed, fmt.Errorf("保存项目 %s 的向量失败: %w", repo.ID, err)
+			}
+		}
+		indexed += len(repos)
+
+		if len(repos) < s.cfg.IndexBatch {
+			return indexed, nil
+		}
+	}
+}
+
+// Candidates 召回与问题最相关的项目；只读取已有的向量，向量由挖矿入库后或启动时的 Index 补齐
+func (s *SearchService) Candidates(ctx context.Context, query string) ([]*domain.Repo, error) {
+	ctx = s.budget.Meter(ctx, "")
+	if !s.VectorEnabled() {
+		return s.repoStore.GetAllCandidates(ctx)
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("查询向量数量 %d 不正确", len(vectors))
+	}
+
+	scored, err := s.vectors.NearestRepos(ctx, s.embedder.Model(), vectors[0], s.cfg.TopK)
+	if err != nil {
+		return nil, fmt.Errorf("向量检索失败: %w", err)
+	}
+	repos := make([]*domain.Repo, len(scored))
+	for i, sr := range scored {
+		repos[i] = sr.Repo
+	}
+	return repos, nil
+}
+
+// Search 召回候选项目后交给 LLM 按用户意图挑选，返回校验后的结果和候选项目
+// 没有候选项目时不调用 LLM，结果为空
+func (s *SearchService) Search(ctx context.Context, query string) ([]*domain.SearchResult, []*domain.Repo, error) {
+	ctx = s.budget.Meter(ctx, "")
+	candidates, err := s.Candidates(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil, nil
+	}
+
+	raw, err := s.appraiser.SemanticSearch(ctx, candidates, query)
+	if err != nil {
+		return nil, candidates, fmt.Errorf("AI 分析失败: %w", err)
+	}
+	return validateSearchResults(raw, candidates), candidates, nil
+}
+
+// validateSearchResults 只保留候选集合中存在的项目 (LLM 可能编造 ID 或返回项目名)，
+// 回填项目信息、去重、把相关度限制在 0-100，并按相关度从高到低排序
+func validateSearchResults(raw []*domain.SearchResult, candidates []*domain.Repo) []*domain.SearchResult {
+	byID := make(map[string]*domain.Repo, len(candidates))
+	byName := make(map[string]*domain.Repo, len(candidates))
+	for _, repo := range candidates {
+		byID[repo.ID] = repo
+		byName[strings.ToLower(repo.Name)] = repo
+	}
+
+	results := make([]*domain.SearchResult, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, r := range raw {
+		if r == nil {
+			continue
+		}
+		repo, ok := byID[r.RepoID]
+		if !ok {
+			repo, ok = byName[strings.ToLower(r.RepoID)]
+		}
+		if !ok {
+			log.Printf("⚠️ 忽略 AI 返回的未知项目: %q", r.RepoID)
+			continue
+		}
+		if seen[repo.ID] {
+			continue
+		}
+		seen[repo.ID] = true
+
+		result := *r
+		result.RepoID = repo.ID
+		result.Repo = repo
+		result.Relevance = max(0, min(100, r.Relevance))
+		results = append(results, &result)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Relevance > results[j].Relevance })
+	return results
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/adapter/repository"
+	"github-gold-miner/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordEmbedder 按关键词是否出现生成向量，便于构造确定的相似度
+type keywordEmbedder struct {
+	vocab []string
+	calls int
+	texts int
+}
+
+func (e *keywordEmbedder) Model() string { return "keyword" }
+
+func (e *keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	e.texts += len(texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(e.vocab)+1)
+		v[len(e.vocab)] = 0.01 // 避免零向量
+		for j, word := range e.vocab {
+			if strings.Contains(strings.ToLower(text), word) {
+				v[j] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func TestSearchService_VectorRetrieval(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+	repoStore := repository.NewMemoryRepo()
+
+	// 最相关的项目最早入库，超出 GetAllCandidates 的 100 条上限
+	require.NoError(t, repoStore.Save(ctx, &domain.Repo{ID: "github-rust", Name: "x/rustfmt-ai", Description: "Rust formatter", CreatedAt: base}))
+	for i := 0; i < 120; i++ {
+		require.NoError(t, repoStore.Save(ctx, &domain.Repo{
+			ID:          fmt.Sprintf("github-%03d", i),
+			Name:        fmt.Sprintf("x/tool-%03d", i),
+			Description: "Python notebook helper",
+			CreatedAt:   base.Add(time.Duration(i+1) * time.Hour),
+		}))
+	}
+
+	embedder := &keywordEmbedder{vocab: []string{"rust", "python", "notebook"}}
+	appraiser := new(MockAppraiser)
+	appraiser.On("SemanticSearch", mock.Anything, mock.MatchedBy(func(repos []*domain.Repo) bool {
+		return len(repos) == 5 && repos[0].ID == "github-rust"
+	}), "rust").Return([]*domain.SearchResult{{RepoID: "github-rust", Reason: "Rust 格式化", Relevance: 95, Action: "试用"}}, nil)
+
+	svc := NewSearchService(repoStore, appraiser, embedder, SearchConfig{TopK: 5, IndexBatch: 50})
+	require.True(t, svc.VectorEnabled())
+
+	// 搜索只读取已有的向量，不补齐索引
+	results, candidates, err := svc.Search(ctx, "rust")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Empty(t, candidates)
+	assert.Equal(t, 1, embedder.calls)
+
+	// 121 个项目分 3 批向量化
+	indexed, err := svc.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 121, indexed)
+	assert.Equal(t, 4, embedder.calls)
+
+	results, candidates, err = svc.Search(ctx, "rust")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "x/rustfmt-ai", results[0].Repo.Name)
+	require.Len(t, candidates, 5)
+	assert.Equal(t, "github-rust", candidates[0].ID)
+	assert.Equal(t, 5, embedder.calls)
+	assert.Equal(t, 123, embedder.texts)
+
+	// 已有向量的项目不会重复向量化
+	indexed, err = svc.Index(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, indexed)
+
+	// 重新评估后评价变化，只为该项目重新生成向量
+	require.NoError(t, repoStore.Save(ctx, &domain.Repo{ID: "github-000", Name: "x/tool-000", Description: "Python notebook helper", LLMReview: "Rust 重写", CreatedAt: base.Add(time.Hour)}))
+	indexed, err = svc.Index(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, indexed)
+	appraiser.AssertExpectations(t)
+}
+
+func TestMiningService_IndexAfterStore(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryRepo()
+	embedder := &keywordEmbedder{vocab: []string{"agent"}}
+	service := NewMiningService(new(MockScouter), new(MockFilter), new(MockAnalyzer), store, new(MockAppraiser), new(MockNotifier))
+	service.SetIndexer(NewSearchService(store, new(MockAppraiser), embedder, SearchConfig{}))
+
+	// 入库后立即补齐向量，搜索时不再需要向量化
+	saved, err := service.storeStage(ctx, []*domain.Repo{{ID: "github-1", Name: "acme/agent"}}, &domain.StageReport{})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, 1, embedder.texts)
+	pending, err := store.ReposNeedingEmbedding(ctx, embedder.Model(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// 试运行不写数据库，也不补齐向量
+	service.EnableDryRun()
+	_, err = service.storeStage(ctx, []*domain.Repo{{ID: "github-2", Name: "acme/agent-2"}}, &domain.StageReport{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, embedder.texts)
+}
+
+func TestSearchService_FallbackWithoutEmbedder(t *testing.T) {
+	ctx := context.Background()
+	candidates := []*domain.Repo{{ID: "github-1", Name: "test/one"}}
+
+	repoStore := new(MockRepository)
+	repoStore.On("GetAllCandidates", mock.Anything).Return(candidates, nil)
+	appraiser := new(MockAppraiser)
+	appraiser.On("SemanticSearch", mock.Anything, candidates, "代码生成").Return([]*domain.SearchResult{{RepoID: "github-1", Relevance: 80}}, nil)
+
+	svc := NewSearchService(repoStore, appraiser, nil, SearchConfig{})
+	assert.False(t, svc.VectorEnabled())
+
+	results, got, err := svc.Search(ctx, "代码生成")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, candidates[0], results[0].Repo)
+	assert.Equal(t, candidates, got)
+	repoStore.AssertExpectations(t)
+}
+
+func TestSearchService_EmptyDatabase(t *testing.T) {
+	appraiser := new(MockAppraiser)
+	svc := NewSearchService(repository.NewMemoryRepo(), appraiser, &keywordEmbedder{vocab: []string{"go"}}, SearchConfig{})
+
+	results, candidates, err := svc.Search(context.Background(), "go")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Empty(t, candidates)
+	appraiser.AssertNotCalled(t, "SemanticSearch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestValidateSearchResults(t *testing.T) {
+	candidates := []*domain.Repo{
+		{ID: "github-1", Name: "ai/coder"},
+		{ID: "github-2", Name: "ai/reviewer"},
+	}
+	raw := []*domain.SearchResult{
+		{RepoID: "github-404", Reason: "编造的项目", Relevance: 99},
+		{RepoID: "github-1", Reason: "匹配", Relevance: 70},
+		{RepoID: "AI/Reviewer", Reason: "返回了项目名", Relevance: 150},
+		{RepoID: "github-1", Reason: "重复", Relevance: 60},
+		nil,
+	}
+
+	results := validateSearchResults(raw, candidates)
+	require.Len(t, results, 2)
+	// 项目名被映射回 ID，相关度被限制在 100 以内并排在前面
+	assert.Equal(t, "github-2", results[0].RepoID)
+	assert.Equal(t, 100, results[0].Relevance)
+	assert.Same(t, candidates[1], results[0].Repo)
+	assert.Equal(t, "github-1", results[1].RepoID)
+	assert.Equal(t, "匹配", results[1].Reason)
+	// 不修改 AI 返回的原始结果
+	assert.Equal(t, "AI/Reviewer", raw[2].RepoID)
+}
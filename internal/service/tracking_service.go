@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+)
+
+// BreakoutRule 爆发通知的判定阈值
+type BreakoutRule struct {
+	Milestones []int   // Star 里程碑，越过时发送爆发通知
+	MinGrowth  float64 // 越过里程碑时相对推送时 Star 数的最小增长倍数，避免推送时已接近里程碑的项目刷屏
+}
+
+// TrackingConfig 推送后追踪配置
+type TrackingConfig struct {
+	BreakoutRule
+	Window        time.Duration              // 只追踪这段时间内推送过的项目
+	CheckInterval time.Duration              // 同一个项目两次检查之间的最小间隔
+	Retract       []domain.TrackingEventType // 发送撤回通知的状态变化，不在其中的只更新追踪状态
+	Profiles      map[string]BreakoutRule    // 按挖矿方向覆盖爆发阈值，项目使用第一个有配置的发现方向
+}
+
+// DefaultTrackingConfig 默认追踪配置：追踪 30 天，每 6 小时检查一次，归档和删除都发送撤回通知
+func DefaultTrackingConfig() TrackingConfig {
+	return TrackingConfig{
+		BreakoutRule: BreakoutRule{
+			Milestones: []int{1000, 5000, 10000, 50000},
+			MinGrowth:  2,
+		},
+		Window:        30 * 24 * time.Hour,
+		CheckInterval: 6 * time.Hour,
+		Retract:       []domain.TrackingEventType{domain.EventArchived, domain.EventDeleted},
+	}
+}
+
+// TrackingService 定期复查已推送的项目，发现爆发或归档/删除时发送后续通知
+type TrackingService struct {
+	inspector port.Inspector
+	repoStore port.Repository
+	notifier  port.Notifier
+	cfg       TrackingConfig
+	nowFunc   func() time.Time
+}
+
+// NewTrackingService 创建追踪服务
+func NewTrackingService(inspector port.Inspector, repoStore port.Repository, notifier port.Notifier, cfg TrackingConfig) *TrackingService {
+	cfg.Milestones = sortedMilestones(cfg.Milestones)
+	profiles := make(map[string]BreakoutRule, len(cfg.Profiles))
+	for name, rule := range cfg.Profiles {
+		rule.Milestones = sortedMilestones(rule.Milestones)
+		profiles[name] = rule
+	}
+	cfg.Profiles = profiles
+
+	return &TrackingService{
+		inspector: inspector,
+		repoStore: repoStore,
+		notifier:  notifier,
+		cfg:       cfg,
+		nowFunc:   time.Now,
+	}
+}
+
+// Run 执行一轮追踪检查，返回本轮产生的事件
+func (t *TrackingService) Run(ctx context.Context) ([]*domain.TrackingEvent, error) {
+	now := t.nowFunc()
+	repos, err := t.repoStore.GetNotifiedRepos(ctx, now.Add(-t.cfg.Window))
+	if err != nil {
+		return nil, fmt.Errorf("读取已推送项目失败: %w", err)
+	}
+
+	fmt.Printf("🔭 [追踪] 共 %d 个已推送项目待复查\n", len(repos))
+
+	var events []*domain.TrackingEvent
+	for _, repo := range repos {
+		select {
+		case <-ctx.Done():
+			return events, ctx.Err()
+		default:
+		}
+
+		if repo.LastCheckedAt != nil && now.Sub(*repo.LastCheckedAt) < t.cfg.CheckInterval {
+			continue
+		}
+
+		event, err := t.check(ctx, repo, now)
+		if err != nil {
+			log.Printf("⚠️ [追踪] 检查项目 %s 失败: %v", repo.Name, err)
+			continue
+		}
+		if event != nil {
+			events = append(events, event)
+		}
+	}
+
+	fmt.Printf("🔭 [追踪] 本轮产生 %d 个更新事件\n", len(events))
+	return events, nil
+}
+
+// check 复查单个项目，必要时发送通知并更新追踪状态
+func (t *TrackingService) check(ctx context.Context, repo *domain.Repo, now time.Time) (*domain.TrackingEvent, error) {
+	if repo.NotifiedStars == 0 {
+		// 兼容追踪功能上线前推送的项目：首次检查时把当前 Star 数固定为基线并随追踪状态保存，
+		// 之后每次检查都会更新 Stars，不能再用它作为基线
+		repo.NotifiedStars = repo.Stars
+	}
+	baseline := repo.NotifiedStars
+
+	latest, err := t.inspector.GetRepo(ctx, repo.Name)
+	if err != nil && !errors.Is(err, port.ErrNotFound) {
+		return nil, err
+	}
+
+	var event *domain.TrackingEvent
+	if errors.Is(err, port.ErrNotFound) {
+		event = &domain.TrackingEvent{Type: domain.EventDeleted, CurrentStars: repo.Stars}
+	} else {
+		if err := t.repoStore.AddSnapshot(ctx, &domain.StarSnapshot{RepoID: repo.ID, Stars: latest.Stars, CapturedAt: now}); err != nil {
+			log.Printf("⚠️ [追踪] 记录 %s 的 Star 快照失败: %v", repo.Name, err)
+		}
+
+		switch {
+		case latest.Archived && !repo.Archived:
+			event = &domain.TrackingEvent{Type: domain.EventArchived, CurrentStars: latest.Stars}
+		default:
+			if milestone := t.crossedMilestone(t.rule(repo), baseline, repo.TrackedMilestone, latest.Stars); milestone > 0 {
+				event = &domain.TrackingEvent{Type: domain.EventBreakout, CurrentStars: latest.Stars, Milestone: milestone}
+			}
+		}
+		repo.Stars = latest.Stars
+		repo.Archived = latest.Archived
+	}
+	if event != nil && event.IsRetraction() && !slices.Contains(t.cfg.Retract, event.Type) {
+		// 未开启该类撤回通知：只记录状态变化，不通知也不标记为已撤回
+		event = nil
+	}
+
+	if event != nil {
+		event.RepoID = repo.ID
+		event.PreviousStars = baseline
+		event.DetectedAt = now
+
+		if err := t.notify(ctx, repo, event); errors.Is(err, port.ErrPartiallyDelivered) {
+			// 已送达部分渠道，推进追踪状态，避免重复通知已送达的渠道
+			log.Printf("⚠️ [追踪] %s 的 %s 通知部分渠道失败: %v", repo.Name, event.Type, err)
+		} else if err != nil {
+			// 不推进追踪状态，下一轮重试
+			return nil, fmt.Errorf("发送追踪通知失败: %w", err)
+		}
+		if event.IsRetraction() {
+			repo.Retracted = true
+		} else {
+			repo.TrackedMilestone = event.Milestone
+		}
+		fmt.Printf("📣 [追踪] %s: %s (%d → %d stars)\n", repo.Name, event.Type, event.PreviousStars, event.CurrentStars)
+	}
+
+	repo.LastCheckedAt = &now
+	if err := t.repoStore.UpdateTracking(ctx, repo); err != nil {
+		return event, fmt.Errorf("更新追踪状态失败: %w", err)
+	}
+	return event, nil
+}
+
+// rule 返回项目适用的爆发阈值：第一个配置了阈值的发现方向，否则使用全局阈值
+func (t *TrackingService) rule(repo *domain.Repo) BreakoutRule {
+	for _, profile := range repo.Profiles {
+		if rule, ok := t.cfg.Profiles[profile]; ok {
+			return rule
+		}
+	}
+	return t.cfg.BreakoutRule
+}
+
+// crossedMilestone 返回新越过的最高里程碑，没有则返回 0
+func (t *TrackingService) crossedMilestone(rule BreakoutRule, baseline, announced, stars int) int {
+	if float64(stars) < float64(baseline)*rule.MinGrowth {
+		return 0
+	}
+	crossed := 0
+	for _, m := range rule.Milestones {
+		if stars >= m && m > baseline && m > announced {
+			crossed = m
+		}
+	}
+	return crossed
+}
+
+// sortedMilestones 返回排好序的里程碑副本
+func sortedMilestones(milestones []int) []int {
+	sorted := append([]int(nil), milestones...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func (t *TrackingService) notify(ctx context.Context, repo *domain.Repo, event *domain.TrackingEvent) error {
+	updater, ok := t.notifier.(port.UpdateNotifier)
+	if !ok {
+		log.Printf("⚠️ [追踪] 通知渠道不支持追踪更新，跳过 %s 的 %s 通知", repo.Name, event.Type)
+		return nil
+	}
+	return updater.NotifyUpdate(ctx, repo, event)
+}
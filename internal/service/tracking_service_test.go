@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github-gold-miner/internal/domain"
+	"github-gold-miner/internal/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackingService_Run(t *testing.T) {
+	now := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+	notifiedAt := now.Add(-72 * time.Hour)
+	recentCheck := now.Add(-time.Hour)
+
+	newRepo := func(id string, stars int) *domain.Repo {
+		return &domain.Repo{ID: id, Name: "test/" + id, Stars: stars, NotifiedStars: stars, AlreadyNotified: true, NotifiedAt: &notifiedAt}
+	}
+
+	tests := []struct {
+		name          string
+		repo          *domain.Repo
+		latest        *domain.Repo
+		inspectErr    error
+		notifyErr     error
+		expectedEvent domain.TrackingEventType
+		milestone     int
+	}{
+		{name: "越过里程碑", repo: newRepo("breakout", 300), latest: &domain.Repo{Stars: 1200}, expectedEvent: domain.EventBreakout, milestone: 1000},
+		{name: "一次越过多个里程碑只报最高的", repo: newRepo("rocket", 400), latest: &domain.Repo{Stars: 6000}, expectedEvent: domain.EventBreakout, milestone: 5000},
+		{name: "增长倍数不足", repo: newRepo("slow", 900), latest: &domain.Repo{Stars: 1100}},
+		{name: "里程碑已通知过", repo: func() *domain.Repo { r := newRepo("seen", 300); r.TrackedMilestone = 1000; return r }(), latest: &domain.Repo{Stars: 1500}},
+		{name: "仓库被归档", repo: newRepo("archived", 300), latest: &domain.Repo{Stars: 320, Archived: true}, expectedEvent: domain.EventArchived},
+		{name: "仓库被删除", repo: newRepo("deleted", 300), inspectErr: fmt.Errorf("GitHub API 404: %w", port.ErrNotFound), expectedEvent: domain.EventDeleted},
+		{name: "最近检查过则跳过", repo: func() *domain.Repo { r := newRepo("recent", 300); r.LastCheckedAt = &recentCheck; return r }()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspector := new(MockInspector)
+			repoStore := new(MockRepository)
+			notifier := new(MockNotifier)
+
+			repoStore.On("GetNotifiedRepos", mock.Anything, now.Add(-30*24*time.Hour)).Return([]*domain.Repo{tt.repo}, nil)
+			if tt.latest != nil || tt.inspectErr != nil {
+				inspector.On("GetRepo", mock.Anything, tt.repo.Name).Return(tt.latest, tt.inspectErr)
+				repoStore.On("UpdateTracking", mock.Anything, tt.repo).Return(nil)
+			}
+			if tt.latest != nil {
+				repoStore.On("AddSnapshot", mock.Anything, mock.MatchedBy(func(s *domain.StarSnapshot) bool {
+					return s.RepoID == tt.repo.ID && s.Stars == tt.latest.Stars
+				})).Return(nil)
+			}
+			if tt.expectedEvent != "" {
+				notifier.On("NotifyUpdate", mock.Anything, tt.repo, mock.Anything).Return(nil)
+			}
+
+			svc := NewTrackingService(inspector, repoStore, notifier, DefaultTrackingConfig())
+			svc.nowFunc = func() time.Time { return now }
+
+			events, err := svc.Run(context.Background())
+			require.NoError(t, err)
+
+			if tt.expectedEvent == "" {
+				assert.Empty(t, events)
+			} else {
+				require.Len(t, events, 1)
+				assert.Equal(t, tt.expectedEvent, events[0].Type)
+				assert.Equal(t, tt.milestone, events[0].Milestone)
+				assert.Equal(t, tt.repo.ID, events[0].RepoID)
+				assert.Equal(t, tt.repo.NotifiedStars, events[0].PreviousStars)
+				assert.Equal(t, events[0].IsRetraction(), tt.repo.Retracted)
+			}
+
+			inspector.AssertExpectations(t)
+			repoStore.AssertExpectations(t)
+			notifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTrackingService_NotifyFailureKeepsState(t *testing.T) {
+	now := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+	repo := &domain.Repo{ID: "test/repo", Name: "test/repo", Stars: 300, NotifiedStars: 300, AlreadyNotified: true}
+
+	inspector := new(MockInspector)
+	repoStore := new(MockRepository)
+	notifier := new(MockNotifier)
+
+	repoStore.On("GetNotifiedRepos", mock.Anything, mock.Anything).Return([]*domain.Repo{repo}, nil)
+	repoStore.On("AddSnapshot", mock.Anything, mock.Anything).Return(nil)
+	inspector.On("GetRepo", mock.Anything, repo.Name).Return(&domain.Repo{Stars: 1500}, nil)
+	notifier.On("NotifyUpdate", mock.Anything, repo, mock.Anything).Return(errors.New("webhook 403"))
+
+	svc := NewTrackingService(inspector, repoStore, notifier, DefaultTrackingConfig())
+	svc.nowFunc = func() time.Time { return now }
+
+	events, err := svc.Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// 通知失败时不推进里程碑和检查时间，下一轮会重试
+	assert.Equal(t, 0, repo.TrackedMilestone)
+	assert.Nil(t, repo.LastCheckedAt)
+	repoStore.AssertNotCalled(t, "UpdateTracking", mock.Anything, mock.Anything)
+}
+
+func TestTrackingService_LegacyBaseline(t *testing.T) {
+	now := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+	// 追踪功能上线前推送的项目没有 NotifiedStars
+	repo := &domain.Repo{ID: "test/legacy", Name: "test/legacy", Stars: 300, AlreadyNotified: true}
+
+	inspector := new(MockInspector)
+	repoStore := new(MockRepository)
+	notifier := new(MockNotifier)
+
+	repoStore.On("AddSnapshot", mock.Anything, mock.Anything).Return(nil)
+	repoStore.On("UpdateTracking", mock.Anything, repo).Return(nil)
+	inspector.On("GetRepo", mock.Anything, repo.Name).Return(&domain.Repo{Stars: 550}, nil).Once()
+	inspector.On("GetRepo", mock.Anything, repo.Name).Return(&domain.Repo{Stars: 1050}, nil).Once()
+	notifier.On("NotifyUpdate", mock.Anything, repo, mock.Anything).Return(nil)
+
+	svc := NewTrackingService(inspector, repoStore, notifier, DefaultTrackingConfig())
+
+	event, err := svc.check(context.Background(), repo, now)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+	assert.Equal(t, 300, repo.NotifiedStars)
+	assert.Equal(t, 550, repo.Stars)
+
+	// 基线固定为首次检查时的 300，而不是上一次检查更新后的 550
+	event, err = svc.check(context.Background(), repo, now.Add(7*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, domain.EventBreakout, event.Type)
+	assert.Equal(t, 300, event.PreviousStars)
+	assert.Equal(t, 1000, event.Milestone)
+}
+
+func TestTrackingService_ProfileRule(t *testing.T) {
+	now := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+
+	cfg := DefaultTrackingConfig()
+	cfg.Profiles = map[string]BreakoutRule{"rust": {Milestones: []int{500, 300}, MinGrowth: 1.5}}
+
+	inspector := new(MockInspector)
+	repoStore := new(MockRepository)
+	notifier := new(MockNotifier)
+	svc := NewTrackingService(inspector, repoStore, notifier, cfg)
+
+	rust := &domain.Repo{ID: "test/rust", Name: "test/rust", Stars: 200, NotifiedStars: 200, Profiles: []string{"agents", "rust"}}
+	global := &domain.Repo{ID: "test/global", Name: "test/global", Stars: 200, NotifiedStars: 200, Profiles: []string{"agents"}}
+
+	repoStore.On("AddSnapshot", mock.Anything, mock.Anything).Return(nil)
+	repoStore.On("UpdateTracking", mock.Anything, mock.Anything).Return(nil)
+	inspector.On("GetRepo", mock.Anything, mock.Anything).Return(&domain.Repo{Stars: 520}, nil)
+	notifier.On("NotifyUpdate", mock.Anything, rust, mock.Anything).Return(nil)
+
+	// rust 方向的里程碑更低、倍数要求更小
+	event, err := svc.check(context.Background(), rust, now)
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, 500, event.Milestone)
+
+	// 其他方向沿用全局阈值，520 还没到 1000
+	event, err = svc.check(context.Background(), global, now)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+	notifier.AssertNumberOfCalls(t, "NotifyUpdate", 1)
+}
+
+func TestTrackingService_RetractDisabled(t *testing.T) {
+	now := time.Date(2025, 12, 27, 10, 30, 0, 0, time.UTC)
+	repo := &domain.Repo{ID: "test/repo", Name: "test/repo", Stars: 300, NotifiedStars: 300, AlreadyNotified: true}
+
+	cfg := DefaultTrackingConfig()
+	cfg.Retract = []domain.TrackingEventType{domain.EventDeleted}
+
+	inspector := new(MockInspector)
+	repoStore := new(MockRepository)
+	notifier := new(MockNotifier)
+
+	repoStore.On("AddSnapshot", mock.Anything, mock.Anything).Return(nil)
+	repoStore.On("UpdateTracking", mock.Anything, repo).Return(nil)
+	inspector.On("GetRepo", mock.Anything, repo.Name).Return(&domain.Repo{Stars: 310, Archived: true}, nil)
+
+	svc := NewTrackingService(inspector, repoStore, notifier, cfg)
+
+	// 未开启归档撤回：不通知，只记录归档状态
+	event, err := svc.check(context.Background(), repo, now)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+	assert.True(t, repo.Archived)
+	assert.False(t, repo.Retracted)
+	notifier.AssertNotCalled(t, "NotifyUpdate", mock.Anything, mock.Anything, mock.Anything)
+}